@@ -1,24 +1,208 @@
 package api
 
 import (
+	"bytes"
+	"container/list"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
 var ErrBadStatusCode = fmt.Errorf("bad status code")
 
+var (
+	// ErrResponseTooLarge is returned when the body of a response is larger than the maximum response size.
+	ErrResponseTooLarge = errors.New("response too large")
+
+	// ErrMaxDepthExceeded is returned when the JSON body of a response is nested deeper than the maximum decode depth.
+	ErrMaxDepthExceeded = errors.New("maximum JSON nesting depth exceeded")
+
+	// ErrNotJSON is returned when the body of a response is not JSON, e.g. an HTML error page returned by a proxy.
+	ErrNotJSON = errors.New("response is not JSON")
+)
+
+const (
+	// DefaultMaxResponseBytes is the default maximum size of the body of a response.
+	DefaultMaxResponseBytes = 64 << 20
+
+	// DefaultMaxDecodeDepth is the default maximum nesting depth of the JSON body of a response.
+	DefaultMaxDecodeDepth = 256
+
+	// notJSONSnippetLength is the maximum length of the beginning of a non-JSON body included in ErrNotJSON errors.
+	notJSONSnippetLength = 100
+)
+
+// BadStatusCodeError is the error returned for responses with a status code other than 200.
+// It wraps ErrBadStatusCode.
+type BadStatusCodeError struct {
+	StatusCode int
+}
+
+func (e BadStatusCodeError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrBadStatusCode, e.StatusCode)
+}
+
+func (e BadStatusCodeError) Unwrap() error {
+	return ErrBadStatusCode
+}
+
+// StatusCode returns the status code of the given BadStatusCodeError, or 0 if err is not a BadStatusCodeError.
+func StatusCode(err error) int {
+	var statusErr BadStatusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
 type Client struct {
 	BaseURL string
 
+	credentials *Credentials
+
+	httpClient *http.Client
+
+	hooks []Hooks
+
+	// maxResponseBytes is the maximum size of the body of a response, 0 for no limit.
+	maxResponseBytes int64
+
+	// maxDecodeDepth is the maximum nesting depth of the JSON body of a response, 0 for no limit.
+	maxDecodeDepth int
+
+	// responses are the responses with validators of the conditional requests, if enabled.
+	responses *responseCache
+}
+
+// cachedResponse is a response with validators, to send conditional requests for the same URL.
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	contentType  string
+	body         []byte
+}
+
+// maxCachedResponses is the maximum number of responses kept for the conditional requests.
+const maxCachedResponses = 1000
+
+// responseCache stores the responses of the conditional requests, by URL, forgetting the least recently used ones
+// beyond its size. It's safe for concurrent use.
+type responseCache struct {
+	mu   sync.Mutex
+	size int
+	// order has the URLs of the responses, from the most to the least recently used.
+	order     *list.List
+	responses map[string]*list.Element
+}
+
+// cachedEntry is an element of responseCache.order.
+type cachedEntry struct {
+	url  string
+	resp cachedResponse
+}
+
+// newResponseCache returns a new responseCache keeping at most size responses.
+func newResponseCache(size int) *responseCache {
+	return &responseCache{size: size, order: list.New(), responses: map[string]*list.Element{}}
+}
+
+func (c *responseCache) get(url string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.responses[url]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cachedEntry).resp, true
+}
+
+func (c *responseCache) put(url string, resp cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.responses[url]; ok {
+		e.Value.(*cachedEntry).resp = resp
+		c.order.MoveToFront(e)
+		return
+	}
+	c.responses[url] = c.order.PushFront(&cachedEntry{url: url, resp: resp})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.responses, oldest.Value.(*cachedEntry).url)
+	}
+}
+
+func (c *responseCache) delete(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.responses[url]; ok {
+		c.order.Remove(e)
+		delete(c.responses, url)
+	}
+}
+
+// Hooks are functions called for each request made by a Client, to observe the traffic
+// (e.g.: for metrics, tracing or rate limiting). Both functions are optional.
+type Hooks struct {
+	// OnRequest is called before sending the request.
+	OnRequest func(req *http.Request)
+
+	// OnResponse is called when the request is done, with the status code (0 if no response has been received),
+	// the time elapsed since the request has been sent, and the error if the request failed.
+	OnResponse func(req *http.Request, statusCode int, duration time.Duration, err error)
+}
+
+// Credentials are the credentials used by a Client for authentication.
+// They can be replaced while the Client is used, e.g. to rotate the token. They are safe for concurrent use.
+type Credentials struct {
+	mu sync.RWMutex
+
 	token string
 
 	basicAuthUser     string
 	basicAuthPassword string
+}
 
-	httpClient *http.Client
+// NewCredentials returns the Credentials of the given token.
+// The token can be an API key, or it can be in the form of "username:password" for basic authentication.
+func NewCredentials(token string) *Credentials {
+	c := &Credentials{}
+	c.Set(token)
+	return c
+}
+
+// Set replaces the credentials with the given token, in the same form as for NewCredentials.
+func (c *Credentials) Set(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.basicAuthUser, c.basicAuthPassword = "", "", ""
+	if user, password, ok := strings.Cut(token, ":"); ok {
+		c.basicAuthUser, c.basicAuthPassword = user, password
+		return
+	}
+	c.token = token
+}
+
+// authenticate sets the authentication header of the given request.
+func (c *Credentials) authenticate(req *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	// There is two cases, either we have provided a service account's Token or
+	// the basicAuth. As the token is the recommended way to interact with the
+	// API let's use it first
+	if c.token != "" {
+		req.Header.Add("Authorization", "Bearer "+c.token)
+	} else if c.basicAuthUser != "" && c.basicAuthPassword != "" {
+		req.SetBasicAuth(c.basicAuthUser, c.basicAuthPassword)
+	}
 }
 
 type ClientOption func(*Client)
@@ -28,14 +212,14 @@ type ClientOption func(*Client)
 // The token can be an API key, or it can be in the form of "username:password"
 // for basic authentication.
 func WithAuthentication(token string) ClientOption {
+	return WithCredentials(NewCredentials(token))
+}
+
+// WithCredentials returns a ClientOption that sets the credentials to be used for authentication.
+// Unlike WithAuthentication, the credentials can be replaced later with Credentials.Set.
+func WithCredentials(credentials *Credentials) ClientOption {
 	return func(cl *Client) {
-		auth := strings.SplitN(token, ":", 2)
-		if len(auth) == 2 {
-			cl.basicAuthUser = auth[0]
-			cl.basicAuthPassword = auth[1]
-			return
-		}
-		cl.token = token
+		cl.credentials = credentials
 	}
 }
 
@@ -46,11 +230,47 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithHooks returns a ClientOption that adds hooks called for each request.
+// It can be passed multiple times, the hooks are called in the order they have been added.
+func WithHooks(hooks Hooks) ClientOption {
+	return func(cl *Client) {
+		cl.hooks = append(cl.hooks, hooks)
+	}
+}
+
+// WithMaxResponseBytes returns a ClientOption that sets the maximum size of the body of a response
+// (DefaultMaxResponseBytes by default). Larger responses fail with ErrResponseTooLarge. 0 disables the limit.
+func WithMaxResponseBytes(n int64) ClientOption {
+	return func(cl *Client) {
+		cl.maxResponseBytes = n
+	}
+}
+
+// WithMaxDecodeDepth returns a ClientOption that sets the maximum nesting depth of the JSON body of a response
+// (DefaultMaxDecodeDepth by default). Deeper responses fail with ErrMaxDepthExceeded. 0 disables the limit.
+func WithMaxDecodeDepth(n int) ClientOption {
+	return func(cl *Client) {
+		cl.maxDecodeDepth = n
+	}
+}
+
+// WithConditionalRequests returns a ClientOption that makes RequestConditional store the responses
+// with validators (ETag or Last-Modified header), and send them with the next requests to the same URL,
+// so the server doesn't send unchanged responses again. The responses are kept in memory, and shared by the copies of the Client:
+// the least recently used ones are forgotten beyond maxCachedResponses.
+func WithConditionalRequests() ClientOption {
+	return func(cl *Client) {
+		cl.responses = newResponseCache(maxCachedResponses)
+	}
+}
+
 // NewClient returns a new Client with the given baseURL and options.
 func NewClient(baseURL string, opts ...ClientOption) Client {
 	client := Client{
-		BaseURL:    baseURL,
-		httpClient: http.DefaultClient,
+		BaseURL:          baseURL,
+		httpClient:       http.DefaultClient,
+		maxResponseBytes: DefaultMaxResponseBytes,
+		maxDecodeDepth:   DefaultMaxDecodeDepth,
 	}
 	for _, opt := range opts {
 		opt(&client)
@@ -62,42 +282,228 @@ func (cl Client) urlFor(s string) string {
 	return cl.BaseURL + "/" + s
 }
 
-func (cl Client) newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+func (cl Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	if method == "" {
 		method = http.MethodGet
 	}
-	req, err := http.NewRequestWithContext(ctx, method, cl.urlFor(url), nil)
+	req, err := http.NewRequestWithContext(ctx, method, cl.urlFor(url), body)
 	if err != nil {
 		return nil, err
 	}
-	// There is two cases, either we have provided a service account's Token or
-	// the basicAuth. As the token is the recommended way to interact with the
-	// API let's use it first
-	if cl.token != "" {
-		req.Header.Add("Authorization", "Bearer "+cl.token)
-	} else if cl.basicAuthUser != "" && cl.basicAuthPassword != "" {
-		req.SetBasicAuth(cl.basicAuthUser, cl.basicAuthPassword)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cl.credentials != nil {
+		cl.credentials.authenticate(req)
 	}
 	return req, err
 }
 
 func (cl Client) Request(ctx context.Context, method, url string, out interface{}) error {
-	req, err := cl.newRequest(ctx, method, url)
+	return cl.RequestWithBody(ctx, method, url, nil, out)
+}
+
+// RequestWithBody sends a request like Request, with the given JSON body.
+func (cl Client) RequestWithBody(ctx context.Context, method, url string, body io.Reader, out interface{}) error {
+	req, err := cl.newRequest(ctx, method, url, body)
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
 	}
-	resp, err := cl.httpClient.Do(req)
+	resp, err := cl.do(req)
 	if err != nil {
 		return fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: %d", ErrBadStatusCode, resp.StatusCode)
+	// Resources created by the App Platform APIs are returned with a 201 status code
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return BadStatusCodeError{StatusCode: resp.StatusCode}
+	}
+	if out != nil {
+		if err := cl.decode(resp, out); err != nil {
+			return fmt.Errorf("decode: %w", err)
+		}
+	}
+	return nil
+}
+
+// RequestConditional sends a GET request like Request. If the Client has been created with WithConditionalRequests,
+// the validators of the previous response for the same URL are sent (If-None-Match and If-Modified-Since headers),
+// and the body of the previous response is decoded if the server replies that it's not modified (304).
+func (cl Client) RequestConditional(ctx context.Context, url string, out interface{}) error {
+	if cl.responses == nil {
+		return cl.Request(ctx, http.MethodGet, url, out)
+	}
+	req, err := cl.newRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	key := req.URL.String()
+	cached, ok := cl.responses.get(key)
+	if ok {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+	resp, err := cl.do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+	case resp.StatusCode == http.StatusOK:
+		cached = cachedResponse{
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+			contentType:  resp.Header.Get("Content-Type"),
+		}
+		if cached.body, err = cl.readBody(resp); err != nil {
+			return fmt.Errorf("decode: %w", err)
+		}
+		if cached.etag != "" || cached.lastModified != "" {
+			cl.responses.put(key, cached)
+		} else {
+			cl.responses.delete(key)
+		}
+	default:
+		return BadStatusCodeError{StatusCode: resp.StatusCode}
 	}
 	if out != nil {
-		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if err := cl.decodeBody(cached.contentType, cached.body, out); err != nil {
 			return fmt.Errorf("decode: %w", err)
 		}
 	}
 	return nil
 }
+
+// RequestBytes sends a request like Request, but returns the raw body of the response instead of decoding it,
+// for the responses that are not JSON (e.g.: plugin assets). The maximum response size is enforced.
+func (cl Client) RequestBytes(ctx context.Context, method, url string) ([]byte, error) {
+	req, err := cl.newRequest(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := cl.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, BadStatusCodeError{StatusCode: resp.StatusCode}
+	}
+	return cl.readBody(resp)
+}
+
+// readBody reads the body of the given response, enforcing the maximum response size.
+func (cl Client) readBody(resp *http.Response) ([]byte, error) {
+	body := io.Reader(resp.Body)
+	if cl.maxResponseBytes > 0 {
+		// Read one more byte to tell apart bodies of exactly the maximum size from larger ones
+		body = io.LimitReader(resp.Body, cl.maxResponseBytes+1)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if cl.maxResponseBytes > 0 && int64(len(b)) > cl.maxResponseBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrResponseTooLarge, cl.maxResponseBytes)
+	}
+	return b, nil
+}
+
+// decode decodes the JSON body of the given response into out, enforcing the limits of the client.
+func (cl Client) decode(resp *http.Response, out interface{}) error {
+	b, err := cl.readBody(resp)
+	if err != nil {
+		return err
+	}
+	return cl.decodeBody(resp.Header.Get("Content-Type"), b, out)
+}
+
+// decodeBody decodes the given JSON body, with the given content type, into out, enforcing the limits of the client.
+func (cl Client) decodeBody(contentType string, b []byte, out interface{}) error {
+	if err := checkJSON(contentType, b); err != nil {
+		return err
+	}
+	if cl.maxDecodeDepth > 0 {
+		if err := checkDepth(b, cl.maxDecodeDepth); err != nil {
+			return err
+		}
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(out)
+}
+
+// checkJSON returns an ErrNotJSON error if the body, with the given content type, is clearly not JSON
+// (HTML content type or body starting with "<"), including the beginning of the body to help troubleshooting.
+func checkJSON(contentType string, b []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(b)
+	if mediaType != "text/html" && !bytes.HasPrefix(trimmed, []byte("<")) {
+		return nil
+	}
+	snippet := trimmed
+	if len(snippet) > notJSONSnippetLength {
+		snippet = snippet[:notJSONSnippetLength]
+	}
+	if contentType == "" {
+		contentType = "no content type"
+	}
+	return fmt.Errorf("%w (%s), body starts with %q", ErrNotJSON, contentType, snippet)
+}
+
+// checkDepth returns an ErrMaxDepthExceeded error if the objects and arrays in the given JSON document
+// are nested deeper than maxDepth. The document is not validated, which is left to the decoder.
+func checkDepth(b []byte, maxDepth int) error {
+	var depth int
+	var inString, escaped bool
+	for _, c := range b {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("%w: more than %d levels", ErrMaxDepthExceeded, maxDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// do sends the given request, calling the hooks before and after.
+func (cl Client) do(req *http.Request) (*http.Response, error) {
+	for _, h := range cl.hooks {
+		if h.OnRequest != nil {
+			h.OnRequest(req)
+		}
+	}
+	start := time.Now()
+	resp, err := cl.httpClient.Do(req)
+	duration := time.Since(start)
+	var statusCode int
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	for _, h := range cl.hooks {
+		if h.OnResponse != nil {
+			h.OnResponse(req, statusCode, duration, err)
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	var calls []string
+	var statusCodes []int
+	cl := NewClient(srv.URL,
+		WithHooks(Hooks{
+			OnRequest: func(req *http.Request) {
+				calls = append(calls, "request "+req.URL.Path)
+			},
+			OnResponse: func(req *http.Request, statusCode int, duration time.Duration, err error) {
+				require.NoError(t, err)
+				require.Positive(t, duration)
+				calls = append(calls, "response "+req.URL.Path)
+				statusCodes = append(statusCodes, statusCode)
+			},
+		}),
+		// Hooks are optional
+		WithHooks(Hooks{OnRequest: func(*http.Request) { calls = append(calls, "second hook") }}),
+	)
+	require.NoError(t, cl.Request(context.Background(), http.MethodGet, "ok", nil))
+	err := cl.Request(context.Background(), http.MethodGet, "missing", nil)
+	require.ErrorIs(t, err, ErrBadStatusCode)
+	require.Equal(t, http.StatusNotFound, StatusCode(err))
+	require.Zero(t, StatusCode(context.Canceled))
+	require.Equal(t, []string{
+		"request /ok", "second hook", "response /ok",
+		"request /missing", "second hook", "response /missing",
+	}, calls)
+	require.Equal(t, []int{http.StatusOK, http.StatusNotFound}, statusCodes)
+
+	t.Run("request error", func(t *testing.T) {
+		var gotErr error
+		statusCode := -1
+		cl := NewClient("http://127.0.0.1:0", WithHooks(Hooks{
+			OnResponse: func(_ *http.Request, code int, _ time.Duration, err error) {
+				statusCode, gotErr = code, err
+			},
+		}))
+		require.Error(t, cl.Request(context.Background(), http.MethodGet, "ok", nil))
+		require.Error(t, gotErr)
+		require.Zero(t, statusCode)
+	})
+}
+
+func TestCredentials(t *testing.T) {
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	credentials := NewCredentials("t0k3n")
+	cl := NewClient(srv.URL, WithCredentials(credentials))
+	require.NoError(t, cl.Request(context.Background(), http.MethodGet, "ok", nil))
+	require.Equal(t, "Bearer t0k3n", authorization)
+
+	// The client uses the new credentials once replaced
+	credentials.Set("rotated")
+	require.NoError(t, cl.Request(context.Background(), http.MethodGet, "ok", nil))
+	require.Equal(t, "Bearer rotated", authorization)
+
+	credentials.Set("admin:s3cr3t")
+	require.NoError(t, cl.Request(context.Background(), http.MethodGet, "ok", nil))
+	require.Equal(t, "Basic YWRtaW46czNjcjN0", authorization)
+
+	require.NoError(t, NewClient(srv.URL).Request(context.Background(), http.MethodGet, "ok", nil))
+	require.Empty(t, authorization)
+}
+
+func TestLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html><body>502 Bad Gateway</body></html>"))
+		case "/html-as-json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("\n<!DOCTYPE html>"))
+		case "/nested":
+			_, _ = w.Write([]byte(`{"a": [{"b": "[[[[{{{{"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"a": "0123456789"}`))
+		}
+	}))
+	defer srv.Close()
+
+	var out map[string]interface{}
+	t.Run("defaults", func(t *testing.T) {
+		cl := NewClient(srv.URL)
+		require.NoError(t, cl.Request(context.Background(), http.MethodGet, "ok", &out))
+		require.NoError(t, cl.Request(context.Background(), http.MethodGet, "nested", &out))
+		require.Equal(t, []interface{}{map[string]interface{}{"b": "[[[[{{{{"}}, out["a"])
+	})
+
+	t.Run("response size", func(t *testing.T) {
+		err := NewClient(srv.URL, WithMaxResponseBytes(10)).Request(context.Background(), http.MethodGet, "ok", &out)
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+		require.ErrorContains(t, err, "larger than 10 bytes")
+		require.NoError(t, NewClient(srv.URL, WithMaxResponseBytes(19)).Request(context.Background(), http.MethodGet, "ok", &out))
+		require.NoError(t, NewClient(srv.URL, WithMaxResponseBytes(0)).Request(context.Background(), http.MethodGet, "ok", &out))
+	})
+
+	t.Run("decode depth", func(t *testing.T) {
+		// Brackets in strings are not counted
+		require.NoError(t, NewClient(srv.URL, WithMaxDecodeDepth(3)).Request(context.Background(), http.MethodGet, "nested", &out))
+		err := NewClient(srv.URL, WithMaxDecodeDepth(2)).Request(context.Background(), http.MethodGet, "nested", &out)
+		require.ErrorIs(t, err, ErrMaxDepthExceeded)
+		require.ErrorContains(t, err, "more than 2 levels")
+		require.NoError(t, NewClient(srv.URL, WithMaxDecodeDepth(0)).Request(context.Background(), http.MethodGet, "nested", &out))
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		err := NewClient(srv.URL).Request(context.Background(), http.MethodGet, "html", &out)
+		require.ErrorIs(t, err, ErrNotJSON)
+		require.ErrorContains(t, err, `(text/html; charset=utf-8), body starts with "<html><body>502 Bad Gateway</body></html>"`)
+		err = NewClient(srv.URL).Request(context.Background(), http.MethodGet, "html-as-json", &out)
+		require.ErrorIs(t, err, ErrNotJSON)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		b, err := NewClient(srv.URL).RequestBytes(context.Background(), http.MethodGet, "html")
+		require.NoError(t, err)
+		require.Equal(t, "<html><body>502 Bad Gateway</body></html>", string(b))
+		_, err = NewClient(srv.URL, WithMaxResponseBytes(10)).RequestBytes(context.Background(), http.MethodGet, "html")
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+}
+
+func TestRequestWithBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, _ = w.Write([]byte(`{"body": ` + string(b) + `}`))
+	}))
+	defer srv.Close()
+
+	var out map[string]interface{}
+	require.NoError(t, NewClient(srv.URL).RequestWithBody(context.Background(), http.MethodPost, "echo", strings.NewReader(`"value"`), &out))
+	require.Equal(t, map[string]interface{}{"body": "value"}, out)
+}
+
+func TestRequestConditional(t *testing.T) {
+	version := "1"
+	var statusCodes []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			etag := `"v` + version + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		case "/last-modified":
+			lastModified := "Mon, 01 Jan 2024 00:00:0" + version + " GMT"
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", lastModified)
+		default:
+			require.Empty(t, r.Header.Get("If-None-Match"))
+			require.Empty(t, r.Header.Get("If-Modified-Since"))
+		}
+		_, _ = w.Write([]byte(`{"version": "` + version + `"}`))
+	}))
+	defer srv.Close()
+
+	hooks := WithHooks(Hooks{OnResponse: func(_ *http.Request, statusCode int, _ time.Duration, _ error) {
+		statusCodes = append(statusCodes, statusCode)
+	}})
+	for _, path := range []string{"etag", "last-modified", "no-validators"} {
+		t.Run(path, func(t *testing.T) {
+			version, statusCodes = "1", nil
+			cl := NewClient(srv.URL, WithConditionalRequests(), hooks)
+			var out map[string]string
+			for _, v := range []string{"1", "1", "2", "2"} {
+				version = v
+				out = nil
+				require.NoError(t, cl.RequestConditional(context.Background(), path, &out))
+				require.Equal(t, map[string]string{"version": v}, out)
+			}
+			if path == "no-validators" {
+				require.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK}, statusCodes)
+			} else {
+				require.Equal(t, []int{http.StatusOK, http.StatusNotModified, http.StatusOK, http.StatusNotModified}, statusCodes)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		version, statusCodes = "1", nil
+		cl := NewClient(srv.URL, hooks)
+		var out map[string]string
+		require.NoError(t, cl.RequestConditional(context.Background(), "etag", &out))
+		require.NoError(t, cl.RequestConditional(context.Background(), "etag", &out))
+		require.Equal(t, []int{http.StatusOK, http.StatusOK}, statusCodes)
+	})
+}
+
+func TestResponseCache(t *testing.T) {
+	c := newResponseCache(2)
+	c.put("a", cachedResponse{etag: "a"})
+	c.put("b", cachedResponse{etag: "b"})
+	_, ok := c.get("a")
+	require.True(t, ok)
+
+	// "b" is the least recently used
+	c.put("c", cachedResponse{etag: "c"})
+	_, ok = c.get("b")
+	require.False(t, ok)
+	for _, url := range []string{"a", "c"} {
+		resp, ok := c.get(url)
+		require.True(t, ok)
+		require.Equal(t, url, resp.etag)
+	}
+
+	c.put("a", cachedResponse{etag: "a2"})
+	c.delete("c")
+	resp, ok := c.get("a")
+	require.True(t, ok)
+	require.Equal(t, "a2", resp.etag)
+	_, ok = c.get("c")
+	require.False(t, ok)
+	require.Equal(t, 1, c.order.Len())
+}
@@ -0,0 +1,120 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path prefixes of the fixture server, to be used as base URLs of the API clients.
+const (
+	// GrafanaPrefix is the prefix of the Grafana API, whose fixtures are in the "api" subdirectory.
+	GrafanaPrefix = "/api"
+
+	// GCOMPrefix is the prefix of the GCOM (grafana.com) API, whose fixtures are in the "gcom" subdirectory.
+	GCOMPrefix = "/gcom"
+)
+
+// pagingParams are the query parameters used for pagination, which are not part of the fixture file names.
+var pagingParams = map[string]struct{}{"limit": {}, "page": {}, "perpage": {}}
+
+// Handler returns an http.Handler serving the recorded API responses in the given directory,
+// so the real API clients can be used without a Grafana instance.
+//
+// The response to GET /<path>?<query> is the content of the file <dir>/<path>.json.
+// If the query has parameters other than the pagination ones (limit, page, perpage),
+// the file <dir>/<path>_<key>-<value>[_<key>-<value>...].json (sorted by key) is used instead, if it exists
+// (e.g.: api/search_type-dash-folder.json for GET /api/search?type=dash-folder).
+// Fixtures contain all the items in the first page: pages after the first one are empty.
+// Requests without a fixture file and non-GET requests get a 404.
+func Handler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 1 {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		for _, fn := range fixtureFiles(dir, r) {
+			b, err := os.ReadFile(fn)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(b)
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// fixtureFiles returns the files that may contain the response to the given request, in order of preference.
+func fixtureFiles(dir string, r *http.Request) []string {
+	// Clean the path as an absolute one, so it can't go outside of dir
+	base := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if _, ok := pagingParams[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return []string{base + ".json"}
+	}
+	sort.Strings(keys)
+	var suffix strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&suffix, "_%s-%s", key, strings.Join(query[key], ","))
+	}
+	// Query values are user-controlled too, do not let them add path separators
+	name := strings.NewReplacer("/", "-", `\`, "-").Replace(filepath.Base(base) + suffix.String())
+	return []string{filepath.Join(filepath.Dir(base), name+".json"), base + ".json"}
+}
+
+// Server serves the fixtures in a directory over HTTP on a random local port.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer starts a Server serving the fixtures in the given directory (see Handler).
+func NewServer(dir string) (*Server, error) {
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", dir)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	srv := &Server{listener: listener, server: &http.Server{Handler: Handler(dir)}}
+	go func() { _ = srv.server.Serve(listener) }()
+	return srv, nil
+}
+
+// URL returns the base URL of the server, without trailing slash.
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
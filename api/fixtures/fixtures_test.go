@@ -0,0 +1,82 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+	"github.com/grafana/detect-angular-dashboards/api/gcom"
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler("testdata"))
+	defer srv.Close()
+	ctx := context.Background()
+	cl := grafana.NewAPIClient(api.NewClient(srv.URL + GrafanaPrefix))
+
+	t.Run("path", func(t *testing.T) {
+		dashboard, err := cl.GetDashboard(ctx, "worldmap")
+		require.NoError(t, err)
+		require.Equal(t, "worldmap", dashboard.Dashboard.Title)
+		require.Len(t, dashboard.Dashboard.Panels, 1)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		dashboards, err := cl.GetDashboards(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, dashboards, 1)
+		require.Equal(t, "worldmap", dashboards[0].UID)
+
+		dashboards, err = cl.GetDashboards(ctx, 2)
+		require.NoError(t, err)
+		require.Empty(t, dashboards)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		folders, err := cl.GetFolders(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []grafana.Folder{{UID: "ops", Title: "Ops"}}, folders)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := cl.GetOrgUsers(ctx)
+		require.True(t, errors.Is(err, api.ErrBadStatusCode))
+		require.True(t, errors.Is(cl.UserSwitchContext(ctx, "1"), api.ErrBadStatusCode), "non-GET requests should fail")
+	})
+
+	t.Run("outside of dir", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/../fixtures.go")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("gcom", func(t *testing.T) {
+		gcomClient := gcom.NewAPIClient()
+		gcomClient.BaseURL = srv.URL + GCOMPrefix
+		angular, err := gcomClient.GetAngularDetected(ctx, "grafana-worldmap-panel", "1.0.6")
+		require.NoError(t, err)
+		require.True(t, angular)
+		_, err = gcomClient.GetAngularDetected(ctx, "acme-private-panel", "1.0.0")
+		require.True(t, errors.Is(err, gcom.ErrNotFound))
+	})
+}
+
+func TestServer(t *testing.T) {
+	_, err := NewServer(filepath.Join("testdata", "missing"))
+	require.Error(t, err)
+
+	srv, err := NewServer("testdata")
+	require.NoError(t, err)
+	defer srv.Close()
+	dashboards, err := grafana.NewAPIClient(api.NewClient(srv.URL()+GrafanaPrefix)).GetDashboards(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, dashboards, 1)
+}
@@ -0,0 +1,81 @@
+package gcom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// StackStatusActive is the status of the Grafana Cloud stacks that are running.
+const StackStatusActive = "active"
+
+// Stack is a Grafana Cloud stack.
+type Stack struct {
+	ID     int
+	Slug   string
+	Name   string
+	URL    string
+	Status string
+}
+
+// ServiceAccount is a service account created in a Grafana Cloud stack.
+type ServiceAccount struct {
+	ID int
+}
+
+// ServiceAccountToken is a token of a service account created in a Grafana Cloud stack.
+type ServiceAccountToken struct {
+	Key string
+}
+
+// GetStacks returns the stacks of the Grafana Cloud organization with the given slug.
+// It requires a grafana.com token (see api.WithAuthentication) with the "stacks:read" scope.
+func (cl APIClient) GetStacks(ctx context.Context, org string) ([]Stack, error) {
+	var resp struct {
+		Items []Stack
+	}
+	if err := cl.Request(ctx, http.MethodGet, "orgs/"+url.PathEscape(org)+"/instances", &resp); err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	return resp.Items, nil
+}
+
+// CreateStackServiceAccount creates a service account with the given name and role in the given stack,
+// through the grafana.com proxy to the stack API. It requires the "stack-service-accounts:write" scope.
+func (cl APIClient) CreateStackServiceAccount(ctx context.Context, stack, name, role string) (*ServiceAccount, error) {
+	var out ServiceAccount
+	err := cl.post(ctx, "instances/"+url.PathEscape(stack)+"/api/serviceaccounts", map[string]interface{}{
+		"name": name,
+		"role": role,
+	}, &out)
+	return &out, err
+}
+
+// CreateStackServiceAccountToken creates a token for the given service account of the given stack,
+// which expires after the given number of seconds.
+func (cl APIClient) CreateStackServiceAccountToken(ctx context.Context, stack string, serviceAccountID int, name string, secondsToLive int) (*ServiceAccountToken, error) {
+	var out ServiceAccountToken
+	err := cl.post(ctx, "instances/"+url.PathEscape(stack)+"/api/serviceaccounts/"+strconv.Itoa(serviceAccountID)+"/tokens", map[string]interface{}{
+		"name":          name,
+		"secondsToLive": secondsToLive,
+	}, &out)
+	return &out, err
+}
+
+// DeleteStackServiceAccount deletes the given service account of the given stack, and its tokens.
+func (cl APIClient) DeleteStackServiceAccount(ctx context.Context, stack string, serviceAccountID int) error {
+	return cl.Request(ctx, http.MethodDelete, "instances/"+url.PathEscape(stack)+"/api/serviceaccounts/"+strconv.Itoa(serviceAccountID), nil)
+}
+
+// post sends a POST request with the given JSON body, decoding the JSON response into out.
+func (cl APIClient) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return cl.RequestWithBody(ctx, http.MethodPost, path, bytes.NewReader(b), out)
+}
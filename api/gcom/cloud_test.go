@@ -0,0 +1,71 @@
+package gcom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+)
+
+func TestCloud(t *testing.T) {
+	var requests []string
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if r.Method == http.MethodPost {
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			bodies = append(bodies, body)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /orgs/my-org/instances":
+			_, _ = w.Write([]byte(`{"items": [{"id": 1, "slug": "stack", "name": "Stack", "url": "https://stack.grafana.net", "status": "active"}]}`))
+		case "POST /instances/stack/api/serviceaccounts":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 42}`))
+		case "POST /instances/stack/api/serviceaccounts/42/tokens":
+			_, _ = w.Write([]byte(`{"key": "glsa_token"}`))
+		case "DELETE /instances/stack/api/serviceaccounts/42":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cl := APIClient{Client: api.NewClient(srv.URL, api.WithAuthentication("token"))}
+	ctx := context.Background()
+
+	stacks, err := cl.GetStacks(ctx, "my-org")
+	require.NoError(t, err)
+	require.Equal(t, []Stack{{ID: 1, Slug: "stack", Name: "Stack", URL: "https://stack.grafana.net", Status: StackStatusActive}}, stacks)
+
+	sa, err := cl.CreateStackServiceAccount(ctx, "stack", "name", "Viewer")
+	require.NoError(t, err)
+	require.Equal(t, 42, sa.ID)
+
+	token, err := cl.CreateStackServiceAccountToken(ctx, "stack", sa.ID, "name", 60)
+	require.NoError(t, err)
+	require.Equal(t, "glsa_token", token.Key)
+
+	require.NoError(t, cl.DeleteStackServiceAccount(ctx, "stack", sa.ID))
+
+	require.Equal(t, []string{
+		"GET /orgs/my-org/instances",
+		"POST /instances/stack/api/serviceaccounts",
+		"POST /instances/stack/api/serviceaccounts/42/tokens",
+		"DELETE /instances/stack/api/serviceaccounts/42",
+	}, requests)
+	require.Equal(t, []map[string]interface{}{
+		{"name": "name", "role": "Viewer"},
+		{"name": "name", "secondsToLive": float64(60)},
+	}, bodies)
+
+	_, err = cl.GetStacks(ctx, "missing")
+	require.Equal(t, http.StatusNotFound, api.StatusCode(err))
+}
@@ -2,29 +2,36 @@ package gcom
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/grafana/detect-angular-dashboards/api"
 )
 
+// ErrNotFound is returned when a plugin or plugin version is not in GCOM (e.g.: private plugins).
+var ErrNotFound = errors.New("not found in gcom")
+
 type APIClient struct {
 	api.Client
 }
 
-func NewAPIClient() APIClient {
+func NewAPIClient(opts ...api.ClientOption) APIClient {
 	return APIClient{
-		Client: api.NewClient("https://grafana.com/api"),
+		Client: api.NewClient("https://grafana.com/api", opts...),
 	}
 }
 
+// GetAngularDetected returns true if the given version of the plugin with the given slug uses Angular.
+// It returns ErrNotFound if the plugin or the version is not in GCOM, in which case the Angular status is unknown,
+// and another error if GCOM can't be queried (e.g.: unexpected status code).
 func (cl APIClient) GetAngularDetected(ctx context.Context, slug, version string) (bool, error) {
 	var resp PluginVersions
 	if err := cl.Request(ctx, http.MethodGet, "plugins/"+slug+"/versions", &resp); err != nil {
-		if errors.Is(err, api.ErrBadStatusCode) {
-			// Swallow bad status codes
-			return false, nil
+		if api.StatusCode(err) == http.StatusNotFound {
+			return false, fmt.Errorf("plugin %q: %w", slug, ErrNotFound)
 		}
 		return false, fmt.Errorf("request: %w", err)
 	}
@@ -33,5 +40,70 @@ func (cl APIClient) GetAngularDetected(ctx context.Context, slug, version string
 			return pv.AngularDetected, nil
 		}
 	}
-	return false, nil
+	return false, fmt.Errorf("plugin %q version %q: %w", slug, version, ErrNotFound)
+}
+
+// GetLatestAngularDetected returns true if the latest version of the plugin with the given slug uses Angular.
+// It can be used when the installed version of the plugin is not known.
+func (cl APIClient) GetLatestAngularDetected(ctx context.Context, slug string) (bool, error) {
+	pv, err := cl.GetLatestVersion(ctx, slug)
+	if err != nil || pv == nil {
+		return false, err
+	}
+	return pv.AngularDetected, nil
+}
+
+// GetLatestVersion returns the latest version of the plugin with the given slug.
+// It returns nil if the plugin is not in GCOM (e.g.: private plugins).
+func (cl APIClient) GetLatestVersion(ctx context.Context, slug string) (*PluginVersion, error) {
+	var resp PluginVersions
+	if err := cl.Request(ctx, http.MethodGet, "plugins/"+slug+"/versions", &resp); err != nil {
+		if errors.Is(err, api.ErrBadStatusCode) {
+			// Swallow bad status codes
+			return nil, nil
+		}
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	if len(resp.Items) == 0 {
+		return nil, nil
+	}
+	// Versions are sorted from the most recent one
+	return &resp.Items[0], nil
+}
+
+// GetPlugin returns the plugin with the given slug.
+// It returns nil if the plugin is not in GCOM (e.g.: private plugins).
+func (cl APIClient) GetPlugin(ctx context.Context, slug string) (*Plugin, error) {
+	var resp Plugin
+	if err := cl.Request(ctx, http.MethodGet, "plugins/"+slug, &resp); err != nil {
+		if errors.Is(err, api.ErrBadStatusCode) {
+			// Swallow bad status codes
+			return nil, nil
+		}
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	return &resp, nil
+}
+
+// GetDashboard returns the community dashboard with the given id.
+// It returns nil if the dashboard is not in GCOM (e.g.: it has been removed), and an error for the other
+// unexpected status codes (e.g.: rate limiting), so they are not mistaken for removed dashboards.
+func (cl APIClient) GetDashboard(ctx context.Context, id int) (*Dashboard, error) {
+	var resp Dashboard
+	if err := cl.Request(ctx, http.MethodGet, "dashboards/"+strconv.Itoa(id), &resp); err != nil {
+		if api.StatusCode(err) == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	return &resp, nil
+}
+
+// GetDashboardRevision returns the JSON model of the given revision of the community dashboard with the given id.
+func (cl APIClient) GetDashboardRevision(ctx context.Context, id, revision int) (json.RawMessage, error) {
+	var resp json.RawMessage
+	if err := cl.Request(ctx, http.MethodGet, fmt.Sprintf("dashboards/%d/revisions/%d/download", id, revision), &resp); err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	return resp, nil
 }
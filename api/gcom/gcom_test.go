@@ -0,0 +1,68 @@
+package gcom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+)
+
+func TestGetDashboard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dashboards/1":
+			_, _ = w.Write([]byte(`{"id": 1, "revision": 3}`))
+		case "/dashboards/2":
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cl := APIClient{Client: api.NewClient(srv.URL)}
+
+	dashboard, err := cl.GetDashboard(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, dashboard.Revision)
+
+	// Removed dashboards are not errors
+	dashboard, err = cl.GetDashboard(context.Background(), 3)
+	require.NoError(t, err)
+	require.Nil(t, dashboard)
+
+	_, err = cl.GetDashboard(context.Background(), 2)
+	require.Equal(t, http.StatusTooManyRequests, api.StatusCode(err))
+}
+
+func TestGetAngularDetected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plugins/angular-panel/versions":
+			_, _ = w.Write([]byte(`{"items": [{"version": "1.0.0", "angularDetected": true}]}`))
+		case "/plugins/unavailable-panel/versions":
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cl := APIClient{Client: api.NewClient(srv.URL)}
+
+	angular, err := cl.GetAngularDetected(context.Background(), "angular-panel", "1.0.0")
+	require.NoError(t, err)
+	require.True(t, angular)
+
+	_, err = cl.GetAngularDetected(context.Background(), "angular-panel", "2.0.0")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = cl.GetAngularDetected(context.Background(), "private-panel", "1.0.0")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = cl.GetAngularDetected(context.Background(), "unavailable-panel", "1.0.0")
+	require.NotErrorIs(t, err, ErrNotFound)
+	require.Equal(t, http.StatusServiceUnavailable, api.StatusCode(err))
+}
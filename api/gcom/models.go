@@ -8,3 +8,24 @@ type PluginVersion struct {
 type PluginVersions struct {
 	Items []PluginVersion
 }
+
+// PluginStatusDeprecated is the status of the plugins that are deprecated and no longer maintained.
+const PluginStatusDeprecated = "deprecated"
+
+// Dashboard is a community dashboard on grafana.com.
+type Dashboard struct {
+	ID   int
+	Name string
+	// Revision is the latest revision of the dashboard.
+	Revision int
+}
+
+type Plugin struct {
+	Slug string
+	// Status is the status of the plugin (e.g.: "active", "deprecated").
+	Status string
+	// SignatureType is the signature level of the plugin (e.g.: "grafana", "commercial", "community").
+	SignatureType string
+	// UpdatedAt is the date of the last release of the plugin.
+	UpdatedAt string
+}
@@ -0,0 +1,544 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+	"github.com/grafana/detect-angular-dashboards/internal/sliceutil"
+)
+
+const (
+	// appPlatformDashboardVersion is the version of the dashboard API used to list and get dashboards.
+	appPlatformDashboardVersion = "v1beta1"
+
+	// appPlatformFolderVersion is the version of the folder API used to get the folder titles.
+	appPlatformFolderVersion = "v1beta1"
+
+	// appPlatformDefaultNamespace is the namespace of the default org, if Grafana doesn't report it.
+	appPlatformDefaultNamespace = "default"
+
+	// appPlatformListLimit is the number of dashboards listed in each request.
+	appPlatformListLimit = 500
+)
+
+// Annotations of the App Platform resources with the metadata of the dashboards.
+const (
+	annotationFolder           = "grafana.app/folder"
+	annotationCreatedBy        = "grafana.app/createdBy"
+	annotationUpdatedBy        = "grafana.app/updatedBy"
+	annotationUpdatedTimestamp = "grafana.app/updatedTimestamp"
+	annotationManagedBy        = "grafana.app/managedBy"
+	annotationSourcePath       = "grafana.app/sourcePath"
+	annotationMessage          = "grafana.app/message"
+)
+
+// AppPlatformAPIClient is an APIClient listing and getting dashboards with the App Platform (Kubernetes-style) APIs
+// (/apis/dashboard.grafana.app/...), which replace the legacy /api/search and /api/dashboards endpoints.
+// The other endpoints are the ones of APIClient.
+type AppPlatformAPIClient struct {
+	APIClient
+
+	// apis is the client for the App Platform APIs, at the root of the Grafana URL.
+	apis api.Client
+
+	namespaceOnce sync.Once
+	namespace     string
+	namespaceErr  error
+
+	// folderTitles are the titles of the folders, by uid.
+	folderTitlesMu sync.Mutex
+	folderTitles   map[string]*folderTitle
+}
+
+// folderTitle is the title of a folder, fetched once by the first lookup of the folder.
+// The other lookups of the same folder wait for it to be done.
+type folderTitle struct {
+	done  chan struct{}
+	title string
+	err   error
+}
+
+// NewAppPlatformAPIClient returns a new AppPlatformAPIClient. The base URL of the client is the one of the legacy API
+// (e.g.: "http://127.0.0.1:3000/api"), the App Platform APIs are at the same root.
+func NewAppPlatformAPIClient(client api.Client) *AppPlatformAPIClient {
+	apis := client
+	apis.BaseURL = strings.TrimSuffix(strings.TrimSuffix(client.BaseURL, "/"), "/api") + "/apis"
+	return &AppPlatformAPIClient{
+		APIClient:    NewAPIClient(client),
+		apis:         apis,
+		folderTitles: map[string]*folderTitle{},
+	}
+}
+
+// appPlatformMetadata is the metadata of an App Platform resource.
+type appPlatformMetadata struct {
+	Name              string            `json:"name"`
+	CreationTimestamp string            `json:"creationTimestamp"`
+	Annotations       map[string]string `json:"annotations"`
+
+	// Generation is incremented on each change of the spec, like the version of legacy dashboards.
+	Generation int `json:"generation"`
+}
+
+// appPlatformDashboard is a dashboard resource.
+type appPlatformDashboard struct {
+	Metadata appPlatformMetadata `json:"metadata"`
+	Spec     struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	} `json:"spec"`
+	Status struct {
+		// Conversion is set if the dashboard is stored with another version of the API, and couldn't be converted
+		// to the requested one (e.g.: v2 dashboards requested as v1).
+		Conversion *struct {
+			Failed        bool   `json:"failed"`
+			StoredVersion string `json:"storedVersion"`
+		} `json:"conversion"`
+	} `json:"status"`
+}
+
+// getNamespace returns the namespace of the current org, from the frontend settings.
+func (cl *AppPlatformAPIClient) getNamespace(ctx context.Context) (string, error) {
+	cl.namespaceOnce.Do(func() {
+		var settings *FrontendSettings
+		settings, cl.namespaceErr = cl.GetFrontendSettings(ctx)
+		if cl.namespaceErr != nil {
+			cl.namespaceErr = fmt.Errorf("get frontend settings: %w", cl.namespaceErr)
+			return
+		}
+		cl.namespace = settings.Namespace
+		if cl.namespace == "" {
+			cl.namespace = appPlatformDefaultNamespace
+		}
+	})
+	return cl.namespace, cl.namespaceErr
+}
+
+// resourcePath returns the path of the resources of the given kind (plural) in the current namespace.
+func (cl *AppPlatformAPIClient) resourcePath(ctx context.Context, group, version, resource string) (string, error) {
+	namespace, err := cl.getNamespace(ctx)
+	if err != nil {
+		return "", err
+	}
+	return group + "/" + version + "/namespaces/" + url.PathEscape(namespace) + "/" + resource, nil
+}
+
+// GetDashboards returns all the dashboards in the first page. Next pages are empty.
+// The dashboards are filtered by cl.FolderUIDs, cl.Tags and cl.DashboardUIDs, like the search API does.
+func (cl *AppPlatformAPIClient) GetDashboards(ctx context.Context, page int) ([]ListedDashboard, error) {
+	if page > 1 {
+		return nil, nil
+	}
+	path, err := cl.resourcePath(ctx, "dashboard.grafana.app", appPlatformDashboardVersion, "dashboards")
+	if err != nil {
+		return nil, err
+	}
+	var out []ListedDashboard
+	var continueToken string
+	for {
+		query := url.Values{"limit": []string{fmt.Sprint(appPlatformListLimit)}}
+		if continueToken != "" {
+			query.Set("continue", continueToken)
+		}
+		var list struct {
+			Metadata struct {
+				Continue string `json:"continue"`
+			} `json:"metadata"`
+			Items []appPlatformDashboard `json:"items"`
+		}
+		if err := cl.apis.Request(ctx, http.MethodGet, path+"?"+query.Encode(), &list); err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			if !cl.matchesFilters(item) {
+				continue
+			}
+			dash := ListedDashboard{
+				UID:       item.Metadata.Name,
+				URL:       "/d/" + item.Metadata.Name,
+				Title:     item.Spec.Title,
+				FolderUID: item.Metadata.Annotations[annotationFolder],
+				Tags:      item.Spec.Tags,
+				Version:   item.Metadata.Generation,
+			}
+			if dash.FolderUID != "" {
+				if dash.FolderTitle, err = cl.folderTitle(ctx, dash.FolderUID); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, dash)
+		}
+		if list.Metadata.Continue == "" {
+			return out, nil
+		}
+		continueToken = list.Metadata.Continue
+	}
+}
+
+// matchesFilters returns true if the given dashboard matches cl.FolderUIDs, cl.Tags and cl.DashboardUIDs.
+func (cl *AppPlatformAPIClient) matchesFilters(item appPlatformDashboard) bool {
+	if len(cl.FolderUIDs) > 0 {
+		// Dashboards in the root folder have no folder annotation
+		folderUID := item.Metadata.Annotations[annotationFolder]
+		if folderUID == "" {
+			folderUID = GeneralFolderUID
+		}
+		if !sliceutil.Contains(cl.FolderUIDs, folderUID) {
+			return false
+		}
+	}
+	if len(cl.DashboardUIDs) > 0 && !sliceutil.Contains(cl.DashboardUIDs, item.Metadata.Name) {
+		return false
+	}
+	for _, tag := range cl.Tags {
+		if !sliceutil.Contains(item.Spec.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetDashboard returns the dashboard with the given uid. Dashboards stored with the v2 schema are fetched
+// with the version of the API they are stored with, and converted to the v1 model.
+func (cl *AppPlatformAPIClient) GetDashboard(ctx context.Context, uid string) (*DashboardDefinition, error) {
+	b, err := cl.getDashboardResource(ctx, appPlatformDashboardVersion, uid)
+	if err != nil {
+		return nil, err
+	}
+	var resource appPlatformDashboard
+	if err := json.Unmarshal(b, &resource); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if conversion := resource.Status.Conversion; conversion != nil && conversion.Failed && conversion.StoredVersion != "" {
+		version := conversion.StoredVersion
+		// The stored version may be qualified with the group (e.g.: "dashboard.grafana.app/v2beta1")
+		if i := strings.LastIndex(version, "/"); i >= 0 {
+			version = version[i+1:]
+		}
+		if b, err = cl.getDashboardResource(ctx, version, uid); err != nil {
+			return nil, err
+		}
+	}
+	var out DashboardDefinition
+	if err := json.Unmarshal(b, &out.Dashboard); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if out.Meta, err = cl.dashboardMeta(ctx, resource.Metadata); err != nil {
+		return nil, err
+	}
+	out.Dashboard.Version = resource.Metadata.Generation
+	ConvertDashboard(&out.Dashboard)
+	return &out, nil
+}
+
+// getDashboardResource returns the raw dashboard resource with the given uid, using the given version of the API.
+func (cl *AppPlatformAPIClient) getDashboardResource(ctx context.Context, version, uid string) (json.RawMessage, error) {
+	path, err := cl.resourcePath(ctx, "dashboard.grafana.app", version, "dashboards")
+	if err != nil {
+		return nil, err
+	}
+	var out json.RawMessage
+	if err := cl.apis.RequestConditional(ctx, path+"/"+url.PathEscape(uid), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetDashboardJSON returns the dashboard with the given uid in the format of the legacy dashboards API
+// ({"dashboard": ..., "meta": {"folderUid": ...}}), so it can be modified and saved back with SaveDashboard.
+// The version of the dashboard is the generation of the resource.
+// Dashboards stored with the v2 schema can't be edited as v1 models, so an error is returned for them.
+func (cl *AppPlatformAPIClient) GetDashboardJSON(ctx context.Context, uid string) (json.RawMessage, error) {
+	resource, err := cl.getEditableDashboard(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	spec, _ := resource["spec"].(map[string]interface{})
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	spec["uid"] = uid
+	spec["version"] = metadata["generation"]
+	return json.Marshal(map[string]interface{}{
+		"dashboard": spec,
+		"meta":      map[string]interface{}{"folderUid": annotations[annotationFolder]},
+	})
+}
+
+// SaveDashboard saves the given dashboard model in the folder with the given uid, with the given version message,
+// creating the dashboard resource if it doesn't exist. Like the legacy API, the dashboard is not overwritten:
+// saving fails with a 412 status code if it has been changed since its version in the model.
+func (cl *AppPlatformAPIClient) SaveDashboard(ctx context.Context, dashboard map[string]interface{}, folderUID, message string) error {
+	uid, _ := dashboard["uid"].(string)
+	if uid == "" {
+		return errors.New("the dashboard has no uid")
+	}
+	path, err := cl.resourcePath(ctx, "dashboard.grafana.app", appPlatformDashboardVersion, "dashboards")
+	if err != nil {
+		return err
+	}
+	spec := make(map[string]interface{}, len(dashboard))
+	for k, v := range dashboard {
+		switch k {
+		// The identity and the version of the dashboard are in the metadata of the resource
+		case "id", "uid", "version":
+		default:
+			spec[k] = v
+		}
+	}
+	resource, err := cl.getEditableDashboard(ctx, uid)
+	switch {
+	case api.StatusCode(err) == http.StatusNotFound:
+		annotations := map[string]interface{}{annotationMessage: message}
+		if folderUID != "" {
+			annotations[annotationFolder] = folderUID
+		}
+		b, err := json.Marshal(map[string]interface{}{
+			"apiVersion": "dashboard.grafana.app/" + appPlatformDashboardVersion,
+			"kind":       "Dashboard",
+			"metadata":   map[string]interface{}{"name": uid, "annotations": annotations},
+			"spec":       spec,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		return cl.apis.RequestWithBody(ctx, http.MethodPost, path, bytes.NewReader(b), nil)
+	case err != nil:
+		return err
+	}
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	generation, _ := metadata["generation"].(float64)
+	if version := modelVersion(dashboard["version"]); version != int(generation) {
+		return fmt.Errorf("the dashboard has been changed since version %d: %w",
+			version, api.BadStatusCodeError{StatusCode: http.StatusPreconditionFailed})
+	}
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	annotations[annotationMessage] = message
+	if folderUID != "" {
+		annotations[annotationFolder] = folderUID
+	} else {
+		delete(annotations, annotationFolder)
+	}
+	resource["spec"] = spec
+	delete(resource, "status")
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	// The resource version of the metadata makes the update fail if the dashboard has been changed since it was fetched
+	return cl.apis.RequestWithBody(ctx, http.MethodPut, path+"/"+url.PathEscape(uid), bytes.NewReader(b), nil)
+}
+
+// getEditableDashboard returns the dashboard resource with the given uid as generic maps, so it can be saved back
+// without losing fields. It returns an error if the dashboard can't be converted to appPlatformDashboardVersion.
+func (cl *AppPlatformAPIClient) getEditableDashboard(ctx context.Context, uid string) (map[string]interface{}, error) {
+	b, err := cl.getDashboardResource(ctx, appPlatformDashboardVersion, uid)
+	if err != nil {
+		return nil, err
+	}
+	var status appPlatformDashboard
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if conversion := status.Status.Conversion; conversion != nil && conversion.Failed {
+		return nil, fmt.Errorf("the dashboard is stored with version %q of the API, and can't be edited with version %q",
+			conversion.StoredVersion, appPlatformDashboardVersion)
+	}
+	var resource map[string]interface{}
+	if err := json.Unmarshal(b, &resource); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if _, ok := resource["spec"].(map[string]interface{}); !ok {
+		resource["spec"] = map[string]interface{}{}
+	}
+	if _, ok := resource["metadata"].(map[string]interface{}); !ok {
+		resource["metadata"] = map[string]interface{}{}
+	}
+	return resource, nil
+}
+
+// modelVersion returns the version of a dashboard model, decoded from JSON (float64) or set by the caller (int).
+func modelVersion(v interface{}) int {
+	switch v := v.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// dashboardMeta returns the metadata of the dashboard, from the metadata of the resource.
+func (cl *AppPlatformAPIClient) dashboardMeta(ctx context.Context, metadata appPlatformMetadata) (Meta, error) {
+	meta := Meta{
+		FolderUID: metadata.Annotations[annotationFolder],
+		CreatedBy: metadata.Annotations[annotationCreatedBy],
+		UpdatedBy: metadata.Annotations[annotationUpdatedBy],
+		Created:   metadata.CreationTimestamp,
+		Updated:   metadata.Annotations[annotationUpdatedTimestamp],
+
+		Provisioned:           metadata.Annotations[annotationManagedBy] != "",
+		ProvisionedExternalID: metadata.Annotations[annotationSourcePath],
+	}
+	if meta.Updated == "" {
+		meta.Updated = meta.Created
+	}
+	if meta.FolderUID != "" {
+		title, err := cl.folderTitle(ctx, meta.FolderUID)
+		if err != nil {
+			return Meta{}, err
+		}
+		meta.FolderTitle = title
+		meta.FolderURL = "/dashboards/f/" + meta.FolderUID
+	}
+	return meta, nil
+}
+
+// folderTitle returns the title of the folder with the given uid, or an empty string if the folder can't be seen
+// (403 or 404, e.g.: the token can read the dashboard but not its folder), as it's only informative.
+// Titles are cached, so each folder is fetched at most once, without blocking the lookups of the other folders.
+// Other errors are returned and not cached, so the next lookup fetches the folder again.
+func (cl *AppPlatformAPIClient) folderTitle(ctx context.Context, uid string) (string, error) {
+	cl.folderTitlesMu.Lock()
+	entry, ok := cl.folderTitles[uid]
+	if !ok {
+		entry = &folderTitle{done: make(chan struct{})}
+		cl.folderTitles[uid] = entry
+	}
+	cl.folderTitlesMu.Unlock()
+	if ok {
+		select {
+		case <-entry.done:
+			return entry.title, entry.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	entry.title, entry.err = cl.fetchFolderTitle(ctx, uid)
+	if entry.err != nil {
+		cl.folderTitlesMu.Lock()
+		delete(cl.folderTitles, uid)
+		cl.folderTitlesMu.Unlock()
+	}
+	close(entry.done)
+	return entry.title, entry.err
+}
+
+// fetchFolderTitle returns the title of the folder with the given uid, without caching. See folderTitle.
+func (cl *AppPlatformAPIClient) fetchFolderTitle(ctx context.Context, uid string) (string, error) {
+	path, err := cl.resourcePath(ctx, "folder.grafana.app", appPlatformFolderVersion, "folders")
+	if err != nil {
+		return "", err
+	}
+	var folder struct {
+		Spec struct {
+			Title string `json:"title"`
+		} `json:"spec"`
+	}
+	if err := cl.apis.Request(ctx, http.MethodGet, path+"/"+url.PathEscape(uid), &folder); err != nil {
+		switch api.StatusCode(err) {
+		case http.StatusForbidden, http.StatusNotFound:
+			return "", nil
+		}
+		return "", fmt.Errorf("get folder %q: %w", uid, err)
+	}
+	return folder.Spec.Title, nil
+}
+
+// FallbackAPIClient is an APIClient listing and getting dashboards with the legacy APIs, falling back to the
+// App Platform APIs if the legacy ones are not available (e.g.: once they are removed from Grafana).
+type FallbackAPIClient struct {
+	*AppPlatformAPIClient
+
+	useAppPlatform atomic.Bool
+}
+
+// NewFallbackAPIClient returns a new FallbackAPIClient.
+func NewFallbackAPIClient(client api.Client) *FallbackAPIClient {
+	return &FallbackAPIClient{AppPlatformAPIClient: NewAppPlatformAPIClient(client)}
+}
+
+// legacyUnavailable returns true if the given error means that a legacy endpoint is not available.
+func legacyUnavailable(err error) bool {
+	switch api.StatusCode(err) {
+	case http.StatusNotFound, http.StatusGone, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// legacyDashboardUnavailable returns true if the given error of the legacy dashboards API means that the dashboard
+// must be fetched or saved with the App Platform APIs: v2 dashboards (406), or the endpoints have been removed.
+func legacyDashboardUnavailable(err error) bool {
+	switch api.StatusCode(err) {
+	case http.StatusNotAcceptable, http.StatusGone, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// UsesAppPlatform returns true if the client has fallen back to the App Platform APIs.
+func (cl *FallbackAPIClient) UsesAppPlatform() bool {
+	return cl.useAppPlatform.Load()
+}
+
+// GetDashboards lists the dashboards with the legacy search API, or with the App Platform APIs if it's not available.
+func (cl *FallbackAPIClient) GetDashboards(ctx context.Context, page int) ([]ListedDashboard, error) {
+	if !cl.useAppPlatform.Load() {
+		out, err := cl.APIClient.GetDashboards(ctx, page)
+		if !legacyUnavailable(err) {
+			return out, err
+		}
+		cl.useAppPlatform.Store(true)
+	}
+	return cl.AppPlatformAPIClient.GetDashboards(ctx, page)
+}
+
+// GetDashboard returns the dashboard with the given uid, from the legacy dashboards API if the dashboards
+// have been listed with the legacy search API, or from the App Platform APIs otherwise.
+// Dashboards that the legacy API refuses to return (v2 dashboards, with a 406 status code) are also fetched
+// from the App Platform APIs.
+func (cl *FallbackAPIClient) GetDashboard(ctx context.Context, uid string) (*DashboardDefinition, error) {
+	if !cl.useAppPlatform.Load() {
+		out, err := cl.APIClient.GetDashboard(ctx, uid)
+		if !legacyDashboardUnavailable(err) {
+			return out, err
+		}
+	}
+	return cl.AppPlatformAPIClient.GetDashboard(ctx, uid)
+}
+
+// GetDashboardJSON returns the dashboard with the given uid like GetDashboard, in the format of the legacy dashboards API.
+func (cl *FallbackAPIClient) GetDashboardJSON(ctx context.Context, uid string) (json.RawMessage, error) {
+	if !cl.useAppPlatform.Load() {
+		out, err := cl.APIClient.GetDashboardJSON(ctx, uid)
+		if !legacyDashboardUnavailable(err) {
+			return out, err
+		}
+	}
+	return cl.AppPlatformAPIClient.GetDashboardJSON(ctx, uid)
+}
+
+// SaveDashboard saves the given dashboard model with the legacy dashboards API if the dashboards have been listed
+// with the legacy search API, or with the App Platform APIs otherwise.
+func (cl *FallbackAPIClient) SaveDashboard(ctx context.Context, dashboard map[string]interface{}, folderUID, message string) error {
+	if !cl.useAppPlatform.Load() {
+		err := cl.APIClient.SaveDashboard(ctx, dashboard, folderUID, message)
+		if !legacyDashboardUnavailable(err) {
+			return err
+		}
+	}
+	return cl.AppPlatformAPIClient.SaveDashboard(ctx, dashboard, folderUID, message)
+}
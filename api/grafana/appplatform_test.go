@@ -0,0 +1,353 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+)
+
+// newAppPlatformServer returns a server with the given responses (keyed by "path?query"). Other requests get a 404.
+func newAppPlatformServer(t *testing.T, responses map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const appPlatformDashboards = "/apis/dashboard.grafana.app/v1beta1/namespaces/org-2/dashboards"
+
+func TestAppPlatformAPIClient(t *testing.T) {
+	responses := map[string]string{
+		"/api/frontend/settings": `{"namespace": "org-2"}`,
+		appPlatformDashboards + "?limit=500": `{
+			"metadata": {"continue": "next"},
+			"items": [{"metadata": {"name": "a", "generation": 3}, "spec": {"title": "A", "tags": ["team-a", "prod"]}}]
+		}`,
+		appPlatformDashboards + "?continue=next&limit=500": `{
+			"metadata": {},
+			"items": [{"metadata": {"name": "v2", "annotations": {"grafana.app/folder": "f"}}, "spec": {"title": "V2"}}]
+		}`,
+		appPlatformDashboards + "/a": `{
+			"metadata": {
+				"name": "a",
+				"generation": 3,
+				"creationTimestamp": "2024-01-01T00:00:00Z",
+				"annotations": {
+					"grafana.app/folder": "f",
+					"grafana.app/createdBy": "user:admin",
+					"grafana.app/updatedBy": "user:editor",
+					"grafana.app/updatedTimestamp": "2024-02-01T00:00:00Z",
+					"grafana.app/managedBy": "classic-file-provisioning",
+					"grafana.app/sourcePath": "dashboards/a.json"
+				}
+			},
+			"spec": {"title": "A", "schemaVersion": 39, "panels": [{"id": 1, "type": "graph", "datasource": "prometheus"}]}
+		}`,
+		appPlatformDashboards + "/v2": `{
+			"metadata": {"name": "v2"},
+			"spec": {},
+			"status": {"conversion": {"failed": true, "storedVersion": "v2beta1"}}
+		}`,
+		"/apis/dashboard.grafana.app/v2beta1/namespaces/org-2/dashboards/v2": `{
+			"metadata": {"name": "v2"},
+			"spec": {
+				"title": "V2",
+				"elements": {"panel-1": {"kind": "Panel", "spec": {"id": 1, "vizConfig": {"kind": "graph"}}}},
+				"layout": {"kind": "GridLayout", "spec": {"items": [{"kind": "GridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "panel-1"}}}]}}
+			}
+		}`,
+		"/apis/folder.grafana.app/v1beta1/namespaces/org-2/folders/f": `{"metadata": {"name": "f"}, "spec": {"title": "Folder"}}`,
+	}
+	srv := newAppPlatformServer(t, responses)
+	cl := NewAppPlatformAPIClient(api.NewClient(srv.URL + "/api"))
+
+	t.Run("list", func(t *testing.T) {
+		dashboards, err := cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{
+			{UID: "a", URL: "/d/a", Title: "A", Tags: []string{"team-a", "prod"}, Version: 3},
+			{UID: "v2", URL: "/d/v2", Title: "V2", FolderUID: "f", FolderTitle: "Folder"},
+		}, dashboards)
+
+		dashboards, err = cl.GetDashboards(context.Background(), 2)
+		require.NoError(t, err)
+		require.Empty(t, dashboards)
+	})
+
+	t.Run("list folders", func(t *testing.T) {
+		cl := NewAppPlatformAPIClient(api.NewClient(srv.URL + "/api"))
+		cl.FolderUIDs = []string{"f"}
+		dashboards, err := cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "v2", URL: "/d/v2", Title: "V2", FolderUID: "f", FolderTitle: "Folder"}}, dashboards)
+
+		cl.FolderUIDs = []string{"general"}
+		dashboards, err = cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "a", URL: "/d/a", Title: "A", Tags: []string{"team-a", "prod"}, Version: 3}}, dashboards)
+	})
+
+	t.Run("list tags and uids", func(t *testing.T) {
+		cl := NewAppPlatformAPIClient(api.NewClient(srv.URL + "/api"))
+		cl.Tags = []string{"prod", "team-a"}
+		dashboards, err := cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "a", URL: "/d/a", Title: "A", Tags: []string{"team-a", "prod"}, Version: 3}}, dashboards)
+
+		cl.Tags = []string{"prod", "team-b"}
+		dashboards, err = cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Empty(t, dashboards)
+
+		cl.Tags = nil
+		cl.DashboardUIDs = []string{"v2", "missing"}
+		dashboards, err = cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "v2", URL: "/d/v2", Title: "V2", FolderUID: "f", FolderTitle: "Folder"}}, dashboards)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		dashboard, err := cl.GetDashboard(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, "a", dashboard.Dashboard.UID)
+		require.Equal(t, "A", dashboard.Dashboard.Title)
+		require.Len(t, dashboard.Dashboard.Panels, 1)
+		require.Equal(t, "graph", dashboard.Dashboard.Panels[0].Type)
+		require.Equal(t, 3, dashboard.Dashboard.Version)
+		require.Equal(t, "f", dashboard.Meta.FolderUID)
+		require.Equal(t, "Folder", dashboard.Meta.FolderTitle)
+		require.Equal(t, "user:admin", dashboard.Meta.CreatedBy)
+		require.Equal(t, "user:editor", dashboard.Meta.UpdatedBy)
+		require.Equal(t, "2024-01-01T00:00:00Z", dashboard.Meta.Created)
+		require.Equal(t, "2024-02-01T00:00:00Z", dashboard.Meta.Updated)
+		require.True(t, dashboard.Meta.Provisioned)
+		require.Equal(t, "dashboards/a.json", dashboard.Meta.ProvisionedExternalID)
+	})
+
+	t.Run("get v2", func(t *testing.T) {
+		dashboard, err := cl.GetDashboard(context.Background(), "v2")
+		require.NoError(t, err)
+		require.Equal(t, "v2", dashboard.Dashboard.UID)
+		require.Equal(t, "V2", dashboard.Dashboard.Title)
+		require.Len(t, dashboard.Dashboard.Panels, 1)
+		require.Equal(t, "graph", dashboard.Dashboard.Panels[0].Type)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := cl.GetDashboard(context.Background(), "missing")
+		require.Equal(t, http.StatusNotFound, api.StatusCode(err))
+	})
+}
+
+func TestAppPlatformAPIClientFolderTitle(t *testing.T) {
+	var requests sync.Map
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := requests.LoadOrStore(r.URL.Path, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		switch r.URL.Path {
+		case "/api/frontend/settings":
+			_, _ = w.Write([]byte(`{"namespace": "default"}`))
+		case "/apis/folder.grafana.app/v1beta1/namespaces/default/folders/f":
+			_, _ = w.Write([]byte(`{"spec": {"title": "Folder"}}`))
+		case "/apis/folder.grafana.app/v1beta1/namespaces/default/folders/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/apis/folder.grafana.app/v1beta1/namespaces/default/folders/flaky":
+			if failing.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"spec": {"title": "Flaky"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cl := NewAppPlatformAPIClient(api.NewClient(srv.URL + "/api"))
+	requestCount := func(uid string) int32 {
+		n, ok := requests.Load("/apis/folder.grafana.app/v1beta1/namespaces/default/folders/" + uid)
+		if !ok {
+			return 0
+		}
+		return n.(*atomic.Int32).Load()
+	}
+
+	t.Run("fetched once", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				title, err := cl.folderTitle(context.Background(), "f")
+				require.NoError(t, err)
+				require.Equal(t, "Folder", title)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), requestCount("f"))
+	})
+
+	t.Run("not visible", func(t *testing.T) {
+		for _, uid := range []string{"forbidden", "missing"} {
+			for i := 0; i < 2; i++ {
+				title, err := cl.folderTitle(context.Background(), uid)
+				require.NoError(t, err)
+				require.Empty(t, title)
+			}
+			require.Equal(t, int32(1), requestCount(uid))
+		}
+	})
+
+	t.Run("errors not cached", func(t *testing.T) {
+		_, err := cl.folderTitle(context.Background(), "flaky")
+		require.Equal(t, http.StatusInternalServerError, api.StatusCode(err))
+
+		failing.Store(false)
+		title, err := cl.folderTitle(context.Background(), "flaky")
+		require.NoError(t, err)
+		require.Equal(t, "Flaky", title)
+		require.Equal(t, int32(2), requestCount("flaky"))
+	})
+}
+
+func TestFallbackAPIClient(t *testing.T) {
+	t.Run("legacy", func(t *testing.T) {
+		srv := newAppPlatformServer(t, map[string]string{
+			"/api/search?limit=5000&page=1": `[{"uid": "a", "url": "/d/a/slug", "title": "A"}]`,
+			"/api/dashboards/uid/a":         `{"dashboard": {"uid": "a", "title": "A"}, "meta": {"slug": "slug"}}`,
+		})
+		cl := NewFallbackAPIClient(api.NewClient(srv.URL + "/api"))
+		dashboards, err := cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "a", URL: "/d/a/slug", Title: "A"}}, dashboards)
+		require.False(t, cl.UsesAppPlatform())
+		dashboard, err := cl.GetDashboard(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, "slug", dashboard.Meta.Slug)
+		_, err = cl.GetDashboard(context.Background(), "missing")
+		require.Equal(t, http.StatusNotFound, api.StatusCode(err))
+	})
+
+	t.Run("legacy filters", func(t *testing.T) {
+		srv := newAppPlatformServer(t, map[string]string{
+			"/api/search?dashboardUIDs=b&folderUIDs=f1&folderUIDs=f2&limit=5000&page=1&tag=t1&tag=t2": `[{"uid": "b", "url": "/d/b/slug", "title": "B"}]`,
+		})
+		cl := NewFallbackAPIClient(api.NewClient(srv.URL + "/api"))
+		cl.FolderUIDs = []string{"f1", "f2"}
+		cl.Tags = []string{"t1", "t2"}
+		cl.DashboardUIDs = []string{"b"}
+		dashboards, err := cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "b", URL: "/d/b/slug", Title: "B"}}, dashboards)
+		require.False(t, cl.UsesAppPlatform())
+	})
+
+	t.Run("app platform", func(t *testing.T) {
+		srv := newAppPlatformServer(t, map[string]string{
+			"/api/frontend/settings":             `{"namespace": "org-2"}`,
+			appPlatformDashboards + "?limit=500": `{"metadata": {}, "items": [{"metadata": {"name": "a"}, "spec": {"title": "A"}}]}`,
+			appPlatformDashboards + "/a":         `{"metadata": {"name": "a"}, "spec": {"title": "A"}}`,
+		})
+		cl := NewFallbackAPIClient(api.NewClient(srv.URL + "/api"))
+		dashboards, err := cl.GetDashboards(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, []ListedDashboard{{UID: "a", URL: "/d/a", Title: "A"}}, dashboards)
+		require.True(t, cl.UsesAppPlatform())
+		dashboard, err := cl.GetDashboard(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, "A", dashboard.Dashboard.Title)
+	})
+}
+
+func TestAppPlatformAPIClientSaveDashboard(t *testing.T) {
+	// requests are the saved resources, by "METHOD path"
+	requests := map[string]map[string]interface{}{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/frontend/settings":
+			_, _ = w.Write([]byte(`{"namespace": "org-2"}`))
+		case "GET " + appPlatformDashboards + "/a":
+			_, _ = w.Write([]byte(`{
+				"metadata": {
+					"name": "a",
+					"generation": 3,
+					"resourceVersion": "123",
+					"labels": {"team": "a"},
+					"annotations": {"grafana.app/folder": "f"}
+				},
+				"spec": {"title": "A", "panels": [{"id": 1, "type": "graph"}]},
+				"status": {}
+			}`))
+		case "GET " + appPlatformDashboards + "/v2":
+			_, _ = w.Write([]byte(`{"metadata": {"name": "v2"}, "spec": {}, "status": {"conversion": {"failed": true, "storedVersion": "v2beta1"}}}`))
+		case "PUT " + appPlatformDashboards + "/a", "POST " + appPlatformDashboards:
+			var resource map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&resource))
+			requests[r.Method+" "+r.URL.Path] = resource
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	cl := NewAppPlatformAPIClient(api.NewClient(srv.URL + "/api"))
+
+	t.Run("get", func(t *testing.T) {
+		raw, err := cl.GetDashboardJSON(context.Background(), "a")
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"dashboard": {"uid": "a", "version": 3, "title": "A", "panels": [{"id": 1, "type": "graph"}]},
+			"meta": {"folderUid": "f"}
+		}`, string(raw))
+
+		_, err = cl.GetDashboardJSON(context.Background(), "v2")
+		require.ErrorContains(t, err, "v2beta1")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		dashboard := map[string]interface{}{"uid": "a", "version": float64(3), "title": "A", "panels": []interface{}{}}
+		require.NoError(t, cl.SaveDashboard(context.Background(), dashboard, "f", "fix"))
+		resource := requests["PUT "+appPlatformDashboards+"/a"]
+		require.Equal(t, map[string]interface{}{
+			"name":            "a",
+			"generation":      float64(3),
+			"resourceVersion": "123",
+			"labels":          map[string]interface{}{"team": "a"},
+			"annotations":     map[string]interface{}{"grafana.app/folder": "f", "grafana.app/message": "fix"},
+		}, resource["metadata"])
+		require.Equal(t, map[string]interface{}{"title": "A", "panels": []interface{}{}}, resource["spec"])
+		require.NotContains(t, resource, "status")
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		dashboard := map[string]interface{}{"uid": "a", "version": 2, "title": "A"}
+		err := cl.SaveDashboard(context.Background(), dashboard, "f", "fix")
+		require.Equal(t, http.StatusPreconditionFailed, api.StatusCode(err))
+	})
+
+	t.Run("create", func(t *testing.T) {
+		dashboard := map[string]interface{}{"uid": "new", "title": "New"}
+		require.NoError(t, cl.SaveDashboard(context.Background(), dashboard, "", "publish"))
+		require.Equal(t, map[string]interface{}{
+			"apiVersion": "dashboard.grafana.app/v1beta1",
+			"kind":       "Dashboard",
+			"metadata":   map[string]interface{}{"name": "new", "annotations": map[string]interface{}{"grafana.app/message": "publish"}},
+			"spec":       map[string]interface{}{"title": "New"},
+		}, requests["POST "+appPlatformDashboards])
+	})
+}
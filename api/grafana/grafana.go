@@ -1,11 +1,16 @@
 package grafana
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grafana/detect-angular-dashboards/api"
 )
@@ -14,8 +19,20 @@ var errUnknownAngularStatus = errors.New("could not determine if plugin is angul
 
 const DefaultBaseURL = "http://127.0.0.1:3000/api"
 
+// GeneralFolderUID is the uid of the root folder in the folder filters of the search API.
+const GeneralFolderUID = "general"
+
 type APIClient struct {
 	api.Client
+
+	// FolderUIDs are the uids of the folders GetDashboards is restricted to. All the dashboards are returned if empty.
+	FolderUIDs []string
+
+	// Tags restricts GetDashboards to the dashboards with all these tags, if not empty.
+	Tags []string
+
+	// DashboardUIDs restricts GetDashboards to the dashboards with these uids, if not empty.
+	DashboardUIDs []string
 }
 
 func NewAPIClient(client api.Client) APIClient {
@@ -32,30 +49,348 @@ func (cl APIClient) GetPlugins(ctx context.Context) ([]Plugin, error) {
 	return out, err
 }
 
+// GetPluginModule returns the content of the module.js file of the given plugin, served by Grafana
+// outside of the HTTP API (/public/plugins/{id}/module.js).
+func (cl APIClient) GetPluginModule(ctx context.Context, pluginID string) ([]byte, error) {
+	public := cl.Client
+	public.BaseURL = strings.TrimSuffix(strings.TrimSuffix(public.BaseURL, "/"), "/api") + "/public"
+	return public.RequestBytes(ctx, http.MethodGet, "plugins/"+url.PathEscape(pluginID)+"/module.js")
+}
+
 func (cl APIClient) GetDatasourcePluginIDs(ctx context.Context) ([]Datasource, error) {
 	var out []Datasource
 	err := cl.Request(ctx, http.MethodGet, "datasources", &out)
 	return out, err
 }
 
+// GetDashboards returns the given page of the dashboards, restricted by cl.FolderUIDs, cl.Tags and cl.DashboardUIDs if set.
+// The search API only returns the dashboards directly in the given folders, not the ones in their subfolders.
 func (cl APIClient) GetDashboards(ctx context.Context, page int) ([]ListedDashboard, error) {
 	var out []ListedDashboard
-	err := cl.Request(ctx, http.MethodGet, "search?"+url.Values{
+	query := url.Values{
 		"limit": []string{"5000"},
 		"page":  []string{strconv.Itoa(page)},
+	}
+	if len(cl.FolderUIDs) > 0 {
+		query["folderUIDs"] = cl.FolderUIDs
+	}
+	if len(cl.Tags) > 0 {
+		query["tag"] = cl.Tags
+	}
+	if len(cl.DashboardUIDs) > 0 {
+		query["dashboardUIDs"] = cl.DashboardUIDs
+	}
+	err := cl.Request(ctx, http.MethodGet, "search?"+query.Encode(), &out)
+	return out, err
+}
+
+// GetDeletedDashboards returns the dashboards in the trash (soft-deleted), available in Grafana >= 11.
+// Older versions ignore the "deleted" parameter and return all the dashboards, which are not flagged as deleted.
+func (cl APIClient) GetDeletedDashboards(ctx context.Context) ([]ListedDashboard, error) {
+	var out []ListedDashboard
+	err := cl.Request(ctx, http.MethodGet, "search?"+url.Values{
+		"type":    []string{"dash-db"},
+		"deleted": []string{"true"},
+		"limit":   []string{"5000"},
 	}.Encode(), &out)
 	return out, err
 }
 
 func (cl APIClient) GetDashboard(ctx context.Context, uid string) (*DashboardDefinition, error) {
 	var out *DashboardDefinition
+	if err := cl.RequestConditional(ctx, "dashboards/uid/"+uid, &out); err != nil {
+		return nil, err
+	}
+	ConvertDashboard(&out.Dashboard)
+	return out, nil
+}
+
+// GetDashboardJSON returns the dashboard with the given uid and its metadata as returned by the API,
+// without converting the dashboard, so it can be modified and saved back with SaveDashboard.
+func (cl APIClient) GetDashboardJSON(ctx context.Context, uid string) (json.RawMessage, error) {
+	var out json.RawMessage
 	if err := cl.Request(ctx, http.MethodGet, "dashboards/uid/"+uid, &out); err != nil {
 		return nil, err
 	}
-	ConvertPanels(out.Dashboard.Panels)
 	return out, nil
 }
 
+// SaveDashboard saves the given dashboard model in the folder with the given uid, with the given version message.
+// The dashboard is not overwritten: saving fails if it has been changed since its version in the model.
+func (cl APIClient) SaveDashboard(ctx context.Context, dashboard map[string]interface{}, folderUID, message string) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"dashboard": dashboard,
+		"folderUid": folderUID,
+		"message":   message,
+		"overwrite": false,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return cl.RequestWithBody(ctx, http.MethodPost, "dashboards/db", bytes.NewReader(b), nil)
+}
+
+// GetAnnotations returns the dashboard-wide annotations of the dashboard with the given uid that have all the given tags,
+// or the ones of all the dashboards if dashboardUID is empty.
+func (cl APIClient) GetAnnotations(ctx context.Context, dashboardUID string, tags []string) ([]Annotation, error) {
+	query := url.Values{
+		"type":  []string{"annotation"},
+		"tags":  tags,
+		"limit": []string{"10000"},
+	}
+	if dashboardUID != "" {
+		query.Set("dashboardUID", dashboardUID)
+	}
+	var out []Annotation
+	if err := cl.Request(ctx, http.MethodGet, "annotations?"+query.Encode(), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateAnnotation creates the given annotation.
+func (cl APIClient) CreateAnnotation(ctx context.Context, annotation Annotation) error {
+	b, err := json.Marshal(annotation)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return cl.RequestWithBody(ctx, http.MethodPost, "annotations", bytes.NewReader(b), nil)
+}
+
+// UpdateAnnotation replaces the time, end time, tags and text of the annotation with the ID of the given one.
+func (cl APIClient) UpdateAnnotation(ctx context.Context, annotation Annotation) error {
+	b, err := json.Marshal(annotation)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	return cl.RequestWithBody(ctx, http.MethodPut, "annotations/"+strconv.FormatInt(annotation.ID, 10), bytes.NewReader(b), nil)
+}
+
+// DeleteAnnotation deletes the annotation with the given id.
+func (cl APIClient) DeleteAnnotation(ctx context.Context, id int64) error {
+	return cl.Request(ctx, http.MethodDelete, "annotations/"+strconv.FormatInt(id, 10), nil)
+}
+
+// GetDashboardVersions returns the versions of the dashboard with the given uid.
+// Grafana only keeps a limited number of versions for each dashboard, so older versions may be missing.
+func (cl APIClient) GetDashboardVersions(ctx context.Context, uid string) ([]DashboardVersion, error) {
+	var raw json.RawMessage
+	if err := cl.Request(ctx, http.MethodGet, "dashboards/uid/"+uid+"/versions?"+url.Values{
+		"limit": []string{"1000"},
+	}.Encode(), &raw); err != nil {
+		return nil, err
+	}
+	// Grafana < 11 returns an array, Grafana >= 11 returns an object with a "versions" field.
+	var out []DashboardVersion
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil, fmt.Errorf("decode: %w", err)
+		}
+		return out, nil
+	}
+	var resp struct {
+		Versions []DashboardVersion `json:"versions"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	return resp.Versions, nil
+}
+
+// GetDashboardVersion returns the given version of the dashboard with the given uid.
+func (cl APIClient) GetDashboardVersion(ctx context.Context, uid string, version int) (*DashboardVersionDefinition, error) {
+	var out *DashboardVersionDefinition
+	if err := cl.Request(ctx, http.MethodGet, "dashboards/uid/"+uid+"/versions/"+strconv.Itoa(version), &out); err != nil {
+		return nil, err
+	}
+	ConvertDashboard(&out.Data)
+	return out, nil
+}
+
+// GetPublicDashboards returns all the public dashboards of the current org.
+func (cl APIClient) GetPublicDashboards(ctx context.Context) ([]PublicDashboard, error) {
+	var out []PublicDashboard
+	for page := 1; ; page++ {
+		var resp PublicDashboardList
+		if err := cl.Request(ctx, http.MethodGet, "dashboards/public-dashboards?"+url.Values{
+			"perpage": []string{"1000"},
+			"page":    []string{strconv.Itoa(page)},
+		}.Encode(), &resp); err != nil {
+			return nil, err
+		}
+		out = append(out, resp.PublicDashboards...)
+		if len(resp.PublicDashboards) == 0 || len(out) >= resp.TotalCount {
+			break
+		}
+	}
+	return out, nil
+}
+
+// GetOrgPreferences returns the preferences of the current org.
+func (cl APIClient) GetOrgPreferences(ctx context.Context) (*Preferences, error) {
+	var out Preferences
+	if err := cl.Request(ctx, http.MethodGet, "org/preferences", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserPreferences returns the preferences of the current user.
+func (cl APIClient) GetUserPreferences(ctx context.Context) (*Preferences, error) {
+	var out Preferences
+	if err := cl.Request(ctx, http.MethodGet, "user/preferences", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTeams returns all the teams of the current org.
+func (cl APIClient) GetTeams(ctx context.Context) ([]Team, error) {
+	var out []Team
+	for page := 1; ; page++ {
+		var resp TeamSearch
+		if err := cl.Request(ctx, http.MethodGet, "teams/search?"+url.Values{
+			"perpage": []string{"1000"},
+			"page":    []string{strconv.Itoa(page)},
+		}.Encode(), &resp); err != nil {
+			return nil, err
+		}
+		out = append(out, resp.Teams...)
+		if len(resp.Teams) == 0 || len(out) >= resp.TotalCount {
+			break
+		}
+	}
+	return out, nil
+}
+
+// GetTeamPreferences returns the preferences of the team with the given id.
+func (cl APIClient) GetTeamPreferences(ctx context.Context, teamID int) (*Preferences, error) {
+	var out Preferences
+	if err := cl.Request(ctx, http.MethodGet, "teams/"+strconv.Itoa(teamID)+"/preferences", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCurrentUser returns the user (or service account) the API token belongs to.
+func (cl APIClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	var out User
+	if err := cl.Request(ctx, http.MethodGet, "user", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDashboardViews returns the usage insights (view count and last viewed time) of all the dashboards.
+// It uses the search sorting options provided by usage insights, so it's only available
+// on Grafana Enterprise and Cloud.
+func (cl APIClient) GetDashboardViews(ctx context.Context) ([]DashboardViews, error) {
+	search := func(sort string) ([]sortedSearchHit, error) {
+		var out []sortedSearchHit
+		err := cl.Request(ctx, http.MethodGet, "search?"+url.Values{
+			"type":  []string{"dash-db"},
+			"sort":  []string{sort},
+			"limit": []string{"5000"},
+		}.Encode(), &out)
+		return out, err
+	}
+	totals, err := search("views-total")
+	if err != nil {
+		return nil, fmt.Errorf("search by total views: %w", err)
+	}
+	lastViewed, err := search("viewed-recently")
+	if err != nil {
+		return nil, fmt.Errorf("search by last viewed: %w", err)
+	}
+
+	out := make([]DashboardViews, 0, len(totals))
+	byUID := make(map[string]int, len(totals))
+	for _, hit := range totals {
+		byUID[hit.UID] = len(out)
+		out = append(out, DashboardViews{UID: hit.UID, Views: int(hit.SortMeta)})
+	}
+	for _, hit := range lastViewed {
+		i, ok := byUID[hit.UID]
+		if !ok || hit.SortMeta <= 0 {
+			continue
+		}
+		// Last viewed time is returned as a Unix timestamp in milliseconds
+		out[i].LastViewed = time.UnixMilli(hit.SortMeta).UTC().Format(time.RFC3339)
+	}
+	return out, nil
+}
+
+// GetDashboardPermissions returns the permissions of the dashboard with the given uid,
+// including the ones inherited from its folder.
+func (cl APIClient) GetDashboardPermissions(ctx context.Context, uid string) ([]DashboardPermission, error) {
+	var out []DashboardPermission
+	if err := cl.Request(ctx, http.MethodGet, "dashboards/uid/"+uid+"/permissions", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFolderPermissions returns the permissions of the folder with the given uid.
+func (cl APIClient) GetFolderPermissions(ctx context.Context, uid string) ([]DashboardPermission, error) {
+	var out []DashboardPermission
+	if err := cl.Request(ctx, http.MethodGet, "folders/"+uid+"/permissions", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFolders returns all the folders, including nested folders (Grafana >= 10), which have their ParentUID set.
+func (cl APIClient) GetFolders(ctx context.Context) ([]Folder, error) {
+	var out []Folder
+	err := cl.Request(ctx, http.MethodGet, "search?"+url.Values{
+		"type":  []string{"dash-folder"},
+		"limit": []string{"5000"},
+	}.Encode(), &out)
+	return out, err
+}
+
+// GetOrgUsers returns the users of the current org.
+func (cl APIClient) GetOrgUsers(ctx context.Context) ([]OrgUser, error) {
+	var out []OrgUser
+	if err := cl.Request(ctx, http.MethodGet, "org/users", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLibraryElement returns the library panel with the given uid.
+func (cl APIClient) GetLibraryElement(ctx context.Context, uid string) (*LibraryElement, error) {
+	var resp struct {
+		Result LibraryElement `json:"result"`
+	}
+	if err := cl.Request(ctx, http.MethodGet, "library-elements/"+uid, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Result.Model != nil {
+		ConvertPanels([]*DashboardPanel{resp.Result.Model})
+	}
+	return &resp.Result, nil
+}
+
+// GetLibraryElementConnections returns the dashboards using the library panel with the given uid.
+func (cl APIClient) GetLibraryElementConnections(ctx context.Context, uid string) ([]LibraryElementConnection, error) {
+	var resp struct {
+		Result []LibraryElementConnection `json:"result"`
+	}
+	if err := cl.Request(ctx, http.MethodGet, "library-elements/"+uid+"/connections", &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// ConvertDashboard converts the datasources of the panels and template variables of the dashboard to custom types.
+func ConvertDashboard(dashboard *Dashboard) {
+	ConvertPanels(dashboard.Panels)
+	for _, v := range dashboard.Templating.List {
+		v.Datasource = convertDatasource(v.Datasource)
+	}
+}
+
 // ConvertPanels recursively converts datasources map[string]interface{} to custom type.
 // The datasource field can either be a string (old) or object (new).
 // Could check for schema, but this is easier.
@@ -65,20 +400,28 @@ func ConvertPanels(panels []*DashboardPanel) {
 		if len(panel.Panels) > 0 {
 			ConvertPanels(panel.Panels)
 		}
-
-		m, ok := panel.Datasource.(map[string]interface{})
-		if !ok {
-			// String, keep as-is
-			continue
+		panel.Datasource = convertDatasource(panel.Datasource)
+		for _, target := range panel.Targets {
+			target.Datasource = convertDatasource(target.Datasource)
 		}
-		// Use struct instead of generic map
+	}
+}
 
-		// (pointer to value)
-		if m["type"] == nil {
-			m["type"] = ""
-		}
-		panel.Datasource = PanelDatasource{Type: m["type"].(string)}
+// convertDatasource converts a datasource map[string]interface{} to PanelDatasource.
+// Other types (string) are returned as-is.
+func convertDatasource(datasource interface{}) interface{} {
+	m, ok := datasource.(map[string]interface{})
+	if !ok {
+		// String, keep as-is
+		return datasource
+	}
+	// Use struct instead of generic map
+
+	// (pointer to value)
+	if m["type"] == nil {
+		m["type"] = ""
 	}
+	return PanelDatasource{Type: m["type"].(string)}
 }
 
 func (cl APIClient) GetOrgs(ctx context.Context) ([]Org, error) {
@@ -100,6 +443,16 @@ type FrontendSettings struct {
 
 	// Datasources is a map from datasource names to plugin metadata
 	Datasources map[string]FrontendSettingsDatasource
+
+	// Namespace is the App Platform namespace of the current org (e.g.: "default" or "org-2"), used by the
+	// Kubernetes-style APIs. It's empty in older Grafana versions.
+	Namespace string
+
+	// BuildInfo contains information about the Grafana build
+	BuildInfo struct {
+		// Version is the Grafana version
+		Version string
+	}
 }
 
 // FrontendSettingsPanel is a panel present in FrontendSettings.
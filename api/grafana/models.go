@@ -1,5 +1,11 @@
 package grafana
 
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
 type PluginInfo struct {
 	Version string `json:"version"`
 }
@@ -10,14 +16,31 @@ type Plugin struct {
 }
 
 type Datasource struct {
-	Name string
-	Type string
+	UID       string
+	Name      string
+	Type      string
+	URL       string
+	IsDefault bool
 }
 
 type ListedDashboard struct {
+	ID    int
 	UID   string
 	URL   string
 	Title string
+
+	// FolderUID and FolderTitle are the uid and title of the folder of the dashboard, empty in the root folder.
+	FolderUID   string
+	FolderTitle string
+
+	// Tags are the tags of the dashboard.
+	Tags []string
+
+	// IsDeleted is true for dashboards in the trash (Grafana >= 11).
+	IsDeleted bool
+
+	// Version is the version of the dashboard, if listed with it (App Platform API), 0 otherwise.
+	Version int
 }
 
 type PanelDatasource struct {
@@ -25,11 +48,83 @@ type PanelDatasource struct {
 }
 
 type DashboardPanel struct {
+	ID         int
 	Type       string
 	Title      string
 	Datasource interface{}
+	Targets    []*PanelTarget
+
+	// Repeat is the name of the template variable used to repeat the panel (or row), if any.
+	Repeat string
+	// RepeatDirection is the direction in which the panel is repeated ("h" or "v").
+	RepeatDirection string
 
 	Panels []*DashboardPanel // present for collapsed rows
+	Links  []*Link
+
+	// GridPos is the position and size of the panel in the dashboard grid.
+	// It's nil in old dashboards, which use a row-based layout.
+	GridPos *GridPos
+
+	// LibraryPanel is the library panel used by the panel, if any.
+	// Recent Grafana versions only return the reference to the library panel, without the panel model.
+	LibraryPanel *LibraryPanelRef
+
+	// Raw contains all the top-level fields of the panel JSON model, including the ones
+	// that are not mapped to other fields (e.g.: plugin-specific options).
+	Raw map[string]json.RawMessage `json:"-"`
+}
+
+// GridPos is the position (X, Y) and size (W, H) of a panel in the dashboard grid, which is 24 columns wide.
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// UnmarshalJSON unmarshals the panel, and keeps all the top-level fields in p.Raw.
+func (p *DashboardPanel) UnmarshalJSON(b []byte) error {
+	type panel DashboardPanel
+	if err := json.Unmarshal(b, (*panel)(p)); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &p.Raw)
+}
+
+// PanelTarget is a query of a panel.
+// Panels using the "-- Mixed --" datasource have a different datasource for each target.
+type PanelTarget struct {
+	Datasource interface{}
+}
+
+// LibraryPanelRef is a reference to a library panel in a dashboard.
+type LibraryPanelRef struct {
+	UID  string
+	Name string
+}
+
+// LibraryElement is a library panel.
+type LibraryElement struct {
+	UID   string          `json:"uid"`
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Model *DashboardPanel `json:"model"`
+}
+
+// LibraryElementConnection is a dashboard using a library panel.
+type LibraryElementConnection struct {
+	ConnectionUID string `json:"connectionUid"`
+}
+
+type TemplateVariable struct {
+	Type       string
+	Name       string
+	Datasource interface{}
+}
+
+type Templating struct {
+	List []*TemplateVariable
 }
 
 type DashboardDefinition struct {
@@ -38,9 +133,43 @@ type DashboardDefinition struct {
 }
 
 type Dashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
 	Panels        []*DashboardPanel `json:"panels"`
+	Templating    Templating        `json:"templating"`
 	SchemaVersion int               `json:"schemaVersion"`
+	Version       int               `json:"version"`
+	Links         []*Link           `json:"links"`
+	GnetID        GnetID            `json:"gnetId"`
+
+	// V2 is true for the dashboards using the v2 schema, converted to this model. The v2 schema has no schema version,
+	// and replaces the v1 schema versions: v2 dashboards are equivalent to v1 dashboards at the latest schema version,
+	// so they must not be mistaken for old dashboards (e.g.: "table" panels are only Angular with a schema version < 24).
+	V2 bool `json:"-"`
+}
+
+// GnetID is the id of the grafana.com dashboard a dashboard has been imported from, 0 if it has not been imported.
+// Depending on how the dashboard has been exported, it can be a number, a string or null in the JSON model.
+type GnetID int
+
+// UnmarshalJSON unmarshals the id from a number or a string. Invalid ids are ignored.
+func (id *GnetID) UnmarshalJSON(b []byte) error {
+	n, err := strconv.Atoi(strings.Trim(string(b), `"`))
+	if err != nil {
+		*id = 0
+		return nil
+	}
+	*id = GnetID(n)
+	return nil
 }
+
+// Link is a dashboard or panel link.
+type Link struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
 type Meta struct {
 	Slug        string `json:"slug"`
 	UpdatedBy   string `json:"updatedBy"`
@@ -50,6 +179,121 @@ type Meta struct {
 	FolderUID   string `json:"folderUid"`
 	FolderTitle string `json:"folderTitle"`
 	FolderURL   string `json:"folderUrl"`
+
+	Provisioned           bool   `json:"provisioned"`
+	ProvisionedExternalID string `json:"provisionedExternalId"`
+}
+
+type DashboardVersion struct {
+	ID        int    `json:"id"`
+	Version   int    `json:"version"`
+	Created   string `json:"created"`
+	CreatedBy string `json:"createdBy"`
+	Message   string `json:"message"`
+}
+
+type DashboardVersionDefinition struct {
+	DashboardVersion
+	Data Dashboard `json:"data"`
+}
+
+type PublicDashboard struct {
+	UID          string `json:"uid"`
+	DashboardUID string `json:"dashboardUid"`
+	IsEnabled    bool   `json:"isEnabled"`
+}
+
+type PublicDashboardList struct {
+	PublicDashboards []PublicDashboard `json:"publicDashboards"`
+	TotalCount       int               `json:"totalCount"`
+	Page             int               `json:"page"`
+	PerPage          int               `json:"perPage"`
+}
+
+type Preferences struct {
+	HomeDashboardID  int    `json:"homeDashboardId"`
+	HomeDashboardUID string `json:"homeDashboardUID"`
+}
+
+type Team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type TeamSearch struct {
+	Teams      []Team `json:"teams"`
+	TotalCount int    `json:"totalCount"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+}
+
+// User is the user (or service account) the API token belongs to.
+type User struct {
+	ID             int    `json:"id"`
+	Login          string `json:"login"`
+	Email          string `json:"email"`
+	Name           string `json:"name"`
+	OrgID          int    `json:"orgId"`
+	IsGrafanaAdmin bool   `json:"isGrafanaAdmin"`
+}
+
+// DashboardViews are the usage insights of a dashboard (Grafana Enterprise and Cloud).
+type DashboardViews struct {
+	UID string
+	// Views is the total number of views of the dashboard.
+	Views int
+	// LastViewed is the time the dashboard was last viewed, as returned by the API (RFC3339).
+	// It's empty if the dashboard has never been viewed.
+	LastViewed string
+}
+
+// sortedSearchHit is a search result sorted by a usage insights field, which is returned in SortMeta.
+type sortedSearchHit struct {
+	UID      string `json:"uid"`
+	SortMeta int64  `json:"sortMeta"`
+}
+
+// Permission levels of dashboards and folders. PermissionEdit and PermissionAdmin grant edit rights.
+const (
+	PermissionView  = 1
+	PermissionEdit  = 2
+	PermissionAdmin = 4
+)
+
+// DashboardPermission is a permission on a dashboard, either set on the dashboard or inherited from its folder.
+// Exactly one of UserLogin, Team and Role is set. TeamID is set with Team.
+// Folder permissions have the same format.
+type DashboardPermission struct {
+	UserLogin  string `json:"userLogin"`
+	Team       string `json:"team"`
+	TeamID     int    `json:"teamId"`
+	Role       string `json:"role"`
+	Permission int    `json:"permission"`
+	Inherited  bool   `json:"inherited"`
+}
+
+// Folder is a dashboard folder. ParentUID is empty for top-level folders.
+type Folder struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	ParentUID string `json:"folderUid"`
+}
+
+// Annotation is an annotation of a dashboard. PanelID is zero for the annotations of the whole dashboard.
+type Annotation struct {
+	ID           int64    `json:"id,omitempty"`
+	DashboardUID string   `json:"dashboardUID"`
+	PanelID      int      `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	TimeEnd      int64    `json:"timeEnd,omitempty"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// OrgUser is a user of the current org.
+type OrgUser struct {
+	UserID int    `json:"userId"`
+	Login  string `json:"login"`
 }
 
 type Org struct {
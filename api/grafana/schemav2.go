@@ -0,0 +1,344 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// v2VariableTypes maps the kinds of the v2 template variables to the types of the v1 template variables.
+var v2VariableTypes = map[string]string{
+	"QueryVariable":      "query",
+	"DatasourceVariable": "datasource",
+	"AdhocVariable":      "adhoc",
+	"GroupByVariable":    "groupby",
+	"CustomVariable":     "custom",
+	"ConstantVariable":   "constant",
+	"IntervalVariable":   "interval",
+	"TextVariable":       "textbox",
+}
+
+// UnmarshalJSON unmarshals the dashboard, which can be:
+//   - a v1 dashboard JSON model, with the panels in a "panels" array
+//   - a v2 dashboard spec (Grafana 12), with the panels in "elements" and their position in "layout",
+//     converted to the v1 model
+//   - an App Platform resource (Kubernetes-style, with "metadata" and "spec"), whose spec is a v1 or a v2 dashboard
+func (d *Dashboard) UnmarshalJSON(b []byte) error {
+	var probe struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec     json.RawMessage `json:"spec"`
+		Elements json.RawMessage `json:"elements"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return err
+	}
+	switch {
+	case probe.Spec != nil:
+		if err := json.Unmarshal(probe.Spec, d); err != nil {
+			return fmt.Errorf("spec: %w", err)
+		}
+		// The uid is the name of the resource, the spec doesn't contain it
+		if d.UID == "" {
+			d.UID = probe.Metadata.Name
+		}
+		return nil
+	case probe.Elements != nil:
+		return d.unmarshalV2(b)
+	}
+	type dashboard Dashboard
+	return json.Unmarshal(b, (*dashboard)(d))
+}
+
+// v2Kind is an object of the v2 schema, whose spec depends on its kind.
+type v2Kind struct {
+	Kind string          `json:"kind"`
+	Spec json.RawMessage `json:"spec"`
+}
+
+// v2Dashboard is a dashboard using the v2 schema.
+type v2Dashboard struct {
+	UID       string            `json:"uid"`
+	Title     string            `json:"title"`
+	Elements  map[string]v2Kind `json:"elements"`
+	Layout    v2Kind            `json:"layout"`
+	Variables []v2Kind          `json:"variables"`
+	Links     []*Link           `json:"links"`
+}
+
+// v2PanelSpec is the spec of the "Panel" and "LibraryPanel" elements.
+type v2PanelSpec struct {
+	ID    int     `json:"id"`
+	Title string  `json:"title"`
+	Links []*Link `json:"links"`
+	Data  struct {
+		Spec struct {
+			Queries []struct {
+				Spec v2QuerySpec `json:"spec"`
+			} `json:"queries"`
+		} `json:"spec"`
+	} `json:"data"`
+	VizConfig struct {
+		// Kind is the panel plugin id in v2alpha1, while v2beta1 uses Group
+		Kind  string `json:"kind"`
+		Group string `json:"group"`
+		Spec  struct {
+			Options     map[string]json.RawMessage `json:"options"`
+			FieldConfig json.RawMessage            `json:"fieldConfig"`
+		} `json:"spec"`
+	} `json:"vizConfig"`
+	LibraryPanel *LibraryPanelRef `json:"libraryPanel"`
+}
+
+// v2QuerySpec is the spec of a query of a panel or of a query variable.
+type v2QuerySpec struct {
+	// Datasource is set in v2alpha1
+	Datasource *struct {
+		Type string `json:"type"`
+	} `json:"datasource"`
+	Query struct {
+		// Kind is the data source plugin id in v2alpha1, while v2beta1 uses Group
+		Kind  string `json:"kind"`
+		Group string `json:"group"`
+	} `json:"query"`
+}
+
+// datasource returns the datasource of the query, with the same type as the datasources of v1 dashboards,
+// or nil if the plugin id of the data source is not known.
+func (q v2QuerySpec) datasource() interface{} {
+	pluginID := q.Query.Group
+	if q.Datasource != nil && q.Datasource.Type != "" {
+		pluginID = q.Datasource.Type
+	} else if pluginID == "" && q.Query.Kind != "DataQuery" {
+		pluginID = q.Query.Kind
+	}
+	if pluginID == "" {
+		return nil
+	}
+	return PanelDatasource{Type: pluginID}
+}
+
+// v2Repeat is the repeat configuration of a layout item or row.
+type v2Repeat struct {
+	Value     string `json:"value"`
+	Direction string `json:"direction"`
+}
+
+// v2ElementReference is the reference to an element in a layout item.
+type v2ElementReference struct {
+	Name string `json:"name"`
+}
+
+// v2Converter converts a v2 dashboard to the v1 model.
+type v2Converter struct {
+	dashboard v2Dashboard
+
+	// used are the names of the elements referenced by the layout.
+	used map[string]struct{}
+}
+
+// unmarshalV2 unmarshals the given v2 dashboard and converts it to the v1 model.
+// The panels are ordered and nested in rows as in the layout, rows and tabs being converted to collapsed rows.
+// Elements not referenced by the layout are added before the others.
+func (d *Dashboard) unmarshalV2(b []byte) error {
+	c := v2Converter{used: map[string]struct{}{}}
+	if err := json.Unmarshal(b, &c.dashboard); err != nil {
+		return err
+	}
+	panels, err := c.layout(c.dashboard.Layout)
+	if err != nil {
+		return fmt.Errorf("layout: %w", err)
+	}
+	names := make([]string, 0, len(c.dashboard.Elements))
+	for name := range c.dashboard.Elements {
+		if _, ok := c.used[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	// Add them first, as panels following a row are considered in the row (expanded rows in the v1 model)
+	unused := make([]*DashboardPanel, 0, len(names))
+	for _, name := range names {
+		p, err := c.element(name)
+		if err != nil {
+			return err
+		}
+		unused = append(unused, p)
+	}
+	panels = append(unused, panels...)
+	variables := make([]*TemplateVariable, 0, len(c.dashboard.Variables))
+	for _, v := range c.dashboard.Variables {
+		variable, err := v2Variable(v)
+		if err != nil {
+			return fmt.Errorf("variable: %w", err)
+		}
+		variables = append(variables, variable)
+	}
+	*d = Dashboard{
+		UID:        c.dashboard.UID,
+		Title:      c.dashboard.Title,
+		Panels:     panels,
+		Templating: Templating{List: variables},
+		Links:      c.dashboard.Links,
+		V2:         true,
+	}
+	return nil
+}
+
+// layout returns the panels in the given layout, in order.
+func (c *v2Converter) layout(layout v2Kind) ([]*DashboardPanel, error) {
+	var out []*DashboardPanel
+	switch layout.Kind {
+	case "GridLayout", "AutoGridLayout":
+		var spec struct {
+			Items []v2Kind `json:"items"`
+		}
+		if err := json.Unmarshal(layout.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", layout.Kind, err)
+		}
+		for _, item := range spec.Items {
+			p, err := c.layoutItem(item)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				out = append(out, p)
+			}
+		}
+	case "RowsLayout", "TabsLayout":
+		var spec struct {
+			Rows []v2Kind `json:"rows"`
+			Tabs []v2Kind `json:"tabs"`
+		}
+		if err := json.Unmarshal(layout.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", layout.Kind, err)
+		}
+		for _, row := range append(spec.Rows, spec.Tabs...) {
+			var rowSpec struct {
+				Title  string    `json:"title"`
+				Repeat *v2Repeat `json:"repeat"`
+				Layout v2Kind    `json:"layout"`
+			}
+			if err := json.Unmarshal(row.Spec, &rowSpec); err != nil {
+				return nil, fmt.Errorf("%s: %w", row.Kind, err)
+			}
+			panels, err := c.layout(rowSpec.Layout)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v2Row(rowSpec.Title, rowSpec.Repeat, panels))
+		}
+	}
+	return out, nil
+}
+
+// layoutItem returns the panel of the given layout item, or nil if the item is not supported.
+func (c *v2Converter) layoutItem(item v2Kind) (*DashboardPanel, error) {
+	var spec struct {
+		X       int                `json:"x"`
+		Y       int                `json:"y"`
+		Width   int                `json:"width"`
+		Height  int                `json:"height"`
+		Element v2ElementReference `json:"element"`
+		Repeat  *v2Repeat          `json:"repeat"`
+
+		// Title and Elements are set for "GridLayoutRow" (v2alpha1)
+		Title    string   `json:"title"`
+		Elements []v2Kind `json:"elements"`
+	}
+	if err := json.Unmarshal(item.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", item.Kind, err)
+	}
+	switch item.Kind {
+	case "GridLayoutItem", "AutoGridLayoutItem":
+		p, err := c.element(spec.Element.Name)
+		if err != nil || p == nil {
+			return nil, err
+		}
+		if item.Kind == "GridLayoutItem" {
+			p.GridPos = &GridPos{X: spec.X, Y: spec.Y, W: spec.Width, H: spec.Height}
+		}
+		if spec.Repeat != nil {
+			p.Repeat, p.RepeatDirection = spec.Repeat.Value, spec.Repeat.Direction
+		}
+		return p, nil
+	case "GridLayoutRow":
+		panels := make([]*DashboardPanel, 0, len(spec.Elements))
+		for _, element := range spec.Elements {
+			p, err := c.layoutItem(element)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				panels = append(panels, p)
+			}
+		}
+		return v2Row(spec.Title, spec.Repeat, panels), nil
+	}
+	return nil, nil
+}
+
+// element returns the panel of the element with the given name, or nil if there is no such element.
+func (c *v2Converter) element(name string) (*DashboardPanel, error) {
+	element, ok := c.dashboard.Elements[name]
+	if !ok {
+		return nil, nil
+	}
+	c.used[name] = struct{}{}
+	var spec v2PanelSpec
+	if err := json.Unmarshal(element.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("element %q: %w", name, err)
+	}
+	p := &DashboardPanel{
+		ID:           spec.ID,
+		Type:         spec.VizConfig.Group,
+		Title:        spec.Title,
+		Links:        spec.Links,
+		LibraryPanel: spec.LibraryPanel,
+		Raw:          map[string]json.RawMessage{},
+	}
+	if p.Type == "" && spec.VizConfig.Kind != "VizConfig" {
+		p.Type = spec.VizConfig.Kind
+	}
+	for _, query := range spec.Data.Spec.Queries {
+		p.Targets = append(p.Targets, &PanelTarget{Datasource: query.Spec.datasource()})
+	}
+	// Angular options are kept in the panel options, where the v1 model has them at the top level
+	for k, v := range spec.VizConfig.Spec.Options {
+		p.Raw[k] = v
+	}
+	if spec.VizConfig.Spec.FieldConfig != nil {
+		p.Raw["fieldConfig"] = spec.VizConfig.Spec.FieldConfig
+	}
+	return p, nil
+}
+
+// v2Row returns a collapsed row containing the given panels.
+func v2Row(title string, repeat *v2Repeat, panels []*DashboardPanel) *DashboardPanel {
+	row := &DashboardPanel{Type: "row", Title: title, Panels: panels}
+	if repeat != nil {
+		row.Repeat = repeat.Value
+	}
+	return row
+}
+
+// v2Variable converts the given v2 template variable to the v1 model.
+func v2Variable(v v2Kind) (*TemplateVariable, error) {
+	var spec struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(v.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", v.Kind, err)
+	}
+	variable := &TemplateVariable{Type: v2VariableTypes[v.Kind], Name: spec.Name}
+	if variable.Type == "query" {
+		// The query of the other variables is not a data source query (e.g.: "a,b,c" for custom variables)
+		var query v2QuerySpec
+		if err := json.Unmarshal(v.Spec, &query); err != nil {
+			return nil, fmt.Errorf("%s %q: %w", v.Kind, spec.Name, err)
+		}
+		variable.Datasource = query.datasource()
+	}
+	return variable, nil
+}
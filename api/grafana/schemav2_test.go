@@ -0,0 +1,125 @@
+package grafana
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardUnmarshalJSON(t *testing.T) {
+	t.Run("v1", func(t *testing.T) {
+		var d Dashboard
+		require.NoError(t, json.Unmarshal([]byte(`{"uid": "v1", "schemaVersion": 16, "panels": [{"id": 1, "type": "graph"}]}`), &d))
+		require.Equal(t, "v1", d.UID)
+		require.Equal(t, 16, d.SchemaVersion)
+		require.Len(t, d.Panels, 1)
+		require.Equal(t, "graph", d.Panels[0].Type)
+	})
+
+	t.Run("gnet id", func(t *testing.T) {
+		for b, exp := range map[string]GnetID{
+			`{"gnetId": 1860}`:   1860,
+			`{"gnetId": "1860"}`: 1860,
+			`{"gnetId": null}`:   0,
+			`{"gnetId": ""}`:     0,
+			`{}`:                 0,
+		} {
+			var d Dashboard
+			require.NoError(t, json.Unmarshal([]byte(b), &d))
+			require.Equal(t, exp, d.GnetID, b)
+		}
+	})
+
+	t.Run("v1 resource", func(t *testing.T) {
+		var d Dashboard
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"apiVersion": "dashboard.grafana.app/v1beta1",
+			"kind": "Dashboard",
+			"metadata": {"name": "v1-resource"},
+			"spec": {"title": "v1 resource", "schemaVersion": 39, "panels": [{"id": 1, "type": "graph"}]}
+		}`), &d))
+		require.Equal(t, "v1-resource", d.UID)
+		require.Equal(t, "v1 resource", d.Title)
+		require.Len(t, d.Panels, 1)
+	})
+
+	t.Run("v2beta1", func(t *testing.T) {
+		var d Dashboard
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"apiVersion": "dashboard.grafana.app/v2beta1",
+			"kind": "Dashboard",
+			"metadata": {"name": "v2beta1"},
+			"spec": {
+				"title": "v2beta1",
+				"elements": {
+					"panel-1": {"kind": "Panel", "spec": {
+						"id": 1, "title": "worldmap",
+						"data": {"kind": "QueryGroup", "spec": {"queries": [
+							{"kind": "PanelQuery", "spec": {"refId": "A", "query": {"kind": "DataQuery", "group": "akumuli-datasource", "version": "v0", "datasource": {"name": "akumuli"}, "spec": {}}}}
+						]}},
+						"vizConfig": {"kind": "VizConfig", "group": "grafana-worldmap-panel", "version": "1.0.6", "spec": {"options": {"jsonUrl": "https://example.com"}}}
+					}},
+					"panel-2": {"kind": "LibraryPanel", "spec": {"id": 2, "title": "library", "libraryPanel": {"uid": "lib", "name": "library panel"}}}
+				},
+				"layout": {"kind": "TabsLayout", "spec": {"tabs": [
+					{"kind": "TabsLayoutTab", "spec": {"title": "tab", "layout": {"kind": "AutoGridLayout", "spec": {"items": [
+						{"kind": "AutoGridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "panel-1"}}},
+						{"kind": "AutoGridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "panel-2"}}},
+						{"kind": "AutoGridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "missing"}}}
+					]}}}}
+				]}},
+				"variables": [
+					{"kind": "QueryVariable", "spec": {"name": "host", "query": {"kind": "DataQuery", "group": "akumuli-datasource", "spec": {}}}},
+					{"kind": "TextVariable", "spec": {"name": "text", "query": "value"}}
+				]
+			}
+		}`), &d))
+		require.Equal(t, "v2beta1", d.UID)
+		require.True(t, d.V2)
+		require.Zero(t, d.SchemaVersion)
+		require.Len(t, d.Panels, 1)
+		tab := d.Panels[0]
+		require.Equal(t, "row", tab.Type)
+		require.Equal(t, "tab", tab.Title)
+		require.Len(t, tab.Panels, 2)
+
+		worldmap := tab.Panels[0]
+		require.Equal(t, "grafana-worldmap-panel", worldmap.Type)
+		require.Nil(t, worldmap.GridPos)
+		require.Len(t, worldmap.Targets, 1)
+		require.Equal(t, PanelDatasource{Type: "akumuli-datasource"}, worldmap.Targets[0].Datasource)
+		require.JSONEq(t, `"https://example.com"`, string(worldmap.Raw["jsonUrl"]))
+
+		library := tab.Panels[1]
+		require.Empty(t, library.Type)
+		require.Equal(t, &LibraryPanelRef{UID: "lib", Name: "library panel"}, library.LibraryPanel)
+
+		require.Equal(t, []*TemplateVariable{
+			{Type: "query", Name: "host", Datasource: PanelDatasource{Type: "akumuli-datasource"}},
+			{Type: "textbox", Name: "text"},
+		}, d.Templating.List)
+	})
+
+	t.Run("v2alpha1 grid rows", func(t *testing.T) {
+		var d Dashboard
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"title": "v2alpha1",
+			"elements": {
+				"panel-1": {"kind": "Panel", "spec": {"id": 1, "title": "graph", "vizConfig": {"kind": "graph", "spec": {}}}}
+			},
+			"layout": {"kind": "GridLayout", "spec": {"items": [
+				{"kind": "GridLayoutRow", "spec": {"y": 0, "title": "row", "collapsed": true, "repeat": {"mode": "variable", "value": "region"}, "elements": [
+					{"kind": "GridLayoutItem", "spec": {"x": 0, "y": 1, "width": 24, "height": 8, "element": {"kind": "ElementReference", "name": "panel-1"}}}
+				]}}
+			]}}
+		}`), &d))
+		require.Len(t, d.Panels, 1)
+		row := d.Panels[0]
+		require.Equal(t, "row", row.Type)
+		require.Equal(t, "region", row.Repeat)
+		require.Len(t, row.Panels, 1)
+		require.Equal(t, "graph", row.Panels[0].Type)
+		require.Equal(t, &GridPos{X: 0, Y: 1, W: 24, H: 8}, row.Panels[0].GridPos)
+	})
+}
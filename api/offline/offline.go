@@ -0,0 +1,372 @@
+package offline
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/gcom"
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/logger"
+)
+
+var errNotAvailable = errors.New("not available in offline mode")
+
+// errNotDashboard is returned when a JSON file is not a dashboard (e.g.: invalid JSON or other exported resources).
+var errNotDashboard = errors.New("not a dashboard")
+
+// bundledAngularPlugins is a list of plugin ids of well-known Angular plugins.
+// It is used to determine if plugins are Angular when GCOM can't be reached.
+//
+//go:embed angular-plugins.json
+var bundledAngularPlugins []byte
+
+// APIClient is a client that reads exported dashboard JSON files from a directory on disk,
+// rather than using the Grafana API.
+// Dashboards are identified by their path relative to the directory, since exported dashboards
+// may not have unique UIDs.
+type APIClient struct {
+	dir        string
+	gcomClient *gcom.APIClient
+	log        logger.Logger
+}
+
+type Option func(*APIClient)
+
+// WithGCOM returns an Option that makes the APIClient use GCOM to determine if the plugins used in
+// the dashboards are Angular, rather than the bundled list of Angular plugins.
+func WithGCOM(gcomClient gcom.APIClient) Option {
+	return func(cl *APIClient) {
+		cl.gcomClient = &gcomClient
+	}
+}
+
+// WithLogger returns an Option that makes the APIClient log the JSON files skipped because they are not dashboards.
+// By default, they are skipped silently.
+func WithLogger(log logger.Logger) Option {
+	return func(cl *APIClient) {
+		cl.log = log
+	}
+}
+
+// NewAPIClient returns a new APIClient that reads dashboards from the given directory, recursively.
+func NewAPIClient(dir string, opts ...Option) APIClient {
+	cl := APIClient{dir: dir, log: logger.NewNopLogger()}
+	for _, opt := range opts {
+		opt(&cl)
+	}
+	return cl
+}
+
+// BaseURL always returns an empty string, so the dashboard URLs are the paths of the files
+// relative to the directory.
+func (cl APIClient) BaseURL() string {
+	return ""
+}
+
+// walkDashboards calls fn for each dashboard JSON file in cl.dir, recursively.
+// The path passed to fn is relative to cl.dir. The JSON files that are not dashboards are skipped with a warning.
+func (cl APIClient) walkDashboards(fn func(path string, dashboard *grafana.DashboardDefinition) error) error {
+	return filepath.WalkDir(cl.dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+		relPath, err := filepath.Rel(cl.dir, path)
+		if err != nil {
+			return err
+		}
+		dashboard, err := cl.readDashboard(relPath)
+		if errors.Is(err, errNotDashboard) {
+			cl.log.Warn("Skipping %s", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(relPath, dashboard)
+	})
+}
+
+// readDashboard reads the dashboard JSON file at the given path, relative to cl.dir.
+// The file can either contain the dashboard JSON model, or the response of the dashboards API
+// (an object with "dashboard" and "meta" fields). It returns errNotDashboard if the file is not a dashboard.
+func (cl APIClient) readDashboard(relPath string) (*grafana.DashboardDefinition, error) {
+	b, err := os.ReadFile(filepath.Join(cl.dir, relPath))
+	if err != nil {
+		return nil, err
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, fmt.Errorf("%q: %w: %w", relPath, errNotDashboard, err)
+	}
+	if !isDashboard(probe) {
+		return nil, fmt.Errorf("%q: %w", relPath, errNotDashboard)
+	}
+	var out grafana.DashboardDefinition
+	if _, ok := probe["dashboard"]; ok {
+		err = json.Unmarshal(b, &out)
+	} else {
+		err = json.Unmarshal(b, &out.Dashboard)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w: %w", relPath, errNotDashboard, err)
+	}
+	if out.Meta.FolderTitle == "" {
+		if folder := filepath.Dir(relPath); folder != "." {
+			out.Meta.FolderTitle = filepath.ToSlash(folder)
+		}
+	}
+	grafana.ConvertDashboard(&out.Dashboard)
+	return &out, nil
+}
+
+// isDashboard returns true if the given JSON object has the fields of a dashboard JSON model (v1 or v2),
+// of a response of the dashboards API, or of an App Platform resource.
+func isDashboard(fields map[string]json.RawMessage) bool {
+	for _, field := range []string{"dashboard", "spec", "panels", "rows", "elements", "schemaVersion"} {
+		if _, ok := fields[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDashboards returns all the dashboards in the directory.
+// All the dashboards are returned in the first page.
+func (cl APIClient) GetDashboards(_ context.Context, page int) ([]grafana.ListedDashboard, error) {
+	if page > 1 {
+		return nil, nil
+	}
+	var out []grafana.ListedDashboard
+	err := cl.walkDashboards(func(path string, dashboard *grafana.DashboardDefinition) error {
+		title := dashboard.Dashboard.Title
+		if title == "" {
+			title = path
+		}
+		out = append(out, grafana.ListedDashboard{
+			UID:         path,
+			URL:         filepath.ToSlash(path),
+			Title:       title,
+			FolderUID:   dashboard.Meta.FolderUID,
+			FolderTitle: dashboard.Meta.FolderTitle,
+			Tags:        dashboard.Dashboard.Tags,
+		})
+		return nil
+	})
+	return out, err
+}
+
+// GetDashboard returns the dashboard at the given path, relative to the directory.
+func (cl APIClient) GetDashboard(_ context.Context, uid string) (*grafana.DashboardDefinition, error) {
+	return cl.readDashboard(uid)
+}
+
+// GetFrontendSettings returns frontend settings with the Angular status of the plugins.
+// The Angular status is taken from GCOM if the APIClient has been created with WithGCOM,
+// otherwise from the bundled list of Angular plugins.
+func (cl APIClient) GetFrontendSettings(ctx context.Context) (*grafana.FrontendSettings, error) {
+	angularPlugins := map[string]bool{}
+	if cl.gcomClient == nil {
+		var pluginIDs []string
+		if err := json.Unmarshal(bundledAngularPlugins, &pluginIDs); err != nil {
+			return nil, fmt.Errorf("unmarshal bundled angular plugins: %w", err)
+		}
+		for _, pluginID := range pluginIDs {
+			angularPlugins[pluginID] = true
+		}
+	} else {
+		pluginIDs := map[string]struct{}{}
+		if err := cl.walkDashboards(func(_ string, dashboard *grafana.DashboardDefinition) error {
+			collectPluginIDs(dashboard.Dashboard.Panels, pluginIDs)
+			for _, v := range dashboard.Dashboard.Templating.List {
+				if ds, ok := v.Datasource.(grafana.PanelDatasource); ok && ds.Type != "" {
+					pluginIDs[ds.Type] = struct{}{}
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		for pluginID := range pluginIDs {
+			v, err := cl.gcomClient.GetLatestAngularDetected(ctx, pluginID)
+			if err != nil {
+				return nil, fmt.Errorf("get angular detected: %w", err)
+			}
+			angularPlugins[pluginID] = v
+		}
+	}
+
+	out := grafana.FrontendSettings{
+		Panels:      make(map[string]grafana.FrontendSettingsPanel, len(angularPlugins)),
+		Datasources: make(map[string]grafana.FrontendSettingsDatasource, len(angularPlugins)),
+	}
+	for pluginID, isAngular := range angularPlugins {
+		// The plugin type is not known, so add it both as a panel and as a datasource
+		out.Panels[pluginID] = grafana.FrontendSettingsPanel{
+			Angular: &struct{ Detected bool }{Detected: isAngular},
+		}
+		ds := grafana.FrontendSettingsDatasource{Type: pluginID}
+		ds.Meta.Angular = &struct{ Detected bool }{Detected: isAngular}
+		out.Datasources[pluginID] = ds
+	}
+	return &out, nil
+}
+
+// collectPluginIDs adds the ids of the panel and datasource plugins used in panels and their targets
+// to pluginIDs, recursively.
+func collectPluginIDs(panels []*grafana.DashboardPanel, pluginIDs map[string]struct{}) {
+	for _, p := range panels {
+		if p.Type != "" {
+			pluginIDs[p.Type] = struct{}{}
+		}
+		if ds, ok := p.Datasource.(grafana.PanelDatasource); ok && ds.Type != "" {
+			pluginIDs[ds.Type] = struct{}{}
+		}
+		for _, target := range p.Targets {
+			if ds, ok := target.Datasource.(grafana.PanelDatasource); ok && ds.Type != "" {
+				pluginIDs[ds.Type] = struct{}{}
+			}
+		}
+		collectPluginIDs(p.Panels, pluginIDs)
+	}
+}
+
+// GetPlugins always returns no plugins, the Angular status is provided by GetFrontendSettings.
+func (cl APIClient) GetPlugins(_ context.Context) ([]grafana.Plugin, error) {
+	return nil, nil
+}
+
+// GetPluginModule always returns an error, as plugin assets are not available offline.
+func (cl APIClient) GetPluginModule(_ context.Context, _ string) ([]byte, error) {
+	return nil, errNotAvailable
+}
+
+// GetDatasourcePluginIDs always returns no datasources, as they are not available offline.
+// Panels referencing a datasource by name (legacy dashboards) can't be resolved.
+func (cl APIClient) GetDatasourcePluginIDs(_ context.Context) ([]grafana.Datasource, error) {
+	return nil, nil
+}
+
+// GetServiceAccountPermissions always returns an error, as there is no service account in offline mode.
+func (cl APIClient) GetServiceAccountPermissions(_ context.Context) (map[string][]string, error) {
+	return nil, errNotAvailable
+}
+
+// GetPublicDashboards always returns no public dashboards, as they are not available offline.
+func (cl APIClient) GetPublicDashboards(_ context.Context) ([]grafana.PublicDashboard, error) {
+	return nil, nil
+}
+
+// GetDashboardJSON always returns an error, as dashboards can't be saved back offline.
+func (cl APIClient) GetDashboardJSON(_ context.Context, _ string) (json.RawMessage, error) {
+	return nil, errNotAvailable
+}
+
+// SaveDashboard always returns an error, as dashboards can't be saved offline.
+func (cl APIClient) SaveDashboard(_ context.Context, _ map[string]interface{}, _, _ string) error {
+	return errNotAvailable
+}
+
+// GetAnnotations always returns an error, as annotations are not available offline.
+func (cl APIClient) GetAnnotations(_ context.Context, _ string, _ []string) ([]grafana.Annotation, error) {
+	return nil, errNotAvailable
+}
+
+// CreateAnnotation always returns an error, as annotations can't be created offline.
+func (cl APIClient) CreateAnnotation(_ context.Context, _ grafana.Annotation) error {
+	return errNotAvailable
+}
+
+// UpdateAnnotation always returns an error, as annotations can't be updated offline.
+func (cl APIClient) UpdateAnnotation(_ context.Context, _ grafana.Annotation) error {
+	return errNotAvailable
+}
+
+// DeleteAnnotation always returns an error, as annotations can't be deleted offline.
+func (cl APIClient) DeleteAnnotation(_ context.Context, _ int64) error {
+	return errNotAvailable
+}
+
+// GetDashboardVersions always returns an error, as the version history is not available offline.
+func (cl APIClient) GetDashboardVersions(_ context.Context, _ string) ([]grafana.DashboardVersion, error) {
+	return nil, errNotAvailable
+}
+
+// GetDashboardVersion always returns an error, as the version history is not available offline.
+func (cl APIClient) GetDashboardVersion(_ context.Context, _ string, _ int) (*grafana.DashboardVersionDefinition, error) {
+	return nil, errNotAvailable
+}
+
+// GetOrgPreferences always returns an error, as preferences are not available offline.
+func (cl APIClient) GetOrgPreferences(_ context.Context) (*grafana.Preferences, error) {
+	return nil, errNotAvailable
+}
+
+// GetUserPreferences always returns an error, as preferences are not available offline.
+func (cl APIClient) GetUserPreferences(_ context.Context) (*grafana.Preferences, error) {
+	return nil, errNotAvailable
+}
+
+// GetTeams always returns an error, as teams are not available offline.
+func (cl APIClient) GetTeams(_ context.Context) ([]grafana.Team, error) {
+	return nil, errNotAvailable
+}
+
+// GetTeamPreferences always returns an error, as preferences are not available offline.
+func (cl APIClient) GetTeamPreferences(_ context.Context, _ int) (*grafana.Preferences, error) {
+	return nil, errNotAvailable
+}
+
+// GetLibraryElement always returns an error, as library panels are not available offline.
+func (cl APIClient) GetLibraryElement(_ context.Context, _ string) (*grafana.LibraryElement, error) {
+	return nil, errNotAvailable
+}
+
+// GetLibraryElementConnections always returns an error, as library panels are not available offline.
+func (cl APIClient) GetLibraryElementConnections(_ context.Context, _ string) ([]grafana.LibraryElementConnection, error) {
+	return nil, errNotAvailable
+}
+
+// GetCurrentUser always returns an error, as there is no API token in offline mode.
+func (cl APIClient) GetCurrentUser(_ context.Context) (*grafana.User, error) {
+	return nil, errNotAvailable
+}
+
+// GetDeletedDashboards always returns an error, as there is no trash in offline mode.
+func (cl APIClient) GetDeletedDashboards(_ context.Context) ([]grafana.ListedDashboard, error) {
+	return nil, errNotAvailable
+}
+
+// GetOrgUsers always returns an error, as users are not available offline.
+func (cl APIClient) GetOrgUsers(_ context.Context) ([]grafana.OrgUser, error) {
+	return nil, errNotAvailable
+}
+
+// GetDashboardViews always returns an error, as usage insights are not available offline.
+func (cl APIClient) GetDashboardViews(_ context.Context) ([]grafana.DashboardViews, error) {
+	return nil, errNotAvailable
+}
+
+// GetDashboardPermissions always returns an error, as permissions are not available offline.
+func (cl APIClient) GetDashboardPermissions(_ context.Context, _ string) ([]grafana.DashboardPermission, error) {
+	return nil, errNotAvailable
+}
+
+// GetFolderPermissions always returns an error, as permissions are not available offline.
+func (cl APIClient) GetFolderPermissions(_ context.Context, _ string) ([]grafana.DashboardPermission, error) {
+	return nil, errNotAvailable
+}
+
+// GetFolders always returns an error, as the folder hierarchy is not available offline.
+func (cl APIClient) GetFolders(_ context.Context) ([]grafana.Folder, error) {
+	return nil, errNotAvailable
+}
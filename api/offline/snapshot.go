@@ -0,0 +1,46 @@
+package offline
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// bundledAngularPluginsDate is the date (YYYY-MM-DD) the bundled list of Angular plugins has been taken from GCOM.
+// It must be updated with angular-plugins.json.
+//
+//go:embed angular-plugins-date.txt
+var bundledAngularPluginsDate string
+
+// Snapshot describes the provenance of the bundled list of Angular plugins.
+type Snapshot struct {
+	// Date is the date the list has been taken from GCOM.
+	Date time.Time
+
+	// Hash is the truncated SHA-256 of the list, to tell different lists apart.
+	Hash string
+}
+
+// String returns the date and hash of the snapshot.
+func (s Snapshot) String() string {
+	return fmt.Sprintf("%s (sha256 %s)", s.Date.Format(time.DateOnly), s.Hash)
+}
+
+// Age returns the time elapsed since the snapshot has been taken, at the given time.
+func (s Snapshot) Age(now time.Time) time.Duration {
+	return now.Sub(s.Date)
+}
+
+// BundledAngularPluginsSnapshot returns the provenance of the bundled list of Angular plugins,
+// which is embedded in the binary at build time.
+func BundledAngularPluginsSnapshot() (Snapshot, error) {
+	date, err := time.Parse(time.DateOnly, strings.TrimSpace(bundledAngularPluginsDate))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("parse bundled angular plugins date: %w", err)
+	}
+	sum := sha256.Sum256(bundledAngularPlugins)
+	return Snapshot{Date: date, Hash: hex.EncodeToString(sum[:])[:12]}, nil
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// Identity is the identity of the API token used for a run.
+type Identity struct {
+	Login          string
+	Email          string `json:",omitempty"`
+	Name           string `json:",omitempty"`
+	OrgID          int
+	IsGrafanaAdmin bool
+}
+
+// Counts summarizes the results of a detection run.
+type Counts struct {
+	Dashboards               int
+	DashboardsWithDetections int
+	Detections               int
+}
+
+// CountDashboards returns the Counts for the given detection results.
+func CountDashboards(dashboards []output.Dashboard) *Counts {
+	counts := Counts{Dashboards: len(dashboards)}
+	for _, dashboard := range dashboards {
+		if len(dashboard.Detections) > 0 {
+			counts.DashboardsWithDetections++
+		}
+		counts.Detections += len(dashboard.Detections)
+	}
+	return &counts
+}
+
+// Record is an audit record, written for each run.
+type Record struct {
+	// Time is the time the run ended.
+	Time string
+
+	// Version is the version of the tool.
+	Version string
+
+	// Args are the command-line arguments. The API token is read from the environment, so it's not included.
+	Args []string
+
+	// Mode is the mode the tool runs in (e.g.: "cli", "server", "verify").
+	Mode string
+
+	// Target is the Grafana API URL, or the directory in offline mode.
+	Target string
+
+	// Identity is the identity of the API token. It's nil in offline mode, or if it could not be determined.
+	Identity *Identity `json:",omitempty"`
+
+	// Counts are the results of the run, for the modes that detect Angular dashboards.
+	Counts *Counts `json:",omitempty"`
+
+	// Actions are the writes made during the run, in order, once they have happened
+	// (e.g.: `save dashboard "abc"`, `create annotation on dashboard "abc"`).
+	Actions []string `json:",omitempty"`
+
+	// Error is the error that made the run fail, if any.
+	Error string `json:",omitempty"`
+}
+
+// writeMu serializes the writes of all the Loggers, as several Loggers can append to the same file
+// (e.g.: one for each instance scanned concurrently with -instances-file).
+var writeMu sync.Mutex
+
+// Logger appends audit records to a file, one JSON object per line. It's safe for concurrent use.
+// A nil *Logger discards all records, so it can be used when the audit log is disabled.
+type Logger struct {
+	fn   string
+	base Record
+	now  func() time.Time
+}
+
+// NewLogger returns a new Logger that appends records to the file with the given name.
+// base contains the fields that are the same for all the runs (e.g.: version, args, identity).
+func NewLogger(fn string, base Record) *Logger {
+	return &Logger{fn: fn, base: base, now: time.Now}
+}
+
+// Log appends a record for a run with the given counts, error and write actions. counts can be nil.
+func (l *Logger) Log(counts *Counts, runErr error, actions ...string) error {
+	if l == nil {
+		return nil
+	}
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	record := l.base
+	record.Time = l.now().Format(time.RFC3339)
+	record.Counts = counts
+	record.Actions = actions
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	f, err := os.OpenFile(l.fn, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
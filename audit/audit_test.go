@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "audit.log")
+	l := NewLogger(fn, Record{
+		Version:  "v1.2.3",
+		Args:     []string{"-j", "http://grafana.example.com/api"},
+		Mode:     "cli",
+		Target:   "http://grafana.example.com/api",
+		Identity: &Identity{Login: "sa-detect-angular", OrgID: 1},
+	})
+	l.now = func() time.Time {
+		return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	}
+	require.NoError(t, l.Log(CountDashboards([]output.Dashboard{
+		{Detections: []output.Detection{{PluginID: "graph"}, {PluginID: "grafana-worldmap-panel"}}},
+		{Detections: []output.Detection{}},
+	}), nil))
+	require.NoError(t, l.Log(nil, errors.New("run detector: boom"), `save dashboard "a"`))
+
+	b, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	require.Equal(t, "2024-03-01T12:00:00Z", first.Time)
+	require.Equal(t, "sa-detect-angular", first.Identity.Login)
+	require.Equal(t, &Counts{Dashboards: 2, DashboardsWithDetections: 1, Detections: 2}, first.Counts)
+	require.Empty(t, first.Error)
+	require.Empty(t, first.Actions)
+	require.Nil(t, second.Counts)
+	require.Equal(t, "run detector: boom", second.Error)
+	require.Equal(t, []string{`save dashboard "a"`}, second.Actions)
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	require.NoError(t, l.Log(nil, nil))
+}
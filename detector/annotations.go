@@ -0,0 +1,114 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// annotationTags are the tags of the annotations created by Annotate, used to find them again.
+var annotationTags = []string{"angular", "detect-angular-dashboards"}
+
+// Actions of AnnotationChange.
+const (
+	AnnotationCreated = "create"
+	AnnotationUpdated = "update"
+	AnnotationDeleted = "delete"
+)
+
+// AnnotationChange is a change made by Annotate to the annotation of a dashboard.
+type AnnotationChange struct {
+	DashboardUID string
+
+	// Action is AnnotationCreated, AnnotationUpdated or AnnotationDeleted.
+	Action string
+}
+
+// Annotate keeps an annotation on each of the given dashboards with detections, listing their Angular plugins,
+// so the viewers of the dashboards see the warning in context. The annotation is a region from the run that created it
+// to the latest run, so it's visible on the time ranges up to now (e.g.: "Last 24 hours") as long as the detection runs
+// regularly. The annotations are found again by their tags: the annotation of a dashboard is updated on each run
+// (with the current plugins, and extended to now), and deleted once the dashboard has no detections anymore.
+// The annotations of the dashboards that are not given (e.g.: out of the scope of the run) are left as-is.
+// A dashboard that can't be annotated doesn't stop the others. It returns the changes made,
+// and the errors of the dashboards that couldn't be annotated.
+func (d *Detector) Annotate(ctx context.Context, dashboards []output.Dashboard) ([]AnnotationChange, error) {
+	all, err := d.grafanaClient.GetAnnotations(ctx, "", annotationTags)
+	if err != nil {
+		return nil, fmt.Errorf("get annotations: %w", err)
+	}
+	existing := map[string][]grafana.Annotation{}
+	for _, annotation := range all {
+		existing[annotation.DashboardUID] = append(existing[annotation.DashboardUID], annotation)
+	}
+	var changes []AnnotationChange
+	var errs []error
+	for _, dashboard := range dashboards {
+		action, err := d.annotate(ctx, dashboard, existing[dashboard.UID])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dashboard %q: %w", dashboard.UID, err))
+			continue
+		}
+		if action != "" {
+			changes = append(changes, AnnotationChange{DashboardUID: dashboard.UID, Action: action})
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// annotate creates, updates or deletes the annotation of the given dashboard, whose existing annotations are given.
+// Duplicate annotations (e.g.: created by concurrent runs) are deleted. It returns the action taken, if any.
+func (d *Detector) annotate(ctx context.Context, dashboard output.Dashboard, existing []grafana.Annotation) (string, error) {
+	text := annotationText(dashboard)
+	if text == "" {
+		for _, annotation := range existing {
+			if err := d.grafanaClient.DeleteAnnotation(ctx, annotation.ID); err != nil {
+				return "", fmt.Errorf("delete annotation: %w", err)
+			}
+		}
+		if len(existing) == 0 {
+			return "", nil
+		}
+		return AnnotationDeleted, nil
+	}
+	now := d.now().UnixMilli()
+	if len(existing) == 0 {
+		if err := d.grafanaClient.CreateAnnotation(ctx, grafana.Annotation{
+			DashboardUID: dashboard.UID,
+			Time:         now,
+			TimeEnd:      now,
+			Tags:         annotationTags,
+			Text:         text,
+		}); err != nil {
+			return "", fmt.Errorf("create annotation: %w", err)
+		}
+		return AnnotationCreated, nil
+	}
+	annotation := existing[0]
+	annotation.TimeEnd = now
+	annotation.Tags = annotationTags
+	annotation.Text = text
+	if err := d.grafanaClient.UpdateAnnotation(ctx, annotation); err != nil {
+		return "", fmt.Errorf("update annotation: %w", err)
+	}
+	for _, duplicate := range existing[1:] {
+		if err := d.grafanaClient.DeleteAnnotation(ctx, duplicate.ID); err != nil {
+			return "", fmt.Errorf("delete annotation: %w", err)
+		}
+	}
+	return AnnotationUpdated, nil
+}
+
+// annotationText returns the text of the annotation of the given dashboard, listing its Angular plugins,
+// or an empty string if it has none.
+func annotationText(dashboard output.Dashboard) string {
+	pluginIDs := dashboard.PluginIDs()
+	if len(pluginIDs) == 0 {
+		return ""
+	}
+	return "This dashboard contains Angular plugins: " + strings.Join(pluginIDs, ", ")
+}
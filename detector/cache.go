@@ -0,0 +1,214 @@
+package detector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/build"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// WithCacheDir returns an Option that caches the results of the scanned dashboards with their version in the given
+// directory, if not empty. The next runs reuse the results of the dashboards whose version didn't change instead
+// of checking them again: dashboards listed with their version (App Platform API) are not even fetched.
+// Only what depends on the version of the dashboard is reused: its detections, metadata and links (see CachedDashboard).
+// What can change without a new version is refreshed (see cachedResult), and the post-processors are run again,
+// so the latest plugin versions, permissions or owners are never the ones of a previous run.
+// Each Grafana instance and organization has its own cache file in the directory.
+func WithCacheDir(dir string) Option {
+	return func(d *Detector) {
+		d.cacheDir = dir
+	}
+}
+
+// CachedDashboard is the cached result of a dashboard.
+type CachedDashboard struct {
+	// Version is the version of the dashboard the result has been computed for.
+	Version int
+
+	// Created and Updated are the creation and update times in the dashboard metadata, to compute the age of the dashboard.
+	Created string
+	Updated string
+
+	// Result is the result of the dashboard before the post-processing, which is run again when the result is reused,
+	// as the data added by the post-processors (e.g.: the latest plugin versions, the owners) change without a new version
+	// of the dashboard.
+	Result *output.Dashboard
+
+	// Links are the links of the dashboard, to resolve the links to and from it (see WithLinks), nil without WithLinks.
+	Links *DashboardLinks `json:",omitempty"`
+}
+
+// cache is the content of a cache file. It's safe for concurrent use.
+type cache struct {
+	mu sync.Mutex
+
+	// fn is the file the cache is written to.
+	fn string
+	// next are the results recorded by the current run, by dashboard uid.
+	next map[string]CachedDashboard
+
+	// Fingerprint identifies the plugins, data sources, rules and options the results have been computed with
+	// (see cacheFingerprint): the results are discarded when it changes, as the results of unchanged dashboards may change.
+	Fingerprint string
+
+	// Dashboards are the cached results, by dashboard uid.
+	Dashboards map[string]CachedDashboard
+}
+
+// openCache reads the cache of the Grafana instance and organization of the API token, if it exists.
+// The results in the cache are discarded if they have been computed with other plugins, data sources, rules or options.
+func (d *Detector) openCache(ctx context.Context) (*cache, error) {
+	key := d.grafanaClient.BaseURL()
+	if user, err := d.grafanaClient.GetCurrentUser(ctx); err == nil {
+		key += "\n" + strconv.Itoa(user.OrgID)
+	}
+	sum := sha256.Sum256([]byte(key))
+	if err := os.MkdirAll(d.cacheDir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &cache{fn: filepath.Join(d.cacheDir, hex.EncodeToString(sum[:8])+".json"), next: map[string]CachedDashboard{}}
+	b, err := os.ReadFile(c.fn)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, c); err != nil {
+			return nil, fmt.Errorf("unmarshal %q: %w", c.fn, err)
+		}
+	}
+	fingerprint, err := d.cacheFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	if c.Fingerprint != fingerprint && len(c.Dashboards) > 0 {
+		d.log.Log("Plugins, data sources, rules or options changed since the cached results, checking all dashboards again")
+		c.Dashboards = nil
+	}
+	c.Fingerprint = fingerprint
+	return c, nil
+}
+
+// cacheFingerprint returns the fingerprint of everything the cached results depend on, besides the dashboards:
+// the plugins and data sources, the rules, the exclusions, the options and the version of the tool.
+// The options of the post-processors are not part of it, as the post-processing is run again on the cached results.
+// Reconfigure (e.g.: on reload) can change some of them.
+func (d *Detector) cacheFingerprint() (string, error) {
+	b, err := json.Marshal(struct {
+		Version             string
+		AngularDetected     map[string]bool
+		AngularUnknown      map[string]bool
+		DatasourcePluginIDs map[string]string
+		MigrationTargets    map[string]string
+		Rules               *Rules
+		Exclusions          []string
+		URLBase             string
+		RelativeURLs        bool
+		Links               bool
+		Location            string
+	}{
+		Version:             build.LinkerVersion + " " + build.LinkerCommitSHA,
+		AngularDetected:     d.angularDetected,
+		AngularUnknown:      d.angularUnknown,
+		DatasourcePluginIDs: d.datasourcePluginIDs,
+		MigrationTargets:    d.migrationTargets,
+		Rules:               d.rules,
+		Exclusions:          d.exclusions.patterns(),
+		URLBase:             d.urlBase,
+		RelativeURLs:        d.relativeURLs,
+		Links:               d.checkLinks,
+		Location:            d.location.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// get returns the cached result of the dashboard with the given uid, if it has been computed for the given version.
+// Dashboards without version (0) are never cached, as their changes can't be detected.
+func (c *cache) get(uid string, version int) (CachedDashboard, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.Dashboards[uid]
+	return cached, ok && version > 0 && cached.Version == version
+}
+
+// put records the result of the dashboard with the given uid for the next runs.
+func (c *cache) put(uid string, cached CachedDashboard) {
+	if cached.Version == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next[uid] = cached
+}
+
+// write writes the results recorded by the current run to the cache file, with the previous results
+// of the given listed dashboards that have not been scanned (e.g.: when the time budget is exhausted).
+// The results of the dashboards not listed anymore are dropped. The file is replaced atomically.
+func (c *cache) write(listed []grafana.ListedDashboard) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, dash := range listed {
+		if _, ok := c.next[dash.UID]; ok {
+			continue
+		}
+		if cached, ok := c.Dashboards[dash.UID]; ok {
+			c.next[dash.UID] = cached
+		}
+	}
+	c.Dashboards, c.next = c.next, map[string]CachedDashboard{}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := c.fn + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.fn)
+}
+
+// cachedResult returns a copy of the cached result of the given dashboard, before the post-processing, updated with
+// the data that can change without a new version of the dashboard (e.g.: its folder, views, age, or whether it's orphaned).
+// orgUserLogins are the logins of the org users, nil if orphaned dashboards are not checked.
+func (d *Detector) cachedResult(
+	dash grafana.ListedDashboard, cached CachedDashboard,
+	homeDashboards map[string][]string, dashboardViews map[string]grafana.DashboardViews, orgUserLogins map[string]struct{},
+) *output.Dashboard {
+	result := *cached.Result
+	// The post-processors modify the detections in place
+	result.Detections = append([]output.Detection{}, cached.Result.Detections...)
+	result.URL = d.dashboardURL(dash.URL)
+	result.Folder, result.FolderUID = dash.FolderTitle, dash.FolderUID
+	result.Public = d.publicDashboards[dash.UID]
+	result.Deleted = dash.IsDeleted
+	result.HomeDashboardFor = homeDashboards[dash.UID]
+	result.Views, result.LastViewed, result.LastViewedDaysAgo = nil, "", nil
+	if views, ok := dashboardViews[dash.UID]; ok {
+		result.Views = &views.Views
+		result.LastViewed, result.LastViewedDaysAgo = d.normalizeTime(views.LastViewed)
+	}
+	result.Orphaned = false
+	if orgUserLogins != nil {
+		result.Orphaned = isOrphaned(grafana.Meta{
+			Provisioned: result.Provisioned,
+			CreatedBy:   result.CreatedBy,
+			UpdatedBy:   result.UpdatedBy,
+		}, orgUserLogins)
+	}
+	result.Created, result.CreatedDaysAgo = d.normalizeTime(cached.Created)
+	result.Updated, result.UpdatedDaysAgo = d.normalizeTime(cached.Updated)
+	return &result
+}
@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+const (
+	// pluginIDRow is the type of row panels, which are not plugins.
+	pluginIDRow = "row"
+
+	// pluginIDBuiltInDatasource is the type of the built-in "-- Mixed --" and "-- Dashboard --" datasources,
+	// which are not plugins.
+	pluginIDBuiltInDatasource = "datasource"
+)
+
+// pluginUsageKey identifies a plugin in the census.
+type pluginUsageKey struct {
+	pluginType output.PluginType
+	pluginID   string
+}
+
+// Census returns the usage of all the panel and datasource plugins in the dashboards, including the ones
+// that are not Angular, sorted by plugin type and id.
+// Like Run, it returns the partial census together with the error if some dashboards could not be checked.
+func (d *Detector) Census(ctx context.Context) ([]output.PluginUsage, error) {
+	if err := d.loadPlugins(ctx); err != nil {
+		return nil, err
+	}
+	dashboards, err := d.listDashboards(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	counts := map[pluginUsageKey]int{}
+	dashboardURLs := map[pluginUsageKey]map[string]struct{}{}
+	err = d.forEachDashboard(ctx, dashboards, time.Time{}, func(dash grafana.ListedDashboard, dashboardDefinition *grafana.DashboardDefinition) error {
+		d.resolveLibraryPanels(ctx, dashboardDefinition.Dashboard.Panels)
+		used, err := d.dashboardPluginUsage(dashboardDefinition)
+		if err != nil {
+			return err
+		}
+		dashboardURL := d.dashboardURL(dash.URL)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, k := range used {
+			counts[k]++
+			if dashboardURLs[k] == nil {
+				dashboardURLs[k] = map[string]struct{}{}
+			}
+			dashboardURLs[k][dashboardURL] = struct{}{}
+		}
+		return nil
+	})
+
+	out := make([]output.PluginUsage, 0, len(counts))
+	for k, count := range counts {
+		usage := output.PluginUsage{
+			PluginID:   k.pluginID,
+			Type:       k.pluginType,
+			Angular:    d.angularDetected[k.pluginID],
+			Count:      count,
+			Dashboards: make([]string, 0, len(dashboardURLs[k])),
+		}
+		for u := range dashboardURLs[k] {
+			usage.Dashboards = append(usage.Dashboards, u)
+		}
+		sort.Strings(usage.Dashboards)
+		out = append(out, usage)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type > out[j].Type
+		}
+		return out[i].PluginID < out[j].PluginID
+	})
+	return out, err
+}
+
+// dashboardPluginUsage returns the plugins used by each panel and query template variable of the given dashboard.
+// A plugin is returned once for every panel or variable that uses it.
+func (d *Detector) dashboardPluginUsage(dashboardDefinition *grafana.DashboardDefinition) ([]pluginUsageKey, error) {
+	var out []pluginUsageKey
+	err := walkPanels(dashboardDefinition.Dashboard.Panels, func(p, _ *grafana.DashboardPanel) error {
+		if p.Type != "" && p.Type != pluginIDRow {
+			out = append(out, pluginUsageKey{pluginType: output.PluginTypePanel, pluginID: p.Type})
+		}
+		dsPlugins, err := d.panelDatasourcePluginIDs(p)
+		if err != nil {
+			return err
+		}
+		for _, dsPlugin := range dsPlugins {
+			if dsPlugin == pluginIDBuiltInDatasource {
+				continue
+			}
+			out = append(out, pluginUsageKey{pluginType: output.PluginTypeDatasource, pluginID: dsPlugin})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range dashboardDefinition.Dashboard.Templating.List {
+		if v.Type != "query" {
+			continue
+		}
+		dsPlugin, err := d.datasourcePluginID(v.Datasource)
+		if err != nil {
+			return nil, err
+		}
+		if dsPlugin != "" {
+			out = append(out, pluginUsageKey{pluginType: output.PluginTypeDatasource, pluginID: dsPlugin})
+		}
+	}
+	return out, nil
+}
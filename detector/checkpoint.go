@@ -0,0 +1,171 @@
+package detector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// checkpointInterval is the minimum time between two writes of a checkpoint during a scan.
+const checkpointInterval = 10 * time.Second
+
+// Checkpoint records the progress of the current scan and the results of the last complete scan in a file,
+// so a restarted server resumes an interrupted scan instead of starting over, and serves the last results right away.
+// It's safe for concurrent use.
+type Checkpoint struct {
+	mu sync.Mutex
+
+	// fn is the file the checkpoint is written to.
+	fn string
+	// maxAge is the age after which the progress of an interrupted scan is discarded instead of resumed.
+	maxAge time.Duration
+	// written is the last time the checkpoint has been written.
+	written time.Time
+
+	// Started is the time the current scan started, as RFC3339 in UTC, empty if no scan is in progress.
+	Started string
+
+	// Scanned are the results of the dashboards scanned by the current scan, by uid.
+	// Dashboards dropped by the post-processors are recorded with a nil result.
+	Scanned map[string]*output.Dashboard
+
+	// Links are the links of the dashboards scanned by the current scan, by uid, to resolve the links to and from them
+	// when the scan is resumed (see WithLinks).
+	Links map[string]*DashboardLinks `json:",omitempty"`
+
+	// Completed is the time the last complete scan finished, as RFC3339 in UTC, empty if no scan completed yet.
+	Completed string
+
+	// Results are the results of the last complete scan.
+	Results []output.Dashboard
+}
+
+// ReadCheckpoint reads the Checkpoint in the given JSON file, which is then updated during scans.
+// It returns an empty Checkpoint if the file doesn't exist yet.
+// The progress of an interrupted scan started more than maxAge ago is discarded, as its results are outdated.
+func ReadCheckpoint(fn string, maxAge time.Duration) (*Checkpoint, error) {
+	cp := &Checkpoint{fn: fn, maxAge: maxAge}
+	b, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	return cp, nil
+}
+
+// LastResults returns the results of the last complete scan and the time it finished,
+// or false if no scan completed yet.
+func (cp *Checkpoint) LastResults() ([]output.Dashboard, time.Time, bool) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.Completed == "" {
+		return nil, time.Time{}, false
+	}
+	completed, _ := time.Parse(time.RFC3339, cp.Completed)
+	return cp.Results, completed, true
+}
+
+// WithCheckpoint returns an Option that records the progress and the results of the scans in the given Checkpoint.
+// A scan resumes the interrupted scan recorded in the checkpoint, if any: the dashboards it already scanned
+// are not scanned again, and their recorded results are reported instead.
+func WithCheckpoint(cp *Checkpoint) Option {
+	return func(d *Detector) {
+		d.checkpoint = cp
+	}
+}
+
+// begin starts a new scan at the given time, or resumes the interrupted one if it's recent enough.
+// It returns the number of dashboards already scanned by the resumed scan.
+func (cp *Checkpoint) begin(now time.Time) int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	started, err := time.Parse(time.RFC3339, cp.Started)
+	if err == nil && cp.Scanned != nil && now.Sub(started) <= cp.maxAge {
+		return len(cp.Scanned)
+	}
+	cp.Started = now.UTC().Format(time.RFC3339)
+	cp.Scanned = map[string]*output.Dashboard{}
+	cp.Links = nil
+	return 0
+}
+
+// resume removes the dashboards already scanned by the current scan from the given dashboards,
+// and returns the remaining ones with the recorded results of the removed ones, and their recorded links by uid.
+func (cp *Checkpoint) resume(dashboards []grafana.ListedDashboard) ([]grafana.ListedDashboard, []output.Dashboard, map[string]*DashboardLinks) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	var remaining []grafana.ListedDashboard
+	var scanned []output.Dashboard
+	links := map[string]*DashboardLinks{}
+	for _, dash := range dashboards {
+		result, ok := cp.Scanned[dash.UID]
+		if !ok {
+			remaining = append(remaining, dash)
+			continue
+		}
+		if result != nil {
+			scanned = append(scanned, *result)
+		}
+		if dashLinks, ok := cp.Links[dash.UID]; ok {
+			links[dash.UID] = dashLinks
+		}
+	}
+	return remaining, scanned, links
+}
+
+// record records the result of the dashboard with the given uid, nil if it has been dropped, and its links,
+// nil if they are not checked, and writes the checkpoint if it hasn't been written recently.
+func (cp *Checkpoint) record(uid string, result *output.Dashboard, links *DashboardLinks, now time.Time) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.Scanned[uid] = result
+	if links != nil {
+		if cp.Links == nil {
+			cp.Links = map[string]*DashboardLinks{}
+		}
+		cp.Links[uid] = links
+	}
+	if now.Sub(cp.written) < checkpointInterval {
+		return nil
+	}
+	cp.written = now
+	return cp.write()
+}
+
+// complete records the results of the current scan, finished at the given time, as the last results.
+func (cp *Checkpoint) complete(results []output.Dashboard, now time.Time) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.Started = ""
+	cp.Scanned = nil
+	cp.Links = nil
+	cp.Completed = now.UTC().Format(time.RFC3339)
+	cp.Results = results
+	cp.written = now
+	return cp.write()
+}
+
+// write writes the checkpoint to its file. The file is replaced atomically, so it's never left half-written.
+// cp.mu must be held.
+func (cp *Checkpoint) write() error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := cp.fn + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.fn)
+}
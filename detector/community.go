@@ -0,0 +1,147 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// communityRevision is the latest revision of a grafana.com dashboard, with its JSON model.
+type communityRevision struct {
+	revision int
+	model    json.RawMessage
+	angular  bool
+}
+
+// importPayload is the payload of the /api/dashboards/import endpoint.
+type importPayload struct {
+	Dashboard map[string]interface{} `json:"dashboard"`
+	Overwrite bool                   `json:"overwrite"`
+	FolderUID string                 `json:"folderUid,omitempty"`
+	Inputs    []importInput          `json:"inputs"`
+}
+
+// importInput is an input of a grafana.com dashboard (e.g.: its data source), which must be set to import it.
+type importInput struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId,omitempty"`
+	Value    string `json:"value"`
+}
+
+// WithCommunityRevisions returns an Option that makes the Detector check the latest revision of the grafana.com
+// dashboards the dashboards with detections have been imported from, to report if re-importing it removes
+// the Angular detections. If importDir is not empty, the payloads to re-import the revisions without Angular
+// detections are written to it.
+func WithCommunityRevisions(enabled bool, importDir string) Option {
+	return func(d *Detector) {
+		d.communityRevisions = enabled
+		d.communityImportDir = importDir
+	}
+}
+
+// setCommunityRevision sets the latest revision of the grafana.com dashboard the given dashboard has been imported
+// from, and writes the payload to re-import it if it has no Angular detections and d.communityImportDir is set.
+func (d *Detector) setCommunityRevision(ctx context.Context, dashboard *output.Dashboard) {
+	if dashboard.GnetID == 0 || len(dashboard.Detections) == 0 {
+		return
+	}
+	rev := d.latestCommunityRevision(ctx, dashboard.GnetID)
+	if rev == nil {
+		return
+	}
+	dashboard.CommunityRevision = &output.CommunityRevision{Revision: rev.revision, Angular: rev.angular}
+	if rev.angular || d.communityImportDir == "" {
+		return
+	}
+	fn, err := writeImportPayload(d.communityImportDir, dashboard, rev.model)
+	if err != nil {
+		// The revision is still reported, without its import file
+		d.log.Warn("Could not write the import payload of dashboard %q: %s", dashboard.UID, err)
+		return
+	}
+	dashboard.CommunityRevision.ImportFile = fn
+}
+
+// latestCommunityRevision returns the latest revision of the grafana.com dashboard with the given id.
+// It returns nil if the dashboard is not in GCOM or GCOM can't be reached.
+// Results are cached, so GCOM is queried at most once for each dashboard.
+func (d *Detector) latestCommunityRevision(ctx context.Context, gnetID int) *communityRevision {
+	rev, _ := d.communityRevisionsCache.get(strconv.Itoa(gnetID), func() interface{} {
+		rev, err := d.fetchCommunityRevision(ctx, gnetID)
+		if err != nil {
+			// The dashboard is reported without its latest revision
+			d.log.Verbose().Log("(WARNING: could not get latest revision of grafana.com dashboard %d: %v)", gnetID, err)
+		}
+		return rev
+	}).(*communityRevision)
+	return rev
+}
+
+// fetchCommunityRevision downloads the latest revision of the grafana.com dashboard with the given id,
+// and checks it for Angular detections like the dashboards of the instance.
+func (d *Detector) fetchCommunityRevision(ctx context.Context, gnetID int) (*communityRevision, error) {
+	dashboard, err := d.gcomClient.GetDashboard(ctx, gnetID)
+	if err != nil || dashboard == nil {
+		return nil, err
+	}
+	model, err := d.gcomClient.GetDashboardRevision(ctx, gnetID, dashboard.Revision)
+	if err != nil {
+		return nil, fmt.Errorf("get revision %d: %w", dashboard.Revision, err)
+	}
+	var def grafana.DashboardDefinition
+	if err := json.Unmarshal(model, &def.Dashboard); err != nil {
+		return nil, fmt.Errorf("unmarshal revision %d: %w", dashboard.Revision, err)
+	}
+	grafana.ConvertDashboard(&def.Dashboard)
+	detections, err := d.checkDashboard(&def)
+	if err != nil {
+		return nil, fmt.Errorf("check revision %d: %w", dashboard.Revision, err)
+	}
+	return &communityRevision{revision: dashboard.Revision, model: model, angular: len(detections) > 0}, nil
+}
+
+// writeImportPayload writes the payload to import the given grafana.com dashboard model in place of the given
+// dashboard (same uid and folder) to dir, and returns the path of the file. The inputs of the model
+// (e.g.: its data sources) are listed with empty values, to be set before importing it.
+func writeImportPayload(dir string, dashboard *output.Dashboard, model json.RawMessage) (string, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(model, &m); err != nil {
+		return "", fmt.Errorf("unmarshal: %w", err)
+	}
+	var inputs []importInput
+	if raw, ok := m["__inputs"]; ok {
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return "", fmt.Errorf("marshal inputs: %w", err)
+		}
+		if err := json.Unmarshal(b, &inputs); err != nil {
+			return "", fmt.Errorf("unmarshal inputs: %w", err)
+		}
+	}
+	if inputs == nil {
+		inputs = []importInput{}
+	}
+	m["uid"] = dashboard.UID
+	delete(m, "id")
+	b, err := json.MarshalIndent(importPayload{
+		Dashboard: m,
+		Overwrite: true,
+		FolderUID: dashboard.FolderUID,
+		Inputs:    inputs,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(dir, dashboard.UID+".json")
+	return fn, os.WriteFile(fn, b, 0o644)
+}
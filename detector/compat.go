@@ -0,0 +1,119 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// angularSource is the source used to determine if plugins are Angular or not.
+type angularSource int
+
+const (
+	// angularSourceGCOM uses GCOM (Grafana < 10.1.0).
+	// It's slower and it only works for public plugins.
+	angularSourceGCOM angularSource = iota
+
+	// angularSourceAngularDetected uses the "angularDetected" field in frontend settings
+	// (Grafana >= 10.1.0 && < 10.3.0).
+	angularSourceAngularDetected
+
+	// angularSourceAngular uses the "angular" field in frontend settings (Grafana >= 10.3.0).
+	angularSourceAngular
+)
+
+func (s angularSource) String() string {
+	switch s {
+	case angularSourceGCOM:
+		return "gcom"
+	case angularSourceAngularDetected:
+		return "frontendsettings (angularDetected)"
+	case angularSourceAngular:
+		return "frontendsettings (angular)"
+	}
+	return "unknown"
+}
+
+// compatibility describes which APIs and fields are available on the Grafana instance,
+// so the detector can select the correct code paths.
+type compatibility struct {
+	// grafanaVersion is the Grafana version, as reported by frontend settings.
+	// It may be empty if it could not be determined.
+	grafanaVersion string
+
+	// angularSource is the source to use to determine if plugins are Angular or not.
+	angularSource angularSource
+
+	// hasAccessControl is true if the access-control permissions endpoint is available.
+	// It's not available on old Grafana versions without service accounts.
+	hasAccessControl bool
+
+	// permissions are the permissions of the service account, if hasAccessControl is true.
+	permissions map[string][]string
+
+	// accessControlErr is the error returned when probing the access-control endpoint, if any.
+	accessControlErr error
+}
+
+// probeCompatibility determines which APIs and fields are available on the Grafana instance.
+func (d *Detector) probeCompatibility(ctx context.Context, frontendSettings *grafana.FrontendSettings) compatibility {
+	c := compatibility{
+		grafanaVersion: frontendSettings.BuildInfo.Version,
+		angularSource:  angularSourceGCOM,
+	}
+
+	// Check all the plugins rather than a random one, the most recent field wins.
+	for _, p := range frontendSettings.Panels {
+		c.angularSource = maxAngularSource(c.angularSource, p.Angular != nil, p.AngularDetected != nil)
+	}
+	for _, ds := range frontendSettings.Datasources {
+		c.angularSource = maxAngularSource(c.angularSource, ds.Meta.Angular != nil, ds.AngularDetected != nil)
+	}
+
+	c.permissions, c.accessControlErr = d.grafanaClient.GetServiceAccountPermissions(ctx)
+	c.hasAccessControl = c.accessControlErr == nil
+	return c
+}
+
+// maxAngularSource returns the most recent angularSource between current and the one
+// determined by the presence of the "angular" and "angularDetected" fields.
+func maxAngularSource(current angularSource, hasAngular, hasAngularDetected bool) angularSource {
+	s := angularSourceGCOM
+	if hasAngular {
+		s = angularSourceAngular
+	} else if hasAngularDetected {
+		s = angularSourceAngularDetected
+	}
+	if s > current {
+		return s
+	}
+	return current
+}
+
+// versionLess returns true if the Grafana version a is lower than b.
+// Only the major, minor and patch numbers are compared, pre-release and build metadata are ignored.
+func versionLess(a, b string) bool {
+	va, vb := parseVersion(a), parseVersion(b)
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] < vb[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses the major, minor and patch numbers of a version string such as "v10.1.5-beta1".
+// Missing or invalid numbers are returned as 0.
+func parseVersion(v string) [3]int {
+	var out [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	for i, p := range strings.SplitN(v, ".", 3) {
+		out[i], _ = strconv.Atoi(p)
+	}
+	return out
+}
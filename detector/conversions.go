@@ -0,0 +1,151 @@
+package detector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Conversion is a panel conversion defined in a mapping file, as a list of option translations.
+// Options are referenced by their dotted path in the panel JSON model (e.g.: "options.reduceOptions.calcs").
+type Conversion struct {
+	// Plugin is the id of the Angular plugin of the panels to convert.
+	Plugin string `yaml:"plugin"`
+
+	// Target is the id of the React plugin the panels are converted to.
+	Target string `yaml:"target"`
+
+	// AutoMigrateFrom is the id of a panel Grafana migrates automatically, if not empty. The converted panel
+	// is then migrated by Grafana as if it was a panel of that plugin (e.g.: "table-old" for forks of the Angular table panel).
+	AutoMigrateFrom string `yaml:"autoMigrateFrom"`
+
+	// Unsupported are the options that can't be converted: panels using them are not converted.
+	Unsupported []string `yaml:"unsupported"`
+
+	// Rename maps options to the option they are moved to.
+	Rename map[string]string `yaml:"rename"`
+
+	// Set maps options to the value they are set to, after renaming.
+	Set map[string]interface{} `yaml:"set"`
+
+	// Delete are the options removed, after setting.
+	Delete []string `yaml:"delete"`
+}
+
+// ReadConversions reads the panel conversions from the given YAML file, as PanelConverters.
+// Unknown fields are rejected, to catch typos.
+func ReadConversions(fn string) ([]PanelConverter, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Conversions []Conversion `yaml:"conversions"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	out := make([]PanelConverter, 0, len(file.Conversions))
+	seen := map[string]struct{}{}
+	for i, conversion := range file.Conversions {
+		if conversion.Plugin == "" || conversion.Target == "" {
+			return nil, fmt.Errorf("conversion %d: plugin and target are required", i)
+		}
+		if _, ok := seen[conversion.Plugin]; ok {
+			return nil, fmt.Errorf("conversion %d: duplicate plugin %q", i, conversion.Plugin)
+		}
+		seen[conversion.Plugin] = struct{}{}
+		out = append(out, NewPanelConverter(conversion.Plugin, conversion.Target, conversion.convert))
+	}
+	return out, nil
+}
+
+// convert converts the given panel JSON model according to the conversion.
+func (c Conversion) convert(panel map[string]interface{}) ([]string, error) {
+	var unsupported []string
+	for _, option := range c.Unsupported {
+		if _, ok := getOption(panel, option); ok {
+			unsupported = append(unsupported, option)
+		}
+	}
+	if len(unsupported) > 0 {
+		return unsupported, nil
+	}
+	// Translate the options in a stable order, in case they overlap
+	for _, from := range sortedKeys(c.Rename) {
+		if v, ok := getOption(panel, from); ok {
+			deleteOption(panel, from)
+			setOption(panel, c.Rename[from], v)
+		}
+	}
+	for _, option := range sortedKeys(c.Set) {
+		setOption(panel, option, c.Set[option])
+	}
+	for _, option := range c.Delete {
+		deleteOption(panel, option)
+	}
+	if c.AutoMigrateFrom != "" {
+		panel["autoMigrateFrom"] = c.AutoMigrateFrom
+	}
+	return nil, nil
+}
+
+// sortedKeys returns the sorted keys of the given map.
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// getOption returns the value of the option at the given dotted path, and false if it's not set.
+func getOption(panel map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	m := panel
+	for _, key := range keys[:len(keys)-1] {
+		var ok bool
+		if m, ok = m[key].(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	v, ok := m[keys[len(keys)-1]]
+	return v, ok
+}
+
+// setOption sets the option at the given dotted path, creating the intermediate objects.
+func setOption(panel map[string]interface{}, path string, v interface{}) {
+	keys := strings.Split(path, ".")
+	m := panel
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = v
+}
+
+// deleteOption removes the option at the given dotted path, if set.
+func deleteOption(panel map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	m := panel
+	for _, key := range keys[:len(keys)-1] {
+		var ok bool
+		if m, ok = m[key].(map[string]interface{}); !ok {
+			return
+		}
+	}
+	delete(m, keys[len(keys)-1])
+}
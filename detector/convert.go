@@ -0,0 +1,138 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// PanelConverter converts the panels using an Angular plugin to a React plugin, by rewriting their JSON model.
+// Converters are used by Fix. The built-in converters (see builtinPanelConverters) can be replaced
+// or complemented with WithPanelConverters, with converters written in Go or read from a mapping file (see ReadConversions).
+type PanelConverter interface {
+	// PluginID is the id of the Angular plugin of the panels converted by the converter.
+	PluginID() string
+
+	// TargetPluginID is the id of the React plugin the panels are converted to.
+	TargetPluginID() string
+
+	// Convert converts the given panel JSON model in place, including its type.
+	// If the panel has options that can't be converted, it returns their names and must leave the panel unchanged,
+	// so it's left for a manual migration.
+	Convert(panel map[string]interface{}) (unsupported []string, err error)
+}
+
+// panelConverterFunc is a PanelConverter implemented by a function.
+type panelConverterFunc struct {
+	pluginID       string
+	targetPluginID string
+	fn             func(panel map[string]interface{}) ([]string, error)
+}
+
+// NewPanelConverter returns a PanelConverter from the plugin with the given id to the target plugin, calling fn
+// to convert the options of the panels. The type of the panels is set to targetPluginID after fn succeeds,
+// unless fn returns unsupported options.
+func NewPanelConverter(pluginID, targetPluginID string, fn func(panel map[string]interface{}) ([]string, error)) PanelConverter {
+	return panelConverterFunc{pluginID: pluginID, targetPluginID: targetPluginID, fn: fn}
+}
+
+func (c panelConverterFunc) PluginID() string {
+	return c.pluginID
+}
+
+func (c panelConverterFunc) TargetPluginID() string {
+	return c.targetPluginID
+}
+
+func (c panelConverterFunc) Convert(panel map[string]interface{}) ([]string, error) {
+	unsupported, err := c.fn(panel)
+	if err != nil || len(unsupported) > 0 {
+		return unsupported, err
+	}
+	panel["type"] = c.targetPluginID
+	return nil, nil
+}
+
+// WithPanelConverters returns an Option that registers the given panel converters, used by Fix.
+// They replace the built-in converters of the same plugins. It can be passed multiple times.
+func WithPanelConverters(converters ...PanelConverter) Option {
+	return func(d *Detector) {
+		for _, converter := range converters {
+			d.panelConverters[converter.PluginID()] = converter
+		}
+	}
+}
+
+// builtinPanelConverters returns the built-in panel converters, by plugin id.
+func builtinPanelConverters() map[string]PanelConverter {
+	converters := []PanelConverter{
+		autoMigrateConverter(pluginIDGraphOld, "timeseries"),
+		autoMigrateConverter(pluginIDSinglestat, "stat"),
+		autoMigrateConverter(pluginIDTableOld, "table"),
+		autoMigrateConverter("grafana-singlestat-panel", "stat"),
+		autoMigrateConverter("grafana-piechart-panel", "piechart"),
+		autoMigrateConverter("grafana-worldmap-panel", "geomap"),
+		NewPanelConverter("briangann-datatable-panel", "table", convertDatatable),
+	}
+	out := make(map[string]PanelConverter, len(converters))
+	for _, converter := range converters {
+		out[converter.PluginID()] = converter
+	}
+	return out
+}
+
+// autoMigrateConverter returns a PanelConverter for a panel that Grafana migrates to React automatically.
+// The panel type is replaced, and the previous type kept in "autoMigrateFrom", so Grafana migrates the panel
+// options the next time the dashboard is loaded, as it does for Angular panels when Angular is disabled.
+// Panels with options that don't survive the migration (see lossyOptions) are not converted.
+func autoMigrateConverter(pluginID, targetPluginID string) PanelConverter {
+	return NewPanelConverter(pluginID, targetPluginID, func(panel map[string]interface{}) ([]string, error) {
+		p, err := panelModel(panel)
+		if err != nil {
+			return nil, err
+		}
+		if lossy := lossyOptions(p); len(lossy) > 0 {
+			return lossy, nil
+		}
+		panel["autoMigrateFrom"] = pluginID
+		return nil, nil
+	})
+}
+
+// datatableDisplayOptions are the display options of briangann-datatable-panel without equivalent in the table panel,
+// which are dropped by convertDatatable.
+var datatableDisplayOptions = []string{
+	"alignNumbersToRightEnabled", "compactRowsEnabled", "datatablePagingType", "datatableTheme", "emptyData",
+	"fontSize", "hoverEnabled", "infoEnabled", "lengthChangeEnabled", "orderColumnEnabled", "rowsPerPage",
+	"scroll", "searchEnabled", "showCellBorders", "showRowBorders", "stripedRowsEnabled",
+}
+
+// convertDatatable converts a briangann-datatable-panel panel to the table panel. The datatable panel is a fork
+// of the Angular table panel, with the same columns, styles and transform options, so the panel is converted
+// to the Angular table panel model and left for Grafana to migrate automatically (see autoMigrateConverter).
+// The display options without equivalent are dropped, and panels showing row numbers are not converted.
+func convertDatatable(panel map[string]interface{}) ([]string, error) {
+	if enabled, _ := panel["rowNumbersEnabled"].(bool); enabled {
+		return []string{"rowNumbersEnabled"}, nil
+	}
+	delete(panel, "rowNumbersEnabled")
+	for _, option := range datatableDisplayOptions {
+		delete(panel, option)
+	}
+	panel["autoMigrateFrom"] = pluginIDTableOld
+	return nil, nil
+}
+
+// panelModel returns the detector model of the given panel JSON model, e.g. to check its options with lossyOptions.
+func panelModel(panel map[string]interface{}) (*grafana.DashboardPanel, error) {
+	b, err := json.Marshal(panel)
+	if err != nil {
+		return nil, fmt.Errorf("marshal panel: %w", err)
+	}
+	var p grafana.DashboardPanel
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal panel: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,24 @@
+package detector
+
+// coreAngularPlugins maps the ids of core plugins that used Angular to the first Grafana version
+// in which they don't use Angular anymore.
+// Core plugins are not in GCOM, so this is used to detect them when the Angular status
+// can't be determined from frontend settings (Grafana < 10.1.0).
+var coreAngularPlugins = map[string]string{
+	"graphite": "8.5.0",
+	"influxdb": "8.3.0",
+	"mssql":    "9.0.0",
+	"mysql":    "9.0.0",
+	"opentsdb": "9.0.0",
+	"postgres": "9.0.0",
+}
+
+// isCoreAngularPlugin returns true if pluginID is a core plugin that uses Angular in the given Grafana version.
+// If the Grafana version is not known, it returns true for all the core plugins that used Angular.
+func isCoreAngularPlugin(pluginID, grafanaVersion string) bool {
+	angularUntil, ok := coreAngularPlugins[pluginID]
+	if !ok {
+		return false
+	}
+	return grafanaVersion == "" || versionLess(grafanaVersion, angularUntil)
+}
@@ -2,10 +2,16 @@ package detector
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/grafana/detect-angular-dashboards/api/gcom"
 	"github.com/grafana/detect-angular-dashboards/api/grafana"
@@ -14,9 +20,11 @@ import (
 )
 
 const (
-	pluginIDGraphOld = "graph"
-	pluginIDTable    = "table"
-	pluginIDTableOld = "table-old"
+	pluginIDGraphOld   = "graph"
+	pluginIDSinglestat = "singlestat"
+	pluginIDTable      = "table"
+	pluginIDTableOld   = "table-old"
+	pluginIDText       = "text"
 )
 
 // GrafanaDetectorAPIClient is an interface that can be used to interact with the Grafana API for
@@ -24,11 +32,34 @@ const (
 type GrafanaDetectorAPIClient interface {
 	BaseURL() string
 	GetPlugins(ctx context.Context) ([]grafana.Plugin, error)
+	GetPluginModule(ctx context.Context, pluginID string) ([]byte, error)
 	GetFrontendSettings(ctx context.Context) (*grafana.FrontendSettings, error)
 	GetServiceAccountPermissions(ctx context.Context) (map[string][]string, error)
 	GetDatasourcePluginIDs(ctx context.Context) ([]grafana.Datasource, error)
 	GetDashboards(ctx context.Context, page int) ([]grafana.ListedDashboard, error)
 	GetDashboard(ctx context.Context, uid string) (*grafana.DashboardDefinition, error)
+	GetDashboardJSON(ctx context.Context, uid string) (json.RawMessage, error)
+	SaveDashboard(ctx context.Context, dashboard map[string]interface{}, folderUID, message string) error
+	GetAnnotations(ctx context.Context, dashboardUID string, tags []string) ([]grafana.Annotation, error)
+	CreateAnnotation(ctx context.Context, annotation grafana.Annotation) error
+	UpdateAnnotation(ctx context.Context, annotation grafana.Annotation) error
+	DeleteAnnotation(ctx context.Context, id int64) error
+	GetDeletedDashboards(ctx context.Context) ([]grafana.ListedDashboard, error)
+	GetPublicDashboards(ctx context.Context) ([]grafana.PublicDashboard, error)
+	GetDashboardVersions(ctx context.Context, uid string) ([]grafana.DashboardVersion, error)
+	GetDashboardVersion(ctx context.Context, uid string, version int) (*grafana.DashboardVersionDefinition, error)
+	GetOrgPreferences(ctx context.Context) (*grafana.Preferences, error)
+	GetUserPreferences(ctx context.Context) (*grafana.Preferences, error)
+	GetTeams(ctx context.Context) ([]grafana.Team, error)
+	GetTeamPreferences(ctx context.Context, teamID int) (*grafana.Preferences, error)
+	GetLibraryElement(ctx context.Context, uid string) (*grafana.LibraryElement, error)
+	GetLibraryElementConnections(ctx context.Context, uid string) ([]grafana.LibraryElementConnection, error)
+	GetCurrentUser(ctx context.Context) (*grafana.User, error)
+	GetOrgUsers(ctx context.Context) ([]grafana.OrgUser, error)
+	GetDashboardViews(ctx context.Context) ([]grafana.DashboardViews, error)
+	GetDashboardPermissions(ctx context.Context, uid string) ([]grafana.DashboardPermission, error)
+	GetFolders(ctx context.Context) ([]grafana.Folder, error)
+	GetFolderPermissions(ctx context.Context, uid string) ([]grafana.DashboardPermission, error)
 }
 
 // Detector can detect Angular plugins in Grafana dashboards.
@@ -38,200 +69,867 @@ type Detector struct {
 	gcomClient    gcom.APIClient
 
 	angularDetected     map[string]bool
+	angularUnknown      map[string]bool
 	datasourcePluginIDs map[string]string
+	datasources         []grafana.Datasource
+	publicDashboards    map[string]bool
 	maxConcurrency      int
+
+	urlBase       string
+	relativeURLs  bool
+	history       bool
+	checkHome     bool
+	checkLinks    bool
+	checkDeleted  bool
+	checkOrphaned bool
+	usageInsights bool
+	permissions   bool
+	resolveOwners bool
+	location      *time.Location
+
+	// maxDuration is the time budget of a scan, if not zero.
+	maxDuration time.Duration
+	// scanState records when dashboards were last scanned, if not nil.
+	scanState *ScanState
+
+	// checkpoint records the progress and the results of the scans, if not nil.
+	checkpoint *Checkpoint
+
+	// cacheDir is the directory of the cache of the results of the dashboards, if not empty.
+	cacheDir string
+
+	// progress counts the dashboards fetched and scanned by the current scan, if not nil.
+	progress *Progress
+
+	// panelConverters are the converters used by Fix, by plugin id.
+	panelConverters map[string]PanelConverter
+
+	// latestVersions caches the latest version of plugins in GCOM (*gcom.PluginVersion), by plugin id.
+	latestVersions lookups
+
+	// gcomPlugins caches the plugins in GCOM (*gcom.Plugin), by plugin id.
+	gcomPlugins lookups
+
+	// communityRevisionsCache caches the latest revision of grafana.com dashboards (*communityRevision), by id.
+	communityRevisionsCache lookups
+
+	// communityRevisions is true to check the latest revision of the grafana.com dashboards the dashboards
+	// have been imported from, and communityImportDir is the directory to write the import payloads to, if any.
+	communityRevisions bool
+	communityImportDir string
+
+	// libraryElements caches the library panels (*grafana.LibraryElement), by uid.
+	libraryElements lookups
+
+	// folderOwners caches the teams owning folders ([]string), by folder uid.
+	folderOwners lookups
+
+	// migrationTargets maps Angular plugin ids to the suggested React replacements.
+	migrationTargets map[string]string
+
+	// rules are the custom detection rules, if any.
+	rules *Rules
+
+	// dashboardUIDs are the uids of the dashboards to check. If empty, all dashboards are checked.
+	dashboardUIDs map[string]struct{}
+
+	// filters restrict the dashboards to check to some folders and tags, if not empty.
+	filters Filters
+
+	// scope restricts the current run to some dashboards, if not nil (see RunScope).
+	scope *Scope
+
+	// exclusions are the patterns of the folders and dashboards to skip, if any.
+	exclusions *Exclusions
+
+	// updatedSince is the duration or timestamp before which dashboards are not checked, if not empty.
+	updatedSince string
+
+	// postProcessors are the post-processors registered with WithPostProcessors, run after the built-in ones.
+	postProcessors []PostProcessor
+
+	// now returns the current time, used to compute the age of dashboards.
+	now func() time.Time
+}
+
+// Option is a function that can be used to configure a Detector.
+type Option func(*Detector)
+
+// WithURLBase returns an Option that sets the base URL used to build absolute dashboard URLs.
+// By default, the base URL is derived from the Grafana API base URL.
+func WithURLBase(urlBase string) Option {
+	return func(d *Detector) {
+		d.urlBase = urlBase
+	}
+}
+
+// WithRelativeURLs returns an Option that makes the Detector output relative dashboard URLs
+// instead of absolute ones.
+func WithRelativeURLs(relativeURLs bool) Option {
+	return func(d *Detector) {
+		d.relativeURLs = relativeURLs
+	}
+}
+
+// WithHistory returns an Option that makes the Detector walk the version history of dashboards
+// with detections, to find the version in which each Angular plugin was introduced.
+func WithHistory(history bool) Option {
+	return func(d *Detector) {
+		d.history = history
+	}
+}
+
+// WithHomeDashboards returns an Option that makes the Detector check the org, team and user preferences
+// to report which dashboards are configured as home dashboards.
+func WithHomeDashboards(checkHome bool) Option {
+	return func(d *Detector) {
+		d.checkHome = checkHome
+	}
+}
+
+// WithDeletedDashboards returns an Option that makes the Detector also check the dashboards in the trash
+// (Grafana >= 11), since restoring them would reintroduce Angular plugins.
+func WithDeletedDashboards(checkDeleted bool) Option {
+	return func(d *Detector) {
+		d.checkDeleted = checkDeleted
+	}
+}
+
+// WithDashboardUIDs returns an Option that makes the Detector check only the dashboards with the given uids.
+// By default, all dashboards are checked.
+func WithDashboardUIDs(uids []string) Option {
+	return func(d *Detector) {
+		d.dashboardUIDs = make(map[string]struct{}, len(uids))
+		for _, uid := range uids {
+			d.dashboardUIDs[uid] = struct{}{}
+		}
+	}
 }
 
 // NewDetector returns a new Detector.
-func NewDetector(log *logger.LeveledLogger, grafanaClient GrafanaDetectorAPIClient, gcomClient gcom.APIClient, maxConcurrency int) *Detector {
-	return &Detector{
+func NewDetector(log *logger.LeveledLogger, grafanaClient GrafanaDetectorAPIClient, gcomClient gcom.APIClient, maxConcurrency int, opts ...Option) *Detector {
+	d := &Detector{
 		log:             log,
 		grafanaClient:   grafanaClient,
 		gcomClient:      gcomClient,
 		angularDetected: map[string]bool{},
 		maxConcurrency:  maxConcurrency,
+		now:             time.Now,
+
+		migrationTargets: make(map[string]string, len(migrationTargets)),
+		panelConverters:  builtinPanelConverters(),
+	}
+	for pluginID, target := range migrationTargets {
+		d.migrationTargets[pluginID] = target
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Reconfigure applies the given options to the Detector, e.g. to reload the rules between the runs of the server mode.
+// It must not be called while the Detector is running.
+func (d *Detector) Reconfigure(opts ...Option) {
+	for _, opt := range opts {
+		opt(d)
 	}
 }
 
 // Run runs the angular detector tool against the specified Grafana instance.
 func (d *Detector) Run(ctx context.Context) ([]output.Dashboard, error) {
-	var (
-		finalOutput []output.Dashboard
-		// Determine if we should use GCOM or frontendsettings
-		useGCOM bool
-	)
+	if d.progress == nil {
+		return d.run(ctx)
+	}
+	d.progress.start(d.now())
+	out, err := d.run(ctx)
+	d.progress.finish(d.now(), err)
+	return out, err
+}
+
+// run runs the detection, see Run.
+func (d *Detector) run(ctx context.Context) ([]output.Dashboard, error) {
+	var finalOutput []output.Dashboard
+
+	if err := d.loadPlugins(ctx); err != nil {
+		return []output.Dashboard{}, err
+	}
+
+	// Map dashboard uid -> public, to flag publicly shared dashboards
+	publicDashboards, err := d.grafanaClient.GetPublicDashboards(ctx)
+	if err != nil {
+		// Old Grafana versions don't have public dashboards: no dashboard is flagged as public
+		d.log.Verbose().Log("(WARNING: could not get public dashboards: %v)", err)
+	}
+	d.publicDashboards = make(map[string]bool, len(publicDashboards))
+	for _, pd := range publicDashboards {
+		if pd.IsEnabled {
+			d.publicDashboards[pd.DashboardUID] = true
+		}
+	}
+
+	dashboards, err := d.listDashboards(ctx)
+	if err != nil {
+		return []output.Dashboard{}, err
+	}
+
+	var dashboardViews map[string]grafana.DashboardViews
+	if d.usageInsights {
+		dashboardViews = d.dashboardViews(ctx)
+		sortByViews(dashboards, dashboardViews)
+	}
+
+	var deadline time.Time
+	if d.scanState != nil {
+		d.scanState.prioritize(dashboards)
+	}
+	if d.maxDuration > 0 {
+		deadline = d.now().Add(d.maxDuration)
+	}
+
+	var homeDashboards map[string][]string
+	if d.checkHome {
+		homeDashboards = d.homeDashboards(ctx, dashboards)
+	}
+
+	var orgUserLogins map[string]struct{}
+	if d.checkOrphaned {
+		orgUserLogins = d.orgUserLogins(ctx)
+	}
+
+	var teamNames map[int]string
+	if d.resolveOwners {
+		teamNames = d.teamNames(ctx)
+	}
+
+	var since time.Time
+	if d.updatedSince != "" {
+		if since, err = ParseUpdatedSince(d.updatedSince, d.now()); err != nil {
+			return []output.Dashboard{}, fmt.Errorf("updated since: %w", err)
+		}
+	}
+
+	pipeline := append(d.builtinPostProcessors(teamNames), d.postProcessors...)
+
+	var mu sync.Mutex
+	var notUpdated, reused atomic.Int64
+	links := newLinkIndex()
+
+	// scanned records the result of the dashboard with the given uid, nil if it has been dropped by the post-processors,
+	// and its links, nil if they are not checked
+	scanned := func(uid string, result *output.Dashboard, dashLinks *DashboardLinks) {
+		if d.scanState != nil {
+			d.scanState.markScanned(uid, d.now())
+		}
+		if d.checkpoint != nil {
+			if err := d.checkpoint.record(uid, result, dashLinks, d.now()); err != nil {
+				d.log.Warn("Could not write checkpoint: %s", err)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if result != nil {
+			finalOutput = append(finalOutput, *result)
+		}
+		if dashLinks != nil {
+			links.add(uid, dashLinks)
+		}
+	}
 
+	var c *cache
+	listed := dashboards
+	if d.cacheDir != "" {
+		if c, err = d.openCache(ctx); err != nil {
+			return []output.Dashboard{}, fmt.Errorf("open cache: %w", err)
+		}
+	}
+
+	if d.checkpoint != nil {
+		if n := d.checkpoint.begin(d.now()); n > 0 {
+			d.log.Log("Resuming the interrupted scan, %d dashboards already scanned", n)
+		}
+		// The dashboards scanned before the interruption are not scanned again, their links are the recorded ones
+		var resumedLinks map[string]*DashboardLinks
+		dashboards, finalOutput, resumedLinks = d.checkpoint.resume(dashboards)
+		for uid, dashLinks := range resumedLinks {
+			links.add(uid, dashLinks)
+		}
+	}
+
+	// The cached detections are reused with the cached links of the dashboards, and post-processed again.
+	// Dashboards listed with their version are not fetched if it didn't change, so their definition is nil.
+	reuse := func(dash grafana.ListedDashboard, version int, dashboardDefinition *grafana.DashboardDefinition) bool {
+		cached, ok := c.get(dash.UID, version)
+		if !ok || cached.Result == nil {
+			return false
+		}
+		if updatedBefore(cached.Updated, since) {
+			c.put(dash.UID, cached)
+			reused.Add(1)
+			notUpdated.Add(1)
+			return true
+		}
+		result := d.cachedResult(dash, cached, homeDashboards, dashboardViews, orgUserLogins)
+		keep, err := postProcess(ctx, pipeline, result, dashboardDefinition)
+		if err != nil {
+			// The post-processors may need the definition of the dashboard, which is fetched to check it again
+			d.log.Verbose().Log("(WARNING: could not post-process the cached result of dashboard %q, checking it again: %v)", dash.UID, err)
+			return false
+		}
+		if !keep {
+			result = nil
+		}
+		c.put(dash.UID, cached)
+		reused.Add(1)
+		scanned(dash.UID, result, cached.Links)
+		return true
+	}
+	if c != nil {
+		// The post-processors may call the API, so the cached results are post-processed concurrently
+		isReused := make([]bool, len(dashboards))
+		semaphore := make(chan struct{}, d.maxConcurrency)
+		var wg sync.WaitGroup
+		for i, dash := range dashboards {
+			semaphore <- struct{}{}
+			wg.Add(1)
+			go func(i int, dash grafana.ListedDashboard) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				isReused[i] = reuse(dash, dash.Version, nil)
+			}(i, dash)
+		}
+		wg.Wait()
+		var remaining []grafana.ListedDashboard
+		for i, dash := range dashboards {
+			if !isReused[i] {
+				remaining = append(remaining, dash)
+			}
+		}
+		dashboards = remaining
+	}
+	if d.progress != nil {
+		d.progress.setTotal(len(dashboards))
+	}
+
+	err = d.forEachDashboard(ctx, dashboards, deadline, func(dash grafana.ListedDashboard, dashboardDefinition *grafana.DashboardDefinition) error {
+		if updatedBefore(dashboardDefinition.Meta.Updated, since) {
+			notUpdated.Add(1)
+			return nil
+		}
+		if c != nil && reuse(dash, dashboardDefinition.Dashboard.Version, dashboardDefinition) {
+			return nil
+		}
+		dashboardOutput := output.Dashboard{
+			Detections: []output.Detection{},
+			UID:        dash.UID,
+			URL:        d.dashboardURL(dash.URL),
+			Title:      dash.Title,
+			Folder:     dashboardDefinition.Meta.FolderTitle,
+			FolderUID:  dashboardDefinition.Meta.FolderUID,
+			CreatedBy:  dashboardDefinition.Meta.CreatedBy,
+			UpdatedBy:  dashboardDefinition.Meta.UpdatedBy,
+			Public:     d.publicDashboards[dash.UID],
+			Deleted:    dash.IsDeleted,
+
+			SchemaVersion: dashboardDefinition.Dashboard.SchemaVersion,
+			GnetID:        int(dashboardDefinition.Dashboard.GnetID),
+
+			Provisioned:           dashboardDefinition.Meta.Provisioned,
+			ProvisionedExternalID: dashboardDefinition.Meta.ProvisionedExternalID,
+			HomeDashboardFor:      homeDashboards[dash.UID],
+		}
+		if views, ok := dashboardViews[dash.UID]; ok {
+			dashboardOutput.Views = &views.Views
+			dashboardOutput.LastViewed, dashboardOutput.LastViewedDaysAgo = d.normalizeTime(views.LastViewed)
+		}
+		if orgUserLogins != nil {
+			dashboardOutput.Orphaned = isOrphaned(dashboardDefinition.Meta, orgUserLogins)
+		}
+		dashboardOutput.Created, dashboardOutput.CreatedDaysAgo = d.normalizeTime(dashboardDefinition.Meta.Created)
+		dashboardOutput.Updated, dashboardOutput.UpdatedDaysAgo = d.normalizeTime(dashboardDefinition.Meta.Updated)
+		d.resolveLibraryPanels(ctx, dashboardDefinition.Dashboard.Panels)
+		var err error
+		dashboardOutput.Detections, err = d.checkDashboard(dashboardDefinition)
+		if err != nil {
+			return fmt.Errorf("check dashboard: %w", err)
+		}
+		// The result is cached before the post-processing, which modifies the detections in place
+		unprocessed := dashboardOutput
+		unprocessed.Detections = append([]output.Detection{}, dashboardOutput.Detections...)
+		keep, err := postProcess(ctx, pipeline, &dashboardOutput, dashboardDefinition)
+		if err != nil {
+			return fmt.Errorf("post-process dashboard: %w", err)
+		}
+		var result *output.Dashboard
+		if keep {
+			result = &dashboardOutput
+		}
+		var dashLinks *DashboardLinks
+		if d.checkLinks {
+			dashLinks = newDashboardLinks(dashboardDefinition)
+		}
+		if c != nil {
+			c.put(dash.UID, CachedDashboard{
+				Version: dashboardDefinition.Dashboard.Version,
+				Created: dashboardDefinition.Meta.Created,
+				Updated: dashboardDefinition.Meta.Updated,
+				Result:  &unprocessed,
+				Links:   dashLinks,
+			})
+		}
+		scanned(dash.UID, result, dashLinks)
+		return nil
+	})
+	if c != nil {
+		if n := reused.Load(); n > 0 {
+			d.log.Log("Reused the cached results of %d unchanged dashboards", n)
+		}
+		if cacheErr := c.write(listed); cacheErr != nil {
+			d.log.Warn("Could not write cache: %s", cacheErr)
+		}
+	}
+	if n := notUpdated.Load(); n > 0 {
+		d.log.Log("Skipped %d dashboards not updated since %s", n, since.Format(time.RFC3339))
+	}
+	d.addLibraryPanelConnections(ctx, finalOutput)
+	if d.checkLinks {
+		links.setLinkedAngularDashboards(finalOutput)
+	}
+	if dashboardViews != nil {
+		// Report the dashboards whose breakage would hurt most first
+		output.Sort(finalOutput, output.SortByPriority)
+	}
+	if d.checkpoint != nil && err == nil {
+		if cpErr := d.checkpoint.complete(finalOutput, d.now()); cpErr != nil {
+			d.log.Warn("Could not write checkpoint: %s", cpErr)
+		}
+	}
+	return finalOutput, err
+}
+
+// loadPlugins determines which plugins are Angular, and maps the datasource names to their plugin ids.
+func (d *Detector) loadPlugins(ctx context.Context) error {
 	// Determine if plugins are angular.
 	// This can be done from frontendsettings (faster and works with private plugins, but only works with >= 10.1.0)
 	// or from GCOM (slower, but always available, but public plugins only)
 	frontendSettings, err := d.grafanaClient.GetFrontendSettings(ctx)
 	if err != nil {
-		return []output.Dashboard{}, fmt.Errorf("get frontend settings: %w", err)
+		return fmt.Errorf("get frontend settings: %w", err)
 	}
 
-	// Determine if we should use GCOM or frontendsettings
-	// Get any key and see if Angular or AngularDetected is present or not.
+	// Determine which APIs and fields are available, to select the correct code paths.
 	// With Grafana >= 10.3.0, Angular is present.
 	// With Grafana >= 10.1.0 && < 10.3.0, AngularDetected is present.
 	// With Grafana <= 10.1.0, it's always nil as it's not present in the body.
 	// In the last case, we can only rely on the data in GCOM.
-	for _, p := range frontendSettings.Panels {
-		useGCOM = p.Angular == nil && p.AngularDetected == nil
-		break
+	compat := d.probeCompatibility(ctx, frontendSettings)
+	d.log.Verbose().Log(
+		"Grafana version %q, angular source %q, access control available %t",
+		compat.grafanaVersion, compat.angularSource, compat.hasAccessControl,
+	)
+	if d.progress != nil {
+		d.progress.setCompatibility(compat.grafanaVersion, compat.angularSource.String())
 	}
-	if useGCOM {
+	if compat.angularSource == angularSourceGCOM {
 		// Fall back to GCOM (< 10.1.0)
 		d.log.Verbose().Log("Using GCOM to find Angular plugins")
-		d.log.Log("(WARNING, dependencies on private plugins won't be flagged)")
-
-		// Double check that the token has the correct permissions, which is "datasources:create".
-		// If we don't have such permissions, the plugins endpoint will still return a valid response,
-		// but it will contain only core plugins:
-		// https://github.com/grafana/grafana/blob/0315b911ef45b4ce9d3d5c182d8b112c6b9b41da/pkg/api/plugins.go#L56
-		permissions, err := d.grafanaClient.GetServiceAccountPermissions(ctx)
-		if err != nil {
-			// Do not hard fail if we can't get service account permissions
-			// as we may be running against an old Grafana version without service accounts
-			d.log.Verbose().Log("(WARNING: could not get service account permissions: %v)", err)
-			d.log.Verbose().Log("Please make sure that you have created an ADMIN token or the output will be wrong")
-		} else {
-			_, hasDsCreate := permissions["datasources:create"]
-			_, hasPluginsInstall := permissions["plugins:install"]
-			if !hasDsCreate && !hasPluginsInstall {
-				return []output.Dashboard{}, fmt.Errorf(
-					`the service account does not have "datasources:create" or "plugins:install" permission, ` +
-						"please provide a token for a service account with admin privileges",
-				)
-			}
-		}
-
-		// Get the plugins
-		plugins, err := d.grafanaClient.GetPlugins(ctx)
-		if err != nil {
-			return []output.Dashboard{}, fmt.Errorf("get plugins: %w", err)
-		}
-		for _, p := range plugins {
-			if p.Info.Version == "" {
-				continue
-			}
-			d.angularDetected[p.ID], err = d.gcomClient.GetAngularDetected(ctx, p.ID, p.Info.Version)
-			if err != nil {
-				return []output.Dashboard{}, fmt.Errorf("get angular detected: %w", err)
-			}
-		}
+		d.angularDetected, d.angularUnknown, err = d.angularDetectedFromGCOM(ctx, compat, frontendSettings)
 	} else {
 		d.log.Verbose().Log("Using frontendsettings to find Angular plugins")
-		for pluginID, panel := range frontendSettings.Panels {
-			v, err := panel.IsAngular()
-			if err != nil {
-				return []output.Dashboard{}, fmt.Errorf("%q is angular: %w", pluginID, err)
-			}
-			d.angularDetected[pluginID] = v
-		}
-		for _, ds := range frontendSettings.Datasources {
-			v, err := ds.IsAngular()
-			if err != nil {
-				return []output.Dashboard{}, fmt.Errorf("%q is angular: %w", ds.Type, err)
-			}
-			d.angularDetected[ds.Type] = v
-		}
+		d.angularDetected, err = angularDetectedFromFrontendSettings(frontendSettings)
 	}
+	if err != nil {
+		return err
+	}
+	d.applyRules()
 
 	// Debug
 	for p, isAngular := range d.angularDetected {
+		if d.angularUnknown[p] {
+			d.log.Verbose().Log("Plugin %q angular unknown", p)
+			continue
+		}
 		d.log.Verbose().Log("Plugin %q angular %t", p, isAngular)
 	}
+	if len(d.angularUnknown) > 0 {
+		unknown := make([]string, 0, len(d.angularUnknown))
+		for p := range d.angularUnknown {
+			unknown = append(unknown, p)
+		}
+		sort.Strings(unknown)
+		d.log.Warn(
+			"Could not determine if plugins %s use Angular, as they are not in GCOM (e.g.: private plugins). "+
+				"Panels using them are reported as %q detections, verify them manually",
+			strings.Join(unknown, ", "), output.DetectionTypeUnknown,
+		)
+	}
 
 	// Map ds name -> ds plugin id, to resolve legacy dashboards that have ds name
 	apiDs, err := d.grafanaClient.GetDatasourcePluginIDs(ctx)
 	if err != nil {
-		return []output.Dashboard{}, fmt.Errorf("get datasource plugin ids: %w", err)
+		return fmt.Errorf("get datasource plugin ids: %w", err)
 	}
+	d.datasources = apiDs
 	d.datasourcePluginIDs = make(map[string]string, len(apiDs))
 	for _, ds := range apiDs {
 		d.datasourcePluginIDs[ds.Name] = ds.Type
+
+		// Every new panel uses the default datasource, so warn about it even if no dashboard uses it yet
+		if ds.IsDefault && d.angularDetected[ds.Type] {
+			d.log.Warn(
+				"The default data source %q uses the Angular plugin %q, "+
+					"new panels will break if Angular support is disabled",
+				ds.Name, ds.Type,
+			)
+		}
 	}
+	return nil
+}
 
-	dashboards, err := d.grafanaClient.GetDashboards(ctx, 1)
+// listDashboards returns the dashboards to check, without the excluded ones, and filtered by d.filters, d.scope
+// and d.dashboardUIDs if set.
+func (d *Detector) listDashboards(ctx context.Context) ([]grafana.ListedDashboard, error) {
+	dashboards, err := d.listAllPages(ctx)
 	if err != nil {
-		return []output.Dashboard{}, fmt.Errorf("get dashboards: %w", err)
+		return nil, err
+	}
+	if d.checkDeleted {
+		dashboards = append(dashboards, d.deletedDashboards(ctx, dashboards)...)
+	}
+	dashboards = d.filterExcluded(dashboards)
+	if dashboards, err = d.filterDashboards(ctx, dashboards); err != nil {
+		return nil, err
+	}
+	if dashboards, err = d.filterScope(ctx, dashboards); err != nil {
+		return nil, err
+	}
+	if len(d.dashboardUIDs) == 0 {
+		return dashboards, nil
 	}
+	var filtered []grafana.ListedDashboard
+	for _, dash := range dashboards {
+		if _, ok := d.dashboardUIDs[dash.UID]; ok {
+			filtered = append(filtered, dash)
+		}
+	}
+	return filtered, nil
+}
 
+// listAllPages returns the dashboards in all the pages of the search results, until an empty page,
+// or a page with only dashboards already listed (e.g.: if the API ignores the page).
+// Dashboards created, moved or deleted while listing shift the pages, so a dashboard may be listed on multiple pages:
+// each dashboard is only returned once (with its most recent listing, if it changed), and the duplicates are reported.
+func (d *Detector) listAllPages(ctx context.Context) ([]grafana.ListedDashboard, error) {
+	var dashboards []grafana.ListedDashboard
+	indexes := map[string]int{}
+	var duplicates, changed int
+	for page := 1; ; page++ {
+		listed, err := d.grafanaClient.GetDashboards(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("get dashboards (page %d): %w", page, err)
+		}
+		var pageDuplicates, pageChanged int
+		for _, dash := range listed {
+			i, ok := indexes[dash.UID]
+			if !ok {
+				indexes[dash.UID] = len(dashboards)
+				dashboards = append(dashboards, dash)
+				continue
+			}
+			pageDuplicates++
+			if !reflect.DeepEqual(dashboards[i], dash) {
+				pageChanged++
+				d.log.Verbose().Log("(WARNING: dashboard %q changed while listing, from %+v to %+v)", dash.UID, dashboards[i], dash)
+				dashboards[i] = dash
+			}
+		}
+		if pageDuplicates == len(listed) {
+			// Empty page, or same page again
+			break
+		}
+		duplicates += pageDuplicates
+		changed += pageChanged
+	}
+	if duplicates > 0 {
+		d.log.Warn(
+			"%d dashboards were listed more than once (%d of them changed while listing), "+
+				"probably because dashboards were created, moved or deleted during the scan: each dashboard is only checked once",
+			duplicates, changed,
+		)
+	}
+	return dashboards, nil
+}
+
+// deletedDashboards returns the dashboards in the trash that are not in the given dashboards.
+// Errors are not fatal, as the trash is only available in Grafana >= 11.
+func (d *Detector) deletedDashboards(ctx context.Context, dashboards []grafana.ListedDashboard) []grafana.ListedDashboard {
+	deleted, err := d.grafanaClient.GetDeletedDashboards(ctx)
+	if err != nil {
+		d.log.Warn("Could not get deleted dashboards: %s", err)
+		return nil
+	}
+	listed := make(map[string]struct{}, len(dashboards))
+	for _, dash := range dashboards {
+		listed[dash.UID] = struct{}{}
+	}
+	var out []grafana.ListedDashboard
+	for _, dash := range deleted {
+		// Older Grafana versions return all the dashboards, without the deleted flag
+		if _, ok := listed[dash.UID]; ok || !dash.IsDeleted {
+			continue
+		}
+		out = append(out, dash)
+	}
+	return out
+}
+
+// forEachDashboard downloads the given dashboards concurrently (up to d.maxConcurrency at a time),
+// and calls fn for each one of them. fn is called concurrently, so it must be safe for concurrent use.
+// Dashboards are downloaded in order. If deadline is not zero, no more dashboards are downloaded after it,
+// but at least one dashboard is always downloaded.
+// Errors don't stop the other dashboards from being processed, they are all returned at the end.
+func (d *Detector) forEachDashboard(
+	ctx context.Context, dashboards []grafana.ListedDashboard, deadline time.Time,
+	fn func(dash grafana.ListedDashboard, dashboardDefinition *grafana.DashboardDefinition) error,
+) error {
 	// Create a semaphore to limit concurrency
 	semaphore := make(chan struct{}, d.maxConcurrency)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var downloadErrors []error
 
-	for _, dash := range dashboards {
+	for i, dash := range dashboards {
+		// Acquire the semaphore before starting the goroutine, so dashboards are downloaded in order
+		semaphore <- struct{}{}
+		if i > 0 && !deadline.IsZero() && d.now().After(deadline) {
+			<-semaphore
+			d.log.Warn("Time budget exhausted, %d of %d dashboards not scanned, run again to continue", len(dashboards)-i, len(dashboards))
+			break
+		}
 		wg.Add(1)
 		go func(dash grafana.ListedDashboard) {
 			defer wg.Done()
-			semaphore <- struct{}{}        // Acquire semaphore
 			defer func() { <-semaphore }() // Release semaphore
 
-			dashboardAbsURL, err := url.JoinPath(strings.TrimSuffix(d.grafanaClient.BaseURL(), "/api"), dash.URL)
-			if err != nil {
-				dashboardAbsURL = ""
-			}
 			dashboardDefinition, err := d.grafanaClient.GetDashboard(ctx, dash.UID)
-			if err != nil {
-				mu.Lock()
-				downloadErrors = append(downloadErrors, fmt.Errorf("get dashboard %q: %w", dash.UID, err))
-				mu.Unlock()
+			if err != nil && dash.IsDeleted {
+				// Deleted dashboards may have been purged from the trash since they were listed
+				d.log.Verbose().Log("(WARNING: could not get deleted dashboard %q: %v)", dash.UID, err)
+				if d.progress != nil {
+					d.progress.add(0, 1, 0)
+				}
 				return
 			}
-			dashboardOutput := output.Dashboard{
-				Detections: []output.Detection{},
-				URL:        dashboardAbsURL,
-				Title:      dash.Title,
-				Folder:     dashboardDefinition.Meta.FolderTitle,
-				CreatedBy:  dashboardDefinition.Meta.CreatedBy,
-				UpdatedBy:  dashboardDefinition.Meta.UpdatedBy,
-				Created:    dashboardDefinition.Meta.Created,
-				Updated:    dashboardDefinition.Meta.Updated,
+			if err != nil {
+				err = fmt.Errorf("get dashboard %q: %w", dash.UID, err)
+			} else {
+				if d.progress != nil {
+					d.progress.add(1, 0, 0)
+				}
+				err = fn(dash, dashboardDefinition)
 			}
-			dashboardOutput.Detections, err = d.checkPanels(dashboardDefinition, dashboardDefinition.Dashboard.Panels)
 			if err != nil {
 				mu.Lock()
-				downloadErrors = append(downloadErrors, fmt.Errorf("check panels: %w", err))
+				downloadErrors = append(downloadErrors, err)
 				mu.Unlock()
-				return
 			}
-			mu.Lock()
-			finalOutput = append(finalOutput, dashboardOutput)
-			mu.Unlock()
+			if d.progress != nil {
+				if err != nil {
+					d.progress.add(0, 0, 1)
+				} else {
+					d.progress.add(0, 1, 0)
+				}
+			}
 		}(dash)
 	}
 
 	wg.Wait()
 
 	if len(downloadErrors) > 0 {
-		return finalOutput, fmt.Errorf("errors occurred during dashboard download: %v", downloadErrors)
+		return fmt.Errorf("errors occurred during dashboard download: %v", downloadErrors)
 	}
+	return nil
+}
 
-	return finalOutput, nil
+// dashboardURL returns the URL to output for the given dashboard path.
+// The URL is relative if d.relativeURLs is true, otherwise it's joined with d.urlBase,
+// or with the Grafana base URL (without the "/api" suffix) if d.urlBase is empty.
+func (d *Detector) dashboardURL(dashboardPath string) string {
+	if d.relativeURLs {
+		return dashboardPath
+	}
+	urlBase := d.urlBase
+	if urlBase == "" {
+		urlBase = strings.TrimSuffix(d.grafanaClient.BaseURL(), "/api")
+	}
+	u, err := url.JoinPath(urlBase, dashboardPath)
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// setPanelURLs sets the URL to view the panel of the given detections, from the URL of their dashboard.
+// Panel URLs are not set in offline mode, as the dashboard URLs are file paths.
+func (d *Detector) setPanelURLs(dashboardURL string, detections []output.Detection) {
+	if d.grafanaClient.BaseURL() == "" && d.urlBase == "" {
+		return
+	}
+	for i, detection := range detections {
+		if detection.PanelID == 0 {
+			continue
+		}
+		detections[i].PanelURL = dashboardURL + "?" + url.Values{"viewPanel": []string{strconv.Itoa(detection.PanelID)}}.Encode()
+	}
+}
+
+// checkDashboard checks the panels and template variables of the given dashboard for Angular plugins.
+// Detections of ignored plugins are removed, and the others are annotated with the suggested React replacement
+// of the plugin (or of the plugin it's reclassified as), if known.
+func (d *Detector) checkDashboard(dashboardDefinition *grafana.DashboardDefinition) ([]output.Detection, error) {
+	out, err := d.checkPanels(dashboardDefinition, dashboardDefinition.Dashboard.Panels)
+	if err != nil {
+		return nil, fmt.Errorf("check panels: %w", err)
+	}
+	vOut, err := d.checkTemplateVariables(dashboardDefinition.Dashboard.Templating.List)
+	if err != nil {
+		return nil, fmt.Errorf("check template variables: %w", err)
+	}
+	out = append(out, vOut...)
+	out = d.filterIgnored(out)
+	for i := range out {
+		out[i].SuggestedReplacement = d.migrationTargets[out[i].PluginID]
+		if out[i].SuggestedReplacement == "" {
+			out[i].SuggestedReplacement = d.migrationTargets[d.canonicalPluginID(out[i].PluginID)]
+		}
+	}
+	return out, nil
+}
+
+// checkTemplateVariables checks the given template variables for Angular data sources.
+// Only query variables are checked, as they run queries against their data source when the dashboard is loaded.
+func (d *Detector) checkTemplateVariables(variables []*grafana.TemplateVariable) ([]output.Detection, error) {
+	var out []output.Detection
+	for _, v := range variables {
+		if v.Type != "query" {
+			continue
+		}
+		dsPlugin, err := d.datasourcePluginID(v.Datasource)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %w", v.Name, err)
+		}
+		if d.angularDetected[dsPlugin] {
+			out = append(out, output.Detection{
+				DetectionType: output.DetectionTypeTemplateVariable,
+				PluginID:      dsPlugin,
+				Title:         v.Name,
+			})
+		} else if d.angularUnknown[dsPlugin] {
+			out = append(out, output.Detection{
+				DetectionType: output.DetectionTypeUnknown,
+				PluginID:      dsPlugin,
+				Title:         v.Name,
+			})
+		}
+	}
+	return out, nil
+}
+
+// datasourcePluginID returns the plugin id of the given datasource field, as found in panels and template variables.
+// It returns an empty string if the datasource is not set.
+func (d *Detector) datasourcePluginID(datasource interface{}) (string, error) {
+	// The datasource field can either be a string (old) or object (new)
+	if datasource == nil || datasource == "" {
+		return "", nil
+	}
+	if dsName, ok := datasource.(string); ok {
+		return d.datasourcePluginIDs[dsName], nil
+	}
+	if ds, ok := datasource.(grafana.PanelDatasource); ok {
+		return ds.Type, nil
+	}
+	return "", fmt.Errorf("unknown unmarshaled datasource type %T", datasource)
+}
+
+// walkPanels calls fn on the given panels, recursively.
+// row is the row panel containing the panel, or nil if the panel is not in a row.
+// Panels in collapsed rows are nested in the row, while panels in expanded rows follow the row.
+func walkPanels(panels []*grafana.DashboardPanel, fn func(p, row *grafana.DashboardPanel) error) error {
+	var row *grafana.DashboardPanel
+	for _, p := range panels {
+		if p.Type == pluginIDRow {
+			row = p
+		}
+		panelRow := row
+		if p == row {
+			panelRow = nil
+		}
+		if err := fn(p, panelRow); err != nil {
+			return err
+		}
+		// Recurse
+		if err := walkPanels(p.Panels, func(child, _ *grafana.DashboardPanel) error {
+			return fn(child, p)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // checkPanels calls checkPanel recursively on the given panels.
+// Detections in repeated panels or rows are annotated with the repeating variables.
 func (d *Detector) checkPanels(dashboardDefinition *grafana.DashboardDefinition, panels []*grafana.DashboardPanel) ([]output.Detection, error) {
 	var out []output.Detection
-	for _, p := range panels {
+	err := walkPanels(panels, func(p, row *grafana.DashboardPanel) error {
 		r, err := d.checkPanel(dashboardDefinition, p)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		for i := range r {
+			r[i].PanelID = p.ID
+			if p.LibraryPanel != nil {
+				r[i].LibraryPanelUID = p.LibraryPanel.UID
+				r[i].LibraryPanel = p.LibraryPanel.Name
+			}
+			r[i].Repeat = p.Repeat
+			if row != nil {
+				r[i].RowRepeat = row.Repeat
+				r[i].Row = row.Title
+			}
+			if p.GridPos != nil {
+				r[i].GridPos = &output.GridPos{X: p.GridPos.X, Y: p.GridPos.Y, W: p.GridPos.W, H: p.GridPos.H}
+			}
 		}
 		out = append(out, r...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-		// Recurse
-		if len(p.Panels) == 0 {
-			continue
-		}
-		rr, err := d.checkPanels(dashboardDefinition, p.Panels)
+// panelDatasourcePluginIDs returns the unique plugin ids of the datasources of the given panel and of its targets.
+// Panels using the "-- Mixed --" datasource only reference the real datasources in the targets.
+func (d *Detector) panelDatasourcePluginIDs(p *grafana.DashboardPanel) ([]string, error) {
+	datasources := make([]interface{}, 0, len(p.Targets)+1)
+	datasources = append(datasources, p.Datasource)
+	for _, target := range p.Targets {
+		datasources = append(datasources, target.Datasource)
+	}
+	var out []string
+	seen := map[string]struct{}{}
+	for _, datasource := range datasources {
+		dsPlugin, err := d.datasourcePluginID(datasource)
 		if err != nil {
 			return nil, err
 		}
-		out = append(out, rr...)
+		if _, ok := seen[dsPlugin]; ok || dsPlugin == "" {
+			continue
+		}
+		seen[dsPlugin] = struct{}{}
+		out = append(out, dsPlugin)
 	}
 	return out, nil
 }
@@ -242,15 +940,20 @@ func (d *Detector) checkPanel(dashboardDefinition *grafana.DashboardDefinition,
 
 	// Check panel
 	// - "graph" has been replaced with timeseries
+	// - "singlestat" has been replaced with stat (or gauge)
 	// - "table-old" is the old table panel (after it has been migrated)
-	// - "table" with a schema version < 24 is Angular table panel, which will be replaced by `table-old`:
+	// - "table" with a schema version < 24 is Angular table panel, which will be replaced by `table-old`
+	//   (v2 dashboards have no schema version, and are already migrated):
 	//		https://github.com/grafana/grafana/blob/7869ca1932c3a2a8f233acf35a3fe676187847bc/public/app/features/dashboard/state/DashboardMigrator.ts#L595-L610
-	if p.Type == pluginIDGraphOld || p.Type == pluginIDTableOld || (p.Type == pluginIDTable && dashboardDefinition.Dashboard.SchemaVersion < 24) {
+	if p.Type == pluginIDGraphOld || p.Type == pluginIDSinglestat || p.Type == pluginIDTableOld ||
+		(p.Type == pluginIDTable && dashboardDefinition.Dashboard.SchemaVersion < 24 && !dashboardDefinition.Dashboard.V2) {
 		// Different warning on legacy panel that can be migrated to React automatically
 		out = append(out, output.Detection{
 			DetectionType: output.DetectionTypeLegacyPanel,
 			PluginID:      p.Type,
 			Title:         p.Title,
+			LossyOptions:  lossyOptions(p),
+			ReviewOptions: reviewOptions(p),
 		})
 	} else if d.angularDetected[p.Type] {
 		// Angular plugin
@@ -258,25 +961,41 @@ func (d *Detector) checkPanel(dashboardDefinition *grafana.DashboardDefinition,
 			DetectionType: output.DetectionTypePanel,
 			PluginID:      p.Type,
 			Title:         p.Title,
+			LossyOptions:  lossyOptions(p),
+			ReviewOptions: reviewOptions(p),
+		})
+	} else if d.angularUnknown[p.Type] {
+		out = append(out, output.Detection{
+			DetectionType: output.DetectionTypeUnknown,
+			PluginID:      p.Type,
+			Title:         p.Title,
 		})
 	}
 
-	// Check datasource
-	var dsPlugin string
-	// The datasource field can either be a string (old) or object (new)
-	if p.Datasource == nil || p.Datasource == "" {
-		return out, nil
+	// Check options left over by the Angular version of plugins that have been migrated to React
+	if detection := d.checkLegacyOptions(p); detection != nil {
+		out = append(out, *detection)
 	}
-	if dsName, ok := p.Datasource.(string); ok {
-		dsPlugin = d.datasourcePluginIDs[dsName]
-	} else if ds, ok := p.Datasource.(grafana.PanelDatasource); ok {
-		dsPlugin = ds.Type
-	} else {
-		return nil, fmt.Errorf("unknown unmarshaled datasource type %T", p.Datasource)
+
+	// Check text panels relying on Angular to render their content
+	if detection := checkTextPanel(p); detection != nil {
+		out = append(out, *detection)
+	}
+
+	// Check datasources
+	dsPlugins, err := d.panelDatasourcePluginIDs(p)
+	if err != nil {
+		return nil, err
 	}
-	if d.angularDetected[dsPlugin] {
+	for _, dsPlugin := range dsPlugins {
+		detectionType := output.DetectionTypeDatasource
+		if d.angularUnknown[dsPlugin] {
+			detectionType = output.DetectionTypeUnknown
+		} else if !d.angularDetected[dsPlugin] {
+			continue
+		}
 		out = append(out, output.Detection{
-			DetectionType: output.DetectionTypeDatasource,
+			DetectionType: detectionType,
 			PluginID:      dsPlugin,
 			Title:         p.Title,
 		})
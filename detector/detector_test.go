@@ -1,17 +1,27 @@
 package detector
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/detect-angular-dashboards/api"
 	"github.com/grafana/detect-angular-dashboards/api/gcom"
 	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/api/offline"
 	"github.com/grafana/detect-angular-dashboards/logger"
 	"github.com/grafana/detect-angular-dashboards/output"
 )
@@ -19,7 +29,7 @@ import (
 func TestDetector(t *testing.T) {
 	t.Run("meta", func(t *testing.T) {
 		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
-		d := NewDetector(logger.NewLeveledLogger(false), cl, gcom.NewAPIClient(), 5)
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
 		out, err := d.Run(context.Background())
 		require.NoError(t, err)
 		require.Len(t, out, 1)
@@ -30,6 +40,911 @@ func TestDetector(t *testing.T) {
 		require.Equal(t, "admin", out[0].UpdatedBy)
 		require.Equal(t, "2023-11-07T11:13:24+01:00", out[0].Created)
 		require.Equal(t, "2024-02-21T13:09:27+01:00", out[0].Updated)
+		require.False(t, out[0].Public)
+		require.False(t, out[0].Provisioned)
+		require.Empty(t, out[0].ProvisionedExternalID)
+		require.Equal(t, 39, out[0].SchemaVersion)
+		require.Equal(t, 1860, out[0].GnetID)
+	})
+
+	t.Run("community revisions", func(t *testing.T) {
+		revisions := map[string]string{
+			"/dashboards/1860": `{"id": 1860, "name": "Node Exporter Full", "revision": 37}`,
+			"/dashboards/1860/revisions/37/download": `{
+				"__inputs": [{"name": "DS_PROMETHEUS", "type": "datasource", "pluginId": "prometheus"}],
+				"id": null,
+				"uid": "rYdddlPWk",
+				"title": "Node Exporter Full",
+				"panels": [{"id": 1, "type": "timeseries", "datasource": {"type": "prometheus", "uid": "${DS_PROMETHEUS}"}}]
+			}`,
+		}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, ok := revisions[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(resp))
+		}))
+		defer srv.Close()
+
+		dir := t.TempDir()
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		d := NewDetector(
+			logger.NewLeveledLogger(false), cl, gcom.APIClient{Client: api.NewClient(srv.URL)}, 5,
+			WithCommunityRevisions(true, dir),
+		)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		fn := filepath.Join(dir, "test-case-dashboard.json")
+		require.Equal(t, &output.CommunityRevision{Revision: 37, ImportFile: fn}, out[0].CommunityRevision)
+
+		var payload struct {
+			Dashboard map[string]interface{}
+			Overwrite bool
+			Inputs    []map[string]string
+		}
+		require.NoError(t, unmarshalFromFile(fn, &payload))
+		require.Equal(t, "test-case-dashboard", payload.Dashboard["uid"])
+		require.NotContains(t, payload.Dashboard, "id")
+		require.True(t, payload.Overwrite)
+		require.Equal(t, []map[string]string{
+			{"name": "DS_PROMETHEUS", "type": "datasource", "pluginId": "prometheus", "value": ""},
+		}, payload.Inputs)
+
+		// Revisions with Angular panels are reported, without payload
+		revisions["/dashboards/1860/revisions/37/download"] = `{"panels": [{"id": 1, "type": "graph"}]}`
+		d = NewDetector(
+			logger.NewLeveledLogger(false), cl, gcom.APIClient{Client: api.NewClient(srv.URL)}, 5,
+			WithCommunityRevisions(true, ""),
+		)
+		out, err = d.Run(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, &output.CommunityRevision{Revision: 37, Angular: true}, out[0].CommunityRevision)
+	})
+
+	t.Run("panel urls", func(t *testing.T) {
+		for _, tc := range []struct {
+			name        string
+			urlBase     string
+			expPanelURL string
+		}{
+			{
+				name:        "url base",
+				urlBase:     "https://grafana.example.com",
+				expPanelURL: "https://grafana.example.com/d/test-case-dashboard/test-case-dashboard?viewPanel=1",
+			},
+			{name: "offline", expPanelURL: ""},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithURLBase(tc.urlBase))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1)
+				require.Len(t, out[0].Detections, 1)
+				require.Equal(t, 1, out[0].Detections[0].PanelID)
+				require.Equal(t, tc.expPanelURL, out[0].Detections[0].PanelURL)
+			})
+		}
+	})
+
+	t.Run("timezone", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithTimezone(time.UTC))
+		d.now = func() time.Time {
+			return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		}
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Equal(t, "2023-11-07T10:13:24Z", out[0].Created)
+		require.Equal(t, "2024-02-21T12:09:27Z", out[0].Updated)
+		require.NotNil(t, out[0].CreatedDaysAgo)
+		require.Equal(t, 115, *out[0].CreatedDaysAgo)
+		require.NotNil(t, out[0].UpdatedDaysAgo)
+		require.Equal(t, 8, *out[0].UpdatedDaysAgo)
+	})
+
+	t.Run("updated since", func(t *testing.T) {
+		// The dashboard has been updated on 2024-02-21T12:09:27Z
+		for _, tc := range []struct {
+			updatedSince string
+			expChecked   bool
+		}{
+			{updatedSince: "", expChecked: true},
+			{updatedSince: "7d", expChecked: false},
+			{updatedSince: "10d", expChecked: true},
+			{updatedSince: "300h", expChecked: true},
+			{updatedSince: "200h", expChecked: false},
+			{updatedSince: "2024-02-21", expChecked: true},
+			{updatedSince: "2024-02-21T12:09:28Z", expChecked: false},
+		} {
+			t.Run(tc.updatedSince, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithUpdatedSince(tc.updatedSince))
+				d.now = func() time.Time {
+					return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+				}
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				if tc.expChecked {
+					require.Len(t, out, 1)
+				} else {
+					require.Empty(t, out)
+				}
+			})
+		}
+
+		t.Run("invalid", func(t *testing.T) {
+			for _, v := range []string{"yesterday", "-1d", "-2h", "2024-02-30"} {
+				_, err := ParseUpdatedSince(v, time.Now())
+				require.Error(t, err, v)
+			}
+		})
+	})
+
+	t.Run("post-processors", func(t *testing.T) {
+		var calls []string
+		record := func(name string, keep bool, err error) PostProcessor {
+			return NewPostProcessor(name, func(_ context.Context, dashboard *output.Dashboard, def *grafana.DashboardDefinition) (bool, error) {
+				require.NotNil(t, def)
+				calls = append(calls, name)
+				dashboard.Title += " (" + name + ")"
+				return keep, err
+			})
+		}
+		newDetector := func(processors ...PostProcessor) *Detector {
+			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+			return NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithPostProcessors(processors...))
+		}
+
+		t.Run("enrich", func(t *testing.T) {
+			calls = nil
+			out, err := newDetector(record("first", true, nil), record("second", true, nil)).Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			require.Equal(t, "test case dashboard (first) (second)", out[0].Title)
+			// Built-in stages run first
+			require.NotEmpty(t, out[0].Detections[0].Severity)
+			require.Equal(t, []string{"first", "second"}, calls)
+		})
+
+		t.Run("filter", func(t *testing.T) {
+			calls = nil
+			out, err := newDetector(record("filter", false, nil), record("skipped", true, nil)).Run(context.Background())
+			require.NoError(t, err)
+			require.Empty(t, out)
+			require.Equal(t, []string{"filter"}, calls)
+		})
+
+		t.Run("error", func(t *testing.T) {
+			_, err := newDetector(record("failing", true, errors.New("boom"))).Run(context.Background())
+			require.ErrorContains(t, err, `post-processor "failing": boom`)
+		})
+	})
+
+	t.Run("provisioned", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		cl.DashboardMetaFilePath = filepath.Join("testdata", "dashboard-meta-provisioned.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.True(t, out[0].Provisioned)
+		require.Equal(t, "/etc/grafana/provisioning/dashboards/test-case-dashboard.json", out[0].ProvisionedExternalID)
+	})
+
+	t.Run("url base", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithURLBase("https://grafana.example.com/"))
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Equal(t, "https://grafana.example.com/d/test-case-dashboard/test-case-dashboard", out[0].URL)
+	})
+
+	t.Run("relative urls", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithURLBase("https://grafana.example.com"), WithRelativeURLs(true))
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Equal(t, "/d/test-case-dashboard/test-case-dashboard", out[0].URL)
+	})
+
+	t.Run("dashboard uids", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			uids    []string
+			expUIDs []string
+		}{
+			{name: "all", uids: nil, expUIDs: []string{"test-case-dashboard"}},
+			{name: "matching", uids: []string{"test-case-dashboard", "other"}, expUIDs: []string{"test-case-dashboard"}},
+			{name: "not matching", uids: []string{"other"}, expUIDs: nil},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithDashboardUIDs(tc.uids))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				var uids []string
+				for _, dashboard := range out {
+					uids = append(uids, dashboard.UID)
+				}
+				require.Equal(t, tc.expUIDs, uids)
+			})
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "worldmap.json"))
+		cl.DashboardVersionsFilePath = filepath.Join("testdata", "dashboard-versions.json")
+		cl.DashboardVersionFilePaths = map[int]string{
+			1: filepath.Join("testdata", "dashboards", "not-angular.json"),
+			2: filepath.Join("testdata", "dashboards", "worldmap.json"),
+			3: filepath.Join("testdata", "dashboards", "worldmap.json"),
+		}
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithHistory(true))
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Len(t, out[0].Detections, 1)
+		require.Equal(t, 2, out[0].Detections[0].IntroducedVersion)
+		require.Equal(t, "bob", out[0].Detections[0].IntroducedBy)
+		require.Equal(t, "2024-01-10T09:30:00+01:00", out[0].Detections[0].Introduced)
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		cl := offline.NewAPIClient(filepath.Join("testdata", "dashboards"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 18)
+		for _, dashboard := range out {
+			if dashboard.URL != "worldmap.json" {
+				continue
+			}
+			require.Equal(t, "worldmap", dashboard.Title)
+			require.Len(t, dashboard.Detections, 1)
+			require.Equal(t, "grafana-worldmap-panel", dashboard.Detections[0].PluginID)
+			require.Equal(t, output.DetectionTypePanel, dashboard.Detections[0].DetectionType)
+			return
+		}
+		t.Fatal("worldmap.json not found in output")
+	})
+
+	t.Run("offline non-dashboard files", func(t *testing.T) {
+		dir := t.TempDir()
+		b, err := os.ReadFile(filepath.Join("testdata", "dashboards", "worldmap.json"))
+		require.NoError(t, err)
+		for fn, content := range map[string][]byte{
+			"worldmap.json":    b,
+			"datasources.json": []byte(`[{"name": "prometheus"}]`),
+			"package.json":     []byte(`{"name": "dashboards"}`),
+			"broken.json":      []byte(`{"panels": [`),
+		} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, fn), content, 0o644))
+		}
+		var buf bytes.Buffer
+		log := logger.NewLeveledLogger(false)
+		log.WarnLogger = stdlog.New(&buf, "", 0)
+		cl := offline.NewAPIClient(dir, offline.WithLogger(log))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Equal(t, "worldmap.json", out[0].URL)
+		for _, fn := range []string{"datasources.json", "package.json", "broken.json"} {
+			require.Contains(t, buf.String(), `Skipping "`+fn+`": not a dashboard`)
+		}
+	})
+
+	t.Run("core angular datasource with gcom", func(t *testing.T) {
+		for _, tc := range []struct {
+			name           string
+			grafanaVersion string
+			expDetections  int
+		}{
+			{name: "angular", grafanaVersion: "8.4.7", expDetections: 1},
+			{name: "react", grafanaVersion: "9.5.2", expDetections: 0},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "core-datasource.json"))
+				cl.FrontendSettingsFilePath = filepath.Join("testdata", "frontend-settings-8.json")
+				cl.PluginsFilePath = filepath.Join("testdata", "plugins-core.json")
+				cl.ServiceAccountPermissionsErr = fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+				cl.GrafanaVersion = tc.grafanaVersion
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1)
+				require.Len(t, out[0].Detections, tc.expDetections)
+				if tc.expDetections > 0 {
+					require.Equal(t, "mysql", out[0].Detections[0].PluginID)
+					require.Equal(t, output.DetectionTypeDatasource, out[0].Detections[0].DetectionType)
+				}
+			})
+		}
+	})
+
+	t.Run("unknown angular status with gcom", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "private-plugin.json"))
+		cl.FrontendSettingsFilePath = filepath.Join("testdata", "frontend-settings-8.json")
+		cl.PluginsFilePath = filepath.Join("testdata", "plugins-private.json")
+		cl.ServiceAccountPermissionsErr = fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+		cl.GrafanaVersion = "8.4.7"
+		gcomClient := newTestGCOMClient(t, map[string][]gcom.PluginVersion{
+			"grafana-worldmap-panel": {{Version: "1.0.0", AngularDetected: true}},
+		}, nil)
+		d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Len(t, out[0].Detections, 2)
+		detectionTypes := map[string]output.DetectionType{}
+		for _, detection := range out[0].Detections {
+			detectionTypes[detection.PluginID] = detection.DetectionType
+			if detection.DetectionType == output.DetectionTypeUnknown {
+				require.Equal(t, output.SeverityUnknown, detection.Severity)
+			}
+		}
+		require.Equal(t, map[string]output.DetectionType{
+			"acme-private-panel":     output.DetectionTypeUnknown,
+			"grafana-worldmap-panel": output.DetectionTypePanel,
+		}, detectionTypes)
+	})
+
+	t.Run("home dashboards", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		cl.OrgPreferencesFilePath = filepath.Join("testdata", "org-preferences.json")
+		cl.TeamsFilePath = filepath.Join("testdata", "teams.json")
+		cl.TeamPreferencesFilePath = filepath.Join("testdata", "team-preferences.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithHomeDashboards(true))
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Equal(t, []string{"org", "team:Backend"}, out[0].HomeDashboardFor)
+	})
+
+	t.Run("default datasource", func(t *testing.T) {
+		for _, tc := range []struct {
+			name            string
+			datasourcesFile string
+			expWarning      bool
+		}{
+			{name: "angular", datasourcesFile: "datasources-default-angular.json", expWarning: true},
+			{name: "react", datasourcesFile: "datasources.json", expWarning: false},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "not-angular.json"))
+				cl.DatasourcesFilePath = filepath.Join("testdata", tc.datasourcesFile)
+				log := logger.NewLeveledLogger(false)
+				var warnings bytes.Buffer
+				log.WarnLogger.SetOutput(&warnings)
+				d := NewDetector(log, cl, newTestGCOMClient(t, nil, nil), 5)
+				_, err := d.Run(context.Background())
+				require.NoError(t, err)
+				if tc.expWarning {
+					require.Contains(t, warnings.String(), `The default data source "Akumuli" uses the Angular plugin "akumuli-datasource"`)
+				} else {
+					require.Empty(t, warnings.String())
+				}
+			})
+		}
+	})
+
+	t.Run("migration targets", func(t *testing.T) {
+		t.Run("built-in", func(t *testing.T) {
+			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "multiple.json"))
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+			out, err := d.Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			replacements := map[string]string{}
+			for _, detection := range out[0].Detections {
+				replacements[detection.PluginID] = detection.SuggestedReplacement
+			}
+			require.Equal(t, map[string]string{
+				"akumuli-datasource":     "",
+				"grafana-worldmap-panel": "geomap",
+				"graph":                  "timeseries",
+			}, replacements)
+		})
+
+		t.Run("from file", func(t *testing.T) {
+			targets, err := ReadMigrationTargets(filepath.Join("testdata", "migration-targets.json"))
+			require.NoError(t, err)
+			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "multiple.json"))
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithMigrationTargets(targets))
+			out, err := d.Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			replacements := map[string]string{}
+			for _, detection := range out[0].Detections {
+				replacements[detection.PluginID] = detection.SuggestedReplacement
+			}
+			require.Equal(t, map[string]string{
+				"akumuli-datasource":     "grafana-testdata-datasource",
+				"grafana-worldmap-panel": "orchestracities-map-panel",
+				"graph":                  "timeseries",
+			}, replacements)
+		})
+	})
+
+	t.Run("latest version", func(t *testing.T) {
+		gcomClient := newTestGCOMClient(t, map[string][]gcom.PluginVersion{
+			"akumuli-datasource":     {{Version: "2.0.0", AngularDetected: false}, {Version: "1.0.0", AngularDetected: true}},
+			"grafana-worldmap-panel": {{Version: "1.0.6", AngularDetected: true}},
+		}, nil)
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "multiple.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		for _, detection := range out[0].Detections {
+			switch detection.PluginID {
+			case "akumuli-datasource":
+				require.Equal(t, "2.0.0", detection.LatestVersion)
+				require.NotNil(t, detection.LatestIsAngular)
+				require.False(t, *detection.LatestIsAngular)
+			case "grafana-worldmap-panel":
+				require.Equal(t, "1.0.6", detection.LatestVersion)
+				require.NotNil(t, detection.LatestIsAngular)
+				require.True(t, *detection.LatestIsAngular)
+			default:
+				require.Empty(t, detection.LatestVersion)
+				require.Nil(t, detection.LatestIsAngular)
+			}
+		}
+	})
+
+	t.Run("deleted dashboards", func(t *testing.T) {
+		for _, tc := range []struct {
+			name         string
+			checkDeleted bool
+			expDeleted   []string
+		}{
+			{name: "disabled", checkDeleted: false, expDeleted: nil},
+			{name: "enabled", checkDeleted: true, expDeleted: []string{"deleted-dashboard"}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.DeletedDashboardsFilePath = filepath.Join("testdata", "deleted-dashboards.json")
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithDeletedDashboards(tc.checkDeleted))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1+len(tc.expDeleted))
+				var deleted []string
+				for _, dashboard := range out {
+					require.NotEmpty(t, dashboard.Detections)
+					if dashboard.Deleted {
+						deleted = append(deleted, dashboard.UID)
+					}
+				}
+				require.Equal(t, tc.expDeleted, deleted)
+			})
+		}
+	})
+
+	t.Run("paged dashboards", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		cl.DashboardPagesFilePath = filepath.Join("testdata", "dashboard-pages.json")
+		log := logger.NewLeveledLogger(false)
+		var warnings bytes.Buffer
+		log.WarnLogger.SetOutput(&warnings)
+		d := NewDetector(log, cl, newTestGCOMClient(t, nil, nil), 5)
+		dashboards, err := d.listDashboards(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []grafana.ListedDashboard{
+			{ID: 1, UID: "a", URL: "/d/a/a", Title: "a"},
+			{ID: 2, UID: "b", URL: "/d/b/b-renamed", Title: "b renamed"},
+			{ID: 3, UID: "c", URL: "/d/c/c", Title: "c"},
+			{ID: 4, UID: "d", URL: "/d/d/d", Title: "d"},
+		}, dashboards)
+		require.Contains(t, warnings.String(), "2 dashboards were listed more than once (1 of them changed while listing)")
+	})
+
+	t.Run("exclusions", func(t *testing.T) {
+		for _, tc := range []struct {
+			name       string
+			folders    []string
+			dashboards []string
+			expUIDs    []string
+		}{
+			{name: "none", expUIDs: []string{"root", "prod", "old-1", "old-2"}},
+			{name: "folder glob", folders: []string{"zz_*"}, expUIDs: []string{"root", "prod", "old-2"}},
+			{name: "folder uid", folders: []string{"team-a"}, expUIDs: []string{"root", "old-1", "old-2"}},
+			{name: "folder regexp", folders: []string{"re:(?i)^(zz_|archive)"}, expUIDs: []string{"root", "prod"}},
+			{name: "dashboard path", dashboards: []string{"zz_deprecated/*"}, expUIDs: []string{"root", "prod", "old-2"}},
+			{name: "dashboard title and uid", dashboards: []string{"Root*", "old-2"}, expUIDs: []string{"prod", "old-1"}},
+			{name: "dashboard regexp", dashboards: []string{"re:^Old [0-9]$"}, expUIDs: []string{"root", "prod"}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				exclusions, err := NewExclusions(tc.folders, tc.dashboards)
+				require.NoError(t, err)
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.DashboardPagesFilePath = filepath.Join("testdata", "exclusions-dashboards.json")
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithExclusions(exclusions))
+				dashboards, err := d.listDashboards(context.Background())
+				require.NoError(t, err)
+				var uids []string
+				for _, dash := range dashboards {
+					uids = append(uids, dash.UID)
+				}
+				require.Equal(t, tc.expUIDs, uids)
+			})
+		}
+
+		t.Run("invalid", func(t *testing.T) {
+			_, err := NewExclusions([]string{"["}, nil)
+			require.ErrorContains(t, err, "invalid glob")
+			_, err = NewExclusions(nil, []string{"re:("})
+			require.ErrorContains(t, err, "invalid regular expression")
+		})
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		for _, tc := range []struct {
+			name      string
+			filters   Filters
+			noFolders bool
+			expUIDs   []string
+			expErr    string
+		}{
+			{name: "empty", expUIDs: []string{"root", "prod", "old-1", "old-2"}},
+			{name: "folder title with subfolders", filters: Filters{Folders: []string{"Team A"}}, expUIDs: []string{"prod", "old-1"}},
+			{name: "folder uids", filters: Filters{FolderUIDs: []string{"general", "archive"}}, expUIDs: []string{"root", "old-2"}},
+			{name: "tags", filters: Filters{Tags: []string{"prod", "team-a"}}, expUIDs: []string{"prod"}},
+			{name: "folders and tags", filters: Filters{Folders: []string{"Team A"}, Tags: []string{"team-a"}}, expUIDs: []string{"prod", "old-1"}},
+			{name: "unknown folder", filters: Filters{Folders: []string{"Team B"}}, expErr: `folder "Team B" not found`},
+			{
+				name:      "without folders API",
+				filters:   Filters{Folders: []string{"Team A"}, FolderUIDs: []string{"archive"}},
+				noFolders: true,
+				expUIDs:   []string{"prod", "old-2"},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.DashboardPagesFilePath = filepath.Join("testdata", "exclusions-dashboards.json")
+				if !tc.noFolders {
+					cl.FoldersFilePath = filepath.Join("testdata", "folders.json")
+				}
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithFilters(tc.filters))
+				dashboards, err := d.listDashboards(context.Background())
+				if tc.expErr != "" {
+					require.ErrorContains(t, err, tc.expErr)
+					return
+				}
+				require.NoError(t, err)
+				var uids []string
+				for _, dash := range dashboards {
+					uids = append(uids, dash.UID)
+				}
+				require.Equal(t, tc.expUIDs, uids)
+			})
+		}
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			scope   Scope
+			expUIDs []string
+		}{
+			{name: "empty", expUIDs: []string{"root", "prod", "old-1", "old-2"}},
+			{name: "folder with subfolders", scope: Scope{FolderUIDs: []string{"team-a"}}, expUIDs: []string{"prod", "old-1"}},
+			{name: "subfolder", scope: Scope{FolderUIDs: []string{"deprecated"}}, expUIDs: []string{"old-1"}},
+			{
+				name:    "folders and dashboards",
+				scope:   Scope{FolderUIDs: []string{"archive"}, DashboardUIDs: []string{"root"}},
+				expUIDs: []string{"root", "old-2"},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.DashboardPagesFilePath = filepath.Join("testdata", "exclusions-dashboards.json")
+				cl.FoldersFilePath = filepath.Join("testdata", "folders.json")
+				fullProgress, scopeProgress := NewProgress(), NewProgress()
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithProgress(fullProgress))
+				out, err := d.RunScope(context.Background(), tc.scope, scopeProgress)
+				require.NoError(t, err)
+				// The scoped runs don't replace the last scan of the complete runs
+				if tc.scope.IsEmpty() {
+					require.NotNil(t, fullProgress.Status(time.Now()).LastScan)
+					require.Nil(t, scopeProgress.Status(time.Now()).LastScan)
+				} else {
+					require.Nil(t, fullProgress.Status(time.Now()).LastScan)
+					require.Equal(t, len(tc.expUIDs), scopeProgress.Status(time.Now()).LastScan.Dashboards)
+				}
+				require.Same(t, fullProgress, d.progress)
+				var uids []string
+				for _, dashboard := range out {
+					uids = append(uids, dashboard.UID)
+				}
+				require.ElementsMatch(t, tc.expUIDs, uids)
+				require.Nil(t, d.scope)
+			})
+		}
+	})
+
+	t.Run("orphaned", func(t *testing.T) {
+		for _, tc := range []struct {
+			name        string
+			metaFile    string
+			usersFile   string
+			expOrphaned bool
+		}{
+			{name: "deleted users", metaFile: "dashboard-meta.json", usersFile: "org-users.json", expOrphaned: true},
+			{name: "provisioned", metaFile: "dashboard-meta-provisioned.json", usersFile: "org-users.json", expOrphaned: false},
+			{name: "users not available", metaFile: "dashboard-meta.json", usersFile: "", expOrphaned: false},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.DashboardMetaFilePath = filepath.Join("testdata", tc.metaFile)
+				if tc.usersFile != "" {
+					cl.OrgUsersFilePath = filepath.Join("testdata", tc.usersFile)
+				}
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithOrphaned(true))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1)
+				require.Equal(t, tc.expOrphaned, out[0].Orphaned)
+			})
+		}
+	})
+
+	t.Run("usage insights", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		cl.DashboardViewsFilePath = filepath.Join("testdata", "dashboard-views.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithUsageInsights(true), WithTimezone(time.UTC))
+		d.now = func() time.Time {
+			return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		}
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.NotNil(t, out[0].Views)
+		require.Equal(t, 42, *out[0].Views)
+		require.Equal(t, "2024-02-28T12:00:00Z", out[0].LastViewed)
+		require.NotNil(t, out[0].LastViewedDaysAgo)
+		require.Equal(t, 2, *out[0].LastViewedDaysAgo)
+		require.NotNil(t, out[0].Priority)
+		require.Equal(t, 42, *out[0].Priority)
+	})
+
+	t.Run("usage insights not available", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithUsageInsights(true))
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Nil(t, out[0].Views)
+		require.Empty(t, out[0].LastViewed)
+		require.Nil(t, out[0].Priority)
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		for _, tc := range []struct {
+			name            string
+			permissionsFile string
+			expEditors      []string
+		}{
+			{
+				name:            "editors",
+				permissionsFile: filepath.Join("testdata", "dashboard-permissions.json"),
+				expEditors:      []string{"role:Editor", "team:Backend", "user:editor"},
+			},
+			{name: "permissions not available", expEditors: nil},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.DashboardPermissionsFilePath = tc.permissionsFile
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithPermissions(true))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1)
+				require.Equal(t, tc.expEditors, out[0].Editors)
+			})
+		}
+	})
+
+	t.Run("owners", func(t *testing.T) {
+		for _, tc := range []struct {
+			name            string
+			teamsFile       string
+			permissionsFile string
+			expOwners       []string
+		}{
+			{
+				name:            "owners",
+				teamsFile:       filepath.Join("testdata", "teams.json"),
+				permissionsFile: filepath.Join("testdata", "folder-permissions.json"),
+				expOwners:       []string{"Backend"},
+			},
+			{
+				name:            "teams not available",
+				teamsFile:       filepath.Join("testdata", "does-not-exist.json"),
+				permissionsFile: filepath.Join("testdata", "folder-permissions.json"),
+				expOwners:       []string{"Backend", "Deleted team"},
+			},
+			{name: "permissions not available", teamsFile: filepath.Join("testdata", "teams.json"), expOwners: nil},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+				cl.TeamsFilePath = tc.teamsFile
+				cl.FolderPermissionsFilePath = tc.permissionsFile
+				d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithOwners(true))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1)
+				require.Equal(t, tc.expOwners, out[0].Owners)
+			})
+		}
+	})
+
+	t.Run("plugin status", func(t *testing.T) {
+		gcomClient := newTestGCOMClient(t, nil, map[string]gcom.Plugin{
+			"akumuli-datasource": {
+				Slug: "akumuli-datasource", Status: "active", SignatureType: "community",
+				UpdatedAt: "2024-02-20T12:00:00.000Z",
+			},
+			"grafana-worldmap-panel": {
+				Slug: "grafana-worldmap-panel", Status: gcom.PluginStatusDeprecated, SignatureType: "grafana",
+				UpdatedAt: "2022-03-01T12:00:00.000Z",
+			},
+		})
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "multiple.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5, WithTimezone(time.UTC))
+		d.now = func() time.Time {
+			return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		}
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		for _, detection := range out[0].Detections {
+			switch detection.PluginID {
+			case "akumuli-datasource":
+				require.Equal(t, "community", detection.SignatureType)
+				require.False(t, detection.Deprecated)
+				require.Equal(t, "2024-02-20T12:00:00Z", detection.LastRelease)
+				require.NotNil(t, detection.LastReleaseDaysAgo)
+				require.Equal(t, 10, *detection.LastReleaseDaysAgo)
+			case "grafana-worldmap-panel":
+				require.Equal(t, "grafana", detection.SignatureType)
+				require.True(t, detection.Deprecated)
+				require.Equal(t, "2022-03-01T12:00:00Z", detection.LastRelease)
+			default:
+				require.Empty(t, detection.SignatureType)
+				require.False(t, detection.Deprecated)
+				require.Empty(t, detection.LastRelease)
+				require.Nil(t, detection.LastReleaseDaysAgo)
+			}
+		}
+	})
+
+	t.Run("lossy migrations", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "lossy.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Len(t, out[0].Detections, 6)
+
+		lossyGraph := out[0].Detections[0]
+		require.Equal(t, output.DetectionTypeLegacyPanel, lossyGraph.DetectionType)
+		require.Equal(t, []string{"seriesOverrides.zindex", "thresholds", "yaxis.align"}, lossyGraph.LossyOptions)
+		require.Equal(t, output.SeverityReplacementAvailable, lossyGraph.Severity)
+		require.Equal(t,
+			`Found legacy plugin "graph" in panel "lossy graph". `+
+				`It can be migrated to a React-based panel by Grafana when opening the dashboard, `+
+				`but options seriesOverrides.zindex, thresholds, yaxis.align are not migrated.`,
+			lossyGraph.String(),
+		)
+
+		graph := out[0].Detections[1]
+		require.Equal(t, output.DetectionTypeLegacyPanel, graph.DetectionType)
+		require.Empty(t, graph.LossyOptions)
+		require.Equal(t, output.SeverityAutoMigratable, graph.Severity)
+
+		worldmap := out[0].Detections[2]
+		require.Equal(t, output.DetectionTypePanel, worldmap.DetectionType)
+		require.Equal(t, []string{"locationData", "jsonUrl"}, worldmap.LossyOptions)
+
+		reviewSinglestat := out[0].Detections[3]
+		require.Equal(t, output.DetectionTypeLegacyPanel, reviewSinglestat.DetectionType)
+		require.Empty(t, reviewSinglestat.LossyOptions)
+		require.Equal(t, []string{"thresholds", "rangeMaps", "valueName"}, reviewSinglestat.ReviewOptions)
+		require.Equal(t, output.SeverityAutoMigratable, reviewSinglestat.Severity)
+
+		gaugeSinglestat := out[0].Detections[4]
+		require.Equal(t, []string{"gauge.thresholds"}, gaugeSinglestat.ReviewOptions)
+
+		singlestat := out[0].Detections[5]
+		require.Equal(t, output.DetectionTypeLegacyPanel, singlestat.DetectionType)
+		require.Empty(t, singlestat.ReviewOptions)
+	})
+
+	t.Run("schema v2", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "schema-v2.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		detections := out[0].Detections
+		for i := range detections {
+			detections[i].Severity = ""
+			detections[i].SuggestedReplacement = ""
+			detections[i].LatestVersion, detections[i].LatestIsAngular = "", nil
+			detections[i].SignatureType, detections[i].LastRelease, detections[i].LastReleaseDaysAgo = "", "", nil
+			detections[i].PanelURL = ""
+		}
+		require.Equal(t, []output.Detection{
+			{DetectionType: output.DetectionTypeDatasource, PluginID: "akumuli-datasource", Title: "akumuli", PanelID: 3},
+			{
+				DetectionType: output.DetectionTypeLegacyPanel, PluginID: "graph", Title: "graph", PanelID: 1,
+				LossyOptions: []string{"seriesOverrides.zindex"}, Row: "overview", GridPos: &output.GridPos{X: 0, Y: 0, W: 12, H: 8},
+			},
+			{
+				DetectionType: output.DetectionTypePanel, PluginID: "grafana-worldmap-panel", Title: "worldmap", PanelID: 2,
+				Repeat: "country", RowRepeat: "region", Row: "maps", GridPos: &output.GridPos{X: 0, Y: 0, W: 24, H: 10},
+			},
+			{DetectionType: output.DetectionTypeTemplateVariable, PluginID: "akumuli-datasource", Title: "host"},
+		}, detections)
+	})
+
+	t.Run("severity", func(t *testing.T) {
+		gcomClient := newTestGCOMClient(t, map[string][]gcom.PluginVersion{
+			"akumuli-datasource": {{Version: "2.0.0", AngularDetected: false}, {Version: "1.0.0", AngularDetected: true}},
+		}, nil)
+		for _, tc := range []struct {
+			name             string
+			migrationTargets map[string]string
+			expSeverities    map[string]output.Severity
+		}{
+			{
+				name: "built-in migration targets",
+				expSeverities: map[string]output.Severity{
+					"akumuli-datasource":     output.SeverityReplacementAvailable,
+					"grafana-worldmap-panel": output.SeverityReplacementAvailable,
+					"graph":                  output.SeverityAutoMigratable,
+				},
+			},
+			{
+				name:             "no replacement",
+				migrationTargets: map[string]string{"grafana-worldmap-panel": ""},
+				expSeverities: map[string]output.Severity{
+					"akumuli-datasource":     output.SeverityReplacementAvailable,
+					"grafana-worldmap-panel": output.SeverityNoReplacement,
+					"graph":                  output.SeverityAutoMigratable,
+				},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "multiple.json"))
+				d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5, WithMigrationTargets(tc.migrationTargets))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				require.Len(t, out, 1)
+				severities := map[string]output.Severity{}
+				for _, detection := range out[0].Detections {
+					severities[detection.PluginID] = detection.Severity
+				}
+				require.Equal(t, tc.expSeverities, severities)
+			})
+		}
+	})
+
+	t.Run("public dashboard", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "graph-old.json"))
+		cl.PublicDashboardsFilePath = filepath.Join("testdata", "public-dashboards.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.True(t, out[0].Public)
 	})
 
 	type expDetection struct {
@@ -37,100 +952,1258 @@ func TestDetector(t *testing.T) {
 		detectionType output.DetectionType
 		title         string
 		message       string
+		repeat        string
+		rowRepeat     string
+		row           string
+		gridPos       *output.GridPos
+	}
+	for _, tc := range []struct {
+		name          string
+		file          string
+		expDetections []expDetection
+	}{
+		{
+			name: "legacy panel",
+			file: "graph-old.json",
+			expDetections: []expDetection{{
+				pluginID:      "graph",
+				detectionType: output.DetectionTypeLegacyPanel,
+				title:         "Flot graph",
+				message:       `Found legacy plugin "graph" in panel "Flot graph". It can be migrated to a React-based panel by Grafana when opening the dashboard.`,
+			}},
+		},
+		{
+			name: "angular panel",
+			file: "worldmap.json",
+			expDetections: []expDetection{{
+				pluginID:      "grafana-worldmap-panel",
+				detectionType: output.DetectionTypePanel,
+				title:         "Panel Title",
+				message:       `Found angular panel "Panel Title" ("grafana-worldmap-panel")`,
+			}},
+		},
+		{
+			name: "datasource",
+			file: "datasource.json",
+			expDetections: []expDetection{{
+				pluginID:      "akumuli-datasource",
+				detectionType: output.DetectionTypeDatasource,
+				title:         "akumuli",
+				message:       `Found panel with angular data source "akumuli" ("akumuli-datasource")`,
+			}},
+		},
+		{
+			name: "multiple",
+			file: "multiple.json",
+			expDetections: []expDetection{
+				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeDatasource, title: "akumuli"},
+				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "worldmap + akumuli"},
+				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeDatasource, title: "worldmap + akumuli"},
+				{pluginID: "graph", detectionType: output.DetectionTypeLegacyPanel, title: "graph-old"},
+			},
+		},
+		{
+			name: "mixed datasource",
+			file: "mixed-datasource.json",
+			expDetections: []expDetection{
+				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeDatasource, title: "mixed"},
+				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeDatasource, title: "mixed legacy"},
+			},
+		},
+		{
+			name: "repeats",
+			file: "repeats.json",
+			expDetections: []expDetection{
+				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "repeated", repeat: "server"},
+				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "in expanded row", rowRepeat: "datacenter"},
+				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "in collapsed row", repeat: "server", rowRepeat: "region"},
+			},
+		},
+		{
+			name: "legacy options",
+			file: "legacy-options.json",
+			expDetections: []expDetection{{
+				pluginID:      "grafana-polystat-panel",
+				detectionType: output.DetectionTypeLegacyOptions,
+				title:         "legacy",
+				message: `Found Angular options polystat, savedComposites, savedOverrides in panel "legacy" using the React version of plugin "grafana-polystat-panel". ` +
+					`The panel may render differently than before the migration.`,
+			}},
+		},
+		{
+			name: "text angular mode",
+			file: "text-angular.json",
+			expDetections: []expDetection{
+				{pluginID: "text", detectionType: output.DetectionTypeTextAngularMode, title: "angular mode"},
+				{
+					pluginID:      "text",
+					detectionType: output.DetectionTypeTextAngularMode,
+					title:         "angular template",
+					message:       `Found text panel "angular template" with Angular templates. The content may render differently without Angular.`,
+				},
+			},
+		},
+		{
+			name:          "not angular",
+			file:          "not-angular.json",
+			expDetections: nil,
+		},
+		{
+			name: "mix of angular and react",
+			file: "mixed.json",
+			expDetections: []expDetection{
+				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "angular"},
+			},
+		},
+		{
+			name: "rows expanded",
+			file: "rows-expanded.json",
+			expDetections: []expDetection{
+				{
+					pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "expanded",
+					row: "Row title", gridPos: &output.GridPos{X: 0, Y: 1, W: 24, H: 9},
+				},
+			},
+		},
+		{
+			name: "template variables",
+			file: "template-variables.json",
+			expDetections: []expDetection{
+				{
+					pluginID:      "akumuli-datasource",
+					detectionType: output.DetectionTypeTemplateVariable,
+					title:         "metric",
+					message:       `Found template variable "metric" with angular data source ("akumuli-datasource")`,
+				},
+				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeTemplateVariable, title: "legacy"},
+			},
+		},
+		{
+			name: "rows collapsed",
+			file: "rows-collapsed.json",
+			expDetections: []expDetection{
+				{
+					pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "collapsed",
+					row: "Row title", gridPos: &output.GridPos{X: 0, Y: 1, W: 24, H: 9},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", tc.file))
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+			out, err := d.Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, out, 1, "should have result for one dashboard")
+			detections := out[0].Detections
+			require.Len(t, detections, len(tc.expDetections), "should have the correct number of detections in the dashboard")
+			for i, actual := range detections {
+				exp := tc.expDetections[i]
+				require.Equal(t, exp.pluginID, actual.PluginID)
+				require.Equal(t, exp.detectionType, actual.DetectionType)
+				require.Equal(t, exp.title, actual.Title)
+				require.Equal(t, exp.repeat, actual.Repeat)
+				require.Equal(t, exp.rowRepeat, actual.RowRepeat)
+				if exp.row != "" || exp.gridPos != nil {
+					require.Equal(t, exp.row, actual.Row)
+					require.Equal(t, exp.gridPos, actual.GridPos)
+				}
+				if exp.message != "" {
+					require.Equal(t, exp.message, actual.String())
+				}
+			}
+		})
+	}
+}
+
+func TestProbeCompatibility(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		frontendSettingsFile string
+		permissionsErr       error
+		expGrafanaVersion    string
+		expAngularSource     angularSource
+		expHasAccessControl  bool
+	}{
+		{
+			name:                 "grafana >= 10.3.0",
+			frontendSettingsFile: "frontend-settings.json",
+			expAngularSource:     angularSourceAngular,
+			expHasAccessControl:  true,
+		},
+		{
+			name:                 "grafana >= 10.1.0 && < 10.3.0",
+			frontendSettingsFile: "frontend-settings-10.1.json",
+			expGrafanaVersion:    "10.1.5",
+			expAngularSource:     angularSourceAngularDetected,
+			expHasAccessControl:  true,
+		},
+		{
+			name:                 "grafana 9.x",
+			frontendSettingsFile: "frontend-settings-9.json",
+			expGrafanaVersion:    "9.5.2",
+			expAngularSource:     angularSourceGCOM,
+			expHasAccessControl:  true,
+		},
+		{
+			name:                 "grafana 8.x without access control",
+			frontendSettingsFile: "frontend-settings-9.json",
+			permissionsErr:       fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404),
+			expGrafanaVersion:    "9.5.2",
+			expAngularSource:     angularSourceGCOM,
+			expHasAccessControl:  false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := NewTestAPIClient("")
+			cl.FrontendSettingsFilePath = filepath.Join("testdata", tc.frontendSettingsFile)
+			cl.ServiceAccountPermissionsErr = tc.permissionsErr
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+			frontendSettings, err := cl.GetFrontendSettings(context.Background())
+			require.NoError(t, err)
+			compat := d.probeCompatibility(context.Background(), frontendSettings)
+			if tc.expGrafanaVersion != "" {
+				require.Equal(t, tc.expGrafanaVersion, compat.grafanaVersion)
+			}
+			require.Equal(t, tc.expAngularSource, compat.angularSource)
+			require.Equal(t, tc.expHasAccessControl, compat.hasAccessControl)
+			if tc.permissionsErr != nil {
+				require.ErrorIs(t, compat.accessControlErr, api.ErrBadStatusCode)
+			}
+		})
+	}
+}
+
+func TestCompareAngularSources(t *testing.T) {
+	cl := NewTestAPIClient("")
+	cl.PluginsFilePath = filepath.Join("testdata", "plugins-core.json")
+	cl.ServiceAccountPermissionsErr = fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+	d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+	discrepancies, err := d.CompareAngularSources(context.Background())
+	require.NoError(t, err)
+	// Plugins are not in GCOM, so only frontend settings report Angular plugins
+	require.Contains(t, discrepancies, AngularSourceDiscrepancy{PluginID: "akumuli-datasource", FrontendSettings: true, GCOM: false})
+	require.Contains(t, discrepancies, AngularSourceDiscrepancy{PluginID: "grafana-worldmap-panel", FrontendSettings: true, GCOM: false})
+	for _, ds := range discrepancies {
+		require.True(t, ds.FrontendSettings, "%q should be angular in frontend settings", ds.PluginID)
+	}
+
+	t.Run("grafana < 10.1.0", func(t *testing.T) {
+		cl := NewTestAPIClient("")
+		cl.FrontendSettingsFilePath = filepath.Join("testdata", "frontend-settings-9.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		_, err := d.CompareAngularSources(context.Background())
+		require.Error(t, err)
+	})
+}
+
+func TestDatasourceRemapPlan(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		target string
+		exp    []output.DatasourceRemap
+	}{
+		{
+			name:   "url",
+			target: "yesoreyeram-infinity-datasource",
+			exp: []output.DatasourceRemap{
+				{
+					UID: "akumuli-1", Name: "Akumuli", PluginID: "akumuli-datasource", TargetPluginID: "yesoreyeram-infinity-datasource",
+					TargetUID: "infinity-1", TargetName: "Infinity", Match: "url",
+				},
+				{
+					UID: "akumuli-2", Name: "Akumuli staging", PluginID: "akumuli-datasource", TargetPluginID: "yesoreyeram-infinity-datasource",
+					Candidates: []string{"infinity-1", "infinity-2"},
+				},
+			},
+		},
+		{
+			name:   "only candidate",
+			target: "grafana-testdata-datasource",
+			exp: []output.DatasourceRemap{
+				{
+					UID: "akumuli-1", Name: "Akumuli", PluginID: "akumuli-datasource", TargetPluginID: "grafana-testdata-datasource",
+					TargetUID: "testdata", TargetName: "TestData", Match: "only-candidate",
+				},
+				{
+					UID: "akumuli-2", Name: "Akumuli staging", PluginID: "akumuli-datasource", TargetPluginID: "grafana-testdata-datasource",
+					TargetUID: "testdata", TargetName: "TestData", Match: "only-candidate",
+				},
+			},
+		},
+		{name: "replacement not installed", target: "acme-datasource", exp: nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := NewTestAPIClient("")
+			cl.DatasourcesFilePath = filepath.Join("testdata", "datasources-remap.json")
+			d := NewDetector(
+				logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5,
+				WithMigrationTargets(map[string]string{"akumuli-datasource": tc.target}),
+			)
+			plan, err := d.DatasourceRemapPlan(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, plan)
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	t.Run("read", func(t *testing.T) {
+		rules, err := ReadRules(filepath.Join("testdata", "rules.yaml"))
+		require.NoError(t, err)
+		require.Equal(t, &Rules{
+			Angular:    []string{"acme-private-panel"},
+			Ignore:     []string{"graph"},
+			Reclassify: map[string]string{"acme-worldmap-fork-panel": "grafana-worldmap-panel"},
+			Private:    []string{"acme-*"},
+		}, rules)
+	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "rules.yaml")
+		require.NoError(t, os.WriteFile(fn, []byte("angualr:\n  - acme-private-panel\n"), 0o600))
+		_, err := ReadRules(fn)
+		require.Error(t, err)
+	})
+
+	for _, tc := range []struct {
+		name            string
+		rules           *Rules
+		expTypes        map[string]output.DetectionType
+		expReplacements map[string]string
+	}{
+		{
+			name: "no rules",
+			expTypes: map[string]output.DetectionType{
+				"acme-private-panel":     output.DetectionTypeUnknown,
+				"grafana-worldmap-panel": output.DetectionTypePanel,
+			},
+			expReplacements: map[string]string{"acme-private-panel": "", "grafana-worldmap-panel": "geomap"},
+		},
+		{
+			name:  "angular",
+			rules: &Rules{Angular: []string{"acme-private-panel"}},
+			expTypes: map[string]output.DetectionType{
+				"acme-private-panel":     output.DetectionTypePanel,
+				"grafana-worldmap-panel": output.DetectionTypePanel,
+			},
+			expReplacements: map[string]string{"acme-private-panel": "", "grafana-worldmap-panel": "geomap"},
+		},
+		{
+			name:            "ignore",
+			rules:           &Rules{Ignore: []string{"grafana-worldmap-panel"}},
+			expTypes:        map[string]output.DetectionType{"acme-private-panel": output.DetectionTypeUnknown},
+			expReplacements: map[string]string{"acme-private-panel": ""},
+		},
+		{
+			name:  "reclassify",
+			rules: &Rules{Reclassify: map[string]string{"acme-private-panel": "grafana-worldmap-panel"}},
+			expTypes: map[string]output.DetectionType{
+				"acme-private-panel":     output.DetectionTypePanel,
+				"grafana-worldmap-panel": output.DetectionTypePanel,
+			},
+			expReplacements: map[string]string{"acme-private-panel": "geomap", "grafana-worldmap-panel": "geomap"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "private-plugin.json"))
+			cl.FrontendSettingsFilePath = filepath.Join("testdata", "frontend-settings-8.json")
+			cl.PluginsFilePath = filepath.Join("testdata", "plugins-private.json")
+			cl.ServiceAccountPermissionsErr = fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+			cl.GrafanaVersion = "8.4.7"
+			gcomClient := newTestGCOMClient(t, map[string][]gcom.PluginVersion{
+				"grafana-worldmap-panel": {{Version: "1.0.0", AngularDetected: true}},
+			}, nil)
+			d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5, WithRules(tc.rules))
+			out, err := d.Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, out, 1)
+			detectionTypes := map[string]output.DetectionType{}
+			replacements := map[string]string{}
+			for _, detection := range out[0].Detections {
+				detectionTypes[detection.PluginID] = detection.DetectionType
+				replacements[detection.PluginID] = detection.SuggestedReplacement
+			}
+			require.Equal(t, tc.expTypes, detectionTypes)
+			require.Equal(t, tc.expReplacements, replacements)
+		})
+	}
+
+	t.Run("reconfigure", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "private-plugin.json"))
+		cl.FrontendSettingsFilePath = filepath.Join("testdata", "frontend-settings-8.json")
+		cl.PluginsFilePath = filepath.Join("testdata", "plugins-private.json")
+		cl.ServiceAccountPermissionsErr = fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+		cl.GrafanaVersion = "8.4.7"
+		gcomClient := newTestGCOMClient(t, map[string][]gcom.PluginVersion{
+			"grafana-worldmap-panel": {{Version: "1.0.0", AngularDetected: true}},
+		}, nil)
+		d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out[0].Detections, 2)
+
+		d.Reconfigure(WithRules(&Rules{Ignore: []string{"grafana-worldmap-panel"}}))
+		out, err = d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out[0].Detections, 1)
+		require.Equal(t, "acme-private-panel", out[0].Detections[0].PluginID)
+	})
+
+	t.Run("private", func(t *testing.T) {
+		// A public plugin with the same id as the private one, which is not Angular
+		gcomClient := newTestGCOMClient(t, map[string][]gcom.PluginVersion{
+			"acme-private-panel":     {{Version: "1.0.0", AngularDetected: false}},
+			"grafana-worldmap-panel": {{Version: "1.0.0", AngularDetected: true}},
+		}, nil)
+		for _, tc := range []struct {
+			name    string
+			rules   *Rules
+			modules map[string]string
+			expType output.DetectionType
+		}{
+			{name: "not private", expType: ""},
+			{name: "no module", rules: &Rules{Private: []string{"acme-*"}}, expType: output.DetectionTypeUnknown},
+			{
+				name:    "angular module",
+				rules:   &Rules{Private: []string{"acme-*"}},
+				modules: map[string]string{"acme-private-panel": filepath.Join("testdata", "modules", "angular.js")},
+				expType: output.DetectionTypePanel,
+			},
+			{
+				name:    "react module",
+				rules:   &Rules{Private: []string{"acme-*"}},
+				modules: map[string]string{"acme-private-panel": filepath.Join("testdata", "modules", "react.js")},
+				expType: "",
+			},
+			{
+				name:    "listed",
+				rules:   &Rules{Private: []string{"acme-*"}, Angular: []string{"acme-private-panel"}},
+				modules: map[string]string{"acme-private-panel": filepath.Join("testdata", "modules", "react.js")},
+				expType: output.DetectionTypePanel,
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "private-plugin.json"))
+				cl.FrontendSettingsFilePath = filepath.Join("testdata", "frontend-settings-8.json")
+				cl.PluginsFilePath = filepath.Join("testdata", "plugins-private.json")
+				cl.ServiceAccountPermissionsErr = fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+				cl.GrafanaVersion = "8.4.7"
+				cl.PluginModuleFilePaths = tc.modules
+				d := NewDetector(logger.NewLeveledLogger(false), cl, gcomClient, 5, WithRules(tc.rules))
+				out, err := d.Run(context.Background())
+				require.NoError(t, err)
+				var detectionType output.DetectionType
+				if len(out) > 0 {
+					for _, detection := range out[0].Detections {
+						if detection.PluginID == "acme-private-panel" {
+							detectionType = detection.DetectionType
+						}
+					}
+				}
+				require.Equal(t, tc.expType, detectionType)
+			})
+		}
+	})
+}
+
+func TestLinks(t *testing.T) {
+	cl := offline.NewAPIClient(filepath.Join("testdata", "links"))
+	for _, tc := range []struct {
+		name       string
+		checkLinks bool
+		exp        map[string][]string
+	}{
+		{name: "disabled", checkLinks: false, exp: map[string][]string{}},
+		{name: "enabled", checkLinks: true, exp: map[string][]string{"linking.json": {"angular.json"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5, WithLinks(tc.checkLinks))
+			out, err := d.Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, out, 3)
+			linked := map[string][]string{}
+			for _, dashboard := range out {
+				if dashboard.LinkedAngularDashboards != nil {
+					linked[dashboard.URL] = dashboard.LinkedAngularDashboards
+				}
+			}
+			require.Equal(t, tc.exp, linked)
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	cl := offline.NewAPIClient(filepath.Join("testdata", "links"))
+	fn := filepath.Join(t.TempDir(), "checkpoint.json")
+	cp, err := ReadCheckpoint(fn, time.Hour)
+	require.NoError(t, err)
+	_, _, ok := cp.LastResults()
+	require.False(t, ok)
+
+	d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, WithCheckpoint(cp))
+	out, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, out, 3)
+
+	read, err := ReadCheckpoint(fn, time.Hour)
+	require.NoError(t, err)
+	results, _, ok := read.LastResults()
+	require.True(t, ok)
+	require.ElementsMatch(t, out, results)
+	require.Empty(t, read.Started)
+
+	for _, tc := range []struct {
+		name    string
+		started time.Duration
+		exp     []string
+	}{
+		// The first dashboard has been reported and the second one dropped before the interruption
+		{name: "resume", started: time.Minute, exp: []string{"from checkpoint", out[2].Title}},
+		{name: "expired", started: 2 * time.Hour, exp: []string{out[0].Title, out[1].Title, out[2].Title}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cp := &Checkpoint{
+				fn:      filepath.Join(t.TempDir(), "checkpoint.json"),
+				maxAge:  time.Hour,
+				Started: time.Now().Add(-tc.started).UTC().Format(time.RFC3339),
+				Scanned: map[string]*output.Dashboard{
+					out[0].UID: {UID: out[0].UID, Title: "from checkpoint"},
+					out[1].UID: nil,
+				},
+			}
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, WithCheckpoint(cp))
+			resumed, err := d.Run(context.Background())
+			require.NoError(t, err)
+			var titles []string
+			for _, dashboard := range resumed {
+				titles = append(titles, dashboard.Title)
+			}
+			require.ElementsMatch(t, tc.exp, titles)
+			require.Empty(t, cp.Started)
+			require.FileExists(t, cp.fn)
+		})
+	}
+
+	t.Run("resume links", func(t *testing.T) {
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, WithLinks(true))
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		scanned := map[string]*output.Dashboard{}
+		for i := range out {
+			if out[i].UID != "clean.json" {
+				// The links are resolved once all the dashboards are scanned
+				out[i].LinkedAngularDashboards = nil
+				scanned[out[i].UID] = &out[i]
+			}
+		}
+		cp := &Checkpoint{
+			fn:      filepath.Join(t.TempDir(), "checkpoint.json"),
+			maxAge:  time.Hour,
+			Started: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+			Scanned: scanned,
+			Links: map[string]*DashboardLinks{
+				"angular.json": {ModelUID: "angular-dash"},
+				"linking.json": {ModelUID: "linking-dash", Links: []DashboardLink{{UID: "angular-dash"}}},
+			},
+		}
+		d = NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, WithLinks(true), WithCheckpoint(cp))
+		resumed, err := d.Run(context.Background())
+		require.NoError(t, err)
+		linked := map[string][]string{}
+		for _, dashboard := range resumed {
+			if dashboard.LinkedAngularDashboards != nil {
+				linked[dashboard.URL] = dashboard.LinkedAngularDashboards
+			}
+		}
+		require.Equal(t, map[string][]string{"linking.json": {"angular.json"}}, linked)
+	})
+}
+
+func TestProgress(t *testing.T) {
+	t.Run("run", func(t *testing.T) {
+		cl := offline.NewAPIClient(filepath.Join("testdata", "links"))
+		progress := NewProgress()
+		require.Equal(t, ProgressStatus{Elapsed: "0s"}, progress.Status(time.Now()))
+
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, WithProgress(progress))
+		started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		d.now = func() time.Time { return started }
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 3)
+		require.Equal(t, ProgressStatus{
+			Started:    "2024-01-01T00:00:00Z",
+			Dashboards: 3,
+			Fetched:    3,
+			Scanned:    3,
+			Elapsed:    "1m0s",
+
+			AngularSource: "frontendsettings (angular)",
+			LastScan: &LastScanStatus{
+				Started:    "2024-01-01T00:00:00Z",
+				Finished:   "2024-01-01T00:00:00Z",
+				Duration:   "0s",
+				Dashboards: 3,
+				Scanned:    3,
+			},
+		}, progress.Status(started.Add(time.Minute)))
+	})
+
+	t.Run("eta", func(t *testing.T) {
+		progress := NewProgress()
+		started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		progress.start(started)
+		progress.setTotal(10)
+		require.Empty(t, progress.Status(started.Add(time.Second)).ETA)
+		progress.add(4, 3, 1)
+		require.Equal(t, ProgressStatus{
+			Running:    true,
+			Started:    "2024-01-01T00:00:00Z",
+			Dashboards: 10,
+			Fetched:    4,
+			Scanned:    3,
+			Errors:     1,
+			Elapsed:    "2m0s",
+			ETA:        "3m0s",
+		}, progress.Status(started.Add(2*time.Minute)))
+	})
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	// writeDashboards copies the dashboards of testdata/links to dir, with the given version,
+	// and the given type for the Angular panel
+	writeDashboards := func(version int, panelType string) {
+		for _, name := range []string{"angular.json", "clean.json", "linking.json"} {
+			b, err := os.ReadFile(filepath.Join("testdata", "links", name))
+			require.NoError(t, err)
+			var dashboard map[string]interface{}
+			require.NoError(t, json.Unmarshal(b, &dashboard))
+			// The dashboards are either models with metadata, or bare models
+			model := dashboard
+			if m, ok := dashboard["dashboard"].(map[string]interface{}); ok {
+				model = m
+			}
+			model["version"] = version
+			if name == "angular.json" {
+				model["panels"].([]interface{})[0].(map[string]interface{})["type"] = panelType
+			}
+			b, err = json.Marshal(dashboard)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), b, 0o600))
+		}
+	}
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	runAll := func(opts ...Option) []output.Dashboard {
+		cl := offline.NewAPIClient(dir)
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, append(opts, WithCacheDir(cacheDir))...)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 3)
+		return out
+	}
+	run := func(opts ...Option) []output.Detection {
+		for _, dashboard := range runAll(opts...) {
+			if dashboard.Title == "angular" {
+				return dashboard.Detections
+			}
+		}
+		require.FailNow(t, "angular dashboard not found")
+		return nil
+	}
+
+	writeDashboards(1, "grafana-worldmap-panel")
+	require.Len(t, run(), 1)
+	files, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Same version: the cached result is reused, even if the dashboard changed
+	writeDashboards(1, "geomap")
+	require.Len(t, run(), 1)
+
+	// New version: the dashboard is checked again
+	writeDashboards(2, "geomap")
+	require.Empty(t, run())
+
+	// Other rules: the cached results are discarded, even if the version didn't change
+	writeDashboards(3, "grafana-worldmap-panel")
+	require.Len(t, run(), 1)
+	require.Empty(t, run(WithRules(&Rules{Ignore: []string{"grafana-worldmap-panel"}})))
+	require.Len(t, run(), 1)
+
+	// The links to and from the cached dashboards are resolved
+	writeDashboards(4, "grafana-worldmap-panel")
+	runAll(WithLinks(true))
+	writeDashboards(4, "geomap")
+	linked := map[string][]string{}
+	for _, dashboard := range runAll(WithLinks(true)) {
+		if dashboard.Title == "angular" {
+			require.Len(t, dashboard.Detections, 1, "the angular dashboard should be served from the cache")
+		}
+		if dashboard.LinkedAngularDashboards != nil {
+			linked[dashboard.URL] = dashboard.LinkedAngularDashboards
+		}
+	}
+	require.Equal(t, map[string][]string{"linking.json": {"angular.json"}}, linked)
+
+	// The post-processors run again on the cached results, so what they add is not outdated
+	writeDashboards(5, "grafana-worldmap-panel")
+	owners := func(team string) Option {
+		return WithPostProcessors(NewPostProcessor("owners", func(_ context.Context, dashboard *output.Dashboard, _ *grafana.DashboardDefinition) (bool, error) {
+			if len(dashboard.Detections) > 0 {
+				dashboard.Owners = append(dashboard.Owners, team)
+			}
+			return true, nil
+		}))
+	}
+	runAll(owners("team-a"))
+	writeDashboards(5, "geomap")
+	for _, dashboard := range runAll(owners("team-b")) {
+		if dashboard.Title == "angular" {
+			require.Len(t, dashboard.Detections, 1, "the angular dashboard should be served from the cache")
+			require.Equal(t, []string{"team-b"}, dashboard.Owners)
+			require.NotEmpty(t, dashboard.Detections[0].Severity)
+		}
+	}
+
+	// Without version, dashboards are never cached
+	writeDashboards(0, "grafana-worldmap-panel")
+	require.Len(t, run(), 1)
+	writeDashboards(0, "geomap")
+	require.Empty(t, run())
+}
+
+func TestMaxDuration(t *testing.T) {
+	cl := offline.NewAPIClient(filepath.Join("testdata", "links"))
+	state := NewScanState()
+	scanned := map[string]struct{}{}
+	for i := 0; i < 3; i++ {
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 1, WithMaxDuration(time.Minute, state))
+		// Each call to now advances the clock by one hour, so the budget is exhausted after the first dashboard
+		var mu sync.Mutex
+		now := time.Date(2024, 1, 1, i, 0, 0, 0, time.UTC)
+		d.now = func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			now = now.Add(time.Hour)
+			return now
+		}
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		scanned[out[0].URL] = struct{}{}
+	}
+	require.Len(t, scanned, 3, "each run should scan a dashboard not scanned before")
+	require.Len(t, state.LastScanned, 3)
+
+	t.Run("read and write", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "state.json")
+		empty, err := ReadScanState(fn)
+		require.NoError(t, err)
+		require.Empty(t, empty.LastScanned)
+
+		require.NoError(t, state.WriteFile(fn))
+		read, err := ReadScanState(fn)
+		require.NoError(t, err)
+		require.Equal(t, state.LastScanned, read.LastScanned)
+	})
+
+	t.Run("prioritize", func(t *testing.T) {
+		s := &ScanState{LastScanned: map[string]string{
+			"a": "2024-01-02T00:00:00Z",
+			"b": "2024-01-01T00:00:00Z",
+		}}
+		dashboards := []grafana.ListedDashboard{{UID: "a"}, {UID: "b"}, {UID: "c"}, {UID: "d"}}
+		s.prioritize(dashboards)
+		var uids []string
+		for _, dash := range dashboards {
+			uids = append(uids, dash.UID)
+		}
+		require.Equal(t, []string{"c", "d", "b", "a"}, uids)
+	})
+}
+
+func TestPriority(t *testing.T) {
+	t.Run("scan order", func(t *testing.T) {
+		views := map[string]grafana.DashboardViews{"a": {UID: "a", Views: 3}, "c": {UID: "c", Views: 42}}
+		dashboards := []grafana.ListedDashboard{{UID: "a"}, {UID: "b"}, {UID: "c"}, {UID: "d"}}
+		sortByViews(dashboards, views)
+		var uids []string
+		for _, dash := range dashboards {
+			uids = append(uids, dash.UID)
+		}
+		require.Equal(t, []string{"c", "a", "b", "d"}, uids)
+	})
+
+	t.Run("score", func(t *testing.T) {
+		require.Zero(t, priority(42, nil))
+		require.Equal(t, 42, priority(42, []output.Detection{{Severity: output.SeverityLow}}))
+		require.Equal(t, 126, priority(42, []output.Detection{
+			{Severity: output.SeverityAutoMigratable},
+			{Severity: output.SeverityNoReplacement},
+			{Severity: output.SeverityReplacementAvailable},
+		}))
+	})
+}
+
+func TestFolderTree(t *testing.T) {
+	detections := []output.Detection{{PluginID: "graph"}, {PluginID: "singlestat"}}
+	dashboards := []output.Dashboard{
+		{URL: "d/a", Detections: detections},
+		{URL: "d/b", FolderUID: "team", Folder: "Team", Detections: []output.Detection{}},
+		{URL: "d/c", FolderUID: "nested", Folder: "Nested", Detections: detections[:1]},
+		{URL: "d/d", Folder: "offline", Detections: detections[:1]},
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		tree := folderTree([]grafana.Folder{
+			{UID: "team", Title: "Team"},
+			{UID: "nested", Title: "Nested", ParentUID: "team"},
+			{UID: "empty", Title: "Empty"},
+		}, dashboards)
+		require.Equal(t, &output.Folder{
+			Title: "Dashboards", Dashboards: 4, AngularDashboards: 3, Detections: 4,
+			Subfolders: []*output.Folder{
+				{UID: "empty", Title: "Empty", Subfolders: []*output.Folder{}},
+				{UID: "team", Title: "Team", Dashboards: 2, AngularDashboards: 1, Detections: 1, Subfolders: []*output.Folder{
+					{UID: "nested", Title: "Nested", Dashboards: 1, AngularDashboards: 1, Detections: 1, Subfolders: []*output.Folder{}},
+				}},
+				{Title: "offline", Dashboards: 1, AngularDashboards: 1, Detections: 1, Subfolders: []*output.Folder{}},
+			},
+		}, tree)
+	})
+
+	t.Run("folders not available", func(t *testing.T) {
+		d := NewDetector(logger.NewLeveledLogger(false), offline.NewAPIClient("testdata"), newTestGCOMClient(t, nil, nil), 5)
+		tree := d.FolderTree(context.Background(), dashboards)
+		require.Equal(t, 4, tree.Dashboards)
+		var titles []string
+		for _, folder := range tree.Subfolders {
+			titles = append(titles, folder.Title)
+			require.Empty(t, folder.Subfolders)
+		}
+		require.Equal(t, []string{"Nested", "Team", "offline"}, titles)
+	})
+}
+
+func TestLibraryPanels(t *testing.T) {
+	t.Run("resolve library panel model", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "library-panel.json"))
+		cl.LibraryElementFilePath = filepath.Join("testdata", "library-element.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Len(t, out[0].Detections, 1)
+		require.Equal(t, "grafana-worldmap-panel", out[0].Detections[0].PluginID)
+		require.Equal(t, output.DetectionTypePanel, out[0].Detections[0].DetectionType)
+		require.Equal(t, "lib-worldmap", out[0].Detections[0].LibraryPanelUID)
+		require.Equal(t, "Shared worldmap", out[0].Detections[0].LibraryPanel)
+	})
+
+	t.Run("library panel not available", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "library-panel.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		out, err := d.Run(context.Background())
+		require.NoError(t, err)
+		require.Len(t, out, 1)
+		require.Empty(t, out[0].Detections)
+	})
+
+	t.Run("connections", func(t *testing.T) {
+		cl := NewTestAPIClient("")
+		cl.LibraryElementConnectionsFilePath = filepath.Join("testdata", "library-element-connections.json")
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		detection := output.Detection{
+			DetectionType:   output.DetectionTypePanel,
+			PluginID:        "grafana-worldmap-panel",
+			Title:           "Shared worldmap",
+			LibraryPanelUID: "lib-worldmap",
+			LibraryPanel:    "Shared worldmap",
+			Repeat:          "server",
+		}
+		dashboards := []output.Dashboard{
+			{UID: "with-model", Detections: []output.Detection{detection}},
+			{UID: "stale-model", Detections: []output.Detection{}},
+			{UID: "not-connected", Detections: []output.Detection{}},
+		}
+		d.addLibraryPanelConnections(context.Background(), dashboards)
+		require.Equal(t, []output.Detection{detection}, dashboards[0].Detections)
+		detection.Repeat = ""
+		require.Equal(t, []output.Detection{detection}, dashboards[1].Detections)
+		require.Empty(t, dashboards[2].Detections)
+	})
+}
+
+func TestCheckIdentity(t *testing.T) {
+	t.Run("identity", func(t *testing.T) {
+		cl := NewTestAPIClient("")
+		cl.CurrentUserFilePath = filepath.Join("testdata", "user.json")
+		cl.ServiceAccountPermissions = map[string][]string{
+			"dashboards:read":  {"dashboards:*"},
+			"folders:read":     {"folders:*"},
+			"datasources:read": {"datasources:*"},
+		}
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		user, err := d.CheckIdentity(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "sa-1-detect-angular", user.Login)
+		require.Equal(t, 1, user.OrgID)
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		d := NewDetector(logger.NewLeveledLogger(false), offline.NewAPIClient("testdata"), newTestGCOMClient(t, nil, nil), 5)
+		_, err := d.CheckIdentity(context.Background())
+		require.Error(t, err)
+	})
+
+	viewer := map[string][]string{
+		"dashboards:read":  {"dashboards:*"},
+		"folders:read":     {"folders:*"},
+		"datasources:read": {"datasources:*"},
+	}
+	admin := map[string][]string{
+		"dashboards:read":    {"dashboards:*"},
+		"dashboards:write":   {"dashboards:*"},
+		"folders:read":       {"folders:*"},
+		"datasources:read":   {"datasources:*"},
+		"datasources:create": {},
+		"users:write":        {"global.users:*"},
 	}
 	for _, tc := range []struct {
-		name          string
-		file          string
-		expDetections []expDetection
+		name   string
+		user   grafana.User
+		compat compatibility
+		exp    []string
 	}{
 		{
-			name: "legacy panel",
-			file: "graph-old.json",
-			expDetections: []expDetection{{
-				pluginID:      "graph",
-				detectionType: output.DetectionTypeLegacyPanel,
-				title:         "Flot graph",
-				message:       `Found legacy plugin "graph" in panel "Flot graph". It can be migrated to a React-based panel by Grafana when opening the dashboard.`,
-			}},
-		},
-		{
-			name: "angular panel",
-			file: "worldmap.json",
-			expDetections: []expDetection{{
-				pluginID:      "grafana-worldmap-panel",
-				detectionType: output.DetectionTypePanel,
-				title:         "Panel Title",
-				message:       `Found angular panel "Panel Title" ("grafana-worldmap-panel")`,
-			}},
+			name:   "viewer",
+			user:   grafana.User{Login: "viewer"},
+			compat: compatibility{angularSource: angularSourceAngular, hasAccessControl: true, permissions: viewer},
 		},
 		{
-			name: "datasource",
-			file: "datasource.json",
-			expDetections: []expDetection{{
-				pluginID:      "akumuli-datasource",
-				detectionType: output.DetectionTypeDatasource,
-				title:         "akumuli",
-				message:       `Found panel with angular data source "akumuli" ("akumuli-datasource")`,
-			}},
+			name:   "no access control",
+			user:   grafana.User{Login: "viewer"},
+			compat: compatibility{angularSource: angularSourceAngular},
 		},
 		{
-			name: "multiple",
-			file: "multiple.json",
-			expDetections: []expDetection{
-				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeDatasource, title: "akumuli"},
-				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "worldmap + akumuli"},
-				{pluginID: "akumuli-datasource", detectionType: output.DetectionTypeDatasource, title: "worldmap + akumuli"},
-				{pluginID: "graph", detectionType: output.DetectionTypeLegacyPanel, title: "graph-old"},
+			name:   "server admin",
+			user:   grafana.User{Login: "admin", IsGrafanaAdmin: true},
+			compat: compatibility{angularSource: angularSourceAngular},
+			exp: []string{
+				`"admin" is a Grafana server admin, which has far more privileges than needed for a read-only scan. ` +
+					`Consider using a service account with the Viewer role instead`,
 			},
 		},
 		{
-			name:          "not angular",
-			file:          "not-angular.json",
-			expDetections: nil,
-		},
-		{
-			name: "mix of angular and react",
-			file: "mixed.json",
-			expDetections: []expDetection{
-				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "angular"},
+			name:   "write permissions",
+			user:   grafana.User{Login: "editor"},
+			compat: compatibility{angularSource: angularSourceAngular, hasAccessControl: true, permissions: admin},
+			exp: []string{
+				`The token has write permissions dashboards:write, datasources:create, users:write, which are not needed for a read-only scan. ` +
+					`Consider using a service account with the Viewer role instead`,
 			},
 		},
 		{
-			name: "rows expanded",
-			file: "rows-expanded.json",
-			expDetections: []expDetection{
-				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "expanded"},
+			name:   "write permissions with gcom",
+			user:   grafana.User{Login: "editor"},
+			compat: compatibility{angularSource: angularSourceGCOM, hasAccessControl: true, permissions: admin},
+			exp: []string{
+				`The token has write permissions dashboards:write, users:write, which are not needed for a read-only scan. ` +
+					`Consider using a service account with the Viewer role instead`,
 			},
 		},
 		{
-			name: "rows collapsed",
-			file: "rows-collapsed.json",
-			expDetections: []expDetection{
-				{pluginID: "grafana-worldmap-panel", detectionType: output.DetectionTypePanel, title: "collapsed"},
-			},
+			name: "missing permissions",
+			user: grafana.User{Login: "nobody"},
+			compat: compatibility{angularSource: angularSourceAngular, hasAccessControl: true, permissions: map[string][]string{
+				"folders:read": {"folders:*"},
+			}},
+			exp: []string{"Missing permissions dashboards:read, datasources:read, some dashboards or data sources may not be checked"},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", tc.file))
-			d := NewDetector(logger.NewLeveledLogger(false), cl, gcom.NewAPIClient(), 5)
-			out, err := d.Run(context.Background())
+			require.Equal(t, tc.exp, privilegeWarnings(&tc.user, tc.compat))
+		})
+	}
+}
+
+func TestIsOrphaned(t *testing.T) {
+	logins := map[string]struct{}{"editor": {}}
+	for _, tc := range []struct {
+		name      string
+		createdBy string
+		updatedBy string
+		exp       bool
+	}{
+		{name: "existing users", createdBy: "editor", updatedBy: "editor", exp: false},
+		{name: "updated by existing user", createdBy: "bob", updatedBy: "editor", exp: false},
+		{name: "deleted users", createdBy: "bob", updatedBy: "alice", exp: true},
+		{name: "anonymous", createdBy: "Anonymous", updatedBy: "Anonymous", exp: true},
+		{name: "service account", createdBy: "Anonymous", updatedBy: "sa-1-ci", exp: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, isOrphaned(grafana.Meta{CreatedBy: tc.createdBy, UpdatedBy: tc.updatedBy}, logins))
+		})
+	}
+}
+
+func TestCensus(t *testing.T) {
+	cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "mixed-datasource.json"))
+	d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+	usages, err := d.Census(context.Background())
+	require.NoError(t, err)
+	dashboards := []string{"d/test-case-dashboard/test-case-dashboard"}
+	require.Equal(t, []output.PluginUsage{
+		{PluginID: "timeseries", Type: output.PluginTypePanel, Angular: false, Count: 2, Dashboards: dashboards},
+		{PluginID: "akumuli-datasource", Type: output.PluginTypeDatasource, Angular: true, Count: 2, Dashboards: dashboards},
+		{PluginID: "grafana-testdata-datasource", Type: output.PluginTypeDatasource, Angular: false, Count: 1, Dashboards: dashboards},
+	}, usages)
+}
+
+func TestFix(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry run %t", dryRun), func(t *testing.T) {
+			cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "lossy.json"))
+			d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+			data, err := d.Run(context.Background())
 			require.NoError(t, err)
-			require.Len(t, out, 1, "should have result for one dashboard")
-			detections := out[0].Detections
-			require.Len(t, detections, len(tc.expDetections), "should have the correct number of detections in the dashboard")
-			for i, actual := range detections {
-				exp := tc.expDetections[i]
-				require.Equal(t, exp.pluginID, actual.PluginID)
-				require.Equal(t, exp.detectionType, actual.DetectionType)
-				require.Equal(t, exp.title, actual.Title)
-				if exp.message != "" {
-					require.Equal(t, exp.message, actual.String())
-				}
+			backupDir := t.TempDir()
+
+			fixes := d.Fix(context.Background(), data, backupDir, "", dryRun)
+			require.Len(t, fixes, 1)
+			fix := fixes[0]
+			require.Empty(t, fix.Error)
+			require.Equal(t, []output.PanelFix{
+				{Title: "graph", PluginID: "graph", TargetPluginID: "timeseries"},
+				{Title: "singlestat review", PluginID: "singlestat", TargetPluginID: "stat"},
+				{Title: "singlestat gauge", PluginID: "singlestat", TargetPluginID: "stat"},
+				{Title: "singlestat", PluginID: "singlestat", TargetPluginID: "stat"},
+			}, fix.Panels)
+			require.Equal(t, []output.PanelFix{
+				{Title: "lossy graph", PluginID: "graph", TargetPluginID: "timeseries", LossyOptions: []string{"seriesOverrides.zindex", "thresholds", "yaxis.align"}},
+				{Title: "lossy worldmap", PluginID: "grafana-worldmap-panel", TargetPluginID: "geomap", LossyOptions: []string{"locationData", "jsonUrl"}},
+			}, fix.Skipped)
+
+			if dryRun {
+				require.False(t, fix.Saved)
+				require.Empty(t, fix.Backup)
+				require.Empty(t, cl.SavedDashboards)
+				return
 			}
+			require.True(t, fix.Saved)
+			require.FileExists(t, fix.Backup)
+			require.Len(t, cl.SavedDashboards, 1)
+			var types, autoMigrateFrom []interface{}
+			for _, panel := range cl.SavedDashboards[0]["panels"].([]interface{}) {
+				types = append(types, panel.(map[string]interface{})["type"])
+				autoMigrateFrom = append(autoMigrateFrom, panel.(map[string]interface{})["autoMigrateFrom"])
+			}
+			require.Equal(t, []interface{}{"graph", "timeseries", "grafana-worldmap-panel", "stat", "stat", "stat"}, types)
+			require.Equal(t, []interface{}{nil, "graph", nil, "singlestat", "singlestat", "singlestat"}, autoMigrateFrom)
+		})
+	}
+
+	t.Run("output dir", func(t *testing.T) {
+		cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "lossy.json"))
+		d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+		data, err := d.Run(context.Background())
+		require.NoError(t, err)
+		outputDir := t.TempDir()
+
+		fixes := d.Fix(context.Background(), data, t.TempDir(), outputDir, false)
+		require.Len(t, fixes, 1)
+		require.False(t, fixes[0].Saved)
+		require.Empty(t, cl.SavedDashboards)
+		require.Equal(t, filepath.Join(outputDir, "test-case-dashboard.json"), fixes[0].Output)
+		var converted map[string]interface{}
+		require.NoError(t, unmarshalFromFile(fixes[0].Output, &converted))
+		require.Equal(t, "timeseries", converted["panels"].([]interface{})[1].(map[string]interface{})["type"])
+	})
+}
+
+func TestAnnotate(t *testing.T) {
+	cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "lossy.json"))
+	d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	data, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+	uid := data[0].UID
+	data = append(data, output.Dashboard{UID: "no-detections"})
+	const text = "This dashboard contains Angular plugins: grafana-worldmap-panel, graph, singlestat"
+
+	changes, err := d.Annotate(context.Background(), data)
+	require.NoError(t, err)
+	require.Equal(t, []AnnotationChange{{DashboardUID: uid, Action: AnnotationCreated}}, changes)
+	require.Equal(t, []grafana.Annotation{{
+		ID:           1,
+		DashboardUID: uid,
+		Time:         1704164645000,
+		TimeEnd:      1704164645000,
+		Tags:         []string{"angular", "detect-angular-dashboards"},
+		Text:         text,
+	}}, cl.Annotations)
+
+	t.Run("extended and updated", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		data[0].Detections = data[0].Detections[:1]
+		changes, err := d.Annotate(context.Background(), data)
+		require.NoError(t, err)
+		require.Equal(t, []AnnotationChange{{DashboardUID: uid, Action: AnnotationUpdated}}, changes)
+		require.Len(t, cl.Annotations, 1)
+		require.Equal(t, int64(1704164645000), cl.Annotations[0].Time)
+		require.Equal(t, int64(1704168245000), cl.Annotations[0].TimeEnd)
+		require.Equal(t, "This dashboard contains Angular plugins: "+data[0].Detections[0].PluginID, cl.Annotations[0].Text)
+	})
+
+	t.Run("duplicates deleted", func(t *testing.T) {
+		require.NoError(t, cl.CreateAnnotation(context.Background(), grafana.Annotation{DashboardUID: uid, Text: text}))
+		changes, err := d.Annotate(context.Background(), data)
+		require.NoError(t, err)
+		require.Equal(t, []AnnotationChange{{DashboardUID: uid, Action: AnnotationUpdated}}, changes)
+		require.Len(t, cl.Annotations, 1)
+		require.Equal(t, int64(1), cl.Annotations[0].ID)
+	})
+
+	t.Run("deleted when resolved", func(t *testing.T) {
+		data[0].Detections = nil
+		changes, err := d.Annotate(context.Background(), data)
+		require.NoError(t, err)
+		require.Equal(t, []AnnotationChange{{DashboardUID: uid, Action: AnnotationDeleted}}, changes)
+		require.Empty(t, cl.Annotations)
+
+		changes, err = d.Annotate(context.Background(), data)
+		require.NoError(t, err)
+		require.Empty(t, changes)
+	})
+}
+
+func TestPublishDashboard(t *testing.T) {
+	cl := NewTestAPIClient(filepath.Join("testdata", "dashboards", "rows-expanded.json"))
+	d := NewDetector(logger.NewLeveledLogger(false), cl, newTestGCOMClient(t, nil, nil), 5)
+	dashboard := map[string]interface{}{"uid": output.StatusDashboardUID, "title": "Angular Migration Status"}
+	require.NoError(t, d.PublishDashboard(context.Background(), dashboard))
+	require.Equal(t, []map[string]interface{}{{
+		"uid":     output.StatusDashboardUID,
+		"title":   "Angular Migration Status",
+		"id":      220,
+		"version": 9,
+	}}, cl.SavedDashboards)
+}
+
+func TestPanelConverters(t *testing.T) {
+	t.Run("datatable", func(t *testing.T) {
+		converter := builtinPanelConverters()["briangann-datatable-panel"]
+		panel := map[string]interface{}{
+			"type":        "briangann-datatable-panel",
+			"styles":      []interface{}{map[string]interface{}{"pattern": "Time", "type": "date"}},
+			"rowsPerPage": 10.0,
+		}
+		unsupported, err := converter.Convert(panel)
+		require.NoError(t, err)
+		require.Empty(t, unsupported)
+		require.Equal(t, map[string]interface{}{
+			"type":            "table",
+			"autoMigrateFrom": "table-old",
+			"styles":          []interface{}{map[string]interface{}{"pattern": "Time", "type": "date"}},
+		}, panel)
+
+		withRowNumbers := map[string]interface{}{"type": "briangann-datatable-panel", "rowNumbersEnabled": true}
+		unsupported, err = converter.Convert(withRowNumbers)
+		require.NoError(t, err)
+		require.Equal(t, []string{"rowNumbersEnabled"}, unsupported)
+		require.Equal(t, "briangann-datatable-panel", withRowNumbers["type"])
+	})
+
+	t.Run("conversions file", func(t *testing.T) {
+		converters, err := ReadConversions(filepath.Join("testdata", "conversions.yaml"))
+		require.NoError(t, err)
+		require.Len(t, converters, 2)
+		require.Equal(t, "acme-status-panel", converters[0].PluginID())
+		require.Equal(t, "stat", converters[0].TargetPluginID())
+
+		panel := map[string]interface{}{
+			"type":          "acme-status-panel",
+			"valueFontSize": "80%",
+			"colorMode":     "background",
+			"legacyTheme":   "dark",
+		}
+		unsupported, err := converters[0].Convert(panel)
+		require.NoError(t, err)
+		require.Empty(t, unsupported)
+		require.Equal(t, map[string]interface{}{
+			"type": "stat",
+			"options": map[string]interface{}{
+				"text":      map[string]interface{}{"valueSize": "80%"},
+				"colorMode": "background",
+				"graphMode": "none",
+			},
+		}, panel)
+
+		unsupported, err = converters[0].Convert(map[string]interface{}{"type": "acme-status-panel", "customScript": "x"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"customScript"}, unsupported)
+
+		table := map[string]interface{}{"type": "acme-table-panel"}
+		_, err = converters[1].Convert(table)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"type": "table", "autoMigrateFrom": "table-old"}, table)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		converter := NewPanelConverter("graph", "barchart", func(panel map[string]interface{}) ([]string, error) {
+			return nil, nil
 		})
+		d := NewDetector(logger.NewLeveledLogger(false), NewTestAPIClient(""), newTestGCOMClient(t, nil, nil), 5, WithPanelConverters(converter))
+		require.Equal(t, "barchart", d.panelConverters["graph"].TargetPluginID())
+	})
+}
+
+func TestVersionLess(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		exp  bool
+	}{
+		{a: "8.4.7", b: "9.0.0", exp: true},
+		{a: "9.0.0", b: "9.0.0", exp: false},
+		{a: "10.1.0", b: "9.0.0", exp: false},
+		{a: "v9.0.0-beta1", b: "9.0.1", exp: true},
+		{a: "9.5", b: "9.5.1", exp: true},
+	} {
+		require.Equal(t, tc.exp, versionLess(tc.a, tc.b), "%s < %s", tc.a, tc.b)
 	}
 }
 
+// newTestGCOMClient returns a GCOM client backed by a test server, which returns the given versions and plugin
+// for each plugin id.
+// Unknown plugins return a 404 status code.
+func newTestGCOMClient(t *testing.T, versions map[string][]gcom.PluginVersion, plugins map[string]gcom.Plugin) gcom.APIClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/versions") {
+			plugin, ok := plugins[strings.TrimPrefix(r.URL.Path, "/plugins/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(plugin)
+			return
+		}
+		pluginID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/plugins/"), "/versions")
+		items, ok := versions[pluginID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(gcom.PluginVersions{Items: items})
+	}))
+	t.Cleanup(srv.Close)
+	return gcom.APIClient{Client: api.NewClient(srv.URL)}
+}
+
 // TestAPIClient is a GrafanaDetectorAPIClient implementation for testing.
 type TestAPIClient struct {
 	DashboardJSONFilePath    string
@@ -138,6 +2211,50 @@ type TestAPIClient struct {
 	FrontendSettingsFilePath string
 	DatasourcesFilePath      string
 	PluginsFilePath          string
+	PublicDashboardsFilePath string
+
+	// PluginModuleFilePaths maps plugin ids to the module.js files returned by GetPluginModule.
+	// Other plugins get a 404.
+	PluginModuleFilePaths map[string]string
+
+	// GrafanaVersion overrides the Grafana version in the frontend settings, if not empty.
+	GrafanaVersion string
+
+	// ServiceAccountPermissions are the permissions returned by GetServiceAccountPermissions.
+	ServiceAccountPermissions map[string][]string
+	// ServiceAccountPermissionsErr is the error returned by GetServiceAccountPermissions.
+	ServiceAccountPermissionsErr error
+
+	CurrentUserFilePath string
+
+	DeletedDashboardsFilePath string
+	OrgUsersFilePath          string
+	DashboardViewsFilePath    string
+
+	DashboardPermissionsFilePath string
+	FoldersFilePath              string
+	FolderPermissionsFilePath    string
+
+	OrgPreferencesFilePath  string
+	TeamsFilePath           string
+	TeamPreferencesFilePath string
+	UserPreferencesFilePath string
+
+	DashboardVersionsFilePath string
+	DashboardVersionFilePaths map[int]string
+
+	LibraryElementFilePath            string
+	LibraryElementConnectionsFilePath string
+
+	// DashboardPagesFilePath is a JSON file with the pages of dashboards returned by GetDashboards, if not empty.
+	DashboardPagesFilePath string
+
+	// SavedDashboards are the dashboards saved with SaveDashboard.
+	SavedDashboards []map[string]interface{}
+
+	// Annotations are the annotations created with CreateAnnotation, and lastAnnotationID is the id of the last one.
+	Annotations      []grafana.Annotation
+	lastAnnotationID int64
 }
 
 func NewTestAPIClient(dashboardJSONFilePath string) *TestAPIClient {
@@ -171,16 +2288,37 @@ func (c *TestAPIClient) GetPlugins(_ context.Context) (plugins []grafana.Plugin,
 	return
 }
 
+// GetPluginModule returns the content of the file in c.PluginModuleFilePaths for the given plugin.
+func (c *TestAPIClient) GetPluginModule(_ context.Context, pluginID string) ([]byte, error) {
+	fn, ok := c.PluginModuleFilePaths[pluginID]
+	if !ok {
+		return nil, api.BadStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	return os.ReadFile(fn)
+}
+
 // GetDatasourcePluginIDs returns the content of c.DatasourcesFilePath.
 func (c *TestAPIClient) GetDatasourcePluginIDs(_ context.Context) (datasources []grafana.Datasource, err error) {
 	err = unmarshalFromFile(c.DatasourcesFilePath, &datasources)
 	return
 }
 
-// GetDashboards returns a dummy response with only one dashboard.
-func (c *TestAPIClient) GetDashboards(_ context.Context, _ int) ([]grafana.ListedDashboard, error) {
+// GetDashboards returns the given page of c.DashboardPagesFilePath if set,
+// or a dummy response with only one dashboard for every page.
+func (c *TestAPIClient) GetDashboards(_ context.Context, page int) ([]grafana.ListedDashboard, error) {
+	if c.DashboardPagesFilePath != "" {
+		var pages [][]grafana.ListedDashboard
+		if err := unmarshalFromFile(c.DashboardPagesFilePath, &pages); err != nil {
+			return nil, err
+		}
+		if page > len(pages) {
+			return nil, nil
+		}
+		return pages[page-1], nil
+	}
 	return []grafana.ListedDashboard{
 		{
+			ID:    221,
 			UID:   "test-case-dashboard",
 			URL:   "/d/test-case-dashboard/test-case-dashboard",
 			Title: "test case dashboard",
@@ -188,6 +2326,15 @@ func (c *TestAPIClient) GetDashboards(_ context.Context, _ int) ([]grafana.Liste
 	}, nil
 }
 
+// GetDeletedDashboards returns the content of c.DeletedDashboardsFilePath, or no dashboards if it's empty.
+func (c *TestAPIClient) GetDeletedDashboards(_ context.Context) (dashboards []grafana.ListedDashboard, err error) {
+	if c.DeletedDashboardsFilePath == "" {
+		return nil, nil
+	}
+	err = unmarshalFromFile(c.DeletedDashboardsFilePath, &dashboards)
+	return
+}
+
 // GetDashboard returns a new DashboardDefinition that can be used for testing purposes.
 // The dashboard definition is taken from the file specified in c.DashboardJSONFilePath.
 // The dashboard meta is taken from the file specified in c.DashboardMetaFilePath.
@@ -202,20 +2349,279 @@ func (c *TestAPIClient) GetDashboard(_ context.Context, _ string) (*grafana.Dash
 	if err := unmarshalFromFile(c.DashboardJSONFilePath, &out.Dashboard); err != nil {
 		return nil, fmt.Errorf("unmarshal dashboard: %w", err)
 	}
-	grafana.ConvertPanels(out.Dashboard.Panels)
+	grafana.ConvertDashboard(&out.Dashboard)
 	return &out, nil
 }
 
+// GetDashboardJSON returns the content of c.DashboardJSONFilePath and c.DashboardMetaFilePath,
+// as returned by the API.
+func (c *TestAPIClient) GetDashboardJSON(_ context.Context, _ string) (json.RawMessage, error) {
+	var out struct {
+		Dashboard json.RawMessage `json:"dashboard"`
+		Meta      json.RawMessage `json:"meta"`
+	}
+	if err := unmarshalFromFile(c.DashboardJSONFilePath, &out.Dashboard); err != nil {
+		return nil, fmt.Errorf("unmarshal dashboard: %w", err)
+	}
+	var meta struct {
+		Meta json.RawMessage `json:"meta"`
+	}
+	if err := unmarshalFromFile(c.DashboardMetaFilePath, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal meta: %w", err)
+	}
+	out.Meta = meta.Meta
+	return json.Marshal(out)
+}
+
+// SaveDashboard appends the given dashboard to c.SavedDashboards.
+func (c *TestAPIClient) SaveDashboard(_ context.Context, dashboard map[string]interface{}, _, _ string) error {
+	c.SavedDashboards = append(c.SavedDashboards, dashboard)
+	return nil
+}
+
+// GetAnnotations returns the annotations of c.Annotations of the given dashboard, or all of them if dashboardUID is empty.
+func (c *TestAPIClient) GetAnnotations(_ context.Context, dashboardUID string, _ []string) ([]grafana.Annotation, error) {
+	var out []grafana.Annotation
+	for _, annotation := range c.Annotations {
+		if dashboardUID == "" || annotation.DashboardUID == dashboardUID {
+			out = append(out, annotation)
+		}
+	}
+	return out, nil
+}
+
+// CreateAnnotation appends the given annotation to c.Annotations, with the next id.
+func (c *TestAPIClient) CreateAnnotation(_ context.Context, annotation grafana.Annotation) error {
+	c.lastAnnotationID++
+	annotation.ID = c.lastAnnotationID
+	c.Annotations = append(c.Annotations, annotation)
+	return nil
+}
+
+// UpdateAnnotation replaces the annotation of c.Annotations with the id of the given one.
+func (c *TestAPIClient) UpdateAnnotation(_ context.Context, annotation grafana.Annotation) error {
+	for i := range c.Annotations {
+		if c.Annotations[i].ID == annotation.ID {
+			c.Annotations[i] = annotation
+			return nil
+		}
+	}
+	return api.BadStatusCodeError{StatusCode: http.StatusNotFound}
+}
+
+// DeleteAnnotation removes the annotation with the given id from c.Annotations.
+func (c *TestAPIClient) DeleteAnnotation(_ context.Context, id int64) error {
+	for i := range c.Annotations {
+		if c.Annotations[i].ID == id {
+			c.Annotations = append(c.Annotations[:i], c.Annotations[i+1:]...)
+			return nil
+		}
+	}
+	return api.BadStatusCodeError{StatusCode: http.StatusNotFound}
+}
+
 // GetFrontendSettings returns the content of c.FrontendSettingsFilePath.
+// If c.GrafanaVersion is not empty, it's used as the Grafana version.
 func (c *TestAPIClient) GetFrontendSettings(_ context.Context) (frontendSettings *grafana.FrontendSettings, err error) {
 	err = unmarshalFromFile(c.FrontendSettingsFilePath, &frontendSettings)
+	if err == nil && c.GrafanaVersion != "" {
+		frontendSettings.BuildInfo.Version = c.GrafanaVersion
+	}
 	return
 }
 
-// GetServiceAccountPermissions is not implemented for testing purposes and always returns an empty map and a nil error.
+// GetServiceAccountPermissions is not implemented for testing purposes and always returns an empty map
+// and c.ServiceAccountPermissionsErr.
 func (c *TestAPIClient) GetServiceAccountPermissions(_ context.Context) (map[string][]string, error) {
-	return nil, nil
+	return c.ServiceAccountPermissions, c.ServiceAccountPermissionsErr
+}
+
+// GetPublicDashboards returns the content of c.PublicDashboardsFilePath.
+// If c.PublicDashboardsFilePath is empty, it returns no public dashboards.
+func (c *TestAPIClient) GetPublicDashboards(_ context.Context) (publicDashboards []grafana.PublicDashboard, err error) {
+	if c.PublicDashboardsFilePath == "" {
+		return nil, nil
+	}
+	err = unmarshalFromFile(c.PublicDashboardsFilePath, &publicDashboards)
+	return
 }
 
-// static check
-var _ GrafanaDetectorAPIClient = &TestAPIClient{}
+// GetDashboardVersions returns the content of c.DashboardVersionsFilePath.
+func (c *TestAPIClient) GetDashboardVersions(_ context.Context, _ string) (versions []grafana.DashboardVersion, err error) {
+	err = unmarshalFromFile(c.DashboardVersionsFilePath, &versions)
+	return
+}
+
+// GetDashboardVersion returns the metadata of the version from c.DashboardVersionsFilePath, and
+// the dashboard definition from the file specified in c.DashboardVersionFilePaths for that version.
+func (c *TestAPIClient) GetDashboardVersion(ctx context.Context, uid string, version int) (*grafana.DashboardVersionDefinition, error) {
+	fn, ok := c.DashboardVersionFilePaths[version]
+	if !ok {
+		return nil, fmt.Errorf("TestAPIClient has no file for dashboard version %d", version)
+	}
+	versions, err := c.GetDashboardVersions(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("get versions: %w", err)
+	}
+	var out grafana.DashboardVersionDefinition
+	for _, v := range versions {
+		if v.Version == version {
+			out.DashboardVersion = v
+			break
+		}
+	}
+	if err := unmarshalFromFile(fn, &out.Data); err != nil {
+		return nil, fmt.Errorf("unmarshal dashboard: %w", err)
+	}
+	grafana.ConvertDashboard(&out.Data)
+	return &out, nil
+}
+
+// GetOrgPreferences returns the content of c.OrgPreferencesFilePath, or empty preferences if it's empty.
+func (c *TestAPIClient) GetOrgPreferences(_ context.Context) (*grafana.Preferences, error) {
+	return unmarshalPreferences(c.OrgPreferencesFilePath)
+}
+
+// GetUserPreferences returns the content of c.UserPreferencesFilePath, or empty preferences if it's empty.
+func (c *TestAPIClient) GetUserPreferences(_ context.Context) (*grafana.Preferences, error) {
+	return unmarshalPreferences(c.UserPreferencesFilePath)
+}
+
+// GetTeams returns the content of c.TeamsFilePath, or no teams if it's empty.
+func (c *TestAPIClient) GetTeams(_ context.Context) (teams []grafana.Team, err error) {
+	if c.TeamsFilePath == "" {
+		return nil, nil
+	}
+	err = unmarshalFromFile(c.TeamsFilePath, &teams)
+	return
+}
+
+// GetTeamPreferences returns the content of c.TeamPreferencesFilePath for the team with id 1,
+// and empty preferences for the other teams.
+func (c *TestAPIClient) GetTeamPreferences(_ context.Context, teamID int) (*grafana.Preferences, error) {
+	if teamID != 1 {
+		return &grafana.Preferences{}, nil
+	}
+	return unmarshalPreferences(c.TeamPreferencesFilePath)
+}
+
+// unmarshalPreferences unmarshals the preferences in the given file, or returns empty preferences if fn is empty.
+func unmarshalPreferences(fn string) (*grafana.Preferences, error) {
+	var out grafana.Preferences
+	if fn == "" {
+		return &out, nil
+	}
+	if err := unmarshalFromFile(fn, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLibraryElement returns the content of c.LibraryElementFilePath for any uid.
+func (c *TestAPIClient) GetLibraryElement(_ context.Context, _ string) (*grafana.LibraryElement, error) {
+	if c.LibraryElementFilePath == "" {
+		return nil, fmt.Errorf("%w: %d", api.ErrBadStatusCode, 404)
+	}
+	var out grafana.LibraryElement
+	if err := unmarshalFromFile(c.LibraryElementFilePath, &out); err != nil {
+		return nil, err
+	}
+	if out.Model != nil {
+		grafana.ConvertPanels([]*grafana.DashboardPanel{out.Model})
+	}
+	return &out, nil
+}
+
+// GetLibraryElementConnections returns the content of c.LibraryElementConnectionsFilePath for any uid,
+// or no connections if it's empty.
+func (c *TestAPIClient) GetLibraryElementConnections(_ context.Context, _ string) (connections []grafana.LibraryElementConnection, err error) {
+	if c.LibraryElementConnectionsFilePath == "" {
+		return nil, nil
+	}
+	err = unmarshalFromFile(c.LibraryElementConnectionsFilePath, &connections)
+	return
+}
+
+// GetCurrentUser returns the content of c.CurrentUserFilePath.
+func (c *TestAPIClient) GetCurrentUser(_ context.Context) (*grafana.User, error) {
+	var out grafana.User
+	if err := unmarshalFromFile(c.CurrentUserFilePath, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrgUsers returns the content of c.OrgUsersFilePath.
+func (c *TestAPIClient) GetOrgUsers(_ context.Context) (users []grafana.OrgUser, err error) {
+	err = unmarshalFromFile(c.OrgUsersFilePath, &users)
+	return
+}
+
+// GetDashboardViews returns the content of c.DashboardViewsFilePath.
+func (c *TestAPIClient) GetDashboardViews(_ context.Context) (views []grafana.DashboardViews, err error) {
+	err = unmarshalFromFile(c.DashboardViewsFilePath, &views)
+	return
+}
+
+// GetDashboardPermissions returns the content of c.DashboardPermissionsFilePath for any dashboard.
+func (c *TestAPIClient) GetDashboardPermissions(_ context.Context, _ string) (permissions []grafana.DashboardPermission, err error) {
+	err = unmarshalFromFile(c.DashboardPermissionsFilePath, &permissions)
+	return
+}
+
+// GetFolderPermissions returns the content of c.FolderPermissionsFilePath for any folder.
+func (c *TestAPIClient) GetFolderPermissions(_ context.Context, _ string) (permissions []grafana.DashboardPermission, err error) {
+	err = unmarshalFromFile(c.FolderPermissionsFilePath, &permissions)
+	return
+}
+
+// GetFolders returns the content of c.FoldersFilePath.
+func (c *TestAPIClient) GetFolders(_ context.Context) (folders []grafana.Folder, err error) {
+	err = unmarshalFromFile(c.FoldersFilePath, &folders)
+	return
+}
+
+func TestLookups(t *testing.T) {
+	var l lookups
+
+	t.Run("different keys don't wait", func(t *testing.T) {
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		done := make(chan interface{})
+		go func() {
+			done <- l.get("slow", func() interface{} {
+				close(started)
+				<-unblock
+				return "slow"
+			})
+		}()
+		<-started
+		require.Equal(t, "fast", l.get("fast", func() interface{} { return "fast" }))
+		close(unblock)
+		require.Equal(t, "slow", <-done)
+	})
+
+	t.Run("same key computed once", func(t *testing.T) {
+		var calls int
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v := l.get("once", func() interface{} {
+					calls++
+					return 1
+				})
+				require.Equal(t, 1, v)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, 1, calls)
+	})
+}
+
+// static checks
+var (
+	_ GrafanaDetectorAPIClient = &TestAPIClient{}
+	_ GrafanaDetectorAPIClient = offline.APIClient{}
+)
@@ -0,0 +1,152 @@
+package detector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// regexpPrefix is the prefix of the exclusion patterns that are regular expressions instead of globs.
+const regexpPrefix = "re:"
+
+// pattern is an exclusion pattern, either a glob (path.Match syntax) or a regular expression.
+type pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// newPattern returns the pattern for the given string, a regular expression if it starts with regexpPrefix,
+// or a glob otherwise.
+func newPattern(s string) (pattern, error) {
+	if expr, ok := strings.CutPrefix(s, regexpPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return pattern{}, fmt.Errorf("invalid regular expression %q: %w", expr, err)
+		}
+		return pattern{re: re}, nil
+	}
+	if _, err := path.Match(s, ""); err != nil {
+		return pattern{}, fmt.Errorf("invalid glob %q: %w", s, err)
+	}
+	return pattern{glob: s}, nil
+}
+
+// match returns true if any of the given values matches the pattern.
+func (p pattern) match(values ...string) bool {
+	for _, v := range values {
+		if p.re != nil && p.re.MatchString(v) {
+			return true
+		}
+		if p.re == nil {
+			if ok, _ := path.Match(p.glob, v); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns the pattern as passed to newPattern.
+func (p pattern) String() string {
+	if p.re != nil {
+		return regexpPrefix + p.re.String()
+	}
+	return p.glob
+}
+
+// Exclusions are patterns of folders and dashboards that are skipped, e.g. known archived folders.
+// Excluded dashboards are not checked, so they are not reported and do not fail the verify command.
+type Exclusions struct {
+	folders    []pattern
+	dashboards []pattern
+}
+
+// NewExclusions returns the Exclusions for the given patterns, which are globs (e.g.: "zz_deprecated*"),
+// or regular expressions if prefixed with "re:" (e.g.: "re:^(?i)archive").
+// Folder patterns are matched against the title and the uid of the folder of each dashboard.
+// Dashboard patterns are matched against the title and the uid of each dashboard, and against its path,
+// made of the title of its folder and its title (e.g.: "zz_deprecated/*" matches all the dashboards in the
+// "zz_deprecated" folder).
+func NewExclusions(folders, dashboards []string) (*Exclusions, error) {
+	var out Exclusions
+	for _, s := range folders {
+		p, err := newPattern(s)
+		if err != nil {
+			return nil, fmt.Errorf("folder pattern: %w", err)
+		}
+		out.folders = append(out.folders, p)
+	}
+	for _, s := range dashboards {
+		p, err := newPattern(s)
+		if err != nil {
+			return nil, fmt.Errorf("dashboard pattern: %w", err)
+		}
+		out.dashboards = append(out.dashboards, p)
+	}
+	return &out, nil
+}
+
+// WithExclusions returns an Option that makes the Detector skip the dashboards matching the given exclusions.
+func WithExclusions(exclusions *Exclusions) Option {
+	return func(d *Detector) {
+		d.exclusions = exclusions
+	}
+}
+
+// patterns returns the folder and dashboard patterns, prefixed with "folder:" and "dashboard:", or nil if e is nil.
+func (e *Exclusions) patterns() []string {
+	if e == nil {
+		return nil
+	}
+	var out []string
+	for _, p := range e.folders {
+		out = append(out, "folder:"+p.String())
+	}
+	for _, p := range e.dashboards {
+		out = append(out, "dashboard:"+p.String())
+	}
+	return out
+}
+
+// excluded returns true if the given dashboard matches any of the exclusions.
+func (e *Exclusions) excluded(dash grafana.ListedDashboard) bool {
+	if dash.FolderUID != "" || dash.FolderTitle != "" {
+		for _, p := range e.folders {
+			if p.match(dash.FolderTitle, dash.FolderUID) {
+				return true
+			}
+		}
+	}
+	dashPath := dash.Title
+	if dash.FolderTitle != "" {
+		dashPath = dash.FolderTitle + "/" + dash.Title
+	}
+	for _, p := range e.dashboards {
+		if p.match(dash.Title, dash.UID, dashPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterExcluded returns the given dashboards without the ones matching d.exclusions, if set.
+func (d *Detector) filterExcluded(dashboards []grafana.ListedDashboard) []grafana.ListedDashboard {
+	if d.exclusions == nil {
+		return dashboards
+	}
+	var out []grafana.ListedDashboard
+	for _, dash := range dashboards {
+		if d.exclusions.excluded(dash) {
+			d.log.Verbose().Log("Excluding dashboard %q (%q in folder %q)", dash.UID, dash.Title, dash.FolderTitle)
+			continue
+		}
+		out = append(out, dash)
+	}
+	if excluded := len(dashboards) - len(out); excluded > 0 {
+		d.log.Log("Excluded %d dashboards matching the exclusion patterns", excluded)
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/internal/sliceutil"
+)
+
+// Filters restrict the dashboards checked by the Detector to some folders and tags, like the filters of the search API.
+// They are applied to the listed dashboards, so they also restrict the clients that don't filter the dashboards
+// themselves (e.g.: offline or simulate mode).
+type Filters struct {
+	// Folders and FolderUIDs are the titles and uids of the folders whose dashboards are checked, including the
+	// dashboards of their subfolders. grafana.GeneralFolderUID is the uid of the root folder.
+	Folders    []string
+	FolderUIDs []string
+
+	// Tags are the tags the checked dashboards must all have.
+	Tags []string
+}
+
+// IsEmpty returns true if the filters don't restrict the dashboards.
+func (f Filters) IsEmpty() bool {
+	return len(f.Folders) == 0 && len(f.FolderUIDs) == 0 && len(f.Tags) == 0
+}
+
+// WithFilters returns an Option that makes the Detector check only the dashboards matching the given filters.
+// By default, all dashboards are checked.
+func WithFilters(filters Filters) Option {
+	return func(d *Detector) {
+		d.filters = filters
+	}
+}
+
+// ResolveFolderUIDs returns the uids of the folders with the given titles or uids, followed by the uids of their
+// subfolders (recursively), as the search API only returns the dashboards directly in the given folders.
+func ResolveFolderUIDs(folders []grafana.Folder, titles, uids []string) ([]string, error) {
+	var out []string
+	selected := map[string]struct{}{}
+	add := func(uid string) {
+		if _, ok := selected[uid]; !ok {
+			selected[uid] = struct{}{}
+			out = append(out, uid)
+		}
+	}
+	for _, uid := range uids {
+		add(uid)
+	}
+	for _, title := range titles {
+		found := false
+		for _, folder := range folders {
+			if folder.Title == title {
+				add(folder.UID)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("folder %q not found", title)
+		}
+	}
+	// Add the subfolders until there are no new ones, as folders are not sorted by depth
+	for added := true; added; {
+		added = false
+		for _, folder := range folders {
+			if _, ok := selected[folder.ParentUID]; ok && folder.ParentUID != "" {
+				if _, ok := selected[folder.UID]; !ok {
+					add(folder.UID)
+					added = true
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// filterDashboards returns the given dashboards matching d.filters, or all of them if there are no filters.
+// If the folders can't be listed (e.g.: offline mode), only the dashboards directly in the given folders are matched.
+func (d *Detector) filterDashboards(ctx context.Context, dashboards []grafana.ListedDashboard) ([]grafana.ListedDashboard, error) {
+	if d.filters.IsEmpty() {
+		return dashboards, nil
+	}
+	folderUIDs := map[string]struct{}{}
+	folderTitles := map[string]struct{}{}
+	if len(d.filters.Folders) > 0 || len(d.filters.FolderUIDs) > 0 {
+		folders, err := d.grafanaClient.GetFolders(ctx)
+		if err != nil {
+			d.log.Verbose().Log("(WARNING: could not get folders, the subfolders of the filtered folders are not checked: %v)", err)
+			for _, uid := range d.filters.FolderUIDs {
+				folderUIDs[uid] = struct{}{}
+			}
+			for _, title := range d.filters.Folders {
+				folderTitles[title] = struct{}{}
+			}
+		} else {
+			uids, err := ResolveFolderUIDs(folders, d.filters.Folders, d.filters.FolderUIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, uid := range uids {
+				folderUIDs[uid] = struct{}{}
+			}
+		}
+	}
+	var out []grafana.ListedDashboard
+	for _, dash := range dashboards {
+		if d.matchesFilters(dash, folderUIDs, folderTitles) {
+			out = append(out, dash)
+		}
+	}
+	return out, nil
+}
+
+// matchesFilters returns true if the given dashboard has all the tags of d.filters, and is in one of the given folders
+// (by uid or title) if the filters restrict the folders.
+func (d *Detector) matchesFilters(dash grafana.ListedDashboard, folderUIDs, folderTitles map[string]struct{}) bool {
+	if len(folderUIDs) > 0 || len(folderTitles) > 0 {
+		folderUID := dash.FolderUID
+		if folderUID == "" {
+			folderUID = grafana.GeneralFolderUID
+		}
+		_, uidOK := folderUIDs[folderUID]
+		_, titleOK := folderTitles[dash.FolderTitle]
+		if !uidOK && !(titleOK && dash.FolderTitle != "") {
+			return false
+		}
+	}
+	for _, tag := range d.filters.Tags {
+		if !sliceutil.Contains(dash.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
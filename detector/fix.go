@@ -0,0 +1,150 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// fixMessage is the version message of the dashboards saved by Fix.
+const fixMessage = "Migrate Angular panels to React (detect-angular-dashboards fix)"
+
+// Fix converts the Angular panels of the given dashboards with the panel converters (see PanelConverter),
+// and saves the dashboards through the API, after writing their previous version to backupDir.
+// Panels with options that can't be converted are skipped. If outputDir is not empty, the converted dashboards
+// are written to it for review instead of being saved. In dry-run mode, nothing is saved or written.
+// A dashboard that can't be fixed doesn't stop the others, its error is reported in the returned fixes.
+func (d *Detector) Fix(ctx context.Context, dashboards []output.Dashboard, backupDir, outputDir string, dryRun bool) []output.DashboardFix {
+	var out []output.DashboardFix
+	for _, dashboard := range dashboards {
+		if !d.hasPanelConverters(dashboard) {
+			continue
+		}
+		fix := output.DashboardFix{UID: dashboard.UID, Title: dashboard.Title, URL: dashboard.URL}
+		if err := d.fixDashboard(ctx, &fix, backupDir, outputDir, dryRun); err != nil {
+			fix.Error = err.Error()
+		}
+		if len(fix.Panels) > 0 || len(fix.Skipped) > 0 || fix.Error != "" {
+			out = append(out, fix)
+		}
+	}
+	return out
+}
+
+// hasPanelConverters returns true if the given dashboard has Angular panels with a panel converter.
+func (d *Detector) hasPanelConverters(dashboard output.Dashboard) bool {
+	for _, detection := range dashboard.Detections {
+		switch detection.DetectionType {
+		case output.DetectionTypeLegacyPanel, output.DetectionTypePanel, output.DetectionTypeUnknown:
+		default:
+			continue
+		}
+		if _, ok := d.panelConverters[detection.PluginID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fixDashboard converts the panels of the dashboard of the given fix, and saves it, or writes it to outputDir
+// if not empty, unless dryRun is true.
+func (d *Detector) fixDashboard(ctx context.Context, fix *output.DashboardFix, backupDir, outputDir string, dryRun bool) error {
+	raw, err := d.grafanaClient.GetDashboardJSON(ctx, fix.UID)
+	if err != nil {
+		return fmt.Errorf("get dashboard: %w", err)
+	}
+	var definition struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		Meta      struct {
+			FolderUID string `json:"folderUid"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(raw, &definition); err != nil {
+		return fmt.Errorf("unmarshal dashboard: %w", err)
+	}
+	panels, _ := definition.Dashboard["panels"].([]interface{})
+	if err := d.convertPanels(fix, panels); err != nil {
+		return err
+	}
+	if len(fix.Panels) == 0 || dryRun {
+		return nil
+	}
+	if outputDir != "" {
+		if fix.Output, err = writeConverted(outputDir, fix.UID, definition.Dashboard); err != nil {
+			return fmt.Errorf("write converted dashboard: %w", err)
+		}
+		return nil
+	}
+	if fix.Backup, err = writeBackup(backupDir, fix.UID, definition.Dashboard["version"], raw); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	if err := d.grafanaClient.SaveDashboard(ctx, definition.Dashboard, definition.Meta.FolderUID, fixMessage); err != nil {
+		return fmt.Errorf("save dashboard: %w", err)
+	}
+	fix.Saved = true
+	return nil
+}
+
+// convertPanels converts the given panels of a dashboard model, and the panels of collapsed rows, in place,
+// and adds them to the panels or the skipped panels of the given fix.
+// Library panels are left as-is, as their model is not part of the dashboard.
+func (d *Detector) convertPanels(fix *output.DashboardFix, panels []interface{}) error {
+	for _, v := range panels {
+		panel, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if rowPanels, ok := panel["panels"].([]interface{}); ok {
+			if err := d.convertPanels(fix, rowPanels); err != nil {
+				return err
+			}
+		}
+		pluginID, _ := panel["type"].(string)
+		converter, ok := d.panelConverters[pluginID]
+		if !ok || panel["libraryPanel"] != nil {
+			continue
+		}
+		title, _ := panel["title"].(string)
+		panelFix := output.PanelFix{Title: title, PluginID: pluginID, TargetPluginID: converter.TargetPluginID()}
+		unsupported, err := converter.Convert(panel)
+		if err != nil {
+			return fmt.Errorf("convert panel %q: %w", title, err)
+		}
+		if len(unsupported) > 0 {
+			panelFix.LossyOptions = unsupported
+			fix.Skipped = append(fix.Skipped, panelFix)
+			continue
+		}
+		fix.Panels = append(fix.Panels, panelFix)
+	}
+	return nil
+}
+
+// writeConverted writes the given converted dashboard model to <dir>/<uid>.json, and returns the file name.
+func writeConverted(dir, uid string, dashboard map[string]interface{}) (string, error) {
+	b, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(dir, uid+".json")
+	return fn, os.WriteFile(fn, b, 0o644)
+}
+
+// writeBackup writes the given dashboard, as returned by the API, to <dir>/<uid>-v<version>.json,
+// and returns the file name.
+func writeBackup(dir, uid string, version interface{}, raw json.RawMessage) (string, error) {
+	// JSON numbers are decoded as float64, the version is 0 if missing
+	v, _ := version.(float64)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	fn := filepath.Join(dir, fmt.Sprintf("%s-v%d.json", uid, int(v)))
+	return fn, os.WriteFile(fn, raw, 0o644)
+}
@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// rootFolderTitle is the title of the root of the folder tree, which contains the dashboards in the General folder.
+const rootFolderTitle = "Dashboards"
+
+// FolderTree returns the folder hierarchy annotated with the Angular detections of the given dashboards,
+// as returned by Run. The root has an empty uid and contains the dashboards not in a folder.
+// If the folders can't be listed (e.g.: offline), the folders are built from the dashboards' folder titles, without nesting.
+func (d *Detector) FolderTree(ctx context.Context, dashboards []output.Dashboard) *output.Folder {
+	folders, err := d.grafanaClient.GetFolders(ctx)
+	if err != nil {
+		d.log.Verbose().Log("(WARNING: could not get folders, nested folders won't be reported: %v)", err)
+		folders = nil
+	}
+	return folderTree(folders, dashboards)
+}
+
+// folderTree builds the folder tree from the given folders and dashboards.
+// Dashboards in unknown folders are attached to a folder created from their folder title, under the root.
+func folderTree(folders []grafana.Folder, dashboards []output.Dashboard) *output.Folder {
+	root := &output.Folder{Title: rootFolderTitle, Subfolders: []*output.Folder{}}
+	byUID := make(map[string]*output.Folder, len(folders))
+	parents := make(map[string]string, len(folders))
+	for _, f := range folders {
+		byUID[f.UID] = &output.Folder{UID: f.UID, Title: f.Title, Subfolders: []*output.Folder{}}
+		parents[f.UID] = f.ParentUID
+	}
+	for _, f := range folders {
+		parent, ok := byUID[parents[f.UID]]
+		if !ok {
+			parent = root
+		}
+		parent.Subfolders = append(parent.Subfolders, byUID[f.UID])
+	}
+
+	// Folders without uid (e.g.: offline) are identified by title
+	byTitle := map[string]*output.Folder{}
+	for _, dashboard := range dashboards {
+		folder := root
+		switch {
+		case byUID[dashboard.FolderUID] != nil:
+			folder = byUID[dashboard.FolderUID]
+		case dashboard.Folder != "" && dashboard.FolderUID == "":
+			if byTitle[dashboard.Folder] == nil {
+				byTitle[dashboard.Folder] = &output.Folder{Title: dashboard.Folder, Subfolders: []*output.Folder{}}
+				root.Subfolders = append(root.Subfolders, byTitle[dashboard.Folder])
+			}
+			folder = byTitle[dashboard.Folder]
+		case dashboard.FolderUID != "":
+			byUID[dashboard.FolderUID] = &output.Folder{UID: dashboard.FolderUID, Title: dashboard.Folder, Subfolders: []*output.Folder{}}
+			root.Subfolders = append(root.Subfolders, byUID[dashboard.FolderUID])
+			folder = byUID[dashboard.FolderUID]
+		}
+		folder.Dashboards++
+		folder.Detections += len(dashboard.Detections)
+		if len(dashboard.Detections) > 0 {
+			folder.AngularDashboards++
+		}
+	}
+	sumFolderCounts(root)
+	return root
+}
+
+// sumFolderCounts adds the counts of the subfolders of the given folder to its own counts, recursively,
+// and sorts the subfolders by title.
+func sumFolderCounts(folder *output.Folder) {
+	sort.SliceStable(folder.Subfolders, func(i, j int) bool {
+		return folder.Subfolders[i].Title < folder.Subfolders[j].Title
+	})
+	for _, sub := range folder.Subfolders {
+		sumFolderCounts(sub)
+		folder.Dashboards += sub.Dashboards
+		folder.AngularDashboards += sub.AngularDashboards
+		folder.Detections += sub.Detections
+	}
+}
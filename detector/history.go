@@ -0,0 +1,77 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// detectionKey identifies a detection in a dashboard regardless of the panel that triggered it.
+type detectionKey struct {
+	detectionType output.DetectionType
+	pluginID      string
+}
+
+func newDetectionKey(d output.Detection) detectionKey {
+	return detectionKey{detectionType: d.DetectionType, pluginID: d.PluginID}
+}
+
+// setIntroduced walks the version history of the dashboard with the given uid, from the newest version
+// to the oldest one, and sets the version in which each detection was introduced.
+// A detection is considered introduced in the oldest version of the uninterrupted sequence of versions
+// (ending with the latest one) that contain it.
+// Grafana only keeps a limited number of versions, so this may be the oldest version still available.
+func (d *Detector) setIntroduced(ctx context.Context, uid string, detections []output.Detection) error {
+	versions, err := d.grafanaClient.GetDashboardVersions(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("get dashboard versions: %w", err)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+
+	pending := make(map[detectionKey]struct{}, len(detections))
+	for _, detection := range detections {
+		pending[newDetectionKey(detection)] = struct{}{}
+	}
+	introduced := make(map[detectionKey]grafana.DashboardVersion, len(detections))
+	for _, v := range versions {
+		if len(pending) == 0 {
+			break
+		}
+		dashboardVersion, err := d.grafanaClient.GetDashboardVersion(ctx, uid, v.Version)
+		if err != nil {
+			return fmt.Errorf("get dashboard version %d: %w", v.Version, err)
+		}
+		versionDetections, err := d.checkDashboard(&grafana.DashboardDefinition{Dashboard: dashboardVersion.Data})
+		if err != nil {
+			return fmt.Errorf("check dashboard version %d: %w", v.Version, err)
+		}
+		present := make(map[detectionKey]struct{}, len(versionDetections))
+		for _, detection := range versionDetections {
+			present[newDetectionKey(detection)] = struct{}{}
+		}
+		for k := range pending {
+			if _, ok := present[k]; !ok {
+				// Not present in this version, so it was introduced in the previous (newer) one
+				delete(pending, k)
+				continue
+			}
+			introduced[k] = v
+		}
+	}
+
+	for i, detection := range detections {
+		v, ok := introduced[newDetectionKey(detection)]
+		if !ok {
+			continue
+		}
+		detections[i].IntroducedVersion = v.Version
+		detections[i].IntroducedBy = v.CreatedBy
+		detections[i].Introduced, _ = d.normalizeTime(v.Created)
+	}
+	return nil
+}
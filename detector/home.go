@@ -0,0 +1,61 @@
+package detector
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// homeDashboards returns a map from dashboard uid to the preferences that set it as home dashboard.
+// Preferences are "org", "team:<team name>" or "user" (the user of the token).
+// Errors are logged and don't cause the detection to fail.
+func (d *Detector) homeDashboards(ctx context.Context, dashboards []grafana.ListedDashboard) map[string][]string {
+	// Old Grafana versions reference the home dashboard by id rather than by uid
+	uids := make(map[int]string, len(dashboards))
+	for _, dash := range dashboards {
+		uids[dash.ID] = dash.UID
+	}
+	out := map[string][]string{}
+	add := func(preferences *grafana.Preferences, source string) {
+		uid := preferences.HomeDashboardUID
+		if uid == "" && preferences.HomeDashboardID != 0 {
+			uid = uids[preferences.HomeDashboardID]
+		}
+		if uid == "" {
+			return
+		}
+		out[uid] = append(out[uid], source)
+	}
+
+	if preferences, err := d.grafanaClient.GetOrgPreferences(ctx); err != nil {
+		d.log.Warn("Could not get org preferences: %s", err)
+	} else {
+		add(preferences, "org")
+	}
+
+	teams, err := d.grafanaClient.GetTeams(ctx)
+	if err != nil {
+		d.log.Warn("Could not get teams: %s", err)
+	}
+	for _, team := range teams {
+		preferences, err := d.grafanaClient.GetTeamPreferences(ctx, team.ID)
+		if err != nil {
+			d.log.Warn("Could not get preferences for team %q: %s", team.Name, err)
+			continue
+		}
+		name := team.Name
+		if name == "" {
+			name = strconv.Itoa(team.ID)
+		}
+		add(preferences, "team:"+name)
+	}
+
+	if preferences, err := d.grafanaClient.GetUserPreferences(ctx); err != nil {
+		// Service accounts may not have user preferences
+		d.log.Verbose().Log("(WARNING: could not get user preferences: %v)", err)
+	} else {
+		add(preferences, "user")
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/internal/sliceutil"
+)
+
+// readOnlyPermissions are the permissions needed to scan the dashboards.
+var readOnlyPermissions = []string{"dashboards:read", "folders:read", "datasources:read"}
+
+// gcomPermissions are the permissions needed to list all the installed plugins when using GCOM
+// (Grafana < 10.1.0). Only one of them is required.
+var gcomPermissions = []string{"datasources:create", "plugins:install"}
+
+// writePermissions are permissions granting write access, which are not needed for a read-only scan.
+var writePermissions = []string{
+	"dashboards:write", "dashboards:delete", "dashboards.permissions:write",
+	"folders:write", "folders:delete",
+	"datasources:create", "datasources:write", "datasources:delete",
+	"plugins:write", "plugins:install",
+	"users:write", "users:create", "users:delete",
+	"orgs:write", "orgs:create", "orgs:delete",
+	"serviceaccounts:write", "serviceaccounts:create", "serviceaccounts:delete",
+	"teams:write", "teams:create", "teams:delete",
+}
+
+// CheckIdentity logs the identity the API token belongs to, and warns if the token has far more privileges
+// than needed for a read-only scan, or too few. It returns the identity, so it can be recorded.
+// It returns an error if the identity can't be determined (e.g.: in offline mode).
+func (d *Detector) CheckIdentity(ctx context.Context) (*grafana.User, error) {
+	user, err := d.grafanaClient.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get current user: %w", err)
+	}
+	d.log.Log("Running as %q (org %d)", user.Login, user.OrgID)
+
+	frontendSettings, err := d.grafanaClient.GetFrontendSettings(ctx)
+	if err != nil {
+		return user, fmt.Errorf("get frontend settings: %w", err)
+	}
+	compat := d.probeCompatibility(ctx, frontendSettings)
+	if !compat.hasAccessControl {
+		// Old Grafana versions don't have service accounts: the permissions of the token are not checked
+		d.log.Verbose().Log("(WARNING: could not get service account permissions: %v)", compat.accessControlErr)
+	}
+	for _, warning := range privilegeWarnings(user, compat) {
+		d.log.Warn("%s", warning)
+	}
+	return user, nil
+}
+
+// privilegeWarnings returns the warnings about the privileges of the given user.
+// Permissions are only checked if the access-control endpoint is available.
+func privilegeWarnings(user *grafana.User, compat compatibility) []string {
+	var out []string
+	if user.IsGrafanaAdmin {
+		out = append(out, fmt.Sprintf(
+			"%q is a Grafana server admin, which has far more privileges than needed for a read-only scan. "+
+				"Consider using a service account with the Viewer role instead",
+			user.Login,
+		))
+	}
+	if !compat.hasAccessControl {
+		return out
+	}
+
+	if missing := missingPermissions(compat.permissions, readOnlyPermissions); len(missing) > 0 {
+		out = append(out, fmt.Sprintf(
+			"Missing permissions %s, some dashboards or data sources may not be checked",
+			strings.Join(missing, ", "),
+		))
+	}
+
+	// Plugins permissions are needed when using GCOM, don't report them
+	needsGCOMPermissions := compat.angularSource == angularSourceGCOM
+	var excess []string
+	for _, permission := range writePermissions {
+		if _, ok := compat.permissions[permission]; !ok {
+			continue
+		}
+		if needsGCOMPermissions && sliceutil.Contains(gcomPermissions, permission) {
+			continue
+		}
+		excess = append(excess, permission)
+	}
+	if len(excess) > 0 {
+		sort.Strings(excess)
+		out = append(out, fmt.Sprintf(
+			"The token has write permissions %s, which are not needed for a read-only scan. "+
+				"Consider using a service account with the Viewer role instead",
+			strings.Join(excess, ", "),
+		))
+	}
+	return out
+}
+
+// missingPermissions returns the permissions in required that are not in permissions.
+func missingPermissions(permissions map[string][]string, required []string) []string {
+	var out []string
+	for _, permission := range required {
+		if _, ok := permissions[permission]; !ok {
+			out = append(out, permission)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// ScanState records when each dashboard was last scanned, so time-boxed scans can resume
+// from the dashboards that have not been scanned recently.
+type ScanState struct {
+	mu sync.Mutex
+
+	// LastScanned maps dashboard uids to the time they were last scanned, as RFC3339 in UTC.
+	LastScanned map[string]string
+}
+
+// NewScanState returns a new empty ScanState.
+func NewScanState() *ScanState {
+	return &ScanState{LastScanned: map[string]string{}}
+}
+
+// ReadScanState reads the ScanState in the given JSON file.
+// It returns an empty ScanState if the file doesn't exist yet.
+func ReadScanState(fn string) (*ScanState, error) {
+	b, err := os.ReadFile(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewScanState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := NewScanState()
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	if state.LastScanned == nil {
+		state.LastScanned = map[string]string{}
+	}
+	return state, nil
+}
+
+// WriteFile writes the ScanState to the given JSON file.
+func (s *ScanState) WriteFile(fn string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fn, b, 0o644)
+}
+
+// markScanned records that the dashboard with the given uid has been scanned at the given time.
+func (s *ScanState) markScanned(uid string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastScanned[uid] = t.UTC().Format(time.RFC3339)
+}
+
+// prioritize sorts the given dashboards in place, so the ones never scanned come first,
+// followed by the ones scanned least recently. The order is otherwise preserved.
+func (s *ScanState) prioritize(dashboards []grafana.ListedDashboard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.SliceStable(dashboards, func(i, j int) bool {
+		// RFC3339 timestamps in UTC sort lexicographically, and never scanned dashboards have an empty timestamp
+		return s.LastScanned[dashboards[i].UID] < s.LastScanned[dashboards[j].UID]
+	})
+}
+
+// WithMaxDuration returns an Option that makes the Detector stop scanning new dashboards after the given duration,
+// prioritizing the dashboards not scanned recently according to state, which is updated with the scanned dashboards.
+// Huge instances can then be covered across multiple short runs.
+// At least one dashboard is always scanned, so each run makes progress.
+func WithMaxDuration(maxDuration time.Duration, state *ScanState) Option {
+	return func(d *Detector) {
+		d.maxDuration = maxDuration
+		d.scanState = state
+	}
+}
@@ -0,0 +1,43 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/grafana/detect-angular-dashboards/api/gcom"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// setLatestVersion sets the latest version of the plugin in GCOM, and whether it still uses Angular,
+// for the given detections. Legacy panels and text panels are core plugins, so they are not in GCOM and are skipped,
+// as well as plugins with an unknown Angular status.
+func (d *Detector) setLatestVersion(ctx context.Context, detections []output.Detection) {
+	for i, detection := range detections {
+		if detection.DetectionType == output.DetectionTypeLegacyPanel ||
+			detection.DetectionType == output.DetectionTypeTextAngularMode ||
+			detection.DetectionType == output.DetectionTypeUnknown {
+			continue
+		}
+		latest := d.latestPluginVersion(ctx, d.canonicalPluginID(detection.PluginID))
+		if latest == nil {
+			continue
+		}
+		isAngular := latest.AngularDetected
+		detections[i].LatestVersion = latest.Version
+		detections[i].LatestIsAngular = &isAngular
+	}
+}
+
+// latestPluginVersion returns the latest version of the given plugin in GCOM.
+// It returns nil if the plugin is not in GCOM or GCOM can't be reached.
+// Results are cached, so GCOM is queried at most once for each plugin.
+func (d *Detector) latestPluginVersion(ctx context.Context, pluginID string) *gcom.PluginVersion {
+	pv, _ := d.latestVersions.get(pluginID, func() interface{} {
+		pv, err := d.gcomClient.GetLatestVersion(ctx, pluginID)
+		if err != nil {
+			// The severity is computed without the latest version, as for the private plugins
+			d.log.Verbose().Log("(WARNING: could not get latest version of plugin %q from GCOM: %v)", pluginID, err)
+		}
+		return pv
+	}).(*gcom.PluginVersion)
+	return pv
+}
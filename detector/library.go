@@ -0,0 +1,109 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// resolveLibraryPanels replaces the given panels that only reference a library panel with the library panel model,
+// so they can be checked like the other panels. Recent Grafana versions don't include the model in the dashboard.
+// The reference to the library panel is kept, so detections can be attributed to it.
+func (d *Detector) resolveLibraryPanels(ctx context.Context, panels []*grafana.DashboardPanel) {
+	for i, p := range panels {
+		if p.LibraryPanel == nil || p.Type != "" {
+			d.resolveLibraryPanels(ctx, p.Panels)
+			continue
+		}
+		element := d.libraryElement(ctx, p.LibraryPanel.UID)
+		if element == nil || element.Model == nil {
+			continue
+		}
+		model := *element.Model
+		model.LibraryPanel = p.LibraryPanel
+		// The id and position of the panel in the library panel model are not the ones in the dashboard
+		model.ID, model.GridPos = p.ID, p.GridPos
+		if model.Type == "" {
+			model.Type = element.Type
+		}
+		panels[i] = &model
+	}
+}
+
+// libraryElement returns the library panel with the given uid.
+// It returns nil if the library panel can't be fetched.
+// Results are cached, so each library panel is fetched at most once.
+func (d *Detector) libraryElement(ctx context.Context, uid string) *grafana.LibraryElement {
+	element, _ := d.libraryElements.get(uid, func() interface{} {
+		element, err := d.grafanaClient.GetLibraryElement(ctx, uid)
+		if err != nil {
+			// The library panel is not checked, the other panels of the dashboard still are
+			d.log.Verbose().Log("(WARNING: could not get library panel %q: %v)", uid, err)
+		}
+		return element
+	}).(*grafana.LibraryElement)
+	return element
+}
+
+// addLibraryPanelConnections adds the detections of the library panels with detections to all the given
+// dashboards using them, according to the library panel connections, even if the dashboard JSON models
+// don't show them (e.g.: if the dashboard has not been saved since the library panel has been changed).
+func (d *Detector) addLibraryPanelConnections(ctx context.Context, dashboards []output.Dashboard) {
+	// Map library panel uid -> detections
+	libraryPanelDetections := map[string][]output.Detection{}
+	for _, dashboard := range dashboards {
+		for _, detection := range dashboard.Detections {
+			if detection.LibraryPanelUID == "" {
+				continue
+			}
+			if !containsDetection(libraryPanelDetections[detection.LibraryPanelUID], detection) {
+				libraryPanelDetections[detection.LibraryPanelUID] = append(libraryPanelDetections[detection.LibraryPanelUID], detection)
+			}
+		}
+	}
+	if len(libraryPanelDetections) == 0 {
+		return
+	}
+
+	byUID := make(map[string]int, len(dashboards))
+	for i, dashboard := range dashboards {
+		byUID[dashboard.UID] = i
+	}
+	for libraryPanelUID, detections := range libraryPanelDetections {
+		connections, err := d.grafanaClient.GetLibraryElementConnections(ctx, libraryPanelUID)
+		if err != nil {
+			// The dashboards using the library panel are still reported from their JSON model
+			d.log.Verbose().Log("(WARNING: could not get connections of library panel %q: %v)", libraryPanelUID, err)
+			continue
+		}
+		for _, connection := range connections {
+			i, ok := byUID[connection.ConnectionUID]
+			if !ok {
+				continue
+			}
+			for _, detection := range detections {
+				if containsDetection(dashboards[i].Detections, detection) {
+					continue
+				}
+				// Repeats and panel locations are specific to the dashboard where the library panel has been found
+				detection.Repeat, detection.RowRepeat, detection.Row = "", "", ""
+				detection.PanelID, detection.PanelURL, detection.GridPos = 0, "", nil
+				dashboards[i].Detections = append(dashboards[i].Detections, detection)
+			}
+		}
+	}
+}
+
+// containsDetection returns true if detections contains a detection of the same plugin, type and library panel
+// as the given detection.
+func containsDetection(detections []output.Detection, detection output.Detection) bool {
+	for _, other := range detections {
+		if other.LibraryPanelUID == detection.LibraryPanelUID &&
+			other.PluginID == detection.PluginID &&
+			other.DetectionType == detection.DetectionType {
+			return true
+		}
+	}
+	return false
+}
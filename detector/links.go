@@ -0,0 +1,137 @@
+package detector
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// dashboardLinkRegexp matches links to dashboards, either by uid ("/d/<uid>/<slug>") or,
+// for old links, by slug ("/dashboard/db/<slug>").
+var dashboardLinkRegexp = regexp.MustCompile(`/d/([A-Za-z0-9_-]+)|/dashboard/db/([A-Za-z0-9_-]+)`)
+
+// WithLinks returns an Option that makes the Detector resolve the links to other dashboards found in
+// dashboard links, panel links and text panels, to report the dashboards linking to Angular dashboards.
+func WithLinks(checkLinks bool) Option {
+	return func(d *Detector) {
+		d.checkLinks = checkLinks
+	}
+}
+
+// DashboardLink is a link to a dashboard, by uid or by slug.
+type DashboardLink struct {
+	UID  string `json:",omitempty"`
+	Slug string `json:",omitempty"`
+}
+
+// DashboardLinks are the links of a dashboard to other dashboards, with the uid and slug the links to the dashboard use.
+// They are recorded with the results reused by the next runs or by a resumed scan (see WithCacheDir and WithCheckpoint),
+// as the dashboard is not fetched again.
+type DashboardLinks struct {
+	// ModelUID is the uid in the dashboard JSON model, and Slug the slug of the dashboard.
+	ModelUID string `json:",omitempty"`
+	Slug     string `json:",omitempty"`
+
+	// Links are the links to other dashboards in the dashboard.
+	Links []DashboardLink `json:",omitempty"`
+}
+
+// newDashboardLinks returns the DashboardLinks of the given dashboard.
+func newDashboardLinks(dashboardDefinition *grafana.DashboardDefinition) *DashboardLinks {
+	return &DashboardLinks{
+		ModelUID: dashboardDefinition.Dashboard.UID,
+		Slug:     dashboardDefinition.Meta.Slug,
+		Links:    dashboardLinks(&dashboardDefinition.Dashboard),
+	}
+}
+
+// dashboardLinks returns the links to other dashboards found in the dashboard links, panel links and
+// the content of text panels of the given dashboard.
+func dashboardLinks(dashboard *grafana.Dashboard) []DashboardLink {
+	var texts []string
+	for _, l := range dashboard.Links {
+		texts = append(texts, l.URL)
+	}
+	_ = walkPanels(dashboard.Panels, func(p, _ *grafana.DashboardPanel) error {
+		for _, l := range p.Links {
+			texts = append(texts, l.URL)
+		}
+		if p.Type == pluginIDText {
+			texts = append(texts, textPanelContent(p))
+		}
+		return nil
+	})
+
+	var out []DashboardLink
+	for _, text := range texts {
+		for _, m := range dashboardLinkRegexp.FindAllStringSubmatch(text, -1) {
+			out = append(out, DashboardLink{UID: m[1], Slug: m[2]})
+		}
+	}
+	return out
+}
+
+// linkIndex contains the links between dashboards, collected while checking them.
+type linkIndex struct {
+	// links maps the uid of the dashboards to the links they contain.
+	links map[string][]DashboardLink
+
+	// uids maps the uid in the dashboard JSON model and the slug to the uid used by the Detector,
+	// which are different in offline mode.
+	uids  map[string]string
+	slugs map[string]string
+}
+
+func newLinkIndex() *linkIndex {
+	return &linkIndex{
+		links: map[string][]DashboardLink{},
+		uids:  map[string]string{},
+		slugs: map[string]string{},
+	}
+}
+
+// add adds the given links of the dashboard with the given uid to the index. It's not safe for concurrent use.
+func (idx *linkIndex) add(uid string, links *DashboardLinks) {
+	idx.links[uid] = links.Links
+	idx.uids[uid] = uid
+	if links.ModelUID != "" {
+		idx.uids[links.ModelUID] = uid
+	}
+	if links.Slug != "" {
+		idx.slugs[links.Slug] = uid
+	}
+}
+
+// setLinkedAngularDashboards sets the URLs of the linked dashboards that have detections
+// for each one of the given dashboards.
+func (idx *linkIndex) setLinkedAngularDashboards(dashboards []output.Dashboard) {
+	byUID := make(map[string]output.Dashboard, len(dashboards))
+	for _, dashboard := range dashboards {
+		byUID[dashboard.UID] = dashboard
+	}
+	for i, dashboard := range dashboards {
+		seen := map[string]struct{}{}
+		for _, link := range idx.links[dashboard.UID] {
+			uid, ok := idx.uids[link.UID]
+			if link.Slug != "" {
+				uid = idx.slugs[link.Slug]
+			} else if !ok {
+				// The linked dashboard is not indexed (e.g.: its cached result has been recorded without its links),
+				// the uid in the link is the one used by the Detector, except in offline mode
+				uid = link.UID
+			}
+			linked, ok := byUID[uid]
+			if !ok || uid == dashboard.UID || len(linked.Detections) == 0 {
+				continue
+			}
+			if _, ok := seen[linked.URL]; ok {
+				continue
+			}
+			seen[linked.URL] = struct{}{}
+			dashboards[i].LinkedAngularDashboards = append(dashboards[i].LinkedAngularDashboards, linked.URL)
+		}
+		sort.Strings(dashboards[i].LinkedAngularDashboards)
+	}
+}
@@ -0,0 +1,35 @@
+package detector
+
+import "sync"
+
+// lookups caches the results of lookups (e.g.: API requests) by key, computing each of them once.
+// Unlike a map guarded by a mutex held during the lookups, lookups of different keys run concurrently:
+// only the lookups of the same key wait for the first one. The zero value is ready to use.
+type lookups struct {
+	mu      sync.Mutex
+	entries map[string]*lookup
+}
+
+// lookup is the result of the lookup of a key.
+type lookup struct {
+	once  sync.Once
+	value interface{}
+}
+
+// get returns the result of the lookup of the given key, calling fn to compute it if it's the first lookup of the key.
+func (l *lookups) get(key string, fn func() interface{}) interface{} {
+	l.mu.Lock()
+	if l.entries == nil {
+		l.entries = map[string]*lookup{}
+	}
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &lookup{}
+		l.entries[key] = entry
+	}
+	l.mu.Unlock()
+	entry.once.Do(func() {
+		entry.value = fn()
+	})
+	return entry.value
+}
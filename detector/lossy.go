@@ -0,0 +1,261 @@
+package detector
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// lossyOption is a panel configuration that doesn't survive the automatic migration of a panel to React.
+type lossyOption struct {
+	// name is the name of the option, reported in the detection.
+	name string
+
+	// lossy returns true if the panel uses the option.
+	lossy func(p *grafana.DashboardPanel) bool
+}
+
+// lossyMigrationOptions maps the ids of the Angular panels migrated to React automatically by Grafana
+// to the configurations that are dropped or changed by the migration.
+var lossyMigrationOptions = map[string][]lossyOption{
+	// Migrated to timeseries
+	pluginIDGraphOld: {
+		{name: "seriesOverrides.zindex", lossy: hasSeriesOverride("zindex")},
+		{name: "seriesOverrides.hideTooltip", lossy: hasSeriesOverride("hideTooltip")},
+		{name: "thresholds", lossy: hasMixedThresholds},
+		{name: "timeRegions", lossy: hasNonEmptyArray("timeRegions")},
+		{name: "yaxis.align", lossy: hasYAxisAlign},
+	},
+	// Migrated to stat
+	pluginIDSinglestat: {
+		{name: "tableColumn", lossy: hasNonEmptyString("tableColumn")},
+		{name: "valueName", lossy: hasValueName("name")},
+		{name: "colorPrefix", lossy: hasTrue("colorPrefix")},
+		{name: "colorPostfix", lossy: hasTrue("colorPostfix")},
+		{name: "rangeMaps", lossy: hasOpenRangeMaps},
+	},
+	// Migrated to geomap
+	"grafana-worldmap-panel": {
+		{name: "locationData", lossy: hasCustomLocationData},
+		{name: "jsonUrl", lossy: hasNonEmptyString("jsonUrl")},
+	},
+}
+
+// reviewMigrationOptions maps the ids of the Angular panels migrated to React automatically by Grafana
+// to the configurations that are migrated, but whose result may differ, so the migrated panel should be reviewed manually.
+var reviewMigrationOptions = map[string][]lossyOption{
+	// Migrated to stat or gauge
+	pluginIDSinglestat: {
+		{name: "thresholds", lossy: hasUncoloredThresholds},
+		{name: "gauge.thresholds", lossy: hasMismatchedGaugeColors},
+		{name: "valueMaps", lossy: hasInactiveMaps("valueMaps", 2)},
+		{name: "rangeMaps", lossy: hasInactiveMaps("rangeMaps", 1)},
+		{name: "valueName", lossy: hasMathValueName},
+	},
+}
+
+// lossyOptions returns the names of the options of the given panel that don't survive the automatic
+// migration to React, or nil if the panel is not migrated automatically or can be migrated without losses.
+func lossyOptions(p *grafana.DashboardPanel) []string {
+	var out []string
+	for _, option := range lossyMigrationOptions[p.Type] {
+		if option.lossy(p) {
+			out = append(out, option.name)
+		}
+	}
+	return out
+}
+
+// reviewOptions returns the names of the options of the given panel that are migrated to React automatically,
+// but whose result may differ, or nil if the panel is not migrated automatically or needs no manual review.
+func reviewOptions(p *grafana.DashboardPanel) []string {
+	var out []string
+	for _, option := range reviewMigrationOptions[p.Type] {
+		if option.lossy(p) {
+			out = append(out, option.name)
+		}
+	}
+	return out
+}
+
+// unmarshalRaw unmarshals the given top-level field of the panel into v.
+// It returns false if the field is not present or can't be unmarshaled into v.
+func unmarshalRaw(p *grafana.DashboardPanel, field string, v interface{}) bool {
+	raw, ok := p.Raw[field]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// hasSeriesOverride returns a function that checks if a series override of a graph panel sets the given property.
+func hasSeriesOverride(property string) func(p *grafana.DashboardPanel) bool {
+	return func(p *grafana.DashboardPanel) bool {
+		var overrides []map[string]json.RawMessage
+		unmarshalRaw(p, "seriesOverrides", &overrides)
+		for _, override := range overrides {
+			if _, ok := override[property]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// hasMixedThresholds checks if a graph panel has both "gt" and "lt" thresholds,
+// which can't be represented with the thresholds of the timeseries panel.
+func hasMixedThresholds(p *grafana.DashboardPanel) bool {
+	var thresholds []struct {
+		Op string `json:"op"`
+	}
+	unmarshalRaw(p, "thresholds", &thresholds)
+	ops := map[string]struct{}{}
+	for _, threshold := range thresholds {
+		ops[threshold.Op] = struct{}{}
+	}
+	_, gt := ops["gt"]
+	_, lt := ops["lt"]
+	return gt && lt
+}
+
+// hasYAxisAlign checks if a graph panel aligns its left and right y-axes.
+func hasYAxisAlign(p *grafana.DashboardPanel) bool {
+	var yaxis struct {
+		Align bool `json:"align"`
+	}
+	unmarshalRaw(p, "yaxis", &yaxis)
+	return yaxis.Align
+}
+
+// hasOpenRangeMaps checks if a singlestat panel has range mappings without a lower or upper bound.
+func hasOpenRangeMaps(p *grafana.DashboardPanel) bool {
+	var mappingType int
+	if !unmarshalRaw(p, "mappingType", &mappingType) || mappingType != 2 {
+		return false
+	}
+	var rangeMaps []struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	unmarshalRaw(p, "rangeMaps", &rangeMaps)
+	for _, rangeMap := range rangeMaps {
+		if rangeMap.From == "" || rangeMap.From == "null" || rangeMap.To == "" || rangeMap.To == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// singlestatThresholds returns the thresholds and the colors of a singlestat panel.
+func singlestatThresholds(p *grafana.DashboardPanel) (thresholds []string, colors []string) {
+	var rawThresholds string
+	unmarshalRaw(p, "thresholds", &rawThresholds)
+	for _, threshold := range strings.Split(rawThresholds, ",") {
+		if threshold = strings.TrimSpace(threshold); threshold != "" {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	unmarshalRaw(p, "colors", &colors)
+	return thresholds, colors
+}
+
+// singlestatGauge returns true if a singlestat panel is shown as a gauge, which is migrated to the gauge panel.
+func singlestatGauge(p *grafana.DashboardPanel) bool {
+	var gauge struct {
+		Show bool `json:"show"`
+	}
+	unmarshalRaw(p, "gauge", &gauge)
+	return gauge.Show
+}
+
+// hasUncoloredThresholds checks if a singlestat panel has thresholds without coloring the value, the background or a gauge.
+// The thresholds had no visible effect, but the stat panel colors the value with them after the migration.
+func hasUncoloredThresholds(p *grafana.DashboardPanel) bool {
+	if thresholds, _ := singlestatThresholds(p); len(thresholds) == 0 || singlestatGauge(p) {
+		return false
+	}
+	var colorValue, colorBackground bool
+	unmarshalRaw(p, "colorValue", &colorValue)
+	unmarshalRaw(p, "colorBackground", &colorBackground)
+	return !colorValue && !colorBackground
+}
+
+// hasMismatchedGaugeColors checks if a singlestat panel shown as a gauge doesn't have exactly one more color than thresholds.
+// The colors are assigned to the thresholds of the gauge panel in order, so the gauge bands may change color after the migration.
+func hasMismatchedGaugeColors(p *grafana.DashboardPanel) bool {
+	if !singlestatGauge(p) {
+		return false
+	}
+	thresholds, colors := singlestatThresholds(p)
+	return len(thresholds) > 0 && len(colors) != len(thresholds)+1
+}
+
+// hasInactiveMaps returns a function that checks if a singlestat panel has mappings in the given field
+// while mappingType selects the other kind of mappings (1 for value maps, 2 for range maps).
+// The singlestat panel ignored them, but the migration converts both kinds to value mappings.
+func hasInactiveMaps(field string, activeMappingType int) func(p *grafana.DashboardPanel) bool {
+	return func(p *grafana.DashboardPanel) bool {
+		var mappingType int
+		if !unmarshalRaw(p, "mappingType", &mappingType) || mappingType != activeMappingType {
+			return false
+		}
+		var maps []json.RawMessage
+		unmarshalRaw(p, field, &maps)
+		return len(maps) > 0
+	}
+}
+
+// hasMathValueName checks if a singlestat panel reduces the series with "delta" or "diff",
+// which the reducers of the stat panel compute differently around null values and counter resets.
+func hasMathValueName(p *grafana.DashboardPanel) bool {
+	return hasValueName("delta")(p) || hasValueName("diff")(p)
+}
+
+// hasCustomLocationData checks if a worldmap panel loads its locations from an endpoint or from the data,
+// rather than from the built-in lists of locations.
+func hasCustomLocationData(p *grafana.DashboardPanel) bool {
+	var locationData string
+	unmarshalRaw(p, "locationData", &locationData)
+	switch locationData {
+	case "json endpoint", "jsonp endpoint", "table", "json result", "geohash":
+		return true
+	}
+	return false
+}
+
+// hasNonEmptyArray returns a function that checks if the given field is a non-empty array.
+func hasNonEmptyArray(field string) func(p *grafana.DashboardPanel) bool {
+	return func(p *grafana.DashboardPanel) bool {
+		var v []json.RawMessage
+		unmarshalRaw(p, field, &v)
+		return len(v) > 0
+	}
+}
+
+// hasNonEmptyString returns a function that checks if the given field is a non-empty string.
+func hasNonEmptyString(field string) func(p *grafana.DashboardPanel) bool {
+	return func(p *grafana.DashboardPanel) bool {
+		var v string
+		unmarshalRaw(p, field, &v)
+		return v != ""
+	}
+}
+
+// hasTrue returns a function that checks if the given field is true.
+func hasTrue(field string) func(p *grafana.DashboardPanel) bool {
+	return func(p *grafana.DashboardPanel) bool {
+		var v bool
+		unmarshalRaw(p, field, &v)
+		return v
+	}
+}
+
+// hasValueName returns a function that checks if the "valueName" field has the given value.
+func hasValueName(valueName string) func(p *grafana.DashboardPanel) bool {
+	return func(p *grafana.DashboardPanel) bool {
+		var v string
+		unmarshalRaw(p, "valueName", &v)
+		return v == valueName
+	}
+}
@@ -0,0 +1,54 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// migrationTargets maps the ids of well-known Angular plugins to the suggested React plugins to migrate to.
+var migrationTargets = map[string]string{
+	// Legacy panels, migrated automatically by Grafana
+	"graph":      "timeseries",
+	"table-old":  "table",
+	"singlestat": "stat",
+
+	// Panels
+	"briangann-datatable-panel":       "table",
+	"farski-blendstat-panel":          "stat",
+	"grafana-piechart-panel":          "piechart",
+	"grafana-singlestat-panel":        "stat",
+	"grafana-worldmap-panel":          "geomap",
+	"michaeldmoore-annunciator-panel": "stat",
+	"natel-discrete-panel":            "state-timeline",
+	"petrslavotinek-carpetplot-panel": "heatmap",
+	"savantly-heatmap-panel":          "heatmap",
+	"yesoreyeram-boomtable-panel":     "table",
+
+	// Datasources
+	"grafana-simple-json-datasource": "yesoreyeram-infinity-datasource",
+}
+
+// WithMigrationTargets returns an Option that adds the given suggested React replacements for Angular plugins,
+// in addition to the built-in ones. The given replacements take precedence over the built-in ones.
+func WithMigrationTargets(targets map[string]string) Option {
+	return func(d *Detector) {
+		for pluginID, target := range targets {
+			d.migrationTargets[pluginID] = target
+		}
+	}
+}
+
+// ReadMigrationTargets reads the suggested React replacements for Angular plugins from the given JSON file.
+// The file must contain an object mapping Angular plugin ids to React plugin ids.
+func ReadMigrationTargets(fn string) (map[string]string, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]string
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	return out, nil
+}
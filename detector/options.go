@@ -0,0 +1,48 @@
+package detector
+
+import (
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// angularOnlyPanelOptions maps the ids of panel plugins that have been rewritten from Angular to React
+// to the top-level panel fields that are only used by their Angular versions.
+// React versions store their options in the "options" field instead, and migrate the legacy fields
+// on a best-effort basis when the dashboard is loaded.
+var angularOnlyPanelOptions = map[string][]string{
+	"grafana-polystat-panel": {"polystat", "savedComposites", "savedOverrides"},
+	"grafana-clock-panel":    {"clockType", "countdownSettings", "dateSettings", "timeSettings", "timezoneSettings"},
+	"briangann-gauge-panel":  {"gauge"},
+}
+
+// checkLegacyOptions checks if the given panel uses a plugin whose installed version is React,
+// but still contains options that are only used by the Angular version of the plugin.
+// It returns nil if the panel has no legacy options.
+func (d *Detector) checkLegacyOptions(p *grafana.DashboardPanel) *output.Detection {
+	legacyFields, ok := angularOnlyPanelOptions[p.Type]
+	if !ok {
+		return nil
+	}
+	// Only check installed plugins, the panel is already reported if the installed version is Angular
+	if isAngular, installed := d.angularDetected[p.Type]; !installed || isAngular {
+		return nil
+	}
+	var found []string
+	for _, field := range legacyFields {
+		if _, ok := p.Raw[field]; ok {
+			found = append(found, field)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	sort.Strings(found)
+	return &output.Detection{
+		DetectionType: output.DetectionTypeLegacyOptions,
+		PluginID:      p.Type,
+		Title:         p.Title,
+		LegacyOptions: found,
+	}
+}
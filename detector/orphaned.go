@@ -0,0 +1,68 @@
+package detector
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+const (
+	// loginAnonymous is returned by Grafana as CreatedBy/UpdatedBy when the user can't be found,
+	// for example because it has been deleted.
+	loginAnonymous = "Anonymous"
+
+	// serviceAccountLoginPrefix is the prefix of the logins of service accounts, which are not org users.
+	serviceAccountLoginPrefix = "sa-"
+)
+
+// WithOrphaned returns an Option that makes the Detector check if the users that created and last updated
+// each dashboard still exist, to report orphaned dashboards.
+func WithOrphaned(checkOrphaned bool) Option {
+	return func(d *Detector) {
+		d.checkOrphaned = checkOrphaned
+	}
+}
+
+// orgUserLogins returns the logins of the users of the current org.
+// It returns nil if the users can't be listed. Errors are logged and don't cause the detection to fail.
+func (d *Detector) orgUserLogins(ctx context.Context) map[string]struct{} {
+	users, err := d.grafanaClient.GetOrgUsers(ctx)
+	if err != nil {
+		d.log.Warn("Could not get org users: %s", err)
+		return nil
+	}
+	out := make(map[string]struct{}, len(users))
+	for _, u := range users {
+		out[u.Login] = struct{}{}
+	}
+	return out
+}
+
+// isOrphaned returns true if neither the user that created the dashboard nor the one that last updated it
+// still exist, according to the given org user logins.
+// Provisioned dashboards are never orphaned, as they are owned by the provisioning source.
+func isOrphaned(meta grafana.Meta, logins map[string]struct{}) bool {
+	if meta.Provisioned {
+		return false
+	}
+	for _, login := range []string{meta.CreatedBy, meta.UpdatedBy} {
+		if !isDeletedUser(login, logins) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDeletedUser returns true if the given CreatedBy/UpdatedBy login belongs to a user that doesn't exist anymore.
+// Service accounts are not org users, so they are assumed to exist.
+func isDeletedUser(login string, logins map[string]struct{}) bool {
+	if login == loginAnonymous || login == "" {
+		return true
+	}
+	if strings.HasPrefix(login, serviceAccountLoginPrefix) {
+		return false
+	}
+	_, ok := logins[login]
+	return !ok
+}
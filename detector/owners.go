@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// WithOwners returns an Option that makes the Detector resolve the teams owning the folders of the dashboards
+// with detections, from the folder permissions, to build per-team remediation reports.
+func WithOwners(resolveOwners bool) Option {
+	return func(d *Detector) {
+		d.resolveOwners = resolveOwners
+	}
+}
+
+// teamNames returns a map from team id to team name, from the team API.
+// It returns nil if the teams can't be listed, in which case the team names in the permissions are used.
+func (d *Detector) teamNames(ctx context.Context) map[int]string {
+	teams, err := d.grafanaClient.GetTeams(ctx)
+	if err != nil {
+		d.log.Warn("Could not get teams: %s", err)
+		return nil
+	}
+	out := make(map[int]string, len(teams))
+	for _, team := range teams {
+		out[team.ID] = team.Name
+	}
+	return out
+}
+
+// folderOwnerTeams returns the names of the teams with the highest permission on the folder with the given uid, sorted.
+// For example, if some teams are folder admins, only they are returned, otherwise the teams that can edit the folder.
+// Teams that can only view the folder are not owners. Results are cached by folder uid.
+// Errors are logged and don't cause the detection to fail.
+func (d *Detector) folderOwnerTeams(ctx context.Context, folderUID string, teamNames map[int]string) []string {
+	owners, _ := d.folderOwners.get(folderUID, func() interface{} {
+		return d.fetchFolderOwnerTeams(ctx, folderUID, teamNames)
+	}).([]string)
+	return owners
+}
+
+// fetchFolderOwnerTeams returns the owners of the folder with the given uid, without caching. See folderOwnerTeams.
+func (d *Detector) fetchFolderOwnerTeams(ctx context.Context, folderUID string, teamNames map[int]string) []string {
+	permissions, err := d.grafanaClient.GetFolderPermissions(ctx, folderUID)
+	if err != nil {
+		d.log.Warn("Could not get permissions for folder %q: %s", folderUID, err)
+	}
+	owners := map[int][]string{}
+	var maxPermission int
+	for _, p := range permissions {
+		if p.Team == "" && p.TeamID == 0 {
+			continue
+		}
+		name := p.Team
+		if n, ok := teamNames[p.TeamID]; ok {
+			name = n
+		} else if teamNames != nil && p.TeamID != 0 {
+			// Permissions of deleted teams are not cleaned up in old Grafana versions
+			continue
+		}
+		owners[p.Permission] = append(owners[p.Permission], name)
+		if p.Permission > maxPermission {
+			maxPermission = p.Permission
+		}
+	}
+	var out []string
+	if maxPermission >= grafana.PermissionEdit {
+		seen := map[string]struct{}{}
+		for _, name := range owners[maxPermission] {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			out = append(out, name)
+		}
+		sort.Strings(out)
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package detector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// WithPermissions returns an Option that makes the Detector report the users, teams and roles
+// that can edit the dashboards with detections, so the report tells who should fix them.
+func WithPermissions(permissions bool) Option {
+	return func(d *Detector) {
+		d.permissions = permissions
+	}
+}
+
+// dashboardEditors returns the users ("user:<login>"), teams ("team:<team name>") and roles ("role:<role>")
+// with edit or admin permissions on the dashboard with the given uid, sorted.
+func (d *Detector) dashboardEditors(ctx context.Context, uid string) ([]string, error) {
+	permissions, err := d.grafanaClient.GetDashboardPermissions(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{}
+	var out []string
+	for _, p := range permissions {
+		if p.Permission < grafana.PermissionEdit {
+			continue
+		}
+		var editor string
+		switch {
+		case p.UserLogin != "":
+			editor = "user:" + p.UserLogin
+		case p.Team != "":
+			editor = "team:" + p.Team
+		case p.Role != "":
+			editor = "role:" + p.Role
+		default:
+			continue
+		}
+		if _, ok := seen[editor]; ok {
+			continue
+		}
+		seen[editor] = struct{}{}
+		out = append(out, editor)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// PostProcessor is a stage of the post-processing pipeline, run for each checked dashboard after its detections
+// have been found. A stage can filter the dashboard out of the output, enrich it (e.g.: with its owners),
+// or transform its detections (e.g.: map their severity), in place.
+// Stages are run concurrently for different dashboards, so they must be safe for concurrent use.
+type PostProcessor interface {
+	// Name is the name of the stage, used in errors.
+	Name() string
+
+	// Process processes the given dashboard, whose definition is def. It returns false if the dashboard must be
+	// filtered out of the output, in which case the next stages are not run.
+	// def is nil if the cached result of the dashboard is reused without fetching the dashboard (see WithCacheDir):
+	// returning an error then makes the Detector fetch and check the dashboard again.
+	// Returning an error fails the dashboard, like a failed download.
+	Process(ctx context.Context, dashboard *output.Dashboard, def *grafana.DashboardDefinition) (bool, error)
+}
+
+// postProcessorFunc is a PostProcessor implemented by a function.
+type postProcessorFunc struct {
+	name string
+	fn   func(ctx context.Context, dashboard *output.Dashboard, def *grafana.DashboardDefinition) (bool, error)
+}
+
+// NewPostProcessor returns a PostProcessor with the given name, calling fn.
+func NewPostProcessor(
+	name string, fn func(ctx context.Context, dashboard *output.Dashboard, def *grafana.DashboardDefinition) (bool, error),
+) PostProcessor {
+	return postProcessorFunc{name: name, fn: fn}
+}
+
+func (p postProcessorFunc) Name() string {
+	return p.name
+}
+
+func (p postProcessorFunc) Process(ctx context.Context, dashboard *output.Dashboard, def *grafana.DashboardDefinition) (bool, error) {
+	return p.fn(ctx, dashboard, def)
+}
+
+// enrich returns a PostProcessor with the given name that never filters dashboards out and never fails,
+// for the built-in stages that only add information to the dashboards.
+func enrich(name string, fn func(ctx context.Context, dashboard *output.Dashboard)) PostProcessor {
+	return NewPostProcessor(name, func(ctx context.Context, dashboard *output.Dashboard, _ *grafana.DashboardDefinition) (bool, error) {
+		fn(ctx, dashboard)
+		return true, nil
+	})
+}
+
+// WithPostProcessors returns an Option that registers the given post-processors, which are run in order
+// after the built-in ones (see builtinPostProcessors). It can be passed multiple times.
+func WithPostProcessors(processors ...PostProcessor) Option {
+	return func(d *Detector) {
+		d.postProcessors = append(d.postProcessors, processors...)
+	}
+}
+
+// builtinPostProcessors returns the built-in stages of the post-processing pipeline, depending on the options
+// of the Detector. teamNames maps team ids to names, to resolve the folder owners.
+func (d *Detector) builtinPostProcessors(teamNames map[int]string) []PostProcessor {
+	processors := []PostProcessor{
+		enrich("panel-urls", func(_ context.Context, dashboard *output.Dashboard) {
+			d.setPanelURLs(dashboard.URL, dashboard.Detections)
+		}),
+		enrich("latest-version", func(ctx context.Context, dashboard *output.Dashboard) {
+			d.setLatestVersion(ctx, dashboard.Detections)
+		}),
+		enrich("plugin-status", func(ctx context.Context, dashboard *output.Dashboard) {
+			d.setPluginStatus(ctx, dashboard.Detections)
+		}),
+		enrich("severity", func(_ context.Context, dashboard *output.Dashboard) {
+			d.setSeverity(dashboard.Detections)
+		}),
+		enrich("priority", func(_ context.Context, dashboard *output.Dashboard) {
+			if dashboard.Views != nil {
+				p := priority(*dashboard.Views, dashboard.Detections)
+				dashboard.Priority = &p
+			}
+		}),
+	}
+	if d.history {
+		processors = append(processors, enrich("history", func(ctx context.Context, dashboard *output.Dashboard) {
+			if len(dashboard.Detections) == 0 {
+				return
+			}
+			if err := d.setIntroduced(ctx, dashboard.UID, dashboard.Detections); err != nil {
+				// The detections are reported without the versions that introduced them
+				d.log.Warn("Could not get version history for dashboard %q: %s", dashboard.UID, err)
+			}
+		}))
+	}
+	if d.communityRevisions {
+		processors = append(processors, enrich("community-revisions", d.setCommunityRevision))
+	}
+	if d.permissions {
+		processors = append(processors, enrich("permissions", func(ctx context.Context, dashboard *output.Dashboard) {
+			if len(dashboard.Detections) == 0 {
+				return
+			}
+			var err error
+			if dashboard.Editors, err = d.dashboardEditors(ctx, dashboard.UID); err != nil {
+				// The dashboard is reported without its editors
+				d.log.Warn("Could not get permissions for dashboard %q: %s", dashboard.UID, err)
+			}
+		}))
+	}
+	if d.resolveOwners {
+		processors = append(processors, enrich("owners", func(ctx context.Context, dashboard *output.Dashboard) {
+			if len(dashboard.Detections) > 0 && dashboard.FolderUID != "" {
+				dashboard.Owners = d.folderOwnerTeams(ctx, dashboard.FolderUID, teamNames)
+			}
+		}))
+	}
+	return processors
+}
+
+// postProcess runs the given post-processing pipeline on the given dashboard.
+// It returns false if a stage filtered the dashboard out of the output.
+func postProcess(ctx context.Context, pipeline []PostProcessor, dashboard *output.Dashboard, def *grafana.DashboardDefinition) (bool, error) {
+	for _, p := range pipeline {
+		keep, err := p.Process(ctx, dashboard, def)
+		if err != nil {
+			return false, fmt.Errorf("post-processor %q: %w", p.Name(), err)
+		}
+		if !keep {
+			return false, nil
+		}
+	}
+	return true, nil
+}
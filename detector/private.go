@@ -0,0 +1,58 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"regexp"
+)
+
+// angularModulePatterns are the patterns of the module.js of Angular plugins, used to classify private plugins.
+// They are the same as the ones used by Grafana to detect Angular plugins when loading them.
+var angularModulePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`PanelCtrl`),
+	regexp.MustCompile(`ConfigCtrl`),
+	regexp.MustCompile(`app/plugins/sdk`),
+	regexp.MustCompile(`angular\.isNumber\(`),
+	regexp.MustCompile(`editor\.html`),
+	regexp.MustCompile(`ctrl\.annotation`),
+	regexp.MustCompile(`getLegacyAngularInjector`),
+	regexp.MustCompile(`["']QueryCtrl["']`),
+}
+
+// isAngularModule returns true if the given module.js of a plugin looks like the one of an Angular plugin.
+func isAngularModule(module []byte) bool {
+	for _, p := range angularModulePatterns {
+		if p.Match(module) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivatePlugin returns true if the given plugin id matches one of the private plugin patterns of the rules.
+func (d *Detector) isPrivatePlugin(pluginID string) bool {
+	if d.rules == nil {
+		return false
+	}
+	for _, pattern := range d.rules.Private {
+		if ok, _ := path.Match(pattern, pluginID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// angularDetectedFromModule returns the Angular status of the given private plugin, using the heuristics on its
+// module.js. ok is false if the module could not be fetched, in which case the status is unknown.
+func (d *Detector) angularDetectedFromModule(ctx context.Context, pluginID string) (isAngular bool, ok bool) {
+	module, err := d.grafanaClient.GetPluginModule(ctx, pluginID)
+	if err != nil {
+		d.log.Verbose().Log("(WARNING: could not get module of private plugin %q: %v)", pluginID, err)
+		return false, false
+	}
+	if len(bytes.TrimSpace(module)) == 0 {
+		return false, false
+	}
+	return isAngularModule(module), true
+}
@@ -0,0 +1,175 @@
+package detector
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress counts the dashboards fetched and scanned by the current scan, to report the progress of large scans.
+// It's safe for concurrent use.
+type Progress struct {
+	mu sync.Mutex
+
+	started time.Time
+	running bool
+	total   int
+	fetched int
+	scanned int
+	errors  int
+
+	grafanaVersion string
+	angularSource  string
+	lastScan       *LastScanStatus
+}
+
+// ProgressStatus is a snapshot of the Progress of a scan.
+type ProgressStatus struct {
+	// Running is true while the scan is in progress.
+	Running bool
+
+	// Started is the time the scan started, as RFC3339 in UTC, empty if no scan started yet.
+	Started string `json:",omitempty"`
+
+	// Dashboards is the number of dashboards to scan, known once they have been listed.
+	Dashboards int
+
+	// Fetched is the number of dashboards fetched from the API.
+	Fetched int
+
+	// Scanned is the number of dashboards scanned.
+	Scanned int
+
+	// Errors is the number of dashboards that could not be fetched or scanned.
+	Errors int
+
+	// Elapsed is the time since the scan started.
+	Elapsed string
+
+	// ETA is the estimated time until the end of the scan, from the average time per dashboard so far,
+	// empty if it's not known yet or the scan is not running.
+	ETA string `json:",omitempty"`
+
+	// GrafanaVersion is the version of Grafana detected by the last scan, empty if it could not be determined.
+	GrafanaVersion string `json:",omitempty"`
+
+	// AngularSource is the source used by the last scan to find the Angular plugins: "gcom" (Grafana < 10.1.0)
+	// or "frontendsettings", with the field of the frontend settings used.
+	AngularSource string `json:",omitempty"`
+
+	// LastScan is the last finished scan, nil if no scan finished yet.
+	LastScan *LastScanStatus `json:",omitempty"`
+}
+
+// LastScanStatus describes the last finished scan.
+type LastScanStatus struct {
+	// Started and Finished are the times the scan started and finished, as RFC3339 in UTC.
+	Started  string
+	Finished string
+
+	// Duration is the duration of the scan.
+	Duration string
+
+	// Dashboards is the number of dashboards to scan, Scanned the number of dashboards scanned,
+	// and Errors the number of dashboards that could not be fetched or scanned.
+	Dashboards int
+	Scanned    int
+	Errors     int
+
+	// Error is the error that failed the scan, if any.
+	Error string `json:",omitempty"`
+}
+
+// NewProgress returns a new Progress, to pass to WithProgress.
+func NewProgress() *Progress {
+	return &Progress{}
+}
+
+// WithProgress returns an Option that counts the dashboards fetched and scanned by each scan in the given Progress.
+func WithProgress(progress *Progress) Option {
+	return func(d *Detector) {
+		d.progress = progress
+	}
+}
+
+// Status returns a snapshot of the progress of the current scan, or of the last one if no scan is running,
+// with the elapsed time and the ETA computed at the given time.
+func (p *Progress) Status(now time.Time) ProgressStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := ProgressStatus{
+		Running:    p.running,
+		Dashboards: p.total,
+		Fetched:    p.fetched,
+		Scanned:    p.scanned,
+		Errors:     p.errors,
+		Elapsed:    "0s",
+
+		GrafanaVersion: p.grafanaVersion,
+		AngularSource:  p.angularSource,
+	}
+	if p.lastScan != nil {
+		lastScan := *p.lastScan
+		status.LastScan = &lastScan
+	}
+	if p.started.IsZero() {
+		return status
+	}
+	status.Started = p.started.UTC().Format(time.RFC3339)
+	elapsed := now.Sub(p.started)
+	status.Elapsed = elapsed.Round(time.Second).String()
+	done := p.scanned + p.errors
+	if p.running && done > 0 && p.total > done {
+		eta := elapsed / time.Duration(done) * time.Duration(p.total-done)
+		status.ETA = eta.Round(time.Second).String()
+	}
+	return status
+}
+
+// start resets the counters for a new scan started at the given time.
+func (p *Progress) start(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started, p.running = now, true
+	p.total, p.fetched, p.scanned, p.errors = 0, 0, 0, 0
+}
+
+// setTotal sets the number of dashboards to scan.
+func (p *Progress) setTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = n
+}
+
+// add adds the given numbers of dashboards fetched, scanned and failed.
+func (p *Progress) add(fetched, scanned, errors int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetched += fetched
+	p.scanned += scanned
+	p.errors += errors
+}
+
+// setCompatibility sets the Grafana version and the source of the Angular plugins detected by the current scan.
+func (p *Progress) setCompatibility(grafanaVersion, angularSource string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grafanaVersion, p.angularSource = grafanaVersion, angularSource
+}
+
+// finish marks the scan as finished at the given time, and records it as the last scan, failed with err if not nil.
+func (p *Progress) finish(now time.Time, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+	p.lastScan = &LastScanStatus{
+		Started:    p.started.UTC().Format(time.RFC3339),
+		Finished:   now.UTC().Format(time.RFC3339),
+		Duration:   now.Sub(p.started).Round(time.Second).String(),
+		Dashboards: p.total,
+		Scanned:    p.scanned,
+		Errors:     p.errors,
+	}
+	if err != nil {
+		p.lastScan.Error = err.Error()
+	}
+}
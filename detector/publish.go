@@ -0,0 +1,47 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+)
+
+// publishMessage is the version message of the dashboards saved by PublishDashboard.
+const publishMessage = "Update the Angular migration status (detect-angular-dashboards)"
+
+// PublishDashboard saves the given dashboard model (see output.NewStatusDashboard), creating it in the General folder
+// if it doesn't exist yet, or updating it in its folder otherwise.
+func (d *Detector) PublishDashboard(ctx context.Context, dashboard map[string]interface{}) error {
+	uid, _ := dashboard["uid"].(string)
+	var folderUID string
+	raw, err := d.grafanaClient.GetDashboardJSON(ctx, uid)
+	switch {
+	case api.StatusCode(err) == http.StatusNotFound:
+	case err != nil:
+		return fmt.Errorf("get dashboard: %w", err)
+	default:
+		var existing struct {
+			Dashboard struct {
+				ID      int `json:"id"`
+				Version int `json:"version"`
+			} `json:"dashboard"`
+			Meta struct {
+				FolderUID string `json:"folderUid"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return fmt.Errorf("unmarshal dashboard: %w", err)
+		}
+		// The dashboard is not overwritten by SaveDashboard, so update the latest version
+		dashboard["id"] = existing.Dashboard.ID
+		dashboard["version"] = existing.Dashboard.Version
+		folderUID = existing.Meta.FolderUID
+	}
+	if err := d.grafanaClient.SaveDashboard(ctx, dashboard, folderUID, publishMessage); err != nil {
+		return fmt.Errorf("save dashboard: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package detector
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+const (
+	// remapMatchURL is used when the target datasource is the only candidate with the same URL.
+	remapMatchURL = "url"
+
+	// remapMatchOnlyCandidate is used when the target datasource is the only datasource using the replacement plugin.
+	remapMatchOnlyCandidate = "only-candidate"
+)
+
+// DatasourceRemapPlan returns a plan to replace the datasources using Angular plugins with datasources using
+// their suggested React replacement (see WithMigrationTargets), sorted by name.
+// Only datasources whose replacement plugin is used by at least one datasource are returned.
+// The target datasource is the one with the same URL, or the only datasource using the replacement plugin.
+// Otherwise, all the datasources using the replacement plugin are returned as candidates.
+func (d *Detector) DatasourceRemapPlan(ctx context.Context) ([]output.DatasourceRemap, error) {
+	if err := d.loadPlugins(ctx); err != nil {
+		return nil, err
+	}
+	byType := map[string][]grafana.Datasource{}
+	for _, ds := range d.datasources {
+		byType[ds.Type] = append(byType[ds.Type], ds)
+	}
+
+	var out []output.DatasourceRemap
+	for _, ds := range d.datasources {
+		target := d.migrationTargets[ds.Type]
+		if !d.angularDetected[ds.Type] || target == "" || len(byType[target]) == 0 {
+			continue
+		}
+		remap := output.DatasourceRemap{UID: ds.UID, Name: ds.Name, PluginID: ds.Type, TargetPluginID: target}
+		candidates := byType[target]
+		var urlMatches []grafana.Datasource
+		for _, candidate := range candidates {
+			if ds.URL != "" && normalizeDatasourceURL(candidate.URL) == normalizeDatasourceURL(ds.URL) {
+				urlMatches = append(urlMatches, candidate)
+			}
+		}
+		switch {
+		case len(urlMatches) == 1:
+			remap.TargetUID, remap.TargetName, remap.Match = urlMatches[0].UID, urlMatches[0].Name, remapMatchURL
+		case len(candidates) == 1:
+			remap.TargetUID, remap.TargetName, remap.Match = candidates[0].UID, candidates[0].Name, remapMatchOnlyCandidate
+		default:
+			for _, candidate := range candidates {
+				remap.Candidates = append(remap.Candidates, candidate.UID)
+			}
+			sort.Strings(remap.Candidates)
+		}
+		out = append(out, remap)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// normalizeDatasourceURL returns the given datasource URL without trailing slashes, lowercased,
+// so equivalent URLs can be compared.
+func normalizeDatasourceURL(u string) string {
+	return strings.ToLower(strings.TrimRight(u, "/"))
+}
@@ -0,0 +1,120 @@
+package detector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// Rules are custom detection rules, overriding the Angular status of plugins for environments where
+// the built-in knowledge is not accurate (e.g.: private plugins or internal forks of public plugins).
+type Rules struct {
+	// Angular are the ids of the plugins that are always treated as Angular.
+	Angular []string `yaml:"angular"`
+
+	// Ignore are the ids of the plugins that are never reported, whatever the detection type.
+	Ignore []string `yaml:"ignore"`
+
+	// Reclassify maps plugin ids to the id of another plugin they should be treated as,
+	// for the Angular status, the suggested replacement and the information in GCOM
+	// (e.g.: an internal fork of a public plugin, mapped to the public plugin).
+	Reclassify map[string]string `yaml:"reclassify"`
+
+	// Private are glob patterns of the ids of private plugins (e.g.: "mycorp-*"), which are not in GCOM.
+	// They are not looked up in GCOM, their Angular status is determined from their module.js instead,
+	// unless they are listed in Angular or Ignore.
+	Private []string `yaml:"private"`
+}
+
+// ReadRules reads the detection rules from the given YAML file. Unknown fields are rejected, to catch typos.
+func ReadRules(fn string) (*Rules, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var rules Rules
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	return &rules, nil
+}
+
+// lists returns true if the given plugin id is in the Angular or Ignore lists. It can be called on nil Rules.
+func (r *Rules) lists(pluginID string) bool {
+	if r == nil {
+		return false
+	}
+	for _, ids := range [][]string{r.Angular, r.Ignore} {
+		for _, id := range ids {
+			if id == pluginID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithRules returns an Option that makes the Detector apply the given custom detection rules.
+func WithRules(rules *Rules) Option {
+	return func(d *Detector) {
+		d.rules = rules
+	}
+}
+
+// applyRules overrides the Angular status of plugins according to the custom detection rules, if any.
+// It must be called after the Angular status of the plugins has been determined.
+func (d *Detector) applyRules() {
+	if d.rules == nil {
+		return
+	}
+	for pluginID, target := range d.rules.Reclassify {
+		if isAngular, ok := d.angularDetected[target]; ok && !d.angularUnknown[target] {
+			d.angularDetected[pluginID] = isAngular
+			delete(d.angularUnknown, pluginID)
+		}
+	}
+	for _, pluginID := range d.rules.Angular {
+		d.angularDetected[pluginID] = true
+		delete(d.angularUnknown, pluginID)
+	}
+	for _, pluginID := range d.rules.Ignore {
+		d.angularDetected[pluginID] = false
+		delete(d.angularUnknown, pluginID)
+	}
+}
+
+// canonicalPluginID returns the id of the plugin the given plugin is reclassified as, or the given plugin id.
+func (d *Detector) canonicalPluginID(pluginID string) string {
+	if d.rules != nil {
+		if target, ok := d.rules.Reclassify[pluginID]; ok {
+			return target
+		}
+	}
+	return pluginID
+}
+
+// filterIgnored removes the detections of the plugins ignored by the custom detection rules.
+func (d *Detector) filterIgnored(detections []output.Detection) []output.Detection {
+	if d.rules == nil || len(d.rules.Ignore) == 0 {
+		return detections
+	}
+	ignored := make(map[string]struct{}, len(d.rules.Ignore))
+	for _, pluginID := range d.rules.Ignore {
+		ignored[pluginID] = struct{}{}
+	}
+	out := detections[:0]
+	for _, detection := range detections {
+		if _, ok := ignored[detection.PluginID]; !ok {
+			out = append(out, detection)
+		}
+	}
+	return out
+}
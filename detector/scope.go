@@ -0,0 +1,89 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// Scope restricts a detection run to some dashboards (see RunScope).
+type Scope struct {
+	// FolderUIDs are the uids of the folders whose dashboards are checked, including the dashboards of their subfolders.
+	FolderUIDs []string
+
+	// DashboardUIDs are the uids of the dashboards to check.
+	DashboardUIDs []string
+}
+
+// IsEmpty returns true if the scope doesn't restrict the run.
+func (s Scope) IsEmpty() bool {
+	return len(s.FolderUIDs) == 0 && len(s.DashboardUIDs) == 0
+}
+
+// RunScope runs the detection like Run, but only checks the dashboards in the given scope: the dashboards in one of
+// its folders or with one of its uids. As the results are partial, they are not recorded in the checkpoint (see
+// WithCheckpoint) and don't prune the cache (see WithCacheDir), and the dashboards are counted in the given progress
+// (if not nil) instead of the one of WithProgress, so it keeps the status of the complete runs (e.g.: the last scan).
+// The given progress is not used if the scope is empty. Like Run, it must not be called concurrently.
+func (d *Detector) RunScope(ctx context.Context, scope Scope, progress *Progress) ([]output.Dashboard, error) {
+	if scope.IsEmpty() {
+		return d.Run(ctx)
+	}
+	checkpoint, cacheDir, fullProgress := d.checkpoint, d.cacheDir, d.progress
+	d.scope, d.checkpoint, d.cacheDir, d.progress = &scope, nil, "", progress
+	defer func() {
+		d.scope, d.checkpoint, d.cacheDir, d.progress = nil, checkpoint, cacheDir, fullProgress
+	}()
+	return d.Run(ctx)
+}
+
+// filterScope returns the given dashboards in d.scope, or all of them if there's no scope.
+func (d *Detector) filterScope(ctx context.Context, dashboards []grafana.ListedDashboard) ([]grafana.ListedDashboard, error) {
+	if d.scope == nil {
+		return dashboards, nil
+	}
+	uids := make(map[string]struct{}, len(d.scope.DashboardUIDs))
+	for _, uid := range d.scope.DashboardUIDs {
+		uids[uid] = struct{}{}
+	}
+	folderUIDs := map[string]struct{}{}
+	if len(d.scope.FolderUIDs) > 0 {
+		folders, err := d.grafanaClient.GetFolders(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get folders: %w", err)
+		}
+		folderUIDs = subfolderUIDs(folders, d.scope.FolderUIDs)
+	}
+	var out []grafana.ListedDashboard
+	for _, dash := range dashboards {
+		_, uidOK := uids[dash.UID]
+		_, folderOK := folderUIDs[dash.FolderUID]
+		if uidOK || (folderOK && dash.FolderUID != "") {
+			out = append(out, dash)
+		}
+	}
+	return out, nil
+}
+
+// subfolderUIDs returns the given folder uids and the uids of their subfolders, recursively.
+func subfolderUIDs(folders []grafana.Folder, uids []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(uids))
+	for _, uid := range uids {
+		out[uid] = struct{}{}
+	}
+	// Add the subfolders until there are no new ones, as folders are not sorted by depth
+	for added := true; added; {
+		added = false
+		for _, folder := range folders {
+			if _, ok := out[folder.ParentUID]; ok && folder.ParentUID != "" {
+				if _, ok := out[folder.UID]; !ok {
+					out[folder.UID] = struct{}{}
+					added = true
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package detector
+
+import (
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// setSeverity sets the severity of the given detections, which must have been passed to setLatestVersion first:
+//   - legacy panels and legacy options are migrated automatically by Grafana or by the plugin,
+//     unless the panel has options that don't survive the migration
+//   - text panels with Angular templates still work, but may render differently
+//   - plugins with an unknown Angular status must be verified manually
+//   - plugins with a suggested replacement, or whose latest version in GCOM is not Angular, can be replaced
+//   - other plugins have no known replacement
+func (d *Detector) setSeverity(detections []output.Detection) {
+	for i, detection := range detections {
+		switch {
+		case detection.DetectionType == output.DetectionTypeLegacyPanel && len(detection.LossyOptions) == 0,
+			detection.DetectionType == output.DetectionTypeLegacyOptions:
+			detections[i].Severity = output.SeverityAutoMigratable
+		case detection.DetectionType == output.DetectionTypeTextAngularMode:
+			detections[i].Severity = output.SeverityLow
+		case detection.DetectionType == output.DetectionTypeUnknown:
+			detections[i].Severity = output.SeverityUnknown
+		case detection.SuggestedReplacement != "":
+			detections[i].Severity = output.SeverityReplacementAvailable
+		case detection.LatestIsAngular != nil && !*detection.LatestIsAngular:
+			detections[i].Severity = output.SeverityReplacementAvailable
+		default:
+			detections[i].Severity = output.SeverityNoReplacement
+		}
+	}
+}
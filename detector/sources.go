@@ -0,0 +1,168 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/gcom"
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+)
+
+// angularDetectedFromGCOM returns a map from plugin id to its Angular status, using GCOM.
+// Only public plugins are in GCOM, so the status of private plugins (and of versions not in GCOM) is unknown:
+// they are returned in the unknown map instead.
+// Core plugins are checked against the list of core Angular plugins instead.
+func (d *Detector) angularDetectedFromGCOM(
+	ctx context.Context, compat compatibility, frontendSettings *grafana.FrontendSettings,
+) (angular map[string]bool, unknown map[string]bool, err error) {
+	// Double check that the token has the correct permissions, which is "datasources:create".
+	// If we don't have such permissions, the plugins endpoint will still return a valid response,
+	// but it will contain only core plugins:
+	// https://github.com/grafana/grafana/blob/0315b911ef45b4ce9d3d5c182d8b112c6b9b41da/pkg/api/plugins.go#L56
+	if !compat.hasAccessControl {
+		// Do not hard fail if we can't get service account permissions
+		// as we may be running against an old Grafana version without service accounts
+		d.log.Verbose().Log("(WARNING: could not get service account permissions: %v)", compat.accessControlErr)
+		d.log.Verbose().Log("Please make sure that you have created an ADMIN token or the output will be wrong")
+	} else {
+		_, hasDsCreate := compat.permissions["datasources:create"]
+		_, hasPluginsInstall := compat.permissions["plugins:install"]
+		if !hasDsCreate && !hasPluginsInstall {
+			return nil, nil, fmt.Errorf(
+				`the service account does not have "datasources:create" or "plugins:install" permission, ` +
+					"please provide a token for a service account with admin privileges",
+			)
+		}
+	}
+
+	// Get the plugins
+	plugins, err := d.grafanaClient.GetPlugins(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get plugins: %w", err)
+	}
+	out := make(map[string]bool, len(plugins))
+	unknown = map[string]bool{}
+	for _, p := range plugins {
+		if p.Info.Version == "" {
+			continue
+		}
+		if d.isPrivatePlugin(p.ID) {
+			// Don't look up private plugins in GCOM, a plugin with the same id could be published there.
+			// Plugins listed in the rules are classified by applyRules afterwards.
+			if d.rules.lists(p.ID) {
+				continue
+			}
+			isAngular, ok := d.angularDetectedFromModule(ctx, p.ID)
+			if !ok {
+				unknown[p.ID] = true
+				continue
+			}
+			d.log.Verbose().Log("Private plugin %q classified from its module, Angular: %t", p.ID, isAngular)
+			out[p.ID] = isAngular
+			continue
+		}
+		out[p.ID], err = d.gcomClient.GetAngularDetected(ctx, p.ID, p.Info.Version)
+		if errors.Is(err, gcom.ErrNotFound) {
+			// Private plugins and unpublished versions are not in GCOM, don't assume they are not Angular
+			unknown[p.ID] = true
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("get angular detected: %w", err)
+		}
+	}
+
+	// Core plugins are not in GCOM, cross-check the installed ones with the list of core Angular plugins
+	for pluginID := range frontendSettings.Panels {
+		if isCoreAngularPlugin(pluginID, compat.grafanaVersion) {
+			out[pluginID] = true
+		}
+	}
+	for _, ds := range frontendSettings.Datasources {
+		if isCoreAngularPlugin(ds.Type, compat.grafanaVersion) {
+			out[ds.Type] = true
+		}
+	}
+	return out, unknown, nil
+}
+
+// angularDetectedFromFrontendSettings returns a map from plugin id to its Angular status, using frontend settings.
+// It only works with Grafana >= 10.1.0.
+func angularDetectedFromFrontendSettings(frontendSettings *grafana.FrontendSettings) (map[string]bool, error) {
+	out := make(map[string]bool, len(frontendSettings.Panels)+len(frontendSettings.Datasources))
+	for pluginID, panel := range frontendSettings.Panels {
+		v, err := panel.IsAngular()
+		if err != nil {
+			return nil, fmt.Errorf("%q is angular: %w", pluginID, err)
+		}
+		out[pluginID] = v
+	}
+	for _, ds := range frontendSettings.Datasources {
+		v, err := ds.IsAngular()
+		if err != nil {
+			return nil, fmt.Errorf("%q is angular: %w", ds.Type, err)
+		}
+		out[ds.Type] = v
+	}
+	return out, nil
+}
+
+// AngularSourceDiscrepancy is a plugin whose Angular status differs between frontend settings and GCOM.
+type AngularSourceDiscrepancy struct {
+	// PluginID is the id of the plugin.
+	PluginID string
+
+	// FrontendSettings is the Angular status according to frontend settings.
+	FrontendSettings bool
+
+	// GCOM is the Angular status according to GCOM.
+	// It's false for plugins that are not in GCOM, such as private plugins.
+	GCOM bool
+}
+
+// CompareAngularSources determines the Angular status of the installed plugins using both frontend settings
+// and GCOM, and returns the plugins whose status differs, sorted by plugin id.
+// It only works with Grafana >= 10.1.0, as frontend settings don't contain the Angular status on older versions.
+func (d *Detector) CompareAngularSources(ctx context.Context) ([]AngularSourceDiscrepancy, error) {
+	frontendSettings, err := d.grafanaClient.GetFrontendSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get frontend settings: %w", err)
+	}
+	compat := d.probeCompatibility(ctx, frontendSettings)
+	if compat.angularSource == angularSourceGCOM {
+		return nil, fmt.Errorf("frontend settings don't contain the angular status (grafana version %q), upgrade to 10.1.0 or later", compat.grafanaVersion)
+	}
+	fromFrontendSettings, err := angularDetectedFromFrontendSettings(frontendSettings)
+	if err != nil {
+		return nil, fmt.Errorf("frontend settings: %w", err)
+	}
+	fromGCOM, _, err := d.angularDetectedFromGCOM(ctx, compat, frontendSettings)
+	if err != nil {
+		return nil, fmt.Errorf("gcom: %w", err)
+	}
+
+	pluginIDs := make(map[string]struct{}, len(fromFrontendSettings)+len(fromGCOM))
+	for pluginID := range fromFrontendSettings {
+		pluginIDs[pluginID] = struct{}{}
+	}
+	for pluginID := range fromGCOM {
+		pluginIDs[pluginID] = struct{}{}
+	}
+	var out []AngularSourceDiscrepancy
+	for pluginID := range pluginIDs {
+		if fromFrontendSettings[pluginID] == fromGCOM[pluginID] {
+			continue
+		}
+		out = append(out, AngularSourceDiscrepancy{
+			PluginID:         pluginID,
+			FrontendSettings: fromFrontendSettings[pluginID],
+			GCOM:             fromGCOM[pluginID],
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].PluginID < out[j].PluginID
+	})
+	return out, nil
+}
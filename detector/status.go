@@ -0,0 +1,44 @@
+package detector
+
+import (
+	"context"
+
+	"github.com/grafana/detect-angular-dashboards/api/gcom"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// setPluginStatus sets the signature type, deprecation status and last release date of the plugin in GCOM
+// for the given detections, so abandoned plugins can be told apart from maintained ones.
+// Legacy panels and text panels are core plugins, so they are not in GCOM and are skipped,
+// as well as plugins with an unknown Angular status.
+func (d *Detector) setPluginStatus(ctx context.Context, detections []output.Detection) {
+	for i, detection := range detections {
+		if detection.DetectionType == output.DetectionTypeLegacyPanel ||
+			detection.DetectionType == output.DetectionTypeTextAngularMode ||
+			detection.DetectionType == output.DetectionTypeUnknown {
+			continue
+		}
+		plugin := d.gcomPlugin(ctx, d.canonicalPluginID(detection.PluginID))
+		if plugin == nil {
+			continue
+		}
+		detections[i].SignatureType = plugin.SignatureType
+		detections[i].Deprecated = plugin.Status == gcom.PluginStatusDeprecated
+		detections[i].LastRelease, detections[i].LastReleaseDaysAgo = d.normalizeTime(plugin.UpdatedAt)
+	}
+}
+
+// gcomPlugin returns the given plugin in GCOM.
+// It returns nil if the plugin is not in GCOM or GCOM can't be reached.
+// Results are cached, so GCOM is queried at most once for each plugin.
+func (d *Detector) gcomPlugin(ctx context.Context, pluginID string) *gcom.Plugin {
+	plugin, _ := d.gcomPlugins.get(pluginID, func() interface{} {
+		plugin, err := d.gcomClient.GetPlugin(ctx, pluginID)
+		if err != nil {
+			// The detections are reported without the status of the plugin
+			d.log.Verbose().Log("(WARNING: could not get plugin %q from GCOM: %v)", pluginID, err)
+		}
+		return plugin
+	}).(*gcom.Plugin)
+	return plugin
+}
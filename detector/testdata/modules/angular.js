@@ -0,0 +1,5 @@
+define(["app/plugins/sdk"], function (sdk) {
+  class AcmePanelCtrl extends sdk.MetricsPanelCtrl {}
+  AcmePanelCtrl.templateUrl = "partials/module.html";
+  return { PanelCtrl: AcmePanelCtrl };
+});
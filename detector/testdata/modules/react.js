@@ -0,0 +1,3 @@
+define(["@grafana/data", "react"], function (data, react) {
+  return { plugin: new data.PanelPlugin(function () { return null; }) };
+});
@@ -0,0 +1,71 @@
+package detector
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// textPanelModeAngular is the mode of old text panels rendering their content as an Angular template.
+const textPanelModeAngular = "angular"
+
+// angularTemplateRegexp matches Angular directives (e.g.: ng-repeat="...") and expressions (e.g.: {{ ctrl.value }})
+// in the content of text panels.
+var angularTemplateRegexp = regexp.MustCompile(`\bng-[a-z-]+\s*=|\{\{[^}]+\}\}`)
+
+// checkTextPanel checks if the given panel is a text panel in Angular mode, or with Angular templates
+// in its content. Those are not rendered as templates anymore without Angular.
+// It returns nil if the panel is not a text panel, or does not rely on Angular.
+func checkTextPanel(p *grafana.DashboardPanel) *output.Detection {
+	if p.Type != pluginIDText {
+		return nil
+	}
+	if textPanelMode(p) != textPanelModeAngular && !angularTemplateRegexp.MatchString(textPanelContent(p)) {
+		return nil
+	}
+	return &output.Detection{
+		DetectionType: output.DetectionTypeTextAngularMode,
+		PluginID:      p.Type,
+		Title:         p.Title,
+	}
+}
+
+// textPanelMode returns the mode of the given text panel (e.g.: "markdown", "html").
+// Like the content, the mode is in the options for recent versions, and in the "mode" field for old versions.
+func textPanelMode(p *grafana.DashboardPanel) string {
+	var options struct {
+		Mode string `json:"mode"`
+	}
+	if raw, ok := p.Raw["options"]; ok {
+		_ = json.Unmarshal(raw, &options)
+	}
+	if options.Mode != "" {
+		return options.Mode
+	}
+	var mode string
+	if raw, ok := p.Raw["mode"]; ok {
+		_ = json.Unmarshal(raw, &mode)
+	}
+	return mode
+}
+
+// textPanelContent returns the content of the given text panel.
+// The content is in the options for recent versions, and in the "content" field for old versions.
+func textPanelContent(p *grafana.DashboardPanel) string {
+	var options struct {
+		Content string `json:"content"`
+	}
+	if raw, ok := p.Raw["options"]; ok {
+		_ = json.Unmarshal(raw, &options)
+	}
+	if options.Content != "" {
+		return options.Content
+	}
+	var content string
+	if raw, ok := p.Raw["content"]; ok {
+		_ = json.Unmarshal(raw, &content)
+	}
+	return content
+}
@@ -0,0 +1,74 @@
+package detector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithTimezone returns an Option that converts the timestamps in the output to the given location.
+// By default, timestamps keep the offset returned by the Grafana API.
+func WithTimezone(loc *time.Location) Option {
+	return func(d *Detector) {
+		d.location = loc
+	}
+}
+
+// normalizeTime parses the given timestamp returned by the Grafana API and formats it as RFC3339,
+// in d.location if set. It also returns the number of whole days elapsed since the timestamp.
+// If the timestamp can't be parsed or is the zero time, it's returned as-is and the number of days is nil.
+func (d *Detector) normalizeTime(raw string) (string, *int) {
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || t.IsZero() {
+		return raw, nil
+	}
+	if d.location != nil {
+		t = t.In(d.location)
+	}
+	daysAgo := int(d.now().Sub(t).Hours() / 24)
+	return t.Format(time.RFC3339), &daysAgo
+}
+
+// ParseUpdatedSince parses the value of -updated-since, relative to now: either a duration (e.g.: "12h"),
+// which can also be a number of days (e.g.: "7d"), or an RFC3339 timestamp (e.g.: "2024-01-02T15:04:05Z")
+// or date (e.g.: "2024-01-02", in UTC).
+func ParseUpdatedSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(s); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid duration or timestamp %q", s)
+}
+
+// WithUpdatedSince returns an Option that makes the Detector only check the dashboards updated since the given
+// duration ago or timestamp (see ParseUpdatedSince), for incremental reports. Durations are relative to the start
+// of each run, so they keep sliding in server mode. Dashboards without a valid update time are always checked.
+// By default, all dashboards are checked.
+func WithUpdatedSince(updatedSince string) Option {
+	return func(d *Detector) {
+		d.updatedSince = updatedSince
+	}
+}
+
+// updatedBefore returns true if the given timestamp returned by the Grafana API is before since.
+// It returns false if since is zero, or if the timestamp can't be parsed.
+func updatedBefore(raw string, since time.Time) bool {
+	if since.IsZero() {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || t.IsZero() {
+		return false
+	}
+	return t.Before(since)
+}
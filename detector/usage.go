@@ -0,0 +1,62 @@
+package detector
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// WithUsageInsights returns an Option that makes the Detector add the view count and last viewed time
+// of the dashboards to the output, from usage insights (Grafana Enterprise and Cloud).
+func WithUsageInsights(usageInsights bool) Option {
+	return func(d *Detector) {
+		d.usageInsights = usageInsights
+	}
+}
+
+// dashboardViews returns a map from dashboard uid to its usage insights.
+// It returns nil if usage insights are not available. Errors are logged and don't cause the detection to fail.
+func (d *Detector) dashboardViews(ctx context.Context) map[string]grafana.DashboardViews {
+	views, err := d.grafanaClient.GetDashboardViews(ctx)
+	if err != nil {
+		d.log.Warn("Could not get usage insights (only available on Grafana Enterprise and Cloud): %s", err)
+		return nil
+	}
+	out := make(map[string]grafana.DashboardViews, len(views))
+	for _, v := range views {
+		out[v.UID] = v
+	}
+	return out
+}
+
+// sortByViews sorts the given dashboards in place, most viewed first, so they are scanned first.
+// Dashboards without usage insights are scanned last, in their original order.
+func sortByViews(dashboards []grafana.ListedDashboard, views map[string]grafana.DashboardViews) {
+	sort.SliceStable(dashboards, func(i, j int) bool {
+		return views[dashboards[i].UID].Views > views[dashboards[j].UID].Views
+	})
+}
+
+// severityWeights weights the views of a dashboard by the severity of its detections to compute its priority.
+// Detections without a known replacement hurt the most, as fixing them takes the longest.
+var severityWeights = map[output.Severity]int{
+	output.SeverityNoReplacement:        3,
+	output.SeverityReplacementAvailable: 2,
+	output.SeverityAutoMigratable:       1,
+	output.SeverityLow:                  1,
+	output.SeverityUnknown:              1,
+}
+
+// priority returns the priority of a dashboard with the given views and detections:
+// the views weighted by the severity of the worst detection.
+func priority(views int, detections []output.Detection) int {
+	var weight int
+	for _, detection := range detections {
+		if w := severityWeights[detection.Severity]; w > weight {
+			weight = w
+		}
+	}
+	return views * weight
+}
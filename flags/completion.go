@@ -0,0 +1,173 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// programName is the name of the binary the completion scripts are registered for.
+const programName = "detect-angular-dashboards"
+
+// commands are the commands that can be passed as first argument, with their descriptions.
+var commands = map[string]string{
+	CommandVerify:     "check that the given dashboards have no Angular detections",
+	CommandMerge:      "merge multiple JSON reports into one",
+	CommandCompletion: "print the shell completion script for bash, zsh or fish",
+	CommandSimulate:   "run the detection against a directory of recorded API responses, without side effects",
+	CommandFix:        "convert the Angular panels of the dashboards to React and save them",
+	CommandTrend:      "print the detection runs recorded in the trend file",
+}
+
+// fileFlags are the flags whose value is a file path.
+var fileFlags = map[string]struct{}{
+	"uids": {}, "migration-targets": {}, "audit-log": {}, "state-file": {}, "rules-file": {}, "checkpoint-file": {}, "conversions-file": {}, "trend-file": {},
+	"output-file": {}, "notifiers-file": {}, "o": {}, "output": {},
+}
+
+// dirFlags are the flags whose value is a directory path.
+var dirFlags = map[string]struct{}{"dir": {}, "fix-backup-dir": {}, "fix-output-dir": {}, "cache-dir": {}}
+
+// flagValues are the possible values of the flags that only accept a few values.
+var flagValues = map[string][]string{
+	"sort-by": {output.SortByViews, output.SortByPriority},
+	"output-format": {
+		output.FormatText, output.FormatJSON, output.FormatCSV, output.FormatDetectionsCSV, output.FormatHTML, output.FormatXLSX,
+	},
+	"webhook-payload": {output.WebhookPayloadDetections, output.WebhookPayloadSummary, output.WebhookPayloadChanges},
+	"fail-on-severity": {
+		string(output.SeverityLow), string(output.SeverityAutoMigratable), string(output.SeverityUnknown),
+		string(output.SeverityReplacementAvailable), string(output.SeverityNoReplacement),
+	},
+}
+
+// Completion returns the completion script for the given shell ("bash", "zsh" or "fish"),
+// completing the commands and the flags registered in the given FlagSet.
+func Completion(fs *flag.FlagSet, shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(fs), nil
+	case "zsh":
+		// zsh can run bash completion scripts
+		return "autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion(fs), nil
+	case "fish":
+		return fishCompletion(fs), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q, must be bash, zsh or fish", shell)
+}
+
+// bashCompletion returns the bash completion script.
+func bashCompletion(fs *flag.FlagSet) string {
+	var b strings.Builder
+	fn := "_" + strings.ReplaceAll(programName, "-", "_")
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	for _, name := range sortedKeys(flagValues) {
+		fmt.Fprintf(&b, "\t-%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", name, strings.Join(flagValues[name], " "))
+	}
+	for _, name := range sortedKeys(dirFlags) {
+		fmt.Fprintf(&b, "\t-%s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", name)
+	}
+	for _, name := range sortedKeys(fileFlags) {
+		fmt.Fprintf(&b, "\t-%s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", name)
+	}
+	var valueFlags []string
+	for _, f := range sortedFlags(fs) {
+		_, isFile := fileFlags[f.Name]
+		_, isDir := dirFlags[f.Name]
+		if _, ok := flagValues[f.Name]; !ok && !isFile && !isDir && !isBoolFlag(f) {
+			valueFlags = append(valueFlags, "-"+f.Name)
+		}
+	}
+	if len(valueFlags) > 0 {
+		// No suggestions for free-form values
+		fmt.Fprintf(&b, "\t%s) return ;;\n", strings.Join(valueFlags, "|"))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ $COMP_CWORD -eq 1 && \"$cur\" != -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(sortedKeys(commands), " "))
+	b.WriteString("\t\treturn\n\tfi\n")
+	if names := flagNames(fs); len(names) > 0 {
+		b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+		fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+		b.WriteString("\tfi\n")
+	}
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, programName)
+	return b.String()
+}
+
+// fishCompletion returns the fish completion script.
+func fishCompletion(fs *flag.FlagSet) string {
+	var b strings.Builder
+	for _, name := range sortedKeys(commands) {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -f -a %s -d %s\n", programName, name, fishQuote(commands[name]))
+	}
+	for _, f := range sortedFlags(fs) {
+		fmt.Fprintf(&b, "complete -c %s -o %s -d %s", programName, f.Name, fishQuote(firstLine(f.Usage)))
+		_, isFile := fileFlags[f.Name]
+		_, isDir := dirFlags[f.Name]
+		switch {
+		case isBoolFlag(f):
+		case flagValues[f.Name] != nil:
+			fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(flagValues[f.Name], " ")))
+		case isDir:
+			b.WriteString(" -x -a '(__fish_complete_directories)'")
+		case isFile:
+			b.WriteString(" -r -F")
+		default:
+			b.WriteString(" -x")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// isBoolFlag returns true if the given flag doesn't take a value.
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+// sortedFlags returns the flags registered in the given FlagSet, sorted by name.
+func sortedFlags(fs *flag.FlagSet) []*flag.Flag {
+	var out []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		out = append(out, f)
+	})
+	return out
+}
+
+// flagNames returns the names of the flags registered in the given FlagSet, prefixed with "-", sorted.
+func flagNames(fs *flag.FlagSet) []string {
+	var out []string
+	for _, f := range sortedFlags(fs) {
+		out = append(out, "-"+f.Name)
+	}
+	return out
+}
+
+// sortedKeys returns the keys of the given map, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// firstLine returns the first line of the given string.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// fishQuote quotes the given string for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
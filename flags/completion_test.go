@@ -0,0 +1,46 @@
+package flags
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("j", false, "json output")
+	fs.String("sort-by", "", "sort order")
+	fs.String("dir", "", "directory")
+	fs.String("server", "", "listen address")
+
+	t.Run("bash", func(t *testing.T) {
+		script, err := Completion(fs, "bash")
+		require.NoError(t, err)
+		require.Contains(t, script, `-sort-by) COMPREPLY=($(compgen -W "views priority" -- "$cur")); return ;;`)
+		require.Contains(t, script, `-dir) COMPREPLY=($(compgen -d -- "$cur")); return ;;`)
+		require.Contains(t, script, "\t-server) return ;;\n")
+		require.Contains(t, script, `compgen -W "completion fix merge simulate trend verify"`)
+		require.Contains(t, script, `compgen -W "-dir -j -server -sort-by"`)
+		require.Contains(t, script, "complete -o default -F _detect_angular_dashboards detect-angular-dashboards\n")
+	})
+
+	t.Run("zsh", func(t *testing.T) {
+		script, err := Completion(fs, "zsh")
+		require.NoError(t, err)
+		require.Contains(t, script, "bashcompinit")
+	})
+
+	t.Run("fish", func(t *testing.T) {
+		script, err := Completion(fs, "fish")
+		require.NoError(t, err)
+		require.Contains(t, script, "complete -c detect-angular-dashboards -o j -d 'json output'\n")
+		require.Contains(t, script, "complete -c detect-angular-dashboards -o sort-by -d 'sort order' -x -a 'views priority'\n")
+		require.Contains(t, script, "complete -c detect-angular-dashboards -o server -d 'listen address' -x\n")
+	})
+
+	t.Run("unsupported shell", func(t *testing.T) {
+		_, err := Completion(fs, "powershell")
+		require.Error(t, err)
+	})
+}
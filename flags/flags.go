@@ -2,11 +2,76 @@ package flags
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+	"github.com/grafana/detect-angular-dashboards/output"
 )
 
+const (
+	// CommandVerify is the command that checks that the given dashboards have no detections.
+	CommandVerify = "verify"
+
+	// CommandMerge is the command that merges multiple JSON reports into one.
+	CommandMerge = "merge"
+
+	// CommandCompletion is the command that prints the shell completion script.
+	CommandCompletion = "completion"
+
+	// CommandSimulate is the command that runs the detection against a directory of recorded API responses.
+	CommandSimulate = "simulate"
+
+	// CommandTrend is the command that prints the detection runs recorded in the trend file.
+	CommandTrend = "trend"
+
+	// CommandFix is the command that converts the Angular panels of the dashboards to React and saves them.
+	CommandFix = "fix"
+)
+
+// Strings is a flag that can be repeated, collecting all its values.
+type Strings []string
+
+func (s *Strings) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *Strings) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// Threshold is a flag with a non-negative integer value. It's disabled when not passed.
+type Threshold struct {
+	Enabled bool
+	Value   int
+}
+
+func (t *Threshold) String() string {
+	if t == nil || !t.Enabled {
+		return ""
+	}
+	return strconv.Itoa(t.Value)
+}
+
+func (t *Threshold) Set(v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	t.Enabled, t.Value = true, n
+	return nil
+}
+
 // Flags holds the command-line flags.
 type Flags struct {
+	// Command is the optional command passed as first argument (e.g.: "verify", "merge", "completion", "simulate", "fix" or "trend").
+	// It's empty when running the default detection.
+	Command string
+
 	Version        bool
 	Verbose        bool
 	JSONOutput     bool
@@ -14,6 +79,86 @@ type Flags struct {
 	Server         string
 	Interval       time.Duration
 	MaxConcurrency int
+	URLBase        string
+	RelativeURLs   bool
+	History        bool
+	Dir            string
+	DirUseGCOM     bool
+	HomeDashboards bool
+	CompareSources bool
+	Timezone       string
+	UIDsFile       string
+	Census         bool
+
+	MigrationTargetsFile string
+	WebhookURL           string
+	Links                bool
+	AuditLog             string
+	IncludeDeleted       bool
+	Orphaned             bool
+	UsageInsights        bool
+	SortBy               string
+	Permissions          bool
+	ResolveOwners        bool
+	MaxDuration          time.Duration
+	StateFile            string
+	MaxSnapshotAge       time.Duration
+	RemapDatasources     bool
+	RulesFile            string
+	AppPlatform          bool
+	Folders              Strings
+	FolderUIDs           Strings
+	Tags                 Strings
+	UIDs                 Strings
+	ExcludeFolders       Strings
+	ExcludeDashboards    Strings
+	UpdatedSince         string
+	MaxResponseBytes     int64
+	MaxDecodeDepth       int
+	Summary              bool
+	CSVOutput            bool
+	PreviousReport       string
+	GroupBy              string
+	InstancesFile        string
+	InstancesConcurrency int
+	CommunityRevisions   bool
+	CommunityImportDir   string
+	CloudOrg             string
+	DryRun               bool
+	FixBackupDir         string
+	CheckpointFile       string
+	CheckpointMaxAge     time.Duration
+	ConversionsFile      string
+	FixOutputDir         string
+	TrendFile            string
+	FailOnDetections     Threshold
+	FailOnSeverity       string
+	ProgressInterval     time.Duration
+	CacheDir             string
+	ConditionalRequests  bool
+	OutputFormat         string
+	OutputFile           string
+	Annotate             bool
+	PublishDashboard     bool
+	SlackWebhookURL      string
+	SlackOnlyNew         bool
+	TeamsWebhookURL      string
+	TeamsOnlyNew         bool
+	NotifiersFile        string
+	WebhookPayload       string
+	WebhookHeaders       Strings
+	WebhookRetries       int
+	Outputs              Strings
+	SummaryOnly          bool
+	NoColor              bool
+	Quiet                bool
+	Schema               bool
+	CORSOrigins          Strings
+	CORSMethods          Strings
+	KeepRuns             int
+	Cron                 string
+	Jitter               time.Duration
+	NoInitialRun         bool
 }
 
 // Parse parses the command-line flags.
@@ -26,7 +171,103 @@ func Parse() Flags {
 	flag.DurationVar(&flags.Interval, "interval", 5*time.Minute, "detection refresh interval when running in HTTP server mode")
 	flag.StringVar(&flags.Server, "server", "", "Run as HTTP server instead of CLI. Value must be a listen address (e.g.: 0.0.0.0:5000. Output is exposed as JSON at /detections.")
 	flag.IntVar(&flags.MaxConcurrency, "max-concurrency", 10, "maximum number of concurrent dashboard downloads")
-	flag.Parse()
+	flag.StringVar(&flags.URLBase, "url-base", "", "base URL used for dashboard URLs in the output (default: derived from the Grafana API URL)")
+	flag.BoolVar(&flags.RelativeURLs, "relative-urls", false, "output relative dashboard URLs instead of absolute ones")
+	flag.BoolVar(&flags.History, "history", false, "walk the dashboards version history to find when each Angular plugin was introduced")
+	flag.StringVar(&flags.Dir, "dir", "", "scan exported dashboard JSON files in the given directory (recursively) instead of using the Grafana API")
+	flag.BoolVar(&flags.DirUseGCOM, "dir-use-gcom", false, "when using -dir, use GCOM to determine if plugins are Angular instead of the bundled list of Angular plugins")
+	flag.BoolVar(&flags.HomeDashboards, "home-dashboards", false, "check org, team and user preferences to report Angular dashboards configured as home dashboards")
+	flag.BoolVar(&flags.CompareSources, "compare-sources", false, "compare the angular status of plugins from frontend settings and GCOM, and report discrepancies (requires Grafana >= 10.1.0)")
+	flag.StringVar(&flags.Timezone, "timezone", "", `convert timestamps in the output to the given IANA timezone (e.g.: "UTC", "Europe/Rome" or "Local") instead of keeping the offset returned by Grafana`)
+	flag.StringVar(&flags.UIDsFile, "uids", "", "when using the verify command, file containing the uids of the dashboards to verify, one per line")
+	flag.BoolVar(&flags.Census, "census", false, "report the usage of all panel and data source plugins (not only Angular ones) instead of the Angular detections")
+	flag.BoolVar(&flags.RemapDatasources, "remap-datasources", false, "report a plan to replace the data sources using Angular plugins with existing data sources using their suggested React replacement, instead of the Angular detections")
+	flag.StringVar(&flags.MigrationTargetsFile, "migration-targets", "", "JSON file mapping Angular plugin ids to suggested React replacements, in addition to the built-in ones")
+	flag.StringVar(&flags.RulesFile, "rules-file", "", "YAML file with custom detection rules, to force plugin ids as Angular, ignore them or reclassify them as other plugins")
+	flag.BoolVar(&flags.AppPlatform, "app-platform", false, "list and get the dashboards with the App Platform APIs (/apis/dashboard.grafana.app) only. By default, they are used if the legacy APIs are not available")
+	flag.Var(&flags.Folders, "folder", "only check the dashboards in the folder with the given title and its subfolders (can be repeated)")
+	flag.Var(&flags.FolderUIDs, "folder-uid", `only check the dashboards in the folder with the given uid and its subfolders (can be repeated, "general" for the root folder)`)
+	flag.Var(&flags.Tags, "tag", "only check the dashboards with the given tag (can be repeated, dashboards must have all the tags)")
+	flag.Var(&flags.UIDs, "uid", "only check the dashboard with the given uid (can be repeated, not with -uids)")
+	flag.Var(&flags.ExcludeFolders, "exclude-folder", `skip the dashboards in the folders whose title or uid matches the given glob, or regular expression if prefixed with "re:" (can be repeated)`)
+	flag.Var(&flags.ExcludeDashboards, "exclude-dashboard", `skip the dashboards whose title, uid or "folder title/dashboard title" path matches the given glob, or regular expression if prefixed with "re:" (can be repeated)`)
+	flag.StringVar(&flags.UpdatedSince, "updated-since", "", `only check the dashboards updated since the given duration ago (e.g.: "24h" or "7d") or timestamp (e.g.: "2024-01-02" or "2024-01-02T15:04:05Z"), for incremental reports`)
+	flag.Int64Var(&flags.MaxResponseBytes, "max-response-bytes", api.DefaultMaxResponseBytes, "maximum size in bytes of the Grafana API responses, larger responses fail (0 for no limit)")
+	flag.IntVar(&flags.MaxDecodeDepth, "max-decode-depth", api.DefaultMaxDecodeDepth, "maximum nesting depth of the JSON Grafana API responses, deeper responses fail (0 for no limit)")
+	flag.BoolVar(&flags.Summary, "summary", false, `with -j, output an object with the dashboards under "dashboards" and their summary (counts by plugin, detection type and folder) under "summary", instead of the list of dashboards`)
+	flag.BoolVar(&flags.SummaryOnly, "summary-only", false, "only output the summary (number of dashboards scanned and affected, counts by plugin, detection type and folder), without listing the dashboards, with the text and json output formats")
+	flag.BoolVar(&flags.CSVOutput, "csv", false, "csv output, one row per dashboard with detections with its owner, folder, number of detections, highest severity, first seen date (with -history) and status, for tracking the migration")
+	flag.StringVar(&flags.PreviousReport, "previous-report", "", `with CSV output, JSON report (-j) of a previous run, to set the status of the dashboards: "new", "known" or "resolved"`)
+	flag.StringVar(&flags.GroupBy, "group-by", "", `group the dashboards with detections in the readable output: "plugin" by plugin id, "folder" by folder, "creator" by the user that created them, "instance" by Grafana instance (with -instances-file) or "gnet-id" by the grafana.com dashboard they have been imported from, the groups with the most detections first`)
+	flag.StringVar(&flags.InstancesFile, "instances-file", "", "YAML file listing multiple Grafana instances to scan (name, API URL and token environment variable or file), instead of the instance passed as argument")
+	flag.IntVar(&flags.InstancesConcurrency, "instances-concurrency", 1, "maximum number of instances scanned concurrently with -instances-file or -cloud-org")
+	flag.BoolVar(&flags.CommunityRevisions, "community-revisions", false, "for the Angular dashboards imported from grafana.com, check if the latest revision of the community dashboard still uses Angular")
+	flag.StringVar(&flags.CommunityImportDir, "community-import-dir", "", "write the payloads to re-import the latest revisions of grafana.com dashboards without Angular to the given directory (implies -community-revisions)")
+	flag.StringVar(&flags.CloudOrg, "cloud-org", "", "slug of a Grafana Cloud organization whose active stacks are all scanned, instead of the instance passed as argument. Set the GRAFANA_COM_TOKEN env var to a grafana.com token allowed to read the stacks and manage their service accounts")
+	flag.BoolVar(&flags.DryRun, "dry-run", false, "with the fix command, report the panels that would be migrated without saving the dashboards")
+	flag.StringVar(&flags.FixBackupDir, "fix-backup-dir", "fix-backups", "with the fix command, directory where the previous version of each dashboard is written before saving it")
+	flag.StringVar(&flags.ConversionsFile, "conversions-file", "", "with the fix command, YAML file with panel conversions (plugin, target and option translations), in addition to the built-in ones")
+	flag.StringVar(&flags.FixOutputDir, "fix-output-dir", "", "with the fix command, write the converted dashboards to the given directory for review instead of saving them")
+	flag.StringVar(&flags.TrendFile, "trend-file", "", "file recording the detections of each run (a JSON record per line), to report the changes since the previous run and the trend with the trend command")
+	flag.Var(&flags.FailOnDetections, "fail-on-detections", "exit with status code 3 if there are more detections than the given number (0 to fail on any detection), e.g. to block changes introducing Angular panels in CI")
+	flag.StringVar(&flags.FailOnSeverity, "fail-on-severity", "", `with -fail-on-detections, only count the detections with the given severity or a more severe one ("low", "auto-migratable", "unknown", "replacement-available" or "no-replacement")`)
+	flag.DurationVar(&flags.ProgressInterval, "progress-interval", 30*time.Second, "in CLI mode, interval between the logs of the progress of the scan, 0 to disable them")
+	flag.StringVar(&flags.CacheDir, "cache-dir", "", "directory caching the results of the dashboards with their version, to skip the unchanged dashboards in the next runs")
+	flag.BoolVar(&flags.ConditionalRequests, "conditional-requests", false, "in server mode, keep the dashboards in memory and fetch them with conditional requests (ETag and Last-Modified), so unchanged dashboards are not downloaded again")
+	flag.StringVar(&flags.OutputFormat, "output-format", "", `output format: "text" (default), "json" (same as -j), "csv" (same as -csv), "detections-csv" (one CSV row per detection with its dashboard, plugin, type and panel), "html" (self-contained HTML report) or "xlsx" (Excel workbook with a summary sheet and one sheet per folder, written to a file with -o)`)
+	flag.StringVar(&flags.OutputFile, "output-file", "", "deprecated, use -o: file to write the output to instead of stdout")
+	flag.Var(&flags.Outputs, "o", `write an additional output to the given file, in the format of its extension (.txt, .json, .csv, .html or .xlsx) or given as "format=path", e.g. "detections-csv=detections.csv" (can be repeated)`)
+	flag.Var(&flags.Outputs, "output", "alias of -o")
+	flag.BoolVar(&flags.Annotate, "annotate", false, "keep an annotation on each dashboard with detections, listing its Angular plugins, so the viewers of the dashboard see the warning, and delete it once resolved (requires the annotations:read, annotations:create, annotations:write and annotations:delete permissions)")
+	flag.BoolVar(&flags.PublishDashboard, "publish-dashboard", false, `create or update the "Angular Migration Status" dashboard in Grafana, with the affected dashboards, the detections per plugin and the trend of the runs recorded with -trend-file`)
+	flag.StringVar(&flags.SlackWebhookURL, "slack-webhook", "", "URL of a Slack incoming webhook to post a summary of each detection run to, with links to the dashboards with the most detections")
+	flag.BoolVar(&flags.SlackOnlyNew, "slack-only-new", false, "with -slack-webhook, only post the detections that are new since the previous run (requires -trend-file)")
+	flag.StringVar(&flags.TeamsWebhookURL, "teams-webhook", "", "URL of a Microsoft Teams incoming webhook to post a summary of each detection run to, with links to the dashboards with the most detections")
+	flag.BoolVar(&flags.TeamsOnlyNew, "teams-only-new", false, "with -teams-webhook, only post the detections that are new since the previous run (requires -trend-file)")
+	flag.StringVar(&flags.NotifiersFile, "notifiers-file", "", "YAML file with the notifiers to send a summary of each detection run to (Slack and Microsoft Teams incoming webhooks)")
+	flag.BoolVar(&flags.NoColor, "no-color", false, "disable the colors of the readable output in a terminal, also disabled by the NO_COLOR environment variable")
+	flag.BoolVar(&flags.Quiet, "q", false, "quiet output: only print the results and the warnings and errors, without the informational log messages")
+	flag.BoolVar(&flags.Quiet, "quiet", false, "alias of -q")
+	flag.BoolVar(&flags.Schema, "schema", false, "print the JSON Schema of the JSON output and exit")
+	flag.Var(&flags.CORSOrigins, "cors-origin", `in server mode, origin allowed to call the endpoints from a browser (CORS), e.g. "https://ui.example.com", or "*" for any origin (can be repeated)`)
+	flag.Var(&flags.CORSMethods, "cors-method", "in server mode, HTTP method allowed from the origins of -cors-origin, GET and HEAD by default (can be repeated)")
+	flag.IntVar(&flags.KeepRuns, "keep-runs", 10, "in server mode, number of detection runs whose results are kept in memory, for the /history and /diff endpoints (0 to disable them)")
+	flag.StringVar(&flags.Cron, "cron", "", `in server mode, cron expression of the detection runs, e.g. "30 2 * * *" (in the local time zone, see TZ), instead of -interval`)
+	flag.DurationVar(&flags.Jitter, "jitter", 0, "in server mode, maximum random delay added to each scheduled detection run, e.g. 10m")
+	flag.BoolVar(&flags.NoInitialRun, "no-initial-run", false, "in server mode, do not run the detection on startup, wait for the first scheduled run")
+	flag.StringVar(&flags.CheckpointFile, "checkpoint-file", "", "in server mode, JSON file recording the progress of the current scan and the results of the last one, to resume scans and serve the last results after a restart")
+	flag.DurationVar(&flags.CheckpointMaxAge, "checkpoint-max-age", time.Hour, "with -checkpoint-file, maximum age of an interrupted scan to resume it, older ones start over")
+	flag.StringVar(&flags.WebhookURL, "webhook", "", "URL to send the detections to as JSON (POST) after each detection run. Set the WEBHOOK_SECRET env var to sign the payload")
+	flag.StringVar(&flags.WebhookURL, "webhook-url", "", "alias of -webhook")
+	flag.StringVar(&flags.WebhookPayload, "webhook-payload", output.WebhookPayloadDetections, `payload sent to the webhook: "detections" (the dashboards with detections), "summary" (the counts of the summary) or "changes" (the detections new and resolved since the previous run, server mode only)`)
+	flag.Var(&flags.WebhookHeaders, "webhook-header", `additional header sent to the webhook, as "Name: value", e.g. for authentication (can be repeated)`)
+	flag.IntVar(&flags.WebhookRetries, "webhook-retries", 0, "number of times to retry failed webhook requests (network errors, 429 and 5xx status codes), with an exponential backoff from 1s")
+	flag.BoolVar(&flags.Links, "links", false, "resolve links to other dashboards (dashboard links, panel links and text panels) to report dashboards linking to Angular dashboards")
+	flag.StringVar(&flags.AuditLog, "audit-log", "", "append an audit record (identity, flags, target, counts) for each run to the given file, as JSON lines")
+	flag.BoolVar(&flags.IncludeDeleted, "include-deleted", false, "also check the dashboards in the trash (Grafana >= 11), which are marked as deleted in the output")
+	flag.BoolVar(&flags.Orphaned, "orphaned", false, "check if the users that created and last updated each dashboard still exist, to report orphaned dashboards")
+	flag.BoolVar(&flags.UsageInsights, "usage-insights", false, "add the view count and last viewed time of the dashboards from usage insights (Grafana Enterprise and Cloud)")
+	flag.StringVar(&flags.SortBy, "sort-by", "", `sort the dashboards in the output: "views" sorts by number of views, most viewed first, "priority" by views weighted by severity, highest first (both imply -usage-insights)`)
+	flag.BoolVar(&flags.Permissions, "permissions", false, "report the users, teams and roles that can edit each Angular dashboard (directly or through its folder)")
+	flag.BoolVar(&flags.ResolveOwners, "resolve-owners", false, "report the teams owning the folder of each Angular dashboard (the teams with the highest folder permission)")
+	flag.DurationVar(&flags.MaxDuration, "max-duration", 0, "stop scanning new dashboards after the given duration (e.g.: 10m), scanning the ones not scanned recently first (requires -state-file)")
+	flag.DurationVar(&flags.MaxSnapshotAge, "max-snapshot-age", 90*24*time.Hour, "when using -dir, warn if the bundled list of Angular plugins is older than the given duration (0 to disable)")
+	flag.StringVar(&flags.StateFile, "state-file", "", "JSON file recording when each dashboard was last scanned, updated after each run, to resume scans with -max-duration")
+
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == CommandVerify || args[0] == CommandMerge || args[0] == CommandCompletion || args[0] == CommandSimulate || args[0] == CommandFix || args[0] == CommandTrend) {
+		flags.Command = args[0]
+		args = args[1:]
+	}
+	// Parse can only fail with flag.ExitOnError, which exits the program
+	_ = flag.CommandLine.Parse(args)
 
+	// -j and -csv are shorthands for the output formats
+	switch flags.OutputFormat {
+	case output.FormatJSON:
+		flags.JSONOutput = true
+	case output.FormatCSV:
+		flags.CSVOutput = true
+	}
 	return flags
 }
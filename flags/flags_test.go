@@ -0,0 +1,38 @@
+package flags
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		exp  Threshold
+	}{
+		{args: nil, exp: Threshold{}},
+		{args: []string{"-fail-on-detections", "0"}, exp: Threshold{Enabled: true}},
+		{args: []string{"-fail-on-detections", "5"}, exp: Threshold{Enabled: true, Value: 5}},
+		{args: []string{"-fail-on-detections=5"}, exp: Threshold{Enabled: true, Value: 5}},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		var threshold Threshold
+		fs.Var(&threshold, "fail-on-detections", "")
+		require.NoError(t, fs.Parse(append(tc.args, "http://grafana.example.com/api")))
+		require.Equal(t, tc.exp, threshold, "%v", tc.args)
+		require.Equal(t, []string{"http://grafana.example.com/api"}, fs.Args())
+	}
+
+	// The value is required
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&Threshold{}, "fail-on-detections", "")
+	require.Error(t, fs.Parse([]string{"-fail-on-detections", "http://grafana.example.com/api"}))
+
+	var threshold Threshold
+	require.Error(t, threshold.Set("-1"))
+	require.Error(t, threshold.Set("many"))
+}
@@ -0,0 +1,92 @@
+// Package instances reads the list of Grafana instances to scan in one invocation.
+package instances
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Instance is a Grafana instance to scan.
+type Instance struct {
+	// Name identifies the instance in the output. It must be unique.
+	Name string `yaml:"name"`
+
+	// URL is the URL of the Grafana API of the instance (e.g.: "https://grafana.example.com/api").
+	URL string `yaml:"url"`
+
+	// TokenEnv is the name of the environment variable containing the API token of the instance.
+	TokenEnv string `yaml:"tokenEnv"`
+
+	// TokenFile is the path of a file containing the API token of the instance, if TokenEnv is not set.
+	TokenFile string `yaml:"tokenFile"`
+
+	// URLBase is the base URL of the dashboard URLs in the output, like -url-base. It's derived from URL if empty.
+	URLBase string `yaml:"urlBase"`
+}
+
+// config is the content of the instances file.
+type config struct {
+	Instances []Instance `yaml:"instances"`
+}
+
+// ReadFile reads the instances in the given YAML file. Unknown fields are rejected, to catch typos.
+// The tokens are not read, so they are not kept in memory longer than needed, see Instance.Token.
+func ReadFile(fn string) ([]Instance, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	if len(cfg.Instances) == 0 {
+		return nil, fmt.Errorf("no instances in %q", fn)
+	}
+	names := make(map[string]struct{}, len(cfg.Instances))
+	for i, instance := range cfg.Instances {
+		switch {
+		case instance.Name == "":
+			return nil, fmt.Errorf("instance %d: missing name", i)
+		case instance.URL == "":
+			return nil, fmt.Errorf("instance %q: missing url", instance.Name)
+		case instance.TokenEnv == "" && instance.TokenFile == "":
+			return nil, fmt.Errorf("instance %q: missing tokenEnv or tokenFile", instance.Name)
+		case instance.TokenEnv != "" && instance.TokenFile != "":
+			return nil, fmt.Errorf("instance %q: tokenEnv and tokenFile are mutually exclusive", instance.Name)
+		}
+		if _, ok := names[instance.Name]; ok {
+			return nil, fmt.Errorf("instance %q: duplicate name", instance.Name)
+		}
+		names[instance.Name] = struct{}{}
+	}
+	return cfg.Instances, nil
+}
+
+// Token returns the API token of the instance, from its environment variable or its file.
+func (i Instance) Token() (string, error) {
+	if i.TokenEnv != "" {
+		token := os.Getenv(i.TokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("environment variable %s is not set", i.TokenEnv)
+		}
+		return token, nil
+	}
+	b, err := os.ReadFile(i.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %q is empty", i.TokenFile)
+	}
+	return token, nil
+}
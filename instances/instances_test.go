@@ -0,0 +1,72 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		fn := filepath.Join(t.TempDir(), "instances.yaml")
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0o600))
+		return fn
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		instances, err := ReadFile(write(t, `
+instances:
+  - name: prod
+    url: https://grafana-prod.example.com/api
+    tokenEnv: GRAFANA_TOKEN_PROD
+    urlBase: https://grafana.example.com
+  - name: dev
+    url: https://grafana-dev.example.com/api
+    tokenFile: /run/secrets/grafana-dev
+`))
+		require.NoError(t, err)
+		require.Equal(t, []Instance{
+			{Name: "prod", URL: "https://grafana-prod.example.com/api", TokenEnv: "GRAFANA_TOKEN_PROD", URLBase: "https://grafana.example.com"},
+			{Name: "dev", URL: "https://grafana-dev.example.com/api", TokenFile: "/run/secrets/grafana-dev"},
+		}, instances)
+	})
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		expErr  string
+	}{
+		{name: "empty", content: "", expErr: "no instances"},
+		{name: "unknown field", content: "instances:\n  - name: a\n    uri: x\n", expErr: "field uri not found"},
+		{name: "missing url", content: "instances:\n  - name: a\n    tokenEnv: T\n", expErr: `instance "a": missing url`},
+		{name: "missing token", content: "instances:\n  - name: a\n    url: x\n", expErr: "missing tokenEnv or tokenFile"},
+		{
+			name:    "duplicate",
+			content: "instances:\n  - {name: a, url: x, tokenEnv: T}\n  - {name: a, url: y, tokenEnv: T}\n",
+			expErr:  `instance "a": duplicate name`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadFile(write(t, tc.content))
+			require.ErrorContains(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	t.Setenv("TEST_GRAFANA_TOKEN", "glsa_env")
+	token, err := Instance{TokenEnv: "TEST_GRAFANA_TOKEN"}.Token()
+	require.NoError(t, err)
+	require.Equal(t, "glsa_env", token)
+
+	_, err = Instance{TokenEnv: "TEST_GRAFANA_TOKEN_MISSING"}.Token()
+	require.Error(t, err)
+
+	fn := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(fn, []byte("glsa_file\n"), 0o600))
+	token, err = Instance{TokenFile: fn}.Token()
+	require.NoError(t, err)
+	require.Equal(t, "glsa_file", token)
+}
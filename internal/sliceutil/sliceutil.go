@@ -0,0 +1,13 @@
+// Package sliceutil contains the helpers on slices shared by the packages of the module,
+// which are not in the standard library of the Go version the module targets.
+package sliceutil
+
+// Contains returns true if s contains v, like slices.Contains in Go >= 1.21.
+func Contains[T comparable](s []T, v T) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
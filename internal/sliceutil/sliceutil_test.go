@@ -0,0 +1,14 @@
+package sliceutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContains(t *testing.T) {
+	require.True(t, Contains([]string{"a", "b"}, "b"))
+	require.False(t, Contains([]string{"a", "b"}, "c"))
+	require.False(t, Contains(nil, "a"))
+	require.True(t, Contains([]int{1, 2}, 1))
+}
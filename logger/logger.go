@@ -1,8 +1,19 @@
 package logger
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
+)
+
+// Components of the program, used as prefixes by the child loggers returned by LeveledLogger.WithComponent.
+const (
+	ComponentDetector   = "detector"
+	ComponentGrafanaAPI = "grafana-api"
+	ComponentGCOM       = "gcom"
+	ComponentServer     = "server"
+	ComponentNotifier   = "notifier"
 )
 
 type Logger interface {
@@ -24,9 +35,18 @@ func NewNopLogger() Logger {
 	return &nopLogger{}
 }
 
+// LeveledLogger is a Logger writing info messages to Logger, warnings to WarnLogger and errors to ErrorLogger.
+// It is safe for concurrent use: each message is written with a single call to the underlying log.Logger,
+// so messages from concurrent goroutines are never interleaved.
 type LeveledLogger struct {
 	isVerbose bool
 
+	// component is the component the messages are logged by, if any (e.g.: "detector" or "server/notifier").
+	component string
+
+	// fields are the "key=value" pairs added to each message.
+	fields []string
+
 	Logger      *log.Logger
 	WarnLogger  *log.Logger
 	ErrorLogger *log.Logger
@@ -41,20 +61,52 @@ func NewLeveledLogger(verbose bool) *LeveledLogger {
 	}
 }
 
+// WithComponent returns a child logger that prefixes the messages with the given component (e.g.: "[detector]").
+// The component of a child of a child logger is nested in the component of its parent (e.g.: "[server/notifier]").
+// Child loggers share the writers of their parent, so changing the output of the parent also changes theirs.
+func (l *LeveledLogger) WithComponent(component string) *LeveledLogger {
+	child := *l
+	if l.component != "" {
+		component = l.component + "/" + component
+	}
+	child.component = component
+	return &child
+}
+
+// With returns a child logger that adds the given key and value to the messages (e.g.: "dashboard=abc"),
+// after the component prefix, to attribute the messages logged by concurrent workers.
+func (l *LeveledLogger) With(key string, value any) *LeveledLogger {
+	child := *l
+	child.fields = append(l.fields[:len(l.fields):len(l.fields)], fmt.Sprintf("%s=%v", key, value))
+	return &child
+}
+
+// format formats the given message, prefixed by the component and the fields of the logger.
+func (l *LeveledLogger) format(format string, v ...any) string {
+	msg := fmt.Sprintf(format, v...)
+	if len(l.fields) > 0 {
+		msg = strings.Join(l.fields, " ") + " " + msg
+	}
+	if l.component != "" {
+		msg = "[" + l.component + "] " + msg
+	}
+	return msg
+}
+
 func (l *LeveledLogger) Log(format string, v ...any) {
-	l.Logger.Printf(format, v...)
+	l.Logger.Print(l.format(format, v...))
 }
 
 func (l *LeveledLogger) Warn(format string, v ...any) {
-	l.WarnLogger.Printf(format, v...)
+	l.WarnLogger.Print(l.format(format, v...))
 }
 
 func (l *LeveledLogger) Error(format string, v ...any) {
-	l.ErrorLogger.Printf(format, v...)
+	l.ErrorLogger.Print(l.format(format, v...))
 }
 
 func (l *LeveledLogger) Errorf(format string, v ...any) {
-	l.ErrorLogger.Printf(format, v...)
+	l.ErrorLogger.Print(l.format(format, v...))
 }
 
 func (l *LeveledLogger) Verbose() Logger {
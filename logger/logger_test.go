@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(buf *bytes.Buffer) *LeveledLogger {
+	l := NewLeveledLogger(false)
+	l.Logger = log.New(buf, "INFO: ", 0)
+	l.WarnLogger = log.New(buf, "WARN: ", 0)
+	l.ErrorLogger = log.New(buf, "ERROR: ", 0)
+	return l
+}
+
+func TestLeveledLogger(t *testing.T) {
+	t.Run("prefixes", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := newTestLogger(&buf)
+		l.Log("root")
+		server := l.WithComponent(ComponentServer)
+		server.Warn("server %d", 1)
+		server.WithComponent(ComponentNotifier).With("url", "http://example.com").Errorf("notifier\n")
+		detector := l.WithComponent(ComponentDetector).With("dashboard", "abc")
+		detector.With("panel", 2).Log("detector %q", "100%")
+		detector.Log("parent fields are not changed")
+		require.Equal(t, strings.Join([]string{
+			`INFO: root`,
+			`WARN: [server] server 1`,
+			`ERROR: [server/notifier] url=http://example.com notifier`,
+			`INFO: [detector] dashboard=abc panel=2 detector "100%"`,
+			`INFO: [detector] dashboard=abc parent fields are not changed`,
+		}, "\n")+"\n", buf.String())
+	})
+
+	t.Run("verbose", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := newTestLogger(&buf)
+		l.WithComponent(ComponentGCOM).Verbose().Log("hidden")
+		require.Empty(t, buf.String())
+		l.isVerbose = true
+		l.WithComponent(ComponentGCOM).Verbose().Log("shown")
+		require.Equal(t, "INFO: [gcom] shown\n", buf.String())
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := newTestLogger(&buf)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				worker := l.WithComponent(ComponentDetector).With("worker", i)
+				for j := 0; j < 100; j++ {
+					worker.Log("message %d", j)
+				}
+			}(i)
+		}
+		wg.Wait()
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 1000)
+		for _, line := range lines {
+			require.Regexp(t, `^INFO: \[detector\] worker=\d message \d+$`, line)
+		}
+	})
+}
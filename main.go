@@ -2,184 +2,1593 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/grafana/detect-angular-dashboards/api"
+	"github.com/grafana/detect-angular-dashboards/api/fixtures"
 	"github.com/grafana/detect-angular-dashboards/api/gcom"
 	"github.com/grafana/detect-angular-dashboards/api/grafana"
+	"github.com/grafana/detect-angular-dashboards/api/offline"
+	"github.com/grafana/detect-angular-dashboards/audit"
 	"github.com/grafana/detect-angular-dashboards/build"
 	"github.com/grafana/detect-angular-dashboards/detector"
 	"github.com/grafana/detect-angular-dashboards/flags"
+	"github.com/grafana/detect-angular-dashboards/instances"
 	"github.com/grafana/detect-angular-dashboards/logger"
+	"github.com/grafana/detect-angular-dashboards/notify"
 	"github.com/grafana/detect-angular-dashboards/output"
+	"github.com/grafana/detect-angular-dashboards/schedule"
+	"github.com/grafana/detect-angular-dashboards/server"
+	"github.com/grafana/detect-angular-dashboards/store"
 )
 
-const envGrafana = "GRAFANA_TOKEN"
+const (
+	envGrafana       = "GRAFANA_TOKEN"
+	envGrafanaFile   = "GRAFANA_TOKEN_FILE"
+	envGrafanaCom    = "GRAFANA_COM_TOKEN"
+	envWebhookSecret = "WEBHOOK_SECRET"
+	// envScanToken was the token of the /scan endpoints, which now require the credentials of the other endpoints.
+	envScanToken   = "SCAN_TOKEN"
+	envServerToken = "SERVER_TOKEN"
+	envServerBasic = "SERVER_BASIC_AUTH"
+)
+
+func main() {
+	f := flags.Parse()
+
+	if f.Version {
+		fmt.Printf("%s %s (%s)\n", os.Args[0], build.LinkerVersion, build.LinkerCommitSHA)
+		if snapshot, err := offline.BundledAngularPluginsSnapshot(); err == nil {
+			fmt.Printf("Bundled Angular plugins snapshot: %s\n", snapshot)
+		}
+		os.Exit(0)
+	}
+	if f.Schema {
+		os.Stdout.Write(output.JSONSchema)
+		os.Exit(0)
+	}
+	// Merge JSON reports always outputs JSON
+	log := newLogger(f.Verbose, f.Quiet, f.JSONOutput || f.CSVOutput || f.OutputFormat == output.FormatDetectionsCSV || f.OutputFormat == output.FormatHTML || f.Command == flags.CommandMerge)
+
+	if f.Command == flags.CommandCompletion {
+		script, err := flags.Completion(flag.CommandLine, flag.Arg(0))
+		if err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(script)
+		return
+	}
+
+	if f.Command == flags.CommandMerge {
+		if err := runMergeMode(log, flag.Args()); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.Command == flags.CommandTrend {
+		if err := runTrendMode(&f, log); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.TrendFile != "" {
+		// Fail early if the trend file can't be opened, rather than after a scan
+		s, err := store.Open(f.TrendFile)
+		if err != nil {
+			log.Errorf("Failed to open trend file: %s\n", err.Error())
+			os.Exit(1)
+		}
+		s.Close()
+	}
+
+	var client detector.GrafanaDetectorAPIClient
+	var instanceList []instances.Instance
+	var tokens tokenSource
+	// credentials are the credentials of the Grafana API client, re-read on reload in server mode
+	var credentials *api.Credentials
+	gcomClient := newGCOMClient(log)
+	if f.Command == flags.CommandSimulate {
+		var fixturesServer *fixtures.Server
+		var err error
+		client, gcomClient, fixturesServer, err = initializeFixturesClients(flag.Arg(0), log)
+		if err != nil {
+			log.Errorf("Failed to serve fixtures: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer fixturesServer.Close()
+	} else if f.InstancesFile != "" {
+		// The clients are initialized for each instance
+		var err error
+		instanceList, err = instances.ReadFile(f.InstancesFile)
+		if err != nil {
+			log.Errorf("Failed to read instances: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if err := validateInstancesFlags(&f, "-instances-file"); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		tokens = instanceTokens
+	} else if f.CloudOrg != "" {
+		if err := validateInstancesFlags(&f, "-cloud-org"); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		cloudClient, err := newCloudClient(log)
+		if err != nil {
+			log.Errorf("Failed to initialize the grafana.com API client: %s\n", err.Error())
+			os.Exit(1)
+		}
+		instanceList, err = discoverStacks(cloudClient, f.CloudOrg, log)
+		if err != nil {
+			log.Errorf("Failed to discover the stacks of Cloud organization %q: %s\n", f.CloudOrg, err.Error())
+			os.Exit(1)
+		}
+		tokens = stackTokens(cloudClient, log)
+	} else if f.Dir != "" {
+		client = initializeOfflineClient(&f, log)
+		if !f.DirUseGCOM {
+			checkSnapshotAge(&f, log)
+		}
+	} else {
+		token, err := getToken()
+		if err != nil {
+			log.Errorf("Failed to retrieve Grafana token: %s\n", err.Error())
+			os.Exit(1)
+		}
+		credentials = api.NewCredentials(token)
+		client, err = initializeClient(grafanaURL(), credentials, &f, log)
+		if err != nil {
+			log.Errorf("Failed to initialize the Grafana API client: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var location *time.Location
+	if f.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(f.Timezone)
+		if err != nil {
+			log.Errorf("Invalid timezone %q: %s\n", f.Timezone, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if err := output.ValidateSortBy(f.SortBy); err != nil {
+		log.Errorf("Invalid -sort-by: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if err := output.ValidateSeverity(f.FailOnSeverity); err != nil {
+		log.Errorf("Invalid -fail-on-severity: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if err := output.ValidateFormat(f.OutputFormat); err != nil {
+		log.Errorf("Invalid -output-format: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if f.OutputFile != "" {
+		log.Warn("Flag -output-file is deprecated, use -o instead")
+	}
+
+	if err := output.ValidateGroupBy(f.GroupBy); err != nil {
+		log.Errorf("Invalid -group-by: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if f.Quiet && f.Verbose {
+		log.Errorf("Flags -q and -v are mutually exclusive\n")
+		os.Exit(1)
+	}
+
+	if len(f.UIDs) > 0 && f.UIDsFile != "" {
+		log.Errorf("Flags -uid and -uids are mutually exclusive\n")
+		os.Exit(1)
+	}
+
+	uids := f.UIDs
+	if f.Command == flags.CommandVerify {
+		var err error
+		uids, err = readUIDs(f.UIDsFile)
+		if err != nil {
+			log.Errorf("Failed to read dashboard uids: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var migrationTargets map[string]string
+	if f.MigrationTargetsFile != "" {
+		var err error
+		migrationTargets, err = detector.ReadMigrationTargets(f.MigrationTargetsFile)
+		if err != nil {
+			log.Errorf("Failed to read migration targets: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var rules *detector.Rules
+	if f.RulesFile != "" {
+		var err error
+		rules, err = detector.ReadRules(f.RulesFile)
+		if err != nil {
+			log.Errorf("Failed to read rules: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if f.UpdatedSince != "" {
+		if _, err := detector.ParseUpdatedSince(f.UpdatedSince, time.Now()); err != nil {
+			log.Errorf("Invalid -updated-since: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var exclusions *detector.Exclusions
+	if len(f.ExcludeFolders) > 0 || len(f.ExcludeDashboards) > 0 {
+		var err error
+		exclusions, err = detector.NewExclusions(f.ExcludeFolders, f.ExcludeDashboards)
+		if err != nil {
+			log.Errorf("Invalid exclusion pattern: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var scanState *detector.ScanState
+	if f.StateFile != "" {
+		var err error
+		scanState, err = detector.ReadScanState(f.StateFile)
+		if err != nil {
+			log.Errorf("Failed to read scan state: %s\n", err.Error())
+			os.Exit(1)
+		}
+	} else if f.MaxDuration > 0 {
+		log.Errorf("Flag -max-duration requires -state-file\n")
+		os.Exit(1)
+	}
+
+	if f.Annotate && (f.Server != "" || f.Dir != "" || f.Command != "") {
+		log.Errorf("Flag -annotate only works in CLI mode against a Grafana instance\n")
+		os.Exit(1)
+	}
+
+	if f.PublishDashboard && (f.Server != "" || f.Dir != "" || f.Command != "") {
+		log.Errorf("Flag -publish-dashboard only works in CLI mode against a Grafana instance\n")
+		os.Exit(1)
+	}
+
+	if _, err := newWebhookOutputter(&f); err != nil {
+		log.Errorf("Invalid webhook flags: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if f.WebhookPayload == output.WebhookPayloadChanges && f.Server == "" {
+		log.Errorf("Flag -webhook-payload %s only works in server mode\n", output.WebhookPayloadChanges)
+		os.Exit(1)
+	}
+
+	notifiers, err := newNotifiers(&f)
+	if err != nil {
+		log.Errorf("Invalid notifiers: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if (len(f.CORSOrigins) > 0 || len(f.CORSMethods) > 0) && f.Server == "" {
+		log.Errorf("Flags -cors-origin and -cors-method only work in server mode\n")
+		os.Exit(1)
+	}
+	if f.KeepRuns < 0 {
+		log.Errorf("Flag -keep-runs must not be negative\n")
+		os.Exit(1)
+	}
+	if len(f.CORSMethods) > 0 && len(f.CORSOrigins) == 0 {
+		log.Errorf("Flag -cors-method requires -cors-origin\n")
+		os.Exit(1)
+	}
+
+	if (f.Cron != "" || f.Jitter != 0 || f.NoInitialRun) && f.Server == "" {
+		log.Errorf("Flags -cron, -jitter and -no-initial-run only work in server mode\n")
+		os.Exit(1)
+	}
+	if f.Jitter < 0 {
+		log.Errorf("Flag -jitter must not be negative\n")
+		os.Exit(1)
+	}
+	if f.Server != "" {
+		if _, err := newSchedule(&f); err != nil {
+			log.Errorf("Invalid schedule: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if f.ConditionalRequests && f.Server == "" {
+		log.Errorf("Flag -conditional-requests only works in server mode\n")
+		os.Exit(1)
+	}
+
+	var checkpoint *detector.Checkpoint
+	if f.CheckpointFile != "" {
+		if f.Server == "" {
+			log.Errorf("Flag -checkpoint-file only works in server mode\n")
+			os.Exit(1)
+		}
+		var err error
+		checkpoint, err = detector.ReadCheckpoint(f.CheckpointFile, f.CheckpointMaxAge)
+		if err != nil {
+			log.Errorf("Failed to read checkpoint: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var conversions []detector.PanelConverter
+	if f.ConversionsFile != "" {
+		var err error
+		conversions, err = detector.ReadConversions(f.ConversionsFile)
+		if err != nil {
+			log.Errorf("Failed to read conversions: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// The options shared by all the instances, see runInstance for the options of each instance
+	opts := []detector.Option{
+		// The fixtures server listens on a random port, do not leak it in the URLs
+		detector.WithRelativeURLs(f.RelativeURLs || (f.Command == flags.CommandSimulate && f.URLBase == "")),
+		detector.WithHistory(f.History),
+		detector.WithHomeDashboards(f.HomeDashboards),
+		detector.WithTimezone(location),
+		detector.WithDashboardUIDs(uids),
+		detector.WithFilters(detector.Filters{Folders: f.Folders, FolderUIDs: f.FolderUIDs, Tags: f.Tags}),
+		detector.WithMigrationTargets(migrationTargets),
+		detector.WithRules(rules),
+		detector.WithExclusions(exclusions),
+		detector.WithUpdatedSince(f.UpdatedSince),
+		detector.WithCommunityRevisions(f.CommunityRevisions || f.CommunityImportDir != "", f.CommunityImportDir),
+		detector.WithLinks(f.Links),
+		detector.WithDeletedDashboards(f.IncludeDeleted),
+		detector.WithOrphaned(f.Orphaned),
+		detector.WithPermissions(f.Permissions),
+		detector.WithOwners(f.ResolveOwners),
+		detector.WithMaxDuration(f.MaxDuration, scanState),
+		detector.WithCheckpoint(checkpoint),
+		detector.WithPanelConverters(conversions...),
+		detector.WithCacheDir(f.CacheDir),
+		detector.WithUsageInsights(f.UsageInsights || f.SortBy == output.SortByViews || f.SortBy == output.SortByPriority),
+	}
+
+	if instanceList != nil {
+		if err := runInstancesMode(&f, log, gcomClient, instanceList, tokens, opts, notifiers); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	progress := detector.NewProgress()
+	opts = append(opts, detector.WithURLBase(f.URLBase), detector.WithProgress(progress))
+	d := detector.NewDetector(log.WithComponent(logger.ComponentDetector), client, gcomClient, f.MaxConcurrency, opts...)
+
+	var user *grafana.User
+	if f.Dir == "" && f.Command != flags.CommandSimulate {
+		user = checkIdentity(context.Background(), log, d)
+	}
+
+	var auditLog *audit.Logger
+	if f.AuditLog != "" && f.Command != flags.CommandSimulate {
+		auditLog = newAuditLogger(&f, client.BaseURL(), user)
+	}
+
+	if f.Command == flags.CommandVerify {
+		if err := runVerifyMode(&f, log, d, uids, auditLog); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.Command == flags.CommandFix {
+		if err := runFixMode(&f, log, d, auditLog); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.CompareSources {
+		err := runCompareSourcesMode(&f, log, d)
+		if auditErr := auditLog.Log(nil, err); auditErr != nil {
+			log.Errorf("audit log: %s\n", auditErr)
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.RemapDatasources {
+		err := runRemapDatasourcesMode(&f, log, d)
+		if auditErr := auditLog.Log(nil, err); auditErr != nil {
+			log.Errorf("audit log: %s\n", auditErr)
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.Census {
+		err := runCensusMode(&f, log, d)
+		if auditErr := auditLog.Log(nil, err); auditErr != nil {
+			log.Errorf("audit log: %s\n", auditErr)
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.Command == flags.CommandSimulate {
+		if err := runSimulateMode(&f, log, d); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if f.Server != "" {
+		if err := runServerMode(&f, log, d, credentials, auditLog, scanState, checkpoint, progress, notifiers); err != nil {
+			log.Errorf("%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := runCLIMode(&f, log, d, auditLog, scanState, progress, notifiers); err != nil {
+		log.Errorf("%s\n", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCodeDetections is the exit code when there are more detections than the -fail-on-detections threshold,
+// to tell them apart from errors in CI pipelines.
+const exitCodeDetections = 3
+
+// errTooManyDetections is returned when there are more detections than the -fail-on-detections threshold.
+var errTooManyDetections = errors.New("too many detections")
+
+// exitCode returns the exit code for the given error.
+func exitCode(err error) int {
+	if errors.Is(err, errTooManyDetections) {
+		return exitCodeDetections
+	}
+	return 1
+}
+
+// checkDetectionsThreshold returns an errTooManyDetections error if the given dashboards have more detections
+// (with the -fail-on-severity severity or a more severe one) than the -fail-on-detections threshold, if set.
+func checkDetectionsThreshold(flags *flags.Flags, data []output.Dashboard) error {
+	if !flags.FailOnDetections.Enabled {
+		return nil
+	}
+	n := output.CountDetections(data, output.Severity(flags.FailOnSeverity))
+	if n <= flags.FailOnDetections.Value {
+		return nil
+	}
+	return fmt.Errorf("%w: %d detections, more than the threshold of %d", errTooManyDetections, n, flags.FailOnDetections.Value)
+}
+
+// newAuditLogger returns the audit.Logger writing to the audit log file.
+// user is the identity of the API token, nil in offline mode or if it could not be determined.
+func newAuditLogger(flags *flags.Flags, target string, user *grafana.User) *audit.Logger {
+	base := audit.Record{
+		Version: build.LinkerVersion,
+		Args:    os.Args[1:],
+		Mode:    runMode(flags),
+		Target:  target,
+	}
+	if user != nil {
+		base.Identity = &audit.Identity{
+			Login:          user.Login,
+			Email:          user.Email,
+			Name:           user.Name,
+			OrgID:          user.OrgID,
+			IsGrafanaAdmin: user.IsGrafanaAdmin,
+		}
+	}
+	return audit.NewLogger(flags.AuditLog, base)
+}
+
+// runMode returns the name of the mode the program runs in, depending on the flags.
+func runMode(flags *flags.Flags) string {
+	switch {
+	case flags.Command != "":
+		return flags.Command
+	case flags.CompareSources:
+		return "compare-sources"
+	case flags.Census:
+		return "census"
+	case flags.RemapDatasources:
+		return "remap-datasources"
+	case flags.Server != "":
+		return "server"
+	}
+	return "cli"
+}
+
+// runServerMode runs the program in server (HTTP) mode.
+// If checkpoint is not nil, the results of the last complete scan it recorded are served until the first scan completes.
+// credentials are the credentials of the Grafana API client, nil in offline mode.
+func runServerMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector, credentials *api.Credentials, auditLog *audit.Logger, scanState *detector.ScanState, checkpoint *detector.Checkpoint, progress *detector.Progress, notifiers []notify.Notifier) error {
+	log = log.WithComponent(logger.ComponentServer)
+	auth, err := server.NewAuth(os.Getenv(envServerToken), os.Getenv(envServerBasic))
+	if err != nil {
+		return fmt.Errorf("environment variable %s: %w", envServerBasic, err)
+	}
+	if auth.Enabled() {
+		log.Log("Authentication required on the endpoints, except /ready and /healthz")
+	}
+	if os.Getenv(envScanToken) != "" {
+		log.Warn("%s is not used anymore, the /scan endpoints require the same credentials as the other endpoints", envScanToken)
+	}
+	// ctx is cancelled on shutdown, to cancel the in-flight detection run
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sched, err := newSchedule(flags)
+	if err != nil {
+		return err
+	}
+	if flags.Cron != "" {
+		log.Log("Running detection on schedule %q", flags.Cron)
+	} else {
+		log.Log("Running detection every %s", flags.Interval)
+	}
+
+	// previous are the results of the previous full run, nil before the first one, for the "changes" webhook payload
+	var previous []output.Dashboard
+	var lastResults []output.Dashboard
+	if checkpoint != nil {
+		if data, completed, ok := checkpoint.LastResults(); ok {
+			log.Log("Serving the results of the last scan, completed at %s", completed.Format(time.RFC3339))
+			lastResults, previous = data, nonNilDashboards(data)
+		}
+	}
+	srv := server.New(log, d, server.Config{
+		Auth:         auth,
+		CORS:         server.NewCORS(flags.CORSOrigins, flags.CORSMethods),
+		Schedule:     sched,
+		Jitter:       flags.Jitter,
+		NoInitialRun: flags.NoInitialRun,
+		KeepRuns:     flags.KeepRuns,
+		SortBy:       flags.SortBy,
+		Progress:     progress,
+	}, server.Hooks{
+		AfterRun: func() {
+			if err := writeScanState(flags, scanState); err != nil {
+				log.Errorf("write scan state: %s\n", err)
+			}
+		},
+		Completed: func(ctx context.Context, data []output.Dashboard, err error) {
+			if auditErr := auditLog.Log(audit.CountDashboards(data), err); auditErr != nil {
+				log.Errorf("audit log: %s\n", auditErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := sendWebhook(flags, log, data, previous); err != nil {
+				log.WithComponent(logger.ComponentNotifier).Errorf("webhook: %s\n", err)
+			}
+			previous = nonNilDashboards(data)
+			delta, err := recordRun(flags, log, data)
+			if err != nil {
+				log.Errorf("record run: %s\n", err)
+			}
+			if err := sendNotifications(log, notifiers, data, delta); err != nil {
+				log.WithComponent(logger.ComponentNotifier).Errorf("%s\n", err)
+			}
+		},
+		Reload: func() error {
+			reloaded, err := reloadConfig(flags, d, credentials)
+			if err != nil {
+				return err
+			}
+			notifiers = reloaded
+			return nil
+		},
+	})
+	if lastResults != nil {
+		srv.Publish(ctx, lastResults)
+	}
+
+	// The configuration is reloaded on SIGHUP, like on POST /-/reload
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Log("Received SIGHUP, reloading the configuration")
+				srv.RequestReload()
+			}
+		}
+	}()
+
+	if err := srv.ListenAndServe(ctx, flags.Server); err != nil {
+		log.Error("runServer Failed with the following err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// runCLIMode runs the program in CLI mode.
+// The run is audited once the writes to Grafana (-annotate, -publish-dashboard) have happened.
+func runCLIMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector, auditLog *audit.Logger, scanState *detector.ScanState, progress *detector.Progress, notifiers []notify.Notifier) (err error) {
+	log.Log("Detecting Angular dashboards")
+	out, err := newOutputter(flags, log)
+	if err != nil {
+		return err
+	}
+	if flags.ProgressInterval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go logProgress(log, progress, flags.ProgressInterval, done)
+	}
+	data, runErr := d.Run(context.Background())
+	counts := audit.CountDashboards(data)
+	var actions []string
+	defer func() {
+		if auditErr := auditLog.Log(counts, runErr, actions...); auditErr != nil && err == nil {
+			err = fmt.Errorf("audit log: %w", auditErr)
+		}
+	}()
+	if stateErr := writeScanState(flags, scanState); stateErr != nil {
+		return fmt.Errorf("write scan state: %w", stateErr)
+	}
+	if runErr != nil {
+		return fmt.Errorf("run detector: %w", runErr)
+	}
+	output.Sort(data, flags.SortBy)
+	// Send the webhook first, as the JSON outputter modifies data in place
+	if err := sendWebhook(flags, log, data, nil); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	delta, err := recordRun(flags, log, data)
+	if err != nil {
+		return fmt.Errorf("record run: %w", err)
+	}
+	// Like in server mode, failing to notify is logged, but doesn't fail the run, so the report is still output
+	if err := sendNotifications(log, notifiers, data, delta); err != nil {
+		log.WithComponent(logger.ComponentNotifier).Errorf("%s\n", err)
+	}
+	if flags.Annotate {
+		actions = append(actions, annotateDashboards(log, d, data)...)
+	}
+	if flags.PublishDashboard {
+		actions = append(actions, publishStatusDashboard(flags, log, d, data)...)
+	}
+	if err := out.Output(data); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	return checkDetectionsThreshold(flags, data)
+}
+
+// annotateDashboards creates, updates or deletes the annotations of the dashboards, and returns the audited actions.
+// Failing to annotate dashboards is logged, but doesn't fail the run, so the report is still output.
+func annotateDashboards(log *logger.LeveledLogger, d *detector.Detector, data []output.Dashboard) []string {
+	changes, err := d.Annotate(context.Background(), data)
+	if err != nil {
+		log.Errorf("Failed to annotate dashboards: %s\n", err)
+	}
+	counts := map[string]int{}
+	actions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		counts[change.Action]++
+		actions = append(actions, fmt.Sprintf("%s annotation on dashboard %q", change.Action, change.DashboardUID))
+	}
+	log.Log(
+		"Created %d, updated %d and deleted %d annotations",
+		counts[detector.AnnotationCreated], counts[detector.AnnotationUpdated], counts[detector.AnnotationDeleted],
+	)
+	return actions
+}
+
+// logProgress logs the progress of the running scan every interval, until done is closed.
+func logProgress(log *logger.LeveledLogger, progress *detector.Progress, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		status := progress.Status(time.Now())
+		if !status.Running {
+			continue
+		}
+		if status.Dashboards == 0 {
+			log.Log("Listing dashboards, elapsed %s", status.Elapsed)
+			continue
+		}
+		eta := status.ETA
+		if eta == "" {
+			eta = "unknown"
+		}
+		log.Log(
+			"Scanned %d of %d dashboards (%d fetched, %d errors), elapsed %s, ETA %s",
+			status.Scanned, status.Dashboards, status.Fetched, status.Errors, status.Elapsed, eta,
+		)
+	}
+}
+
+// tokenSource returns the API token of the given instance, and a function to call once the token is no longer needed.
+// record is called with the writes made to create or release the token, once they have happened, to audit them.
+type tokenSource func(ctx context.Context, instance instances.Instance, record func(action string)) (token string, release func(), err error)
+
+// instanceTokens is the tokenSource of the instances file, reading the tokens from the environment or files.
+func instanceTokens(_ context.Context, instance instances.Instance, _ func(string)) (string, func(), error) {
+	token, err := instance.Token()
+	return token, func() {}, err
+}
+
+// runInstancesMode runs the detection against each Grafana instance of the instances file or the Cloud organization,
+// up to -instances-concurrency at a time, and outputs the dashboards of all the instances together,
+// with the name of their instance. A failing instance doesn't stop the others, but makes the run fail after the output.
+// On SIGINT or SIGTERM, the in-flight runs are cancelled, so their tokens are still released (e.g.: the temporary
+// service accounts of the Cloud stacks are deleted) before exiting.
+func runInstancesMode(flags *flags.Flags, log *logger.LeveledLogger, gcomClient gcom.APIClient, instanceList []instances.Instance, tokens tokenSource, opts []detector.Option, notifiers []notify.Notifier) error {
+	out, err := newOutputter(flags, log)
+	if err != nil {
+		return err
+	}
+	concurrency := flags.InstancesConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Log("Detecting Angular dashboards in %d instances", len(instanceList))
+	results := make([][]output.Dashboard, len(instanceList))
+	errs := make([]error, len(instanceList))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, instance := range instanceList {
+		wg.Add(1)
+		go func(i int, instance instances.Instance) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = runInstance(ctx, flags, log.With("instance", instance.Name), gcomClient, instance, tokens, opts)
+		}(i, instance)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("interrupted: %w", err)
+	}
+
+	var data []output.Dashboard
+	var failed []string
+	for i, instance := range instanceList {
+		if errs[i] != nil {
+			log.Errorf("instance %q: %s\n", instance.Name, errs[i])
+			failed = append(failed, instance.Name)
+			continue
+		}
+		data = append(data, results[i]...)
+	}
+	output.Sort(data, flags.SortBy)
+	// Send the webhook first, as the JSON outputter modifies data in place
+	if err := sendWebhook(flags, log, data, nil); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	var delta *store.Delta
+	if len(failed) > 0 && flags.TrendFile != "" {
+		// The dashboards of the failed instances would be reported as resolved by the next run
+		log.Warn("Not recording the run in the trend file, as some instances failed")
+	} else if delta, err = recordRun(flags, log, data); err != nil {
+		return fmt.Errorf("record run: %w", err)
+	}
+	// Like in server mode, failing to notify is logged, but doesn't fail the run, so the report is still output
+	if err := sendNotifications(log, notifiers, data, delta); err != nil {
+		log.WithComponent(logger.ComponentNotifier).Errorf("%s\n", err)
+	}
+	if err := out.Output(data); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d instances failed: %s", len(failed), len(instanceList), strings.Join(failed, ", "))
+	}
+	return checkDetectionsThreshold(flags, data)
+}
+
+// runInstance runs the detection against the given instance, and returns its dashboards with the name of the instance.
+// The detector is configured with the given options shared by all the instances, the base URL of the instance,
+// and a subdirectory of -community-import-dir named after the instance.
+// The run is audited once the token has been released, with the writes made to create and release it.
+func runInstance(ctx context.Context, flags *flags.Flags, log *logger.LeveledLogger, gcomClient gcom.APIClient, instance instances.Instance, tokens tokenSource, opts []detector.Option) (data []output.Dashboard, err error) {
+	var user *grafana.User
+	var actions []string
+	defer func() {
+		if flags.AuditLog == "" {
+			return
+		}
+		if auditErr := newAuditLogger(flags, instance.URL, user).Log(audit.CountDashboards(data), err, actions...); auditErr != nil && err == nil {
+			data, err = nil, fmt.Errorf("audit log: %w", auditErr)
+		}
+	}()
+	token, release, err := tokens(ctx, instance, func(action string) {
+		actions = append(actions, action)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+	defer release()
+	client, err := initializeClient(instance.URL, api.NewCredentials(token), flags, log)
+	if err != nil {
+		return nil, fmt.Errorf("initialize the Grafana API client: %w", err)
+	}
+	instanceOpts := append(append([]detector.Option{}, opts...), detector.WithURLBase(instance.URLBase))
+	if flags.CommunityImportDir != "" {
+		// The dashboards of different instances can have the same uid
+		instanceOpts = append(instanceOpts, detector.WithCommunityRevisions(true, filepath.Join(flags.CommunityImportDir, url.PathEscape(instance.Name))))
+	}
+	d := detector.NewDetector(log.WithComponent(logger.ComponentDetector), client, gcomClient, flags.MaxConcurrency, instanceOpts...)
+	user = checkIdentity(ctx, log, d)
+
+	log.Log("Detecting Angular dashboards in %q", instance.URL)
+	data, err = d.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("run detector: %w", err)
+	}
+	for i := range data {
+		data[i].Instance = instance.Name
+	}
+	return data, nil
+}
+
+// checkIdentity returns the user of the API token of the given detector, or nil if it can't be determined, in which
+// case a warning is logged: the identity is only recorded in the audit log, so the detection runs without it.
+func checkIdentity(ctx context.Context, log *logger.LeveledLogger, d *detector.Detector) *grafana.User {
+	user, err := d.CheckIdentity(ctx)
+	if err != nil {
+		log.Warn("Could not determine the identity of the API token: %s", err)
+	}
+	return user
+}
 
-type Output struct {
-	mu   sync.Mutex
-	data []output.Dashboard
+// validateInstancesFlags returns an error if the given flag scanning multiple instances (-instances-file or -cloud-org)
+// is combined with flags that only work with a single instance.
+func validateInstancesFlags(flags *flags.Flags, name string) error {
+	switch {
+	case flag.NArg() > 0:
+		return fmt.Errorf("the Grafana URL can't be passed as argument with flag %s", name)
+	case flags.InstancesFile != "" && flags.CloudOrg != "":
+		return fmt.Errorf("flags -instances-file and -cloud-org are mutually exclusive")
+	case flags.Command != "", flags.Server != "", flags.Dir != "", flags.CompareSources, flags.Census, flags.RemapDatasources:
+		return fmt.Errorf("flag %s only works in CLI mode", name)
+	case flags.StateFile != "" || flags.MaxDuration > 0:
+		return fmt.Errorf("flag %s can't be combined with -state-file and -max-duration", name)
+	case flags.URLBase != "":
+		return fmt.Errorf("flag %s can't be combined with -url-base, the base URL of each instance is derived from its URL (or its urlBase in -instances-file)", name)
+	case flags.Annotate || flags.PublishDashboard:
+		return fmt.Errorf("flag %s can't be combined with -annotate and -publish-dashboard", name)
+	}
+	return nil
 }
 
-func main() {
-	f := flags.Parse()
+const (
+	// cloudServiceAccountName is the prefix of the names of the service accounts created in the Grafana Cloud stacks,
+	// followed by a random suffix, so concurrent runs don't conflict.
+	cloudServiceAccountName = "detect-angular-dashboards"
 
-	if f.Version {
-		fmt.Printf("%s %s (%s)\n", os.Args[0], build.LinkerVersion, build.LinkerCommitSHA)
-		os.Exit(0)
+	// cloudServiceAccountRole is the role of the service accounts created in the Grafana Cloud stacks.
+	// The detection only reads, as the flags writing to the instances are rejected with -cloud-org.
+	cloudServiceAccountRole = "Viewer"
+
+	// cloudTokenTTL is the time to live of the tokens of the service accounts created in the Grafana Cloud stacks.
+	// The service accounts are deleted once their stack has been scanned, so it only bounds the lifetime of the ones
+	// that couldn't be deleted, while leaving enough time to scan large stacks.
+	cloudTokenTTL = 24 * time.Hour
+)
+
+// newCloudClient returns a GCOM API client authenticated with the grafana.com token in GRAFANA_COM_TOKEN,
+// to discover the stacks of a Cloud organization.
+func newCloudClient(log *logger.LeveledLogger) (gcom.APIClient, error) {
+	token := os.Getenv(envGrafanaCom)
+	if token == "" {
+		return gcom.APIClient{}, fmt.Errorf("environment variable %s is not set", envGrafanaCom)
 	}
-	log := newLogger(f.Verbose, f.JSONOutput)
+	return gcom.NewAPIClient(
+		api.WithAuthentication(token),
+		api.WithHooks(requestLogHooks(log.WithComponent(logger.ComponentGCOM))),
+	), nil
+}
 
-	token, err := getToken()
+// discoverStacks returns the active stacks of the given Grafana Cloud organization, as instances named after their slug.
+func discoverStacks(cloudClient gcom.APIClient, org string, log *logger.LeveledLogger) ([]instances.Instance, error) {
+	stacks, err := cloudClient.GetStacks(context.Background(), org)
 	if err != nil {
-		log.Errorf("Failed to retrieve Grafana token: %s\n", err.Error())
-		os.Exit(1)
+		return nil, err
 	}
-	client := initializeClient(token, &f)
-
-	d := detector.NewDetector(log, client, gcom.NewAPIClient(), f.MaxConcurrency)
+	out := make([]instances.Instance, 0, len(stacks))
+	for _, stack := range stacks {
+		if stack.Status != gcom.StackStatusActive {
+			log.Verbose().Log("Skipping stack %q with status %q", stack.Slug, stack.Status)
+			continue
+		}
+		out = append(out, instances.Instance{Name: stack.Slug, URL: strings.TrimSuffix(stack.URL, "/") + "/api"})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no active stacks")
+	}
+	log.Log("Discovered %d active stacks", len(out))
+	return out, nil
+}
 
-	if f.Server != "" {
-		if err := runServerMode(&f, log, d); err != nil {
-			log.Errorf("%s\n", err)
-			os.Exit(1)
+// stackTokens returns the tokenSource of the Grafana Cloud stacks: for each stack, a temporary viewer service account
+// with a token is created through grafana.com, and deleted once the stack has been scanned.
+func stackTokens(cloudClient gcom.APIClient, log *logger.LeveledLogger) tokenSource {
+	return func(ctx context.Context, instance instances.Instance, record func(string)) (string, func(), error) {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return "", nil, fmt.Errorf("generate service account name: %w", err)
 		}
-		return
+		name := cloudServiceAccountName + "-" + hex.EncodeToString(b)
+		sa, err := cloudClient.CreateStackServiceAccount(ctx, instance.Name, name, cloudServiceAccountRole)
+		if err != nil {
+			return "", nil, fmt.Errorf("create service account: %w", err)
+		}
+		record(fmt.Sprintf("create service account %d in stack %q", sa.ID, instance.Name))
+		release := func() {
+			if err := cloudClient.DeleteStackServiceAccount(context.Background(), instance.Name, sa.ID); err != nil {
+				log.Warn("Could not delete service account %d of stack %q, delete it manually: %s", sa.ID, instance.Name, err)
+				return
+			}
+			record(fmt.Sprintf("delete service account %d in stack %q", sa.ID, instance.Name))
+		}
+		token, err := cloudClient.CreateStackServiceAccountToken(ctx, instance.Name, sa.ID, name, int(cloudTokenTTL.Seconds()))
+		if err != nil {
+			release()
+			return "", nil, fmt.Errorf("create service account token: %w", err)
+		}
+		record(fmt.Sprintf("create token of service account %d in stack %q", sa.ID, instance.Name))
+		return token.Key, release, nil
 	}
+}
 
-	if err := runCLIMode(&f, log, d); err != nil {
-		log.Errorf("%s\n", err)
-		os.Exit(1)
+// runSimulateMode runs the detection against the recorded API responses, like the CLI mode, but without side effects:
+// the webhook request is built but not sent, and the audit log and the scan state are not written.
+func runSimulateMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector) error {
+	log.Log("Simulating detection against the fixtures in %q", flag.Arg(0))
+	out, err := newOutputter(flags, log)
+	if err != nil {
+		return err
+	}
+	data, err := d.Run(context.Background())
+	if err != nil {
+		return fmt.Errorf("run detector: %w", err)
+	}
+	output.Sort(data, flags.SortBy)
+	// Build the webhook request first, as the JSON outputter modifies data in place
+	if flags.WebhookURL != "" {
+		webhook, err := newWebhookOutputter(flags)
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		req, err := webhook.NewRequest(data)
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		log.WithComponent(logger.ComponentNotifier).Log(
+			"Dry run: not sending %d bytes to webhook %s (signed: %t)",
+			req.ContentLength, req.URL.Redacted(), req.Header.Get(output.SignatureHeader) != "",
+		)
 	}
+	if err := out.Output(data); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	return nil
 }
 
-// runServerMode runs the program in server (HTTP) mode.
-func runServerMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector) error {
-	// Readiness flag using atomic boolean
-	var ready atomic.Bool
-	var once sync.Once
+// runVerifyMode checks that the dashboards with the given uids have no detections, and outputs the ones that do.
+// It returns an error if any dashboard has detections or can't be found.
+func runVerifyMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector, uids []string, auditLog *audit.Logger) error {
+	log.Log("Verifying %d dashboards", len(uids))
+	out, err := newOutputter(flags, log)
+	if err != nil {
+		return err
+	}
+	data, err := d.Run(context.Background())
+	if auditErr := auditLog.Log(audit.CountDashboards(data), err); auditErr != nil {
+		return fmt.Errorf("audit log: %w", auditErr)
+	}
+	if err != nil {
+		return fmt.Errorf("run detector: %w", err)
+	}
+	found := make(map[string]struct{}, len(data))
+	var offenders []output.Dashboard
+	for _, dashboard := range data {
+		found[dashboard.UID] = struct{}{}
+		if len(dashboard.Detections) > 0 {
+			offenders = append(offenders, dashboard)
+		}
+	}
+	var missing int
+	for _, uid := range uids {
+		if _, ok := found[uid]; !ok {
+			log.Error("Dashboard %q not found", uid)
+			missing++
+		}
+	}
+	if err := out.Output(offenders); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	if len(offenders) > 0 || missing > 0 {
+		return fmt.Errorf("verification failed: %d dashboards with Angular detections, %d dashboards not found", len(offenders), missing)
+	}
+	log.Log("Verification passed, no Angular detections in %d dashboards", len(uids))
+	return nil
+}
 
-	ticker := time.NewTicker(flags.Interval)
-	defer ticker.Stop()
-	log.Log("Running detection every %s", flags.Interval)
+// runFixMode converts the Angular panels of the dashboards to React and saves them, writes them to -fix-output-dir
+// for review, or only reports them with -dry-run.
+// It returns an error if any dashboard could not be fixed.
+func runFixMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector, auditLog *audit.Logger) error {
+	if flags.Dir != "" {
+		return fmt.Errorf("the fix command can't be used with -dir, the dashboards must be saved through the API")
+	}
+	data, err := d.Run(context.Background())
+	if err != nil {
+		if auditErr := auditLog.Log(audit.CountDashboards(data), err); auditErr != nil {
+			return fmt.Errorf("audit log: %w", auditErr)
+		}
+		return fmt.Errorf("run detector: %w", err)
+	}
+	fixes := d.Fix(context.Background(), data, flags.FixBackupDir, flags.FixOutputDir, flags.DryRun)
+	var failed int
+	var actions []string
+	for _, fix := range fixes {
+		if fix.Error != "" {
+			failed++
+		}
+		if fix.Saved {
+			actions = append(actions, fmt.Sprintf("save dashboard %q", fix.UID))
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("could not fix %d dashboards", failed)
+	}
+	// The saved dashboards are audited once they have been saved
+	if auditErr := auditLog.Log(audit.CountDashboards(data), err, actions...); auditErr != nil {
+		return fmt.Errorf("audit log: %w", auditErr)
+	}
+	if flags.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(fixes); err != nil {
+			return err
+		}
+	} else {
+		logFixes(log, fixes, flags.DryRun)
+	}
+	return err
+}
 
-	var out Output
-	go func() {
-		// Trigger for the first time
-		run := make(chan struct{}, 1)
-		run <- struct{}{}
+// logFixes logs the panels migrated in each dashboard by the fix command in a readable format.
+func logFixes(log *logger.LeveledLogger, fixes []output.DashboardFix, dryRun bool) {
+	if len(fixes) == 0 {
+		log.Log("No panels to migrate")
+		return
+	}
+	verb := "Converted"
+	if dryRun {
+		verb = "Would convert"
+	}
+	for _, fix := range fixes {
+		log.Log("Dashboard %q (%s)", fix.Title, fix.URL)
+		for _, panel := range fix.Panels {
+			log.Log("  %s panel %q from %q to %q", verb, panel.Title, panel.PluginID, panel.TargetPluginID)
+		}
+		for _, panel := range fix.Skipped {
+			log.Warn("  Skipped panel %q (%q): options %s can't be converted", panel.Title, panel.PluginID, strings.Join(panel.LossyOptions, ", "))
+		}
+		switch {
+		case fix.Error != "":
+			log.Error("  Could not fix dashboard: %s", fix.Error)
+		case fix.Saved:
+			log.Log("  Saved dashboard, previous version written to %s", fix.Backup)
+		case fix.Output != "":
+			log.Log("  Converted dashboard written to %s for review", fix.Output)
+		}
+	}
+}
 
-		for {
-			select {
-			case <-run:
-			case <-ticker.C:
-			}
+// writeScanState writes the scan state to the state file, if set.
+func writeScanState(flags *flags.Flags, scanState *detector.ScanState) error {
+	if flags.StateFile == "" {
+		return nil
+	}
+	return scanState.WriteFile(flags.StateFile)
+}
 
-			// Run detection periodically
-			log.Log("Detecting Angular dashboards")
-			data, err := d.Run(context.Background())
-			if err != nil {
-				log.Errorf("%s\n", err)
-				continue
-			}
+// sendWebhook sends the dashboards with detections, or their summary, to the webhook URL, if set.
+// The payload is signed if the WEBHOOK_SECRET environment variable is set.
+// If previous is not nil, it is the results of the previous run, which the "changes" payload is compared with.
+func sendWebhook(flags *flags.Flags, log *logger.LeveledLogger, data, previous []output.Dashboard) error {
+	if flags.WebhookURL == "" {
+		return nil
+	}
+	webhook, err := newWebhookOutputter(flags)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		webhook = webhook.WithPrevious(previous)
+	}
+	if err := webhook.Output(data); err != nil {
+		return err
+	}
+	log.WithComponent(logger.ComponentNotifier).Verbose().Log("Sent %d dashboards to the webhook", len(data))
+	return nil
+}
 
-			// Run detection periodically
-			log.Log("Updating Output Data")
-			out.mu.Lock()
-			out.data = data
-			out.mu.Unlock()
+// reloadConfig re-reads the configuration files of the server mode: the detection rules of -rules-file are applied
+// to d, the token of GRAFANA_TOKEN_FILE replaces the credentials, if not nil, and the notifiers of -notifiers-file
+// (with the -slack-webhook and -teams-webhook ones) are returned. Nothing is applied if a file is invalid.
+// The flags and the other environment variables are not reloaded.
+func reloadConfig(flags *flags.Flags, d *detector.Detector, credentials *api.Credentials) ([]notify.Notifier, error) {
+	var token string
+	if credentials != nil && os.Getenv(envGrafanaFile) != "" {
+		var err error
+		if token, err = getToken(); err != nil {
+			return nil, fmt.Errorf("read token: %w", err)
+		}
+	}
+	var rules *detector.Rules
+	if flags.RulesFile != "" {
+		var err error
+		if rules, err = detector.ReadRules(flags.RulesFile); err != nil {
+			return nil, fmt.Errorf("read rules: %w", err)
+		}
+	}
+	notifiers, err := newNotifiers(flags)
+	if err != nil {
+		return nil, fmt.Errorf("read notifiers: %w", err)
+	}
+	d.Reconfigure(detector.WithRules(rules))
+	if token != "" {
+		credentials.Set(token)
+	}
+	return notifiers, nil
+}
 
-			// Use sync.Once to set readiness only once
-			once.Do(func() {
-				ready.Store(true)
-				log.Log("Updating readiness probe to ready")
-			})
+// newSchedule returns the schedule of the detection runs in server mode: the -cron expression if set,
+// otherwise every -interval.
+func newSchedule(flags *flags.Flags) (schedule.Schedule, error) {
+	if flags.Cron == "" {
+		if flags.Interval <= 0 {
+			return nil, fmt.Errorf("-interval must be positive")
 		}
-	}()
+		return schedule.Every(flags.Interval), nil
+	}
+	return schedule.ParseCron(flags.Cron)
+}
 
-	http.HandleFunc("/detections", func(w http.ResponseWriter, r *http.Request) {
-		handleDetectionsRequest(w, r, &out, log)
-	})
-	http.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		handleReadyRequest(w, r, &ready)
-	})
+// nonNilDashboards returns data, or an empty slice if it is nil, to tell an empty run apart from no run in sendWebhook.
+func nonNilDashboards(data []output.Dashboard) []output.Dashboard {
+	if data == nil {
+		return []output.Dashboard{}
+	}
+	return data
+}
 
-	if err := runServer(flags, log); err != nil {
-		log.Error("runServer Failed with the following err: %v", err)
-		return err
+// newWebhookOutputter returns the WebhookOutputter of the webhook flags.
+func newWebhookOutputter(flags *flags.Flags) (output.WebhookOutputter, error) {
+	if err := output.ValidateWebhookPayload(flags.WebhookPayload); err != nil {
+		return output.WebhookOutputter{}, err
+	}
+	if flags.WebhookRetries < 0 {
+		return output.WebhookOutputter{}, fmt.Errorf("-webhook-retries must not be negative")
+	}
+	headers, err := output.ParseWebhookHeaders(flags.WebhookHeaders)
+	if err != nil {
+		return output.WebhookOutputter{}, err
+	}
+	return output.NewWebhookOutputter(flags.WebhookURL, os.Getenv(envWebhookSecret)).
+		WithPayload(flags.WebhookPayload).
+		WithHeaders(headers).
+		WithRetries(flags.WebhookRetries), nil
+}
+
+// newNotifiers returns the notifiers of the -slack-webhook and -teams-webhook flags and of the notifiers file, if set.
+func newNotifiers(flags *flags.Flags) ([]notify.Notifier, error) {
+	var notifiers []notify.Notifier
+	if flags.NotifiersFile != "" {
+		var err error
+		if notifiers, err = notify.ReadFile(flags.NotifiersFile); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case flags.SlackWebhookURL != "":
+		if flags.SlackOnlyNew && flags.TrendFile == "" {
+			return nil, fmt.Errorf("flag -slack-only-new requires -trend-file")
+		}
+		notifiers = append(notifiers, notify.NewSlackNotifier(flags.SlackWebhookURL, flags.SlackOnlyNew))
+	case flags.SlackOnlyNew:
+		return nil, fmt.Errorf("flag -slack-only-new requires -slack-webhook")
 	}
+	switch {
+	case flags.TeamsWebhookURL != "":
+		if flags.TeamsOnlyNew && flags.TrendFile == "" {
+			return nil, fmt.Errorf("flag -teams-only-new requires -trend-file")
+		}
+		notifiers = append(notifiers, notify.NewTeamsNotifier(flags.TeamsWebhookURL, flags.TeamsOnlyNew))
+	case flags.TeamsOnlyNew:
+		return nil, fmt.Errorf("flag -teams-only-new requires -teams-webhook")
+	}
+	return notifiers, nil
+}
 
+// sendNotifications sends the notification of the run with the given results to the given notifiers.
+// delta is the changes since the previous run, nil if the run has not been recorded in the trend file.
+func sendNotifications(log *logger.LeveledLogger, notifiers []notify.Notifier, data []output.Dashboard, delta *store.Delta) error {
+	if len(notifiers) == 0 {
+		return nil
+	}
+	run := notify.Run{Dashboards: data}
+	if delta != nil {
+		run.Recorded = true
+		run.Changes = delta.ReportDiff
+		if delta.Previous != nil {
+			run.Previous = delta.Previous.Time
+		}
+	}
+	if err := notify.NotifyAll(context.Background(), notifiers, run); err != nil {
+		return err
+	}
+	log.WithComponent(logger.ComponentNotifier).Verbose().Log("Sent the notifications to %d notifiers", len(notifiers))
 	return nil
 }
 
-func runServer(flags *flags.Flags, log *logger.LeveledLogger) error {
-	server := &http.Server{Addr: flags.Server}
+// recordRun records the results of a run in the trend file, if set, and logs the changes since the previous run.
+// It returns the changes since the previous run, nil if the trend file is not set.
+func recordRun(flags *flags.Flags, log *logger.LeveledLogger, data []output.Dashboard) (*store.Delta, error) {
+	if flags.TrendFile == "" {
+		return nil, nil
+	}
+	s, err := store.Open(flags.TrendFile)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	delta, err := s.RecordRun(context.Background(), data)
+	if err != nil {
+		return nil, err
+	}
+	if delta.Previous == nil {
+		log.Log("Recorded the first run in the trend file")
+		return delta, nil
+	}
+	log.Log(
+		"Since the previous run (%s): %d new dashboards with detections, %d resolved, %d with changed detections",
+		delta.Previous.Time, len(delta.New), len(delta.Resolved), len(delta.Changed),
+	)
+	for _, dashboard := range delta.New {
+		log.Verbose().Log("  New: %q %q", dashboard.Title, dashboard.URL)
+	}
+	for _, dashboard := range delta.Resolved {
+		log.Verbose().Log("  Resolved: %q %q", dashboard.Title, dashboard.URL)
+	}
+	for _, dashboard := range delta.Changed {
+		log.Verbose().Log("  Changed: %q %q", dashboard.Title, dashboard.URL)
+	}
+	return delta, nil
+}
 
-	// Channel to listen for OS signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// publishStatusDashboard creates or updates the status dashboard of the given dashboards in Grafana,
+// with the trend of the runs recorded in the trend file, if any.
+// Failing to publish the dashboard is logged, but doesn't fail the run, so the report is still output.
+// It returns the audited actions.
+func publishStatusDashboard(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector, data []output.Dashboard) []string {
+	var trend []output.TrendPoint
+	if flags.TrendFile != "" {
+		runs, err := readRuns(flags.TrendFile)
+		if err != nil {
+			log.Errorf("Failed to read the trend: %s\n", err)
+		}
+		for _, run := range runs {
+			trend = append(trend, output.TrendPoint{
+				Time:                     run.Time,
+				Dashboards:               run.Dashboards,
+				DashboardsWithDetections: run.DashboardsWithDetections,
+				Detections:               run.Detections,
+			})
+		}
+	}
+	if err := d.PublishDashboard(context.Background(), output.NewStatusDashboard(data, trend, time.Now())); err != nil {
+		log.Errorf("Failed to publish the status dashboard: %s\n", err)
+		return nil
+	}
+	log.Log("Published the status dashboard (uid %s)", output.StatusDashboardUID)
+	return []string{fmt.Sprintf("save dashboard %q", output.StatusDashboardUID)}
+}
 
-	// Start the server in a goroutine
-	go func() {
-		log.Log("Listening on %s", flags.Server)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error("ListenAndServe(): %s", err)
+// readRuns returns the detection runs recorded in the given trend file, from the oldest to the newest.
+func readRuns(fn string) ([]store.Run, error) {
+	s, err := store.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.Runs(context.Background())
+}
+
+// runTrendMode outputs the detection runs recorded in the trend file, from the oldest to the newest.
+func runTrendMode(flags *flags.Flags, log *logger.LeveledLogger) error {
+	if flags.TrendFile == "" {
+		return fmt.Errorf("the trend command requires -trend-file")
+	}
+	s, err := store.Open(flags.TrendFile)
+	if err != nil {
+		return fmt.Errorf("open trend file: %w", err)
+	}
+	defer s.Close()
+	runs, err := s.Runs(context.Background())
+	if err != nil {
+		return fmt.Errorf("get runs: %w", err)
+	}
+	if flags.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(runs)
+	}
+	if len(runs) == 0 {
+		log.Log("No runs recorded")
+		return nil
+	}
+	for _, run := range runs {
+		log.Log(
+			"%s: %d dashboards, %d with Angular detections, %d detections",
+			run.Time, run.Dashboards, run.DashboardsWithDetections, run.Detections,
+		)
+	}
+	return nil
+}
+
+// runMergeMode merges the given JSON reports into one, and outputs it as JSON.
+func runMergeMode(log *logger.LeveledLogger, files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no reports to merge")
+	}
+	reports := make([][]output.Dashboard, 0, len(files))
+	for _, fn := range files {
+		report, err := output.ReadJSON(fn)
+		if err != nil {
+			return fmt.Errorf("read report: %w", err)
 		}
-	}()
+		reports = append(reports, report)
+	}
+	merged := output.Merge(reports...)
+	log.Log("Merged %d reports into %d dashboards", len(reports), len(merged))
+	if err := output.NewJSONOutputter(os.Stdout).Output(merged); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+	return nil
+}
 
-	// Wait for a signal interrupt
-	sig := <-sigChan
-	log.Log("Received signal: %s. Shutting down server...", sig)
+// isTerminal returns true if the given file is a terminal, to render the readable output with colors.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
 
-	// Gracefully shut down the server
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
-		log.Error("Server Shutdown Failed:%+v", err)
-		return err
+// newOutputter returns the Outputter to use, depending on the flags.
+func newOutputter(flags *flags.Flags, log *logger.LeveledLogger) (output.Outputter, error) {
+	var previous []output.Dashboard
+	if flags.PreviousReport != "" {
+		var err error
+		previous, err = output.ReadJSON(flags.PreviousReport)
+		if err != nil {
+			return nil, fmt.Errorf("read previous report: %w", err)
+		}
+	}
+	// newFormatOutputter returns a function returning the Outputter of the given format writing to w
+	newFormatOutputter := func(format string) func(w io.Writer) output.Outputter {
+		return func(w io.Writer) output.Outputter {
+			switch format {
+			case output.FormatDetectionsCSV:
+				return output.NewDetectionsCSVOutputter(w)
+			case output.FormatHTML:
+				return output.NewHTMLOutputter(w)
+			case output.FormatXLSX:
+				return output.NewXLSXOutputter(w)
+			case output.FormatCSV:
+				return output.NewCSVOutputter(w, previous)
+			case output.FormatJSON:
+				return output.NewJSONOutputter(w).WithSummary(flags.Summary).WithSummaryOnly(flags.SummaryOnly)
+			}
+			return output.NewWriterReadableOutput(w).WithGroupBy(flags.GroupBy).WithSummaryOnly(flags.SummaryOnly)
+		}
+	}
+	format := flags.OutputFormat
+	switch {
+	case format == output.FormatDetectionsCSV, format == output.FormatHTML, format == output.FormatXLSX:
+	case flags.CSVOutput:
+		format = output.FormatCSV
+	case flags.JSONOutput:
+		format = output.FormatJSON
+	default:
+		format = output.FormatText
+	}
+	if flags.SummaryOnly && format != output.FormatText && format != output.FormatJSON {
+		return nil, fmt.Errorf("flag -summary-only only works with the text and json output formats")
+	}
+	var primary output.Outputter
+	switch {
+	case flags.OutputFile != "":
+		primary = output.NewFileOutputter(flags.OutputFile, newFormatOutputter(format))
+	case format == output.FormatXLSX:
+		return nil, fmt.Errorf("output format xlsx can't be written to stdout, use -o with a .xlsx file")
+	case format == output.FormatText && flags.GroupBy == "" && isTerminal(os.Stdout):
+		color := !flags.NoColor && os.Getenv("NO_COLOR") == ""
+		primary = output.NewTerminalOutputter(os.Stdout, color).WithSummaryOnly(flags.SummaryOnly)
+	case format == output.FormatText && flags.Quiet:
+		// The info messages are discarded, write the results to stdout without the log prefixes
+		primary = newFormatOutputter(format)(os.Stdout)
+	case format == output.FormatText:
+		primary = output.NewLoggerReadableOutput(log).WithGroupBy(flags.GroupBy).WithSummaryOnly(flags.SummaryOnly)
+	default:
+		primary = newFormatOutputter(format)(os.Stdout)
+	}
+	if len(flags.Outputs) == 0 {
+		return primary, nil
+	}
+	outputters := []output.Outputter{primary}
+	for _, spec := range flags.Outputs {
+		format, path, err := output.ParseOutputSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		outputters = append(outputters, output.NewFileOutputter(path, newFormatOutputter(format)))
 	}
-	log.Log("Server gracefully stopped")
+	return output.NewMultiOutputter(outputters...), nil
+}
 
+// runCompareSourcesMode compares the Angular status of plugins from frontend settings and GCOM.
+func runCompareSourcesMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector) error {
+	log.Log("Comparing Angular status from frontend settings and GCOM")
+	discrepancies, err := d.CompareAngularSources(context.Background())
+	if err != nil {
+		return fmt.Errorf("compare angular sources: %w", err)
+	}
+	if flags.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(discrepancies)
+	}
+	if len(discrepancies) == 0 {
+		log.Log("No discrepancies found")
+		return nil
+	}
+	for _, ds := range discrepancies {
+		log.Log("Plugin %q: frontend settings angular %t, GCOM angular %t", ds.PluginID, ds.FrontendSettings, ds.GCOM)
+	}
 	return nil
 }
 
-// runCLIMode runs the program in CLI mode.
-func runCLIMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector) error {
-	log.Log("Detecting Angular dashboards")
-	var out output.Outputter
+// runCensusMode reports the usage of all the panel and datasource plugins in the dashboards.
+func runCensusMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector) error {
+	log.Log("Detecting plugin usage")
+	usages, err := d.Census(context.Background())
+	if err != nil {
+		return fmt.Errorf("census: %w", err)
+	}
 	if flags.JSONOutput {
-		out = output.NewJSONOutputter(os.Stdout)
-	} else {
-		out = output.NewLoggerReadableOutput(log)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(usages)
 	}
-	data, err := d.Run(context.Background())
+	for _, usage := range usages {
+		log.Log(
+			"Plugin %q (%s, angular %t) used %d times in %d dashboards",
+			usage.PluginID, usage.Type, usage.Angular, usage.Count, len(usage.Dashboards),
+		)
+		for _, u := range usage.Dashboards {
+			log.Verbose().Log("  %s", u)
+		}
+	}
+	return nil
+}
+
+// runRemapDatasourcesMode reports the plan to replace the datasources using Angular plugins.
+func runRemapDatasourcesMode(flags *flags.Flags, log *logger.LeveledLogger, d *detector.Detector) error {
+	log.Log("Planning data source remapping")
+	plan, err := d.DatasourceRemapPlan(context.Background())
 	if err != nil {
-		return fmt.Errorf("run detector: %w", err)
+		return fmt.Errorf("datasource remap plan: %w", err)
 	}
-	if err := out.Output(data); err != nil {
-		return fmt.Errorf("output: %w", err)
+	if flags.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	if len(plan) == 0 {
+		log.Log("No data sources to remap")
+		return nil
+	}
+	for _, remap := range plan {
+		if remap.TargetUID != "" {
+			log.Log(
+				"Data source %q (%s, %q) -> %q (%s, %q), matched by %s",
+				remap.Name, remap.UID, remap.PluginID, remap.TargetName, remap.TargetUID, remap.TargetPluginID, remap.Match,
+			)
+			continue
+		}
+		log.Warn(
+			"Data source %q (%s, %q) -> %q: no unique match, candidates %s",
+			remap.Name, remap.UID, remap.PluginID, remap.TargetPluginID, strings.Join(remap.Candidates, ", "),
+		)
 	}
 	return nil
 }
 
-// initializeClient initializes the Grafana API client.
-func initializeClient(token string, flags *flags.Flags) grafana.APIClient {
-	grafanaURL := grafana.DefaultBaseURL
+// grafanaURL returns the URL of the Grafana API passed as argument, or the default one.
+func grafanaURL() string {
 	if flag.NArg() >= 1 {
-		grafanaURL = flag.Arg(0)
+		return flag.Arg(0)
 	}
+	return grafana.DefaultBaseURL
+}
 
-	opts := []api.ClientOption{api.WithAuthentication(token)}
+// initializeClient initializes the Grafana API client for the given URL.
+// Dashboards are listed and fetched with the legacy APIs, falling back to the App Platform APIs if they are not
+// available, or with the App Platform APIs only if -app-platform is set.
+// If -folder or -folder-uid are set, the dashboards are listed only from the given folders and their subfolders,
+// and if -tag or -uid are set, only the dashboards with the given tags or uids are listed, so the other dashboards
+// are not listed at all. The detector applies the same filters to the clients that don't filter the dashboards.
+func initializeClient(grafanaURL string, credentials *api.Credentials, flags *flags.Flags, log *logger.LeveledLogger) (detector.GrafanaDetectorAPIClient, error) {
+	opts := []api.ClientOption{
+		api.WithCredentials(credentials),
+		api.WithHooks(requestLogHooks(log.WithComponent(logger.ComponentGrafanaAPI))),
+		api.WithMaxResponseBytes(flags.MaxResponseBytes),
+		api.WithMaxDecodeDepth(flags.MaxDecodeDepth),
+	}
+	if flags.ConditionalRequests {
+		opts = append(opts, api.WithConditionalRequests())
+	}
 	if flags.SkipTLS {
 		opts = append(opts, api.WithHTTPClient(&http.Client{
 			Transport: &http.Transport{
@@ -187,76 +1596,158 @@ func initializeClient(token string, flags *flags.Flags) grafana.APIClient {
 			},
 		}))
 	}
-	return grafana.NewAPIClient(api.NewClient(grafanaURL, opts...))
+	client := api.NewClient(grafanaURL, opts...)
+	var folderUIDs []string
+	if len(flags.Folders) > 0 || len(flags.FolderUIDs) > 0 {
+		folders, err := grafana.NewAPIClient(client).GetFolders(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("get folders: %w", err)
+		}
+		folderUIDs, err = detector.ResolveFolderUIDs(folders, flags.Folders, flags.FolderUIDs)
+		if err != nil {
+			return nil, fmt.Errorf("resolve folders: %w", err)
+		}
+		log.Verbose().Log("Restricting the scan to %d folder(s): %s", len(folderUIDs), strings.Join(folderUIDs, ", "))
+	}
+	var out detector.GrafanaDetectorAPIClient
+	var cl *grafana.AppPlatformAPIClient
+	if flags.AppPlatform {
+		cl = grafana.NewAppPlatformAPIClient(client)
+		out = cl
+	} else {
+		fallback := grafana.NewFallbackAPIClient(client)
+		cl, out = fallback.AppPlatformAPIClient, fallback
+	}
+	cl.FolderUIDs = folderUIDs
+	cl.Tags = flags.Tags
+	cl.DashboardUIDs = flags.UIDs
+	return out, nil
 }
 
-// handleDetectionsRequest handles the /output HTTP endpoint.
-func handleDetectionsRequest(w http.ResponseWriter, r *http.Request, output *Output, log *logger.LeveledLogger) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// initializeOfflineClient initializes the client that reads exported dashboards from a directory.
+func initializeOfflineClient(flags *flags.Flags, log *logger.LeveledLogger) offline.APIClient {
+	opts := []offline.Option{offline.WithLogger(log)}
+	if flags.DirUseGCOM {
+		opts = append(opts, offline.WithGCOM(newGCOMClient(log)))
 	}
+	return offline.NewAPIClient(flags.Dir, opts...)
+}
 
-	output.mu.Lock()
-	defer output.mu.Unlock()
-	w.Header().Set("Content-Type", "application/json")
-
-	// Have to do this because the JSONOutputter.Output method modifies the slice in place
-	// which results in werid bug where the slice gets duplicate entries. The number of duplicate entries
-	// continues to grow with each request to /output. Something is leaky
-	angularDashboards := filterAngularDashboards(output.data)
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-
-	if err := enc.Encode(angularDashboards); err != nil {
-		log.Errorf("http server: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// initializeFixturesClients starts a server serving the recorded API responses in the given directory,
+// and returns the Grafana and GCOM clients using it. The server must be closed by the caller.
+func initializeFixturesClients(dir string, log *logger.LeveledLogger) (grafana.APIClient, gcom.APIClient, *fixtures.Server, error) {
+	if dir == "" {
+		return grafana.APIClient{}, gcom.APIClient{}, nil, fmt.Errorf("the fixtures directory is required")
+	}
+	srv, err := fixtures.NewServer(dir)
+	if err != nil {
+		return grafana.APIClient{}, gcom.APIClient{}, nil, err
 	}
+	client := grafana.NewAPIClient(api.NewClient(
+		srv.URL()+fixtures.GrafanaPrefix,
+		api.WithHooks(requestLogHooks(log.WithComponent(logger.ComponentGrafanaAPI))),
+	))
+	gcomClient := newGCOMClient(log)
+	gcomClient.BaseURL = srv.URL() + fixtures.GCOMPrefix
+	return client, gcomClient, srv, nil
 }
 
-// handleReadyRequest handles the /ready HTTP endpoint.
-func handleReadyRequest(w http.ResponseWriter, r *http.Request, ready *atomic.Bool) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// newGCOMClient initializes the GCOM (grafana.com) client.
+func newGCOMClient(log *logger.LeveledLogger) gcom.APIClient {
+	return gcom.NewAPIClient(api.WithHooks(requestLogHooks(log.WithComponent(logger.ComponentGCOM))))
+}
 
-	if ready.Load() {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Ready"))
-	} else {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		w.Write([]byte("Not Ready"))
+// requestLogHooks returns hooks logging each request made by an API client, with its outcome and duration, in verbose mode.
+func requestLogHooks(log *logger.LeveledLogger) api.Hooks {
+	return api.Hooks{
+		OnResponse: func(req *http.Request, statusCode int, duration time.Duration, err error) {
+			if err != nil {
+				log.Verbose().Log("%s %s: %s (%s)", req.Method, req.URL.Path, err, duration.Round(time.Millisecond))
+				return
+			}
+			log.Verbose().Log("%s %s: %d (%s)", req.Method, req.URL.Path, statusCode, duration.Round(time.Millisecond))
+		},
 	}
 }
 
-// filterAngularDashboards filters dashboards to include only those with detections.
-func filterAngularDashboards(dashboards []output.Dashboard) []output.Dashboard {
-	var angularDashboards []output.Dashboard
-	for _, dashboard := range dashboards {
-		if len(dashboard.Detections) > 0 {
-			angularDashboards = append(angularDashboards, dashboard)
-		}
+// checkSnapshotAge warns if the bundled list of Angular plugins used in offline mode is older than -max-snapshot-age,
+// since Angular plugins released or detected after the snapshot won't be flagged.
+func checkSnapshotAge(flags *flags.Flags, log *logger.LeveledLogger) {
+	snapshot, err := offline.BundledAngularPluginsSnapshot()
+	if err != nil {
+		log.Warn("Could not determine the age of the bundled list of Angular plugins: %s", err)
+		return
+	}
+	log.Verbose().Log("Using bundled Angular plugins snapshot %s", snapshot)
+	if age := snapshot.Age(time.Now()); flags.MaxSnapshotAge > 0 && age > flags.MaxSnapshotAge {
+		log.Warn(
+			"The bundled list of Angular plugins (snapshot %s) is %d days old, recently detected Angular plugins may not be flagged. "+
+				"Upgrade detect-angular-dashboards or pass -dir-use-gcom to use up-to-date data",
+			snapshot, int(age.Hours()/24),
+		)
 	}
-	return angularDashboards
 }
 
 // newLogger initializes a new leveled logger.
-func newLogger(verbose, jsonOutputFlag bool) *logger.LeveledLogger {
+// If quiet is true, the info messages are discarded, only the warnings and errors are logged.
+func newLogger(verbose, quiet, jsonOutputFlag bool) *logger.LeveledLogger {
 	log := logger.NewLeveledLogger(verbose)
 	if jsonOutputFlag {
 		// Redirect everything to stderr to avoid mixing with json output
 		log.Logger.SetOutput(os.Stderr)
 		log.WarnLogger.SetOutput(os.Stderr)
 	}
+	if quiet {
+		log.Logger.SetOutput(io.Discard)
+	}
 	return log
 }
 
-// getToken retrieves the Grafana token from the environment.
+// readUIDs reads the dashboard uids in the given file, one per line.
+// Empty lines and lines starting with "#" are ignored.
+func readUIDs(fn string) ([]string, error) {
+	if fn == "" {
+		return nil, fmt.Errorf("flag -uids is required")
+	}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uids = append(uids, line)
+	}
+	if len(uids) == 0 {
+		return nil, fmt.Errorf("no dashboard uids in %q", fn)
+	}
+	return uids, nil
+}
+
+// getToken retrieves the Grafana token from the environment, or from the file in GRAFANA_TOKEN_FILE if set,
+// e.g. a mounted secret, which is re-read on reload in server mode to rotate the token.
 func getToken() (string, error) {
-	token := os.Getenv(envGrafana)
+	fn := os.Getenv(envGrafanaFile)
+	if fn == "" {
+		token := os.Getenv(envGrafana)
+		if token == "" {
+			return "", fmt.Errorf("environment variable %s is not set", envGrafana)
+		}
+		return token, nil
+	}
+	if os.Getenv(envGrafana) != "" {
+		return "", fmt.Errorf("environment variables %s and %s are mutually exclusive", envGrafana, envGrafanaFile)
+	}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
 	if token == "" {
-		return "", fmt.Errorf("environment variable %s is not set", envGrafana)
+		return "", fmt.Errorf("token file %s is empty", fn)
 	}
 	return token, nil
 }
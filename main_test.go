@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/api"
+	"github.com/grafana/detect-angular-dashboards/api/fixtures"
+	"github.com/grafana/detect-angular-dashboards/api/gcom"
+	"github.com/grafana/detect-angular-dashboards/audit"
+	"github.com/grafana/detect-angular-dashboards/flags"
+	"github.com/grafana/detect-angular-dashboards/instances"
+	"github.com/grafana/detect-angular-dashboards/logger"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// newTestCloudClient returns a client of a fake grafana.com, with the given handler.
+func newTestCloudClient(t *testing.T, handler http.HandlerFunc) gcom.APIClient {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return gcom.APIClient{Client: api.NewClient(srv.URL)}
+}
+
+func TestDiscoverStacks(t *testing.T) {
+	stacks := `[
+		{"slug": "a", "url": "https://a.grafana.net/", "status": "active"},
+		{"slug": "b", "url": "https://b.grafana.net", "status": "paused"},
+		{"slug": "c", "url": "https://c.grafana.net", "status": "active"}
+	]`
+	cl := newTestCloudClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/org/instances":
+			_, _ = w.Write([]byte(`{"items": ` + stacks + `}`))
+		case "/orgs/paused/instances":
+			_, _ = w.Write([]byte(`{"items": [{"slug": "b", "status": "paused"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	log := logger.NewLeveledLogger(false)
+
+	out, err := discoverStacks(cl, "org", log)
+	require.NoError(t, err)
+	require.Equal(t, []instances.Instance{
+		{Name: "a", URL: "https://a.grafana.net/api"},
+		{Name: "c", URL: "https://c.grafana.net/api"},
+	}, out)
+
+	_, err = discoverStacks(cl, "paused", log)
+	require.EqualError(t, err, "no active stacks")
+
+	_, err = discoverStacks(cl, "missing", log)
+	require.Equal(t, http.StatusNotFound, api.StatusCode(err))
+}
+
+func TestStackTokens(t *testing.T) {
+	type stackRequest struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+	var mu sync.Mutex
+	var requests []stackRequest
+	var failTokens bool
+	cl := newTestCloudClient(t, func(w http.ResponseWriter, r *http.Request) {
+		req := stackRequest{method: r.Method, path: r.URL.Path}
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req.body))
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/instances/stack/api/serviceaccounts":
+			_, _ = w.Write([]byte(`{"id": 7}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/instances/stack/api/serviceaccounts/7/tokens":
+			if failTokens {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"key": "glsa_token"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/instances/stack/api/serviceaccounts/7":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	tokens := stackTokens(cl, logger.NewLeveledLogger(false))
+	stack := instances.Instance{Name: "stack", URL: "https://stack.grafana.net/api"}
+
+	t.Run("created and released", func(t *testing.T) {
+		requests = nil
+		var actions []string
+		token, release, err := tokens(context.Background(), stack, func(action string) { actions = append(actions, action) })
+		require.NoError(t, err)
+		require.Equal(t, "glsa_token", token)
+		release()
+
+		require.Len(t, requests, 3)
+		name, _ := requests[0].body["name"].(string)
+		require.True(t, strings.HasPrefix(name, cloudServiceAccountName+"-"), name)
+		require.Equal(t, map[string]interface{}{"name": name, "role": "Viewer"}, requests[0].body)
+		require.Equal(t, map[string]interface{}{"name": name, "secondsToLive": cloudTokenTTL.Seconds()}, requests[1].body)
+		require.Equal(t, stackRequest{method: http.MethodDelete, path: "/instances/stack/api/serviceaccounts/7"}, requests[2])
+		require.Equal(t, []string{
+			`create service account 7 in stack "stack"`,
+			`create token of service account 7 in stack "stack"`,
+			`delete service account 7 in stack "stack"`,
+		}, actions)
+
+		// The names are unique, so concurrent runs don't conflict
+		requests = nil
+		_, release, err = tokens(context.Background(), stack, func(string) {})
+		require.NoError(t, err)
+		release()
+		require.NotEqual(t, name, requests[0].body["name"])
+	})
+
+	t.Run("released on error", func(t *testing.T) {
+		requests = nil
+		failTokens = true
+		defer func() { failTokens = false }()
+		_, _, err := tokens(context.Background(), stack, func(string) {})
+		require.Error(t, err)
+		require.Len(t, requests, 3)
+		require.Equal(t, http.MethodDelete, requests[2].method)
+	})
+}
+
+func TestRunInstancesMode(t *testing.T) {
+	srv := httptest.NewServer(fixtures.Handler(filepath.Join("api", "fixtures", "testdata")))
+	defer srv.Close()
+	gcomClient := gcom.NewAPIClient()
+	gcomClient.BaseURL = srv.URL + fixtures.GCOMPrefix
+
+	dir := t.TempDir()
+	f := &flags.Flags{
+		MaxConcurrency:       2,
+		InstancesConcurrency: 3,
+		JSONOutput:           true,
+		OutputFile:           filepath.Join(dir, "out.json"),
+		AuditLog:             filepath.Join(dir, "audit.log"),
+	}
+	instanceList := []instances.Instance{
+		{Name: "a", URL: srv.URL + fixtures.GrafanaPrefix, URLBase: "https://a.example.com"},
+		{Name: "b", URL: srv.URL + fixtures.GrafanaPrefix},
+		{Name: "c", URL: srv.URL + fixtures.GrafanaPrefix},
+	}
+	tokens := func(_ context.Context, instance instances.Instance, _ func(string)) (string, func(), error) {
+		if instance.Name == "c" {
+			return "", nil, errors.New("no token")
+		}
+		return "token", func() {}, nil
+	}
+	err := runInstancesMode(f, logger.NewLeveledLogger(false), gcomClient, instanceList, tokens, nil, nil)
+	require.EqualError(t, err, "1 of 3 instances failed: c")
+
+	// Each instance has its own base URL
+	data, err := output.ReadJSON(f.OutputFile)
+	require.NoError(t, err)
+	urls := map[string]string{}
+	for _, dashboard := range data {
+		urls[dashboard.Instance] = dashboard.URL
+	}
+	require.Equal(t, map[string]string{
+		"a": "https://a.example.com/d/worldmap/worldmap",
+		"b": srv.URL + "/d/worldmap/worldmap",
+	}, urls)
+
+	// The instances are audited concurrently, one record each
+	b, err := os.ReadFile(f.AuditLog)
+	require.NoError(t, err)
+	var errs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var record audit.Record
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		errs = append(errs, record.Error)
+	}
+	require.ElementsMatch(t, []string{"", "", "get token: no token"}, errs)
+}
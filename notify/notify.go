@@ -0,0 +1,254 @@
+// Package notify sends a summary of the detection runs to external services, like Slack or Microsoft Teams.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// requestTimeout is the timeout of the requests posting the notifications, so an unresponsive service
+// doesn't block the runs.
+const requestTimeout = 30 * time.Second
+
+// Run is a detection run to notify about.
+type Run struct {
+	// Dashboards are all the scanned dashboards.
+	Dashboards []output.Dashboard
+
+	// Recorded is true if the run has been recorded in the trend file (see flag -trend-file), so Changes are known.
+	Recorded bool
+
+	// Previous is the time of the previous recorded run, empty for the first one.
+	Previous string
+
+	// Changes are the changes of the detections since the previous run (see output.Diff).
+	// All the dashboards with detections are new in the first recorded run.
+	Changes output.ReportDiff
+}
+
+// Notifier sends notifications about detection runs.
+type Notifier interface {
+	// Name is the name of the notifier, used in errors.
+	Name() string
+
+	// Notify sends the notification of the given run.
+	Notify(ctx context.Context, run Run) error
+}
+
+// NotifyAll sends the notification of the given run with all the given notifiers.
+// A failing notifier doesn't stop the others, their errors are returned together.
+func NotifyAll(ctx context.Context, notifiers []Notifier, run Run) error {
+	var errs []error
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, run); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// config is the content of the notifiers file.
+type config struct {
+	Slack []WebhookConfig `yaml:"slack"`
+	Teams []WebhookConfig `yaml:"teams"`
+}
+
+// WebhookConfig is the configuration of a Slack or Microsoft Teams notifier in the notifiers file.
+type WebhookConfig struct {
+	// WebhookURL is the URL of the incoming webhook.
+	WebhookURL string `yaml:"webhookUrl"`
+
+	// WebhookURLEnv is the name of the environment variable containing the URL of the webhook, if WebhookURL is not set,
+	// so the URL, which is a secret, doesn't have to be written in the file.
+	WebhookURLEnv string `yaml:"webhookUrlEnv"`
+
+	// OnlyNew is true to only notify about the detections that are new since the previous run.
+	OnlyNew bool `yaml:"onlyNew"`
+
+	// MaxDashboards is the maximum number of dashboards listed in the message, DefaultMaxDashboards if zero.
+	MaxDashboards int `yaml:"maxDashboards"`
+}
+
+// url validates the configuration, and returns the URL of the webhook, from the configuration or from its environment variable.
+func (c WebhookConfig) url() (string, error) {
+	switch {
+	case c.MaxDashboards < 0:
+		return "", fmt.Errorf("maxDashboards must not be negative")
+	case c.WebhookURL != "" && c.WebhookURLEnv != "":
+		return "", fmt.Errorf("webhookUrl and webhookUrlEnv are mutually exclusive")
+	case c.WebhookURLEnv != "":
+		url := os.Getenv(c.WebhookURLEnv)
+		if url == "" {
+			return "", fmt.Errorf("environment variable %s is not set", c.WebhookURLEnv)
+		}
+		return url, nil
+	case c.WebhookURL == "":
+		return "", fmt.Errorf("missing webhookUrl or webhookUrlEnv")
+	}
+	return c.WebhookURL, nil
+}
+
+// ReadFile reads the notifiers in the given YAML file. Unknown fields are rejected, to catch typos.
+func ReadFile(fn string) ([]Notifier, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	var out []Notifier
+	for i, c := range cfg.Slack {
+		url, err := c.url()
+		if err != nil {
+			return nil, fmt.Errorf("slack notifier %d: %w", i, err)
+		}
+		out = append(out, NewSlackNotifier(url, c.OnlyNew).WithMaxDashboards(c.MaxDashboards))
+	}
+	for i, c := range cfg.Teams {
+		url, err := c.url()
+		if err != nil {
+			return nil, fmt.Errorf("teams notifier %d: %w", i, err)
+		}
+		out = append(out, NewTeamsNotifier(url, c.OnlyNew).WithMaxDashboards(c.MaxDashboards))
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no notifiers in %q", fn)
+	}
+	return out, nil
+}
+
+// message is the content of a notification, rendered by each notifier in its own format.
+type message struct {
+	// Headline is the first line of the message.
+	Headline string
+
+	// Changes are the changes since the previous run, if known.
+	Changes string
+
+	// Dashboards are the dashboards to list, at most the maximum number of dashboards of the notifier,
+	// and More is the number of dashboards left out.
+	Dashboards []output.Dashboard
+	More       int
+
+	// New is true if the Dashboards only have their detections that are new since the previous run.
+	New bool
+}
+
+// newMessage returns the message of the given run, listing at most maxDashboards dashboards, or nil if there is nothing
+// to notify. If onlyNew is true, the message is about the detections that are new since the previous run,
+// including the ones in dashboards that already had detections.
+func newMessage(run Run, onlyNew bool, maxDashboards int) *message {
+	var m message
+	if onlyNew {
+		if len(run.Changes.AddedDetections) == 0 {
+			return nil
+		}
+		summary := output.NewSummary(run.Changes.AddedDetections)
+		m.Headline = fmt.Sprintf(
+			"%d new Angular detections in %d dashboards (%d new dashboards with Angular plugins)",
+			summary.Detections, summary.DashboardsWithDetections, len(run.Changes.New),
+		)
+		if run.Previous != "" {
+			m.Headline += fmt.Sprintf(" since the previous run (%s)", run.Previous)
+		}
+		m.Headline += "."
+		m.Dashboards = worstDashboards(run.Changes.AddedDetections)
+		m.New = true
+	} else {
+		summary := output.NewSummary(run.Dashboards)
+		m.Headline = fmt.Sprintf(
+			"%d dashboards scanned, %d with Angular plugins, %d detections.",
+			summary.Dashboards, summary.DashboardsWithDetections, summary.Detections,
+		)
+		if run.Recorded && run.Previous != "" {
+			m.Changes = fmt.Sprintf(
+				"Since the previous run (%s): %d new, %d resolved, %d with changed detections.",
+				run.Previous, len(run.Changes.New), len(run.Changes.Resolved), len(run.Changes.Changed),
+			)
+		}
+		m.Dashboards = worstDashboards(run.Dashboards)
+	}
+	if len(m.Dashboards) > maxDashboards {
+		m.More = len(m.Dashboards) - maxDashboards
+		m.Dashboards = m.Dashboards[:maxDashboards]
+	}
+	return &m
+}
+
+// listTitle returns the title of the list of the dashboards of the message.
+func (m *message) listTitle() string {
+	if m.New {
+		return "Dashboards with the most new detections"
+	}
+	return "Dashboards with the most detections"
+}
+
+// detections returns the number of detections of the given listed dashboard, as text.
+func (m *message) detections(dashboard output.Dashboard) string {
+	if m.New {
+		return fmt.Sprintf("%d new detections", len(dashboard.Detections))
+	}
+	return fmt.Sprintf("%d detections", len(dashboard.Detections))
+}
+
+// worstDashboards returns the given dashboards with detections, from the one with the most detections
+// to the one with the least, then by title.
+func worstDashboards(dashboards []output.Dashboard) []output.Dashboard {
+	var out []output.Dashboard
+	for _, dashboard := range dashboards {
+		if len(dashboard.Detections) > 0 {
+			out = append(out, dashboard)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if len(out[i].Detections) != len(out[j].Detections) {
+			return len(out[i].Detections) > len(out[j].Detections)
+		}
+		return out[i].Title < out[j].Title
+	})
+	return out
+}
+
+// isAbsoluteURL returns true if the given URL is an absolute http(s) URL, which can be linked from messages.
+func isAbsoluteURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// postJSON posts the given payload as JSON to the given URL.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		fn := filepath.Join(t.TempDir(), "notifiers.yaml")
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0o600))
+		return fn
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/b")
+		notifiers, err := ReadFile(write(t, `
+slack:
+  - webhookUrl: https://hooks.slack.com/services/a
+  - webhookUrlEnv: SLACK_WEBHOOK_URL
+    onlyNew: true
+    maxDashboards: 5
+teams:
+  - webhookUrl: https://example.webhook.office.com/webhookb2/c
+`))
+		require.NoError(t, err)
+		require.Equal(t, []Notifier{
+			NewSlackNotifier("https://hooks.slack.com/services/a", false),
+			NewSlackNotifier("https://hooks.slack.com/services/b", true).WithMaxDashboards(5),
+			NewTeamsNotifier("https://example.webhook.office.com/webhookb2/c", false),
+		}, notifiers)
+	})
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		expErr  string
+	}{
+		{name: "empty", content: "", expErr: "no notifiers"},
+		{name: "unknown field", content: "slack:\n  - url: https://hooks.slack.com/services/a\n", expErr: "field url not found"},
+		{name: "missing url", content: "slack:\n  - onlyNew: true\n", expErr: "slack notifier 0: missing webhookUrl or webhookUrlEnv"},
+		{
+			name:    "url and env",
+			content: "slack:\n  - webhookUrl: https://hooks.slack.com/services/a\n    webhookUrlEnv: SLACK_WEBHOOK_URL\n",
+			expErr:  "slack notifier 0: webhookUrl and webhookUrlEnv are mutually exclusive",
+		},
+		{
+			name:    "negative max dashboards",
+			content: "teams:\n  - webhookUrlEnv: DETECT_ANGULAR_DASHBOARDS_UNSET\n    maxDashboards: -1\n",
+			expErr:  "teams notifier 0: maxDashboards must not be negative",
+		},
+		{
+			name:    "env not set",
+			content: "slack:\n  - webhookUrlEnv: DETECT_ANGULAR_DASHBOARDS_UNSET\n",
+			expErr:  "slack notifier 0: environment variable DETECT_ANGULAR_DASHBOARDS_UNSET is not set",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadFile(write(t, tc.content))
+			require.ErrorContains(t, err, tc.expErr)
+		})
+	}
+}
+
+// testNotifier is a Notifier returning err.
+type testNotifier struct {
+	name     string
+	err      error
+	notified int
+}
+
+func (n *testNotifier) Name() string {
+	return n.name
+}
+
+func (n *testNotifier) Notify(_ context.Context, _ Run) error {
+	n.notified++
+	return n.err
+}
+
+func TestNotifyAll(t *testing.T) {
+	failing := &testNotifier{name: "failing", err: errors.New("boom")}
+	ok := &testNotifier{name: "ok"}
+	err := NotifyAll(context.Background(), []Notifier{failing, ok}, Run{})
+	require.EqualError(t, err, "failing: boom")
+	require.Equal(t, 1, failing.notified)
+	require.Equal(t, 1, ok.notified)
+}
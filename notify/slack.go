@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// DefaultMaxDashboards is the default maximum number of dashboards listed in the messages.
+const DefaultMaxDashboards = 10
+
+// SlackNotifier posts a summary of the detection runs to a Slack incoming webhook, with links to the dashboards
+// with the most detections.
+type SlackNotifier struct {
+	url           string
+	onlyNew       bool
+	maxDashboards int
+	httpClient    *http.Client
+}
+
+// NewSlackNotifier returns a new SlackNotifier posting to the given incoming webhook URL.
+// If onlyNew is true, it only notifies about the detections that are new since the previous run,
+// and doesn't post anything when there are none. This requires the runs to be recorded (see Run.Recorded).
+func NewSlackNotifier(url string, onlyNew bool) SlackNotifier {
+	return SlackNotifier{url: url, onlyNew: onlyNew, maxDashboards: DefaultMaxDashboards, httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// WithMaxDashboards returns a copy of the notifier listing at most maxDashboards dashboards,
+// DefaultMaxDashboards if zero.
+func (n SlackNotifier) WithMaxDashboards(maxDashboards int) SlackNotifier {
+	if maxDashboards == 0 {
+		maxDashboards = DefaultMaxDashboards
+	}
+	n.maxDashboards = maxDashboards
+	return n
+}
+
+func (n SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (n SlackNotifier) Notify(ctx context.Context, run Run) error {
+	if n.onlyNew && !run.Recorded {
+		return fmt.Errorf("only notifying about new detections requires recording the runs in a trend file")
+	}
+	text := n.Text(run)
+	if text == "" {
+		return nil
+	}
+	return postJSON(ctx, n.httpClient, n.url, map[string]string{"text": text})
+}
+
+// Text returns the text of the Slack message of the given run, in Slack's mrkdwn format,
+// or an empty string if there is nothing to notify.
+func (n SlackNotifier) Text(run Run) string {
+	m := newMessage(run, n.onlyNew, n.maxDashboards)
+	if m == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("*Angular detection:* " + m.Headline + "\n")
+	if m.Changes != "" {
+		b.WriteString(m.Changes + "\n")
+	}
+	if len(m.Dashboards) == 0 {
+		return b.String()
+	}
+	b.WriteString(m.listTitle() + ":\n")
+	for _, dashboard := range m.Dashboards {
+		fmt.Fprintf(
+			&b, "• %s: %s (%s)\n",
+			slackLink(dashboard), m.detections(dashboard), slackEscape(strings.Join(dashboard.PluginIDs(), ", ")),
+		)
+	}
+	if m.More > 0 {
+		fmt.Fprintf(&b, "… and %d more\n", m.More)
+	}
+	return b.String()
+}
+
+// slackLink returns the title of the given dashboard, linking to it if its URL is absolute.
+func slackLink(dashboard output.Dashboard) string {
+	title := slackEscape(dashboard.Title)
+	if dashboard.Instance != "" {
+		title = slackEscape(dashboard.Instance) + " / " + title
+	}
+	if !isAbsoluteURL(dashboard.URL) {
+		return title
+	}
+	return "<" + dashboard.URL + "|" + title + ">"
+}
+
+// slackEscape escapes the control characters of Slack's mrkdwn format.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// testDashboards are the dashboards of the tests of the notifiers.
+var testDashboards = []output.Dashboard{
+	{Title: "A", URL: "http://grafana.example.com/d/a/a", Detections: []output.Detection{{PluginID: "graph"}}},
+	{
+		Title: "B <b>", URL: "http://grafana.example.com/d/b/b",
+		Detections: []output.Detection{{PluginID: "singlestat"}, {PluginID: "graph"}, {PluginID: "graph"}},
+	},
+	{Title: "C", URL: "/d/c/c", Detections: []output.Detection{{PluginID: "graph"}}},
+	{Title: "D", URL: "http://grafana.example.com/d/d/d"},
+}
+
+// testChanges are the changes of testDashboards since a previous run: C is new, B has a new singlestat panel,
+// and X and Y are resolved.
+var testChanges = output.Diff([]output.Dashboard{
+	{Title: "A", URL: "http://grafana.example.com/d/a/a", Detections: []output.Detection{{PluginID: "graph"}}},
+	{Title: "B <b>", URL: "http://grafana.example.com/d/b/b", Detections: []output.Detection{{PluginID: "graph"}, {PluginID: "graph"}}},
+	{Title: "X", URL: "/d/x/x", Detections: []output.Detection{{PluginID: "graph"}}},
+	{Title: "Y", URL: "/d/y/y", Detections: []output.Detection{{PluginID: "graph"}}},
+}, testDashboards)
+
+func TestSlackNotifierText(t *testing.T) {
+	t.Run("summary", func(t *testing.T) {
+		require.Equal(
+			t,
+			"*Angular detection:* 4 dashboards scanned, 3 with Angular plugins, 5 detections.\n"+
+				"Dashboards with the most detections:\n"+
+				"• <http://grafana.example.com/d/b/b|B &lt;b&gt;>: 3 detections (graph, singlestat)\n"+
+				"• <http://grafana.example.com/d/a/a|A>: 1 detections (graph)\n"+
+				"• C: 1 detections (graph)\n",
+			NewSlackNotifier("", false).Text(Run{Dashboards: testDashboards}),
+		)
+	})
+
+	t.Run("summary with previous run", func(t *testing.T) {
+		require.Equal(
+			t,
+			"*Angular detection:* 4 dashboards scanned, 3 with Angular plugins, 5 detections.\n"+
+				"Since the previous run (2024-01-01T00:00:00Z): 1 new, 2 resolved, 1 with changed detections.\n"+
+				"Dashboards with the most detections:\n"+
+				"• <http://grafana.example.com/d/b/b|B &lt;b&gt;>: 3 detections (graph, singlestat)\n"+
+				"… and 2 more\n",
+			NewSlackNotifier("", false).WithMaxDashboards(1).Text(Run{
+				Dashboards: testDashboards, Recorded: true, Previous: "2024-01-01T00:00:00Z", Changes: testChanges,
+			}),
+		)
+	})
+
+	t.Run("only new", func(t *testing.T) {
+		require.Equal(
+			t,
+			"*Angular detection:* 2 new Angular detections in 2 dashboards (1 new dashboards with Angular plugins) "+
+				"since the previous run (2024-01-01T00:00:00Z).\n"+
+				"Dashboards with the most new detections:\n"+
+				"• <http://grafana.example.com/d/b/b|B &lt;b&gt;>: 1 new detections (singlestat)\n"+
+				"• C: 1 new detections (graph)\n",
+			NewSlackNotifier("", true).Text(Run{
+				Dashboards: testDashboards, Recorded: true, Previous: "2024-01-01T00:00:00Z", Changes: testChanges,
+			}),
+		)
+	})
+
+	t.Run("only new in known dashboards", func(t *testing.T) {
+		// A new panel in a dashboard that already had detections is notified, without the dashboard's other detections
+		previous := []output.Dashboard{testDashboards[0], testDashboards[1], testDashboards[2]}
+		previous[1].Detections = previous[1].Detections[1:]
+		require.Equal(
+			t,
+			"*Angular detection:* 1 new Angular detections in 1 dashboards (0 new dashboards with Angular plugins).\n"+
+				"Dashboards with the most new detections:\n"+
+				"• <http://grafana.example.com/d/b/b|B &lt;b&gt;>: 1 new detections (singlestat)\n",
+			NewSlackNotifier("", true).Text(Run{
+				Dashboards: testDashboards, Recorded: true, Changes: output.Diff(previous, testDashboards),
+			}),
+		)
+	})
+
+	t.Run("only new without new detections", func(t *testing.T) {
+		require.Empty(t, NewSlackNotifier("", true).Text(Run{
+			Dashboards: testDashboards, Recorded: true, Changes: output.Diff(testDashboards, testDashboards),
+		}))
+	})
+}
+
+func TestSlackNotifierNotify(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		texts = append(texts, payload.Text)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	require.NoError(t, NewSlackNotifier(srv.URL, false).Notify(context.Background(), Run{Dashboards: testDashboards}))
+	require.Len(t, texts, 1)
+	require.Contains(t, texts[0], "3 with Angular plugins")
+
+	// Nothing to notify
+	require.NoError(t, NewSlackNotifier(srv.URL, true).Notify(context.Background(), Run{Dashboards: testDashboards, Recorded: true}))
+	require.Len(t, texts, 1)
+
+	require.EqualError(
+		t, NewSlackNotifier(srv.URL, true).Notify(context.Background(), Run{Dashboards: testDashboards}),
+		"only notifying about new detections requires recording the runs in a trend file",
+	)
+	require.EqualError(
+		t, NewSlackNotifier(srv.URL+"/fail", false).Notify(context.Background(), Run{Dashboards: testDashboards}),
+		"bad status code: 500",
+	)
+}
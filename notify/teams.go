@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// teamsThemeColor is the color of the accent of the Microsoft Teams cards (Grafana orange).
+const teamsThemeColor = "F46800"
+
+// TeamsNotifier posts a summary of the detection runs to a Microsoft Teams incoming webhook, as a connector card
+// (MessageCard) with links to the dashboards with the most detections.
+type TeamsNotifier struct {
+	url           string
+	onlyNew       bool
+	maxDashboards int
+	httpClient    *http.Client
+}
+
+// TeamsCard is a Microsoft Teams connector card (legacy actionable message card format).
+type TeamsCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor"`
+	Title      string `json:"title"`
+
+	// Text is the body of the card, in the Markdown subset supported by Teams.
+	Text string `json:"text"`
+}
+
+// NewTeamsNotifier returns a new TeamsNotifier posting to the given incoming webhook URL.
+// If onlyNew is true, it only notifies about the detections that are new since the previous run,
+// and doesn't post anything when there are none. This requires the runs to be recorded (see Run.Recorded).
+func NewTeamsNotifier(url string, onlyNew bool) TeamsNotifier {
+	return TeamsNotifier{url: url, onlyNew: onlyNew, maxDashboards: DefaultMaxDashboards, httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// WithMaxDashboards returns a copy of the notifier listing at most maxDashboards dashboards,
+// DefaultMaxDashboards if zero.
+func (n TeamsNotifier) WithMaxDashboards(maxDashboards int) TeamsNotifier {
+	if maxDashboards == 0 {
+		maxDashboards = DefaultMaxDashboards
+	}
+	n.maxDashboards = maxDashboards
+	return n
+}
+
+func (n TeamsNotifier) Name() string {
+	return "teams"
+}
+
+func (n TeamsNotifier) Notify(ctx context.Context, run Run) error {
+	if n.onlyNew && !run.Recorded {
+		return fmt.Errorf("only notifying about new detections requires recording the runs in a trend file")
+	}
+	card := n.Card(run)
+	if card == nil {
+		return nil
+	}
+	return postJSON(ctx, n.httpClient, n.url, card)
+}
+
+// Card returns the card of the given run, or nil if there is nothing to notify.
+func (n TeamsNotifier) Card(run Run) *TeamsCard {
+	m := newMessage(run, n.onlyNew, n.maxDashboards)
+	if m == nil {
+		return nil
+	}
+	// Teams needs blank lines between paragraphs
+	paragraphs := []string{m.Headline}
+	if m.Changes != "" {
+		paragraphs = append(paragraphs, m.Changes)
+	}
+	if len(m.Dashboards) > 0 {
+		var b strings.Builder
+		b.WriteString("**" + m.listTitle() + ":**\n\n")
+		for _, dashboard := range m.Dashboards {
+			fmt.Fprintf(
+				&b, "- %s: %s (%s)\n",
+				teamsLink(dashboard), m.detections(dashboard), teamsEscape(strings.Join(dashboard.PluginIDs(), ", ")),
+			)
+		}
+		if m.More > 0 {
+			fmt.Fprintf(&b, "\n… and %d more", m.More)
+		}
+		paragraphs = append(paragraphs, strings.TrimSuffix(b.String(), "\n"))
+	}
+	return &TeamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    "Angular detection: " + m.Headline,
+		ThemeColor: teamsThemeColor,
+		Title:      "Angular detection",
+		Text:       strings.Join(paragraphs, "\n\n"),
+	}
+}
+
+// teamsLink returns the title of the given dashboard, linking to it if its URL is absolute.
+func teamsLink(dashboard output.Dashboard) string {
+	title := teamsEscape(dashboard.Title)
+	if dashboard.Instance != "" {
+		title = teamsEscape(dashboard.Instance) + " / " + title
+	}
+	if !isAbsoluteURL(dashboard.URL) {
+		return title
+	}
+	return "[" + title + "](" + dashboard.URL + ")"
+}
+
+// teamsEscape escapes the Markdown and HTML control characters, which Teams both interprets in cards.
+func teamsEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "&", "&amp;", "<", "&lt;", ">", "&gt;",
+	).Replace(s)
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamsNotifierCard(t *testing.T) {
+	t.Run("summary", func(t *testing.T) {
+		require.Equal(t, &TeamsCard{
+			Type:       "MessageCard",
+			Context:    "https://schema.org/extensions",
+			Summary:    "Angular detection: 4 dashboards scanned, 3 with Angular plugins, 5 detections.",
+			ThemeColor: "F46800",
+			Title:      "Angular detection",
+			Text: "4 dashboards scanned, 3 with Angular plugins, 5 detections.\n\n" +
+				"Since the previous run (2024-01-01T00:00:00Z): 1 new, 2 resolved, 1 with changed detections.\n\n" +
+				"**Dashboards with the most detections:**\n\n" +
+				"- [B &lt;b&gt;](http://grafana.example.com/d/b/b): 3 detections (graph, singlestat)\n" +
+				"- [A](http://grafana.example.com/d/a/a): 1 detections (graph)\n\n" +
+				"… and 1 more",
+		}, NewTeamsNotifier("", false).WithMaxDashboards(2).Card(Run{
+			Dashboards: testDashboards, Recorded: true, Previous: "2024-01-01T00:00:00Z", Changes: testChanges,
+		}))
+	})
+
+	t.Run("only new", func(t *testing.T) {
+		card := NewTeamsNotifier("", true).Card(Run{Dashboards: testDashboards, Recorded: true, Changes: testChanges})
+		require.Equal(
+			t,
+			"2 new Angular detections in 2 dashboards (1 new dashboards with Angular plugins).\n\n"+
+				"**Dashboards with the most new detections:**\n\n"+
+				"- [B &lt;b&gt;](http://grafana.example.com/d/b/b): 1 new detections (singlestat)\n"+
+				"- C: 1 new detections (graph)",
+			card.Text,
+		)
+		require.Nil(t, NewTeamsNotifier("", true).Card(Run{Dashboards: testDashboards, Recorded: true}))
+	})
+}
+
+func TestTeamsNotifierNotify(t *testing.T) {
+	var cards []TeamsCard
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var card TeamsCard
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&card))
+		cards = append(cards, card)
+	}))
+	t.Cleanup(srv.Close)
+
+	require.NoError(t, NewTeamsNotifier(srv.URL, false).Notify(context.Background(), Run{Dashboards: testDashboards}))
+	require.Len(t, cards, 1)
+	require.Equal(t, "MessageCard", cards[0].Type)
+	require.EqualError(
+		t, NewTeamsNotifier(srv.URL, true).Notify(context.Background(), Run{Dashboards: testDashboards}),
+		"only notifying about new detections requires recording the runs in a trend file",
+	)
+}
+
+func TestTeamsNotifierTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	n := NewTeamsNotifier(srv.URL, false)
+	require.Equal(t, requestTimeout, n.httpClient.Timeout)
+	n.httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+	err := n.Notify(context.Background(), Run{Dashboards: testDashboards})
+	require.ErrorContains(t, err, "Client.Timeout exceeded")
+}
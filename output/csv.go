@@ -0,0 +1,182 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status is the status of a dashboard in the CSV export, compared to a previous report.
+type Status string
+
+const (
+	// StatusNew is for dashboards with detections that were not in the previous report.
+	StatusNew Status = "new"
+
+	// StatusKnown is for dashboards with detections that were already in the previous report.
+	StatusKnown Status = "known"
+
+	// StatusResolved is for dashboards of the previous report that no longer have detections.
+	StatusResolved Status = "resolved"
+)
+
+// csvHeader is the header of the CSV export.
+var csvHeader = []string{
+	"UID", "Title", "URL", "Folder", "Owner", "Detections", "MaxSeverity", "FirstSeen", "Status",
+}
+
+// detectionsCSVHeader is the header of the CSV export with one row per detection.
+var detectionsCSVHeader = []string{
+	"Dashboard", "URL", "Folder", "PluginID", "DetectionType", "Panel", "CreatedBy", "Updated",
+}
+
+// severityOrder orders the severities from the least to the most severe, for the MaxSeverity column.
+var severityOrder = []Severity{
+	SeverityLow, SeverityAutoMigratable, SeverityUnknown, SeverityReplacementAvailable, SeverityNoReplacement,
+}
+
+// CSVOutputter outputs one CSV row per dashboard with detections, for tracking the migration in a spreadsheet.
+// The status of each dashboard is determined by comparing it with a previous report.
+type CSVOutputter struct {
+	writer   io.Writer
+	previous []Dashboard
+}
+
+// NewCSVOutputter returns a new CSVOutputter writing to w. previous is the previous report, used to determine
+// the status of the dashboards: without it, all the dashboards are new.
+func NewCSVOutputter(w io.Writer, previous []Dashboard) CSVOutputter {
+	return CSVOutputter{writer: w, previous: previous}
+}
+
+func (o CSVOutputter) Output(v []Dashboard) error {
+	previous := make(map[string]struct{}, len(o.previous))
+	for _, dashboard := range o.previous {
+		if len(dashboard.Detections) > 0 {
+			previous[ReportKey(dashboard)] = struct{}{}
+		}
+	}
+	w := csv.NewWriter(o.writer)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	current := make(map[string]struct{}, len(v))
+	for _, dashboard := range v {
+		if len(dashboard.Detections) == 0 {
+			continue
+		}
+		key := ReportKey(dashboard)
+		current[key] = struct{}{}
+		status := StatusNew
+		if _, ok := previous[key]; ok {
+			status = StatusKnown
+		}
+		if err := w.Write(csvRow(dashboard, status)); err != nil {
+			return err
+		}
+	}
+	for _, dashboard := range o.previous {
+		if _, ok := current[ReportKey(dashboard)]; ok || len(dashboard.Detections) == 0 {
+			continue
+		}
+		if err := w.Write(csvRow(dashboard, StatusResolved)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvRow returns the CSV row of the given dashboard, with the given status.
+// The owner is the teams owning the folder of the dashboard if known, or the user that created it.
+func csvRow(dashboard Dashboard, status Status) []string {
+	owner := strings.Join(dashboard.Owners, "; ")
+	if owner == "" {
+		owner = dashboard.CreatedBy
+	}
+	detections, maxSeverity := strconv.Itoa(len(dashboard.Detections)), MaxSeverity(dashboard.Detections)
+	if status == StatusResolved {
+		// The detections of resolved dashboards are the ones of the previous report, which have been fixed since
+		detections, maxSeverity = "0", ""
+	}
+	return []string{
+		dashboard.UID,
+		dashboard.Title,
+		dashboard.URL,
+		dashboard.Folder,
+		owner,
+		detections,
+		string(maxSeverity),
+		firstSeen(dashboard.Detections),
+		string(status),
+	}
+}
+
+// MaxSeverity returns the highest severity of the given detections, or an empty severity if none is set.
+func MaxSeverity(detections []Detection) Severity {
+	var highest Severity
+	highestIndex := -1
+	for _, detection := range detections {
+		for i, severity := range severityOrder {
+			if severity == detection.Severity && i > highestIndex {
+				highest, highestIndex = severity, i
+			}
+		}
+	}
+	return highest
+}
+
+// firstSeen returns the date of the earliest dashboard version introducing one of the given detections,
+// which is only known when walking the version history. It returns an empty string otherwise.
+func firstSeen(detections []Detection) string {
+	var first time.Time
+	for _, detection := range detections {
+		introduced, err := time.Parse(time.RFC3339, detection.Introduced)
+		if err != nil {
+			continue
+		}
+		if first.IsZero() || introduced.Before(first) {
+			first = introduced
+		}
+	}
+	if first.IsZero() {
+		return ""
+	}
+	return first.Format(time.DateOnly)
+}
+
+// DetectionsCSVOutputter outputs one CSV row per detection, for triaging the panels to migrate in a spreadsheet.
+type DetectionsCSVOutputter struct {
+	writer io.Writer
+}
+
+// NewDetectionsCSVOutputter returns a new DetectionsCSVOutputter writing to w.
+func NewDetectionsCSVOutputter(w io.Writer) DetectionsCSVOutputter {
+	return DetectionsCSVOutputter{writer: w}
+}
+
+func (o DetectionsCSVOutputter) Output(v []Dashboard) error {
+	w := csv.NewWriter(o.writer)
+	if err := w.Write(detectionsCSVHeader); err != nil {
+		return err
+	}
+	for _, dashboard := range v {
+		for _, detection := range dashboard.Detections {
+			if err := w.Write([]string{
+				dashboard.Title,
+				dashboard.URL,
+				dashboard.Folder,
+				detection.PluginID,
+				string(detection.DetectionType),
+				detection.Title,
+				dashboard.CreatedBy,
+				dashboard.Updated,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
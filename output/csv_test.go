@@ -0,0 +1,70 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVOutputter(t *testing.T) {
+	previous := []Dashboard{
+		{UID: "a", URL: "d/a", Title: "A", Detections: []Detection{{PluginID: "graph"}}},
+		{UID: "b", URL: "d/b", Title: "B", Folder: "Team", Owners: []string{"Team A"}, Detections: []Detection{
+			{PluginID: "graph", Severity: SeverityAutoMigratable, Introduced: "2023-05-01T10:00:00+02:00"},
+		}},
+		{UID: "c", URL: "d/c", Title: "C"},
+	}
+	current := []Dashboard{
+		{UID: "a", URL: "d/a", Title: "A", CreatedBy: "admin", Detections: []Detection{
+			{PluginID: "graph", Severity: SeverityAutoMigratable, Introduced: "2024-01-02T00:00:00Z"},
+			{PluginID: "grafana-worldmap-panel", Severity: SeverityReplacementAvailable, Introduced: "2023-12-31T23:00:00Z"},
+		}},
+		{UID: "c", URL: "d/c", Title: "C, with a comma", Folder: "Team", Owners: []string{"Team A", "Team B"}, Detections: []Detection{
+			{PluginID: "acme-panel", Severity: SeverityNoReplacement},
+		}},
+		{UID: "d", URL: "d/d", Title: "D"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewCSVOutputter(&buf, previous).Output(current))
+	require.Equal(t, `UID,Title,URL,Folder,Owner,Detections,MaxSeverity,FirstSeen,Status
+a,A,d/a,,admin,2,replacement-available,2023-12-31,known
+c,"C, with a comma",d/c,Team,Team A; Team B,1,no-replacement,,new
+b,B,d/b,Team,Team A,0,,2023-05-01,resolved
+`, buf.String())
+
+	t.Run("no previous report", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewCSVOutputter(&buf, nil).Output(current[2:]))
+		require.Equal(t, "UID,Title,URL,Folder,Owner,Detections,MaxSeverity,FirstSeen,Status\n", buf.String())
+	})
+}
+
+func TestDetectionsCSVOutputter(t *testing.T) {
+	dashboards := []Dashboard{
+		{
+			Title: "A", URL: "d/a", Folder: "Team", CreatedBy: "admin", Updated: "2024-01-02T00:00:00Z",
+			Detections: []Detection{
+				{PluginID: "graph", DetectionType: DetectionTypePanel, Title: "Requests"},
+				{PluginID: "akumuli-datasource", DetectionType: DetectionTypeDatasource, Title: "Latency, p99"},
+			},
+		},
+		{Title: "B", URL: "d/b"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewDetectionsCSVOutputter(&buf).Output(dashboards))
+	require.Equal(t, `Dashboard,URL,Folder,PluginID,DetectionType,Panel,CreatedBy,Updated
+A,d/a,Team,graph,panel,Requests,admin,2024-01-02T00:00:00Z
+A,d/a,Team,akumuli-datasource,datasource,"Latency, p99",admin,2024-01-02T00:00:00Z
+`, buf.String())
+}
+
+func TestValidateFormat(t *testing.T) {
+	require.NoError(t, ValidateFormat(""))
+	require.NoError(t, ValidateFormat(FormatDetectionsCSV))
+	require.NoError(t, ValidateFormat(FormatHTML))
+	require.NoError(t, ValidateFormat(FormatXLSX))
+	require.Error(t, ValidateFormat("xml"))
+}
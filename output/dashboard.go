@@ -0,0 +1,130 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// StatusDashboardUID is the uid of the dashboard created by NewStatusDashboard, so publishing it again updates it.
+	StatusDashboardUID = "angular-migration-status"
+
+	// statusDashboardTitle is the title of the dashboard created by NewStatusDashboard.
+	statusDashboardTitle = "Angular Migration Status"
+)
+
+// TrendPoint is a past detection run, shown in the trend of the status dashboard.
+type TrendPoint struct {
+	// Time is the time of the run, as RFC3339 in UTC.
+	Time string
+
+	Dashboards               int
+	DashboardsWithDetections int
+	Detections               int
+}
+
+// NewStatusDashboard returns the model of the "Angular Migration Status" Grafana dashboard reporting the given
+// dashboards: a summary, the detections per plugin, the trend of the given runs if any, and a table of the affected
+// dashboards linking to them. The panels are Markdown text panels, so the dashboard doesn't need a data source.
+func NewStatusDashboard(v []Dashboard, trend []TrendPoint, now time.Time) map[string]interface{} {
+	summary := NewSummary(v)
+	var panels []interface{}
+	y := 0
+	addPanel := func(title, content string, w, h int) {
+		panels = append(panels, map[string]interface{}{
+			"id":      len(panels) + 1,
+			"type":    "text",
+			"title":   title,
+			"gridPos": map[string]interface{}{"x": 0, "y": y, "w": w, "h": h},
+			"options": map[string]interface{}{"mode": "markdown", "content": content},
+		})
+		y += h
+	}
+
+	addPanel("Summary", fmt.Sprintf(
+		"**%d** dashboards scanned, **%d** with Angular plugins, **%d** detections.\n\nGenerated by detect-angular-dashboards on %s.",
+		summary.Dashboards, summary.DashboardsWithDetections, summary.Detections, now.UTC().Format(time.RFC3339),
+	), 24, 3)
+
+	var b strings.Builder
+	b.WriteString("| Plugin | Detections | Dashboards |\n|---|---|---|\n")
+	for _, bar := range countBars(summary.PluginIDs) {
+		fmt.Fprintf(&b, "| %s | %d | %d |\n", markdownEscape(bar.Label), bar.Count, dashboardsWithPlugin(v, bar.Label))
+	}
+	addPanel("Detections per plugin", b.String(), 24, 2+len(summary.PluginIDs))
+
+	if len(trend) > 0 {
+		b.Reset()
+		b.WriteString("| Time | Dashboards | Dashboards with Angular plugins | Detections |\n|---|---|---|---|\n")
+		for _, point := range trend {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", point.Time, point.Dashboards, point.DashboardsWithDetections, point.Detections)
+		}
+		addPanel("Trend", b.String(), 24, 2+len(trend))
+	}
+
+	b.Reset()
+	b.WriteString("| Dashboard | Folder | Plugins | Detections |\n|---|---|---|---|\n")
+	for _, dashboard := range v {
+		if len(dashboard.Detections) == 0 {
+			continue
+		}
+		title := markdownEscape(dashboard.Title)
+		if url, ok := markdownURL(dashboard.URL); ok {
+			title = "[" + title + "](" + url + ")"
+		}
+		folder := dashboard.Folder
+		if folder == "" {
+			folder = generalFolder
+		}
+		fmt.Fprintf(
+			&b, "| %s | %s | %s | %d |\n",
+			title, markdownEscape(folder), markdownEscape(strings.Join(dashboard.PluginIDs(), ", ")), len(dashboard.Detections),
+		)
+	}
+	addPanel("Affected dashboards", b.String(), 24, 2+summary.DashboardsWithDetections)
+
+	return map[string]interface{}{
+		"uid":           StatusDashboardUID,
+		"title":         statusDashboardTitle,
+		"tags":          []string{"angular", "detect-angular-dashboards"},
+		"editable":      false,
+		"schemaVersion": 39,
+		"panels":        panels,
+	}
+}
+
+// dashboardsWithPlugin returns the number of the given dashboards with detections of the given plugin id.
+func dashboardsWithPlugin(v []Dashboard, pluginID string) int {
+	var n int
+	for _, dashboard := range v {
+		for _, detection := range dashboard.Detections {
+			if detection.PluginID == pluginID {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// markdownEscape escapes the characters of s that would break a Markdown table cell or link, or format the text.
+func markdownEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "|", `\|`, "[", `\[`, "]", `\]`, "\n", " ",
+		"*", `\*`, "_", `\_`, "`", "\\`", "<", `\<`, ">", `\>`,
+	).Replace(s)
+}
+
+// markdownURL returns the given URL as the destination of a Markdown link, with the characters that would end
+// the link or break the table cell percent-encoded. It returns false if the URL is empty or not a relative or
+// http(s) URL (e.g.: "javascript:"), which must not be linked.
+func markdownURL(url string) (string, bool) {
+	lower := strings.ToLower(url)
+	if url == "" || !(strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(url, "/")) {
+		return "", false
+	}
+	return strings.NewReplacer(
+		" ", "%20", "(", "%28", ")", "%29", "|", "%7C", "<", "%3C", ">", "%3E", `\`, "%5C", "\n", "", "\r", "",
+	).Replace(url), true
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStatusDashboard(t *testing.T) {
+	dashboards := []Dashboard{
+		{
+			Title: "A | B", URL: "/d/a/a", Folder: "Team",
+			Detections: []Detection{
+				{PluginID: "graph", DetectionType: DetectionTypePanel},
+				{PluginID: "graph", DetectionType: DetectionTypePanel},
+				{PluginID: "akumuli-datasource", DetectionType: DetectionTypeDatasource},
+			},
+		},
+		{Title: "B", URL: "/d/b/b", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}},
+		{Title: "C", URL: "/d/c/c"},
+	}
+	contents := func(dashboard map[string]interface{}) map[string]string {
+		out := map[string]string{}
+		for _, panel := range dashboard["panels"].([]interface{}) {
+			panel := panel.(map[string]interface{})
+			out[panel["title"].(string)] = panel["options"].(map[string]interface{})["content"].(string)
+		}
+		return out
+	}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("without trend", func(t *testing.T) {
+		dashboard := NewStatusDashboard(dashboards, nil, now)
+		require.Equal(t, StatusDashboardUID, dashboard["uid"])
+		require.Equal(t, map[string]string{
+			"Summary": "**3** dashboards scanned, **2** with Angular plugins, **4** detections.\n\n" +
+				"Generated by detect-angular-dashboards on 2024-01-02T03:04:05Z.",
+			"Detections per plugin": "| Plugin | Detections | Dashboards |\n|---|---|---|\n" +
+				"| graph | 3 | 2 |\n" +
+				"| akumuli-datasource | 1 | 1 |\n",
+			"Affected dashboards": "| Dashboard | Folder | Plugins | Detections |\n|---|---|---|---|\n" +
+				"| [A \\| B](/d/a/a) | Team | akumuli-datasource, graph | 3 |\n" +
+				"| [B](/d/b/b) | General | graph | 1 |\n",
+		}, contents(dashboard))
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		detections := []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}
+		dashboard := NewStatusDashboard([]Dashboard{
+			{Title: "*D* [x](/y) <b>", URL: "/d/d/a b(c)|d", Folder: "_team_", Detections: detections},
+			{Title: "E", URL: "javascript:alert(1)", Detections: detections},
+		}, nil, now)
+		require.Equal(
+			t,
+			"| Dashboard | Folder | Plugins | Detections |\n|---|---|---|---|\n"+
+				"| [\\*D\\* \\[x\\](/y) \\<b\\>](/d/d/a%20b%28c%29%7Cd) | \\_team\\_ | graph | 1 |\n"+
+				"| E | General | graph | 1 |\n",
+			contents(dashboard)["Affected dashboards"],
+		)
+	})
+
+	t.Run("with trend", func(t *testing.T) {
+		dashboard := NewStatusDashboard(dashboards, []TrendPoint{
+			{Time: "2024-01-01T00:00:00Z", Dashboards: 3, DashboardsWithDetections: 3, Detections: 5},
+			{Time: "2024-01-02T00:00:00Z", Dashboards: 3, DashboardsWithDetections: 2, Detections: 4},
+		}, now)
+		require.Equal(
+			t,
+			"| Time | Dashboards | Dashboards with Angular plugins | Detections |\n|---|---|---|---|\n"+
+				"| 2024-01-01T00:00:00Z | 3 | 3 | 5 |\n"+
+				"| 2024-01-02T00:00:00Z | 3 | 2 | 4 |\n",
+			contents(dashboard)["Trend"],
+		)
+		// Panels are stacked vertically
+		var ys []interface{}
+		for _, panel := range dashboard["panels"].([]interface{}) {
+			ys = append(ys, panel.(map[string]interface{})["gridPos"].(map[string]interface{})["y"])
+		}
+		require.Equal(t, []interface{}{0, 3, 7, 11}, ys)
+	})
+}
@@ -0,0 +1,105 @@
+package output
+
+import "sort"
+
+// ReportDiff is the difference between the dashboards with detections of two reports.
+type ReportDiff struct {
+	// New are the dashboards with detections in the new report, but not in the old one.
+	New []Dashboard
+
+	// Resolved are the dashboards with detections in the old report, but not in the new one, with their old detections.
+	Resolved []Dashboard
+
+	// Changed are the dashboards with detections in both reports, but different ones, with their new detections.
+	Changed []Dashboard
+
+	// AddedDetections are the dashboards of New and Changed with only their detections not in the old report,
+	// and RemovedDetections the dashboards of Resolved and Changed with only their detections not in the new one.
+	AddedDetections   []Dashboard `json:"-"`
+	RemovedDetections []Dashboard `json:"-"`
+}
+
+// Diff returns the difference between the dashboards with detections of the reports from (old) and to (new).
+// Dashboards are matched by ReportKey, and their detections by plugin and detection type, counting duplicates:
+// renaming or moving a panel is not a change, adding a panel of an Angular plugin is, even if the dashboard already had one.
+// Each list is sorted by key.
+func Diff(from, to []Dashboard) ReportDiff {
+	return diff(from, to, func(dashboard Dashboard) bool { return len(dashboard.Detections) > 0 })
+}
+
+// diff returns the difference between the dashboards of the reports from and to for which hasDetections is true.
+func diff(from, to []Dashboard, hasDetections func(Dashboard) bool) ReportDiff {
+	oldByKey := map[string]Dashboard{}
+	for _, dashboard := range from {
+		if hasDetections(dashboard) {
+			oldByKey[ReportKey(dashboard)] = dashboard
+		}
+	}
+	d := ReportDiff{New: []Dashboard{}, Resolved: []Dashboard{}, Changed: []Dashboard{}, AddedDetections: []Dashboard{}, RemovedDetections: []Dashboard{}}
+	newKeys := map[string]struct{}{}
+	for _, dashboard := range to {
+		if !hasDetections(dashboard) {
+			continue
+		}
+		key := ReportKey(dashboard)
+		newKeys[key] = struct{}{}
+		previous, ok := oldByKey[key]
+		if !ok {
+			d.New = append(d.New, dashboard)
+			d.AddedDetections = append(d.AddedDetections, dashboard)
+			continue
+		}
+		added := subtractDetections(dashboard.Detections, previous.Detections)
+		removed := subtractDetections(previous.Detections, dashboard.Detections)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		d.Changed = append(d.Changed, dashboard)
+		if len(added) > 0 {
+			dashboard.Detections = added
+			d.AddedDetections = append(d.AddedDetections, dashboard)
+		}
+		if len(removed) > 0 {
+			previous.Detections = removed
+			d.RemovedDetections = append(d.RemovedDetections, previous)
+		}
+	}
+	for key, dashboard := range oldByKey {
+		if _, ok := newKeys[key]; !ok {
+			d.Resolved = append(d.Resolved, dashboard)
+			d.RemovedDetections = append(d.RemovedDetections, dashboard)
+		}
+	}
+	for _, dashboards := range [][]Dashboard{d.New, d.Resolved, d.Changed, d.AddedDetections, d.RemovedDetections} {
+		sort.Slice(dashboards, func(i, j int) bool { return ReportKey(dashboards[i]) < ReportKey(dashboards[j]) })
+	}
+	return d
+}
+
+// subtractDetections returns the detections of a that are not in b, counting duplicates.
+func subtractDetections(a, b []Detection) []Detection {
+	counts := make(map[detectionKey]int, len(b))
+	for _, detection := range b {
+		counts[newDetectionKey(detection)]++
+	}
+	var out []Detection
+	for _, detection := range a {
+		if k := newDetectionKey(detection); counts[k] > 0 {
+			counts[k]--
+			continue
+		}
+		out = append(out, detection)
+	}
+	return out
+}
+
+// detectionKey identifies a detection of a dashboard across reports. The panel title and id are not part of it,
+// as they change without changing the migration to do (e.g.: the panel is renamed, or the dashboard is re-imported).
+type detectionKey struct {
+	pluginID      string
+	detectionType DetectionType
+}
+
+func newDetectionKey(detection Detection) detectionKey {
+	return detectionKey{detection.PluginID, detection.DetectionType}
+}
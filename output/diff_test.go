@@ -0,0 +1,111 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	graph := Detection{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel, PanelID: 1, Title: "Requests"}
+	worldmap := Detection{PluginID: "grafana-worldmap-panel", DetectionType: DetectionTypePanel, PanelID: 2, Title: "Map"}
+	from := []Dashboard{
+		{URL: "/d/a/a", Detections: []Detection{graph, worldmap}},
+		{URL: "/d/b/b", Detections: []Detection{graph}},
+		{URL: "/d/c/c", Detections: []Detection{graph}},
+		{URL: "/d/d/d"},
+	}
+	to := []Dashboard{
+		{URL: "/d/a/a", Detections: []Detection{worldmap, graph}},
+		{URL: "/d/b/b", Detections: []Detection{worldmap}},
+		{URL: "/d/c/c"},
+		{URL: "/d/d/d", Detections: []Detection{graph}},
+	}
+	keys := func(v []Dashboard) []string {
+		out := []string{}
+		for _, dashboard := range v {
+			out = append(out, ReportKey(dashboard))
+		}
+		return out
+	}
+	diff := Diff(from, to)
+	require.Equal(t, []string{"/d/d/d"}, keys(diff.New))
+	require.Equal(t, []string{"/d/c/c"}, keys(diff.Resolved))
+	require.Equal(t, []string{"/d/b/b"}, keys(diff.Changed))
+	// Resolved dashboards have their old detections, changed ones their new detections
+	require.Equal(t, []Detection{graph}, diff.Resolved[0].Detections)
+	require.Equal(t, []Detection{worldmap}, diff.Changed[0].Detections)
+
+	t.Run("renamed panels", func(t *testing.T) {
+		renamed := graph
+		renamed.PanelID, renamed.Title = 3, "Latency"
+		diff := Diff(
+			[]Dashboard{{URL: "/d/a/a", Detections: []Detection{graph}}},
+			[]Dashboard{{URL: "/d/a/a", Detections: []Detection{renamed}}},
+		)
+		require.Empty(t, diff.Changed)
+		require.Empty(t, diff.AddedDetections)
+		require.Empty(t, diff.RemovedDetections)
+	})
+
+	t.Run("another panel of the same plugin", func(t *testing.T) {
+		other := graph
+		other.PanelID, other.Title = 3, "Latency"
+		diff := Diff(
+			[]Dashboard{{URL: "/d/a/a", Detections: []Detection{graph}}},
+			[]Dashboard{{URL: "/d/a/a", Detections: []Detection{graph, other}}},
+		)
+		require.Equal(t, []string{"/d/a/a"}, keys(diff.Changed))
+		require.Equal(t, []Dashboard{{URL: "/d/a/a", Detections: []Detection{other}}}, diff.AddedDetections)
+		require.Empty(t, diff.RemovedDetections)
+	})
+
+	t.Run("renamed dashboard", func(t *testing.T) {
+		// The slug and the base URL change the URL, not the UID
+		diff := Diff(
+			[]Dashboard{{UID: "a", URL: "/d/a/old-title", Detections: []Detection{graph}}},
+			[]Dashboard{{UID: "a", URL: "https://grafana.example.com/d/a/new-title", Detections: []Detection{graph}}},
+		)
+		require.Empty(t, diff.New)
+		require.Empty(t, diff.Resolved)
+		require.Empty(t, diff.Changed)
+	})
+
+	t.Run("instances", func(t *testing.T) {
+		diff := Diff(
+			[]Dashboard{{Instance: "prod", UID: "a", Detections: []Detection{graph}}},
+			[]Dashboard{{Instance: "dev", UID: "a", Detections: []Detection{graph}}},
+		)
+		require.Equal(t, []string{"dev/a"}, keys(diff.New))
+		require.Equal(t, []string{"prod/a"}, keys(diff.Resolved))
+	})
+}
+
+func TestDiffAddedRemovedDetections(t *testing.T) {
+	graph := Detection{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel, PanelID: 1, Title: "Requests"}
+	worldmap := Detection{PluginID: "grafana-worldmap-panel", DetectionType: DetectionTypePanel, PanelID: 2, Title: "Map"}
+	from := []Dashboard{
+		{URL: "/d/a/a", Detections: []Detection{graph, worldmap}},
+		{URL: "/d/b/b", Detections: []Detection{graph}},
+		{URL: "/d/c/c", Detections: []Detection{graph}},
+	}
+	to := []Dashboard{
+		{URL: "/d/a/a", Detections: []Detection{worldmap, graph}},
+		{URL: "/d/b/b", Detections: []Detection{worldmap}},
+		{URL: "/d/c/c"},
+		{URL: "/d/d/d", Detections: []Detection{graph}},
+	}
+	diff := Diff(from, to)
+	require.Equal(t, []Dashboard{
+		{URL: "/d/b/b", Detections: []Detection{worldmap}},
+		{URL: "/d/d/d", Detections: []Detection{graph}},
+	}, diff.AddedDetections)
+	require.Equal(t, []Dashboard{
+		{URL: "/d/b/b", Detections: []Detection{graph}},
+		{URL: "/d/c/c", Detections: []Detection{graph}},
+	}, diff.RemovedDetections)
+
+	diff = Diff(to, to)
+	require.Empty(t, diff.AddedDetections)
+	require.Empty(t, diff.RemovedDetections)
+}
@@ -0,0 +1,31 @@
+package output
+
+import (
+	"io"
+	"os"
+)
+
+// FileOutputter writes the output of another Outputter to a file. The file is only created when the dashboards
+// are output, so failed runs don't leave an empty file behind.
+type FileOutputter struct {
+	fn           string
+	newOutputter func(w io.Writer) Outputter
+}
+
+// NewFileOutputter returns a new FileOutputter writing to the given file the output of the Outputter
+// returned by newOutputter, called with the file.
+func NewFileOutputter(fn string, newOutputter func(w io.Writer) Outputter) FileOutputter {
+	return FileOutputter{fn: fn, newOutputter: newOutputter}
+}
+
+func (o FileOutputter) Output(v []Dashboard) error {
+	f, err := os.Create(o.fn)
+	if err != nil {
+		return err
+	}
+	if err := o.newOutputter(f).Output(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,57 @@
+package output
+
+import "github.com/grafana/detect-angular-dashboards/internal/sliceutil"
+
+// Filter selects dashboards by folder, plugin, detection type and creator.
+// Each non-empty criterion must match, with any of its values. An empty Filter matches all the dashboards.
+type Filter struct {
+	// Folders are folder titles ("General" for the dashboards not in a folder) or uids.
+	Folders []string
+
+	// PluginIDs and DetectionTypes select the dashboards with a detection of one of the plugins and one of the types.
+	// Both must match the same detection.
+	PluginIDs      []string
+	DetectionTypes []DetectionType
+
+	// Creators are the logins of the users that created the dashboards.
+	Creators []string
+}
+
+// Match returns true if the given dashboard matches the filter.
+func (f Filter) Match(dashboard Dashboard) bool {
+	folder := dashboard.Folder
+	if folder == "" {
+		folder = generalFolder
+	}
+	if len(f.Folders) > 0 && !sliceutil.Contains(f.Folders, folder) &&
+		(dashboard.FolderUID == "" || !sliceutil.Contains(f.Folders, dashboard.FolderUID)) {
+		return false
+	}
+	if len(f.Creators) > 0 && !sliceutil.Contains(f.Creators, dashboard.CreatedBy) {
+		return false
+	}
+	if len(f.PluginIDs) == 0 && len(f.DetectionTypes) == 0 {
+		return true
+	}
+	for _, detection := range dashboard.Detections {
+		if len(f.PluginIDs) > 0 && !sliceutil.Contains(f.PluginIDs, detection.PluginID) {
+			continue
+		}
+		if len(f.DetectionTypes) > 0 && !sliceutil.Contains(f.DetectionTypes, detection.DetectionType) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Apply returns the given dashboards matching the filter, in a new slice.
+func (f Filter) Apply(dashboards []Dashboard) []Dashboard {
+	out := make([]Dashboard, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		if f.Match(dashboard) {
+			out = append(out, dashboard)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	dashboards := []Dashboard{
+		{
+			UID: "a", Folder: "Team", FolderUID: "team", CreatedBy: "alice",
+			Detections: []Detection{
+				{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel},
+				{PluginID: "akumuli-datasource", DetectionType: DetectionTypeDatasource},
+			},
+		},
+		{UID: "b", CreatedBy: "bob", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel}}},
+		{UID: "c", Folder: "Other", FolderUID: "other", CreatedBy: "alice"},
+	}
+	uids := func(v []Dashboard) []string {
+		out := []string{}
+		for _, dashboard := range v {
+			out = append(out, dashboard.UID)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		exp    []string
+	}{
+		{name: "empty", exp: []string{"a", "b", "c"}},
+		{name: "folder title", filter: Filter{Folders: []string{"Team"}}, exp: []string{"a"}},
+		{name: "folder uid", filter: Filter{Folders: []string{"other"}}, exp: []string{"c"}},
+		{name: "general folder", filter: Filter{Folders: []string{"General"}}, exp: []string{"b"}},
+		{name: "any folder", filter: Filter{Folders: []string{"General", "Team"}}, exp: []string{"a", "b"}},
+		{name: "plugin", filter: Filter{PluginIDs: []string{"akumuli-datasource"}}, exp: []string{"a"}},
+		{name: "detection type", filter: Filter{DetectionTypes: []DetectionType{DetectionTypeLegacyPanel}}, exp: []string{"a", "b"}},
+		{
+			name:   "plugin and detection type of different detections",
+			filter: Filter{PluginIDs: []string{"akumuli-datasource"}, DetectionTypes: []DetectionType{DetectionTypeLegacyPanel}},
+			exp:    []string{},
+		},
+		{name: "creator", filter: Filter{Creators: []string{"alice"}}, exp: []string{"a", "c"}},
+		{name: "creator and plugin", filter: Filter{Creators: []string{"alice"}, PluginIDs: []string{"graph"}}, exp: []string{"a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, uids(tc.filter.Apply(dashboards)))
+		})
+	}
+}
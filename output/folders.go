@@ -0,0 +1,19 @@
+package output
+
+// Folder is a node of the folder tree, annotated with the Angular detections of the dashboards it contains.
+// The counts include the dashboards in its subfolders.
+type Folder struct {
+	UID   string
+	Title string
+
+	// Dashboards is the number of scanned dashboards.
+	Dashboards int
+
+	// AngularDashboards is the number of dashboards with Angular detections.
+	AngularDashboards int
+
+	// Detections is the number of Angular detections.
+	Detections int
+
+	Subfolders []*Folder
+}
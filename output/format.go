@@ -0,0 +1,64 @@
+package output
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FormatText outputs the dashboards as readable logs.
+	FormatText = "text"
+
+	// FormatJSON outputs the dashboards as JSON (see JSONOutputter).
+	FormatJSON = "json"
+
+	// FormatCSV outputs one CSV row per dashboard with detections (see CSVOutputter).
+	FormatCSV = "csv"
+
+	// FormatDetectionsCSV outputs one CSV row per detection (see DetectionsCSVOutputter).
+	FormatDetectionsCSV = "detections-csv"
+
+	// FormatHTML outputs a self-contained HTML report (see HTMLOutputter).
+	FormatHTML = "html"
+
+	// FormatXLSX outputs an Excel workbook (see XLSXOutputter).
+	FormatXLSX = "xlsx"
+)
+
+// ValidateFormat returns an error if the given output format is not supported.
+// An empty format selects the format from the other flags (e.g.: -j), or FormatText.
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatText, FormatJSON, FormatCSV, FormatDetectionsCSV, FormatHTML, FormatXLSX:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// extensionFormats are the output formats of the file extensions, see ParseOutputSpec.
+var extensionFormats = map[string]string{
+	".txt":  FormatText,
+	".log":  FormatText,
+	".json": FormatJSON,
+	".csv":  FormatCSV,
+	".html": FormatHTML,
+	".htm":  FormatHTML,
+	".xlsx": FormatXLSX,
+}
+
+// ParseOutputSpec parses an output given as "format=path", or as a path whose extension selects the format
+// (.txt and .log for text, .json, .csv, .html and .xlsx).
+func ParseOutputSpec(spec string) (format, path string, err error) {
+	if format, path, ok := strings.Cut(spec, "="); ok && format != "" && ValidateFormat(format) == nil {
+		if path == "" {
+			return "", "", fmt.Errorf("missing path in output %q", spec)
+		}
+		return format, path, nil
+	}
+	format, ok := extensionFormats[strings.ToLower(filepath.Ext(spec))]
+	if !ok {
+		return "", "", fmt.Errorf(`can't guess the format of output %q from its extension, pass it as "format=path"`, spec)
+	}
+	return format, spec, nil
+}
@@ -0,0 +1,117 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// GroupByPlugin groups the dashboards by the plugin id of their detections.
+	GroupByPlugin = "plugin"
+
+	// GroupByFolder groups the dashboards by folder title.
+	GroupByFolder = "folder"
+
+	// GroupByCreator groups the dashboards by the user that created them.
+	GroupByCreator = "creator"
+
+	// GroupByInstance groups the dashboards by Grafana instance, when scanning multiple instances.
+	GroupByInstance = "instance"
+
+	// GroupByGnetID groups the dashboards imported from grafana.com by the id of the community dashboard.
+	GroupByGnetID = "gnet-id"
+)
+
+// groupLabels are the labels of the groups in the readable output, for each grouping.
+var groupLabels = map[string]string{
+	GroupByPlugin:   "Plugin",
+	GroupByFolder:   "Folder",
+	GroupByCreator:  "Creator",
+	GroupByGnetID:   "grafana.com dashboard",
+	GroupByInstance: "Instance",
+}
+
+// unknownCreator is the key of the group of the dashboards whose creator is not known (e.g.: offline mode).
+const unknownCreator = "unknown"
+
+// Group is a group of dashboards with detections, with the number of detections in the group.
+type Group struct {
+	Key        string
+	Dashboards []Dashboard
+	Detections int
+}
+
+// ValidateGroupBy returns an error if the given grouping is not supported.
+// An empty grouping lists the dashboards one by one.
+func ValidateGroupBy(by string) error {
+	switch by {
+	case "", GroupByPlugin, GroupByFolder, GroupByCreator, GroupByGnetID, GroupByInstance:
+		return nil
+	}
+	return fmt.Errorf("unsupported grouping %q", by)
+}
+
+// GroupDashboards groups the given dashboards with detections by plugin, folder, creator, instance or grafana.com dashboard,
+// sorted by number of detections, highest first. When grouping by plugin, a dashboard is in the group of each plugin
+// it uses, and only the detections of that plugin are counted. When grouping by grafana.com dashboard, the key is the
+// URL of the community dashboard, and the dashboards that have not been imported from grafana.com are left out.
+func GroupDashboards(dashboards []Dashboard, by string) []Group {
+	byKey := map[string]*Group{}
+	add := func(key string, dashboard Dashboard, detections int) {
+		group, ok := byKey[key]
+		if !ok {
+			group = &Group{Key: key}
+			byKey[key] = group
+		}
+		group.Dashboards = append(group.Dashboards, dashboard)
+		group.Detections += detections
+	}
+	for _, dashboard := range dashboards {
+		if len(dashboard.Detections) == 0 {
+			continue
+		}
+		switch by {
+		case GroupByPlugin:
+			counts := map[string]int{}
+			var pluginIDs []string
+			for _, detection := range dashboard.Detections {
+				if counts[detection.PluginID] == 0 {
+					pluginIDs = append(pluginIDs, detection.PluginID)
+				}
+				counts[detection.PluginID]++
+			}
+			for _, pluginID := range pluginIDs {
+				add(pluginID, dashboard, counts[pluginID])
+			}
+		case GroupByFolder:
+			folder := dashboard.Folder
+			if folder == "" {
+				folder = generalFolder
+			}
+			add(folder, dashboard, len(dashboard.Detections))
+		case GroupByCreator:
+			creator := dashboard.CreatedBy
+			if creator == "" {
+				creator = unknownCreator
+			}
+			add(creator, dashboard, len(dashboard.Detections))
+		case GroupByInstance:
+			add(dashboard.Instance, dashboard, len(dashboard.Detections))
+		case GroupByGnetID:
+			if dashboard.GnetID > 0 {
+				add(GnetURL(dashboard.GnetID), dashboard, len(dashboard.Detections))
+			}
+		}
+	}
+	out := make([]Group, 0, len(byKey))
+	for _, group := range byKey {
+		out = append(out, *group)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Detections != out[j].Detections {
+			return out[i].Detections > out[j].Detections
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
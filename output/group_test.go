@@ -0,0 +1,43 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupDashboards(t *testing.T) {
+	a := Dashboard{URL: "d/a", Folder: "Team", CreatedBy: "admin", Detections: []Detection{
+		{PluginID: "graph"}, {PluginID: "grafana-worldmap-panel"}, {PluginID: "graph"},
+	}}
+	b := Dashboard{URL: "d/b", Detections: []Detection{{PluginID: "grafana-worldmap-panel"}}}
+	c := Dashboard{URL: "d/c", Folder: "Team", CreatedBy: "admin", GnetID: 1860}
+	d := Dashboard{URL: "d/d", GnetID: 1860, Detections: []Detection{{PluginID: "graph"}}}
+	dashboards := []Dashboard{a, b, c}
+
+	require.NoError(t, ValidateGroupBy(""))
+	require.NoError(t, ValidateGroupBy(GroupByCreator))
+	require.Error(t, ValidateGroupBy("team"))
+
+	require.Equal(t, []Group{
+		{Key: "grafana-worldmap-panel", Dashboards: []Dashboard{a, b}, Detections: 2},
+		{Key: "graph", Dashboards: []Dashboard{a}, Detections: 2},
+	}, GroupDashboards(dashboards, GroupByPlugin))
+	require.Equal(t, []Group{
+		{Key: "Team", Dashboards: []Dashboard{a}, Detections: 3},
+		{Key: "General", Dashboards: []Dashboard{b}, Detections: 1},
+	}, GroupDashboards(dashboards, GroupByFolder))
+	require.Equal(t, []Group{
+		{Key: "admin", Dashboards: []Dashboard{a}, Detections: 3},
+		{Key: "unknown", Dashboards: []Dashboard{b}, Detections: 1},
+	}, GroupDashboards(dashboards, GroupByCreator))
+	require.Equal(t, []Group{
+		{Key: "https://grafana.com/grafana/dashboards/1860", Dashboards: []Dashboard{d}, Detections: 1},
+	}, GroupDashboards(append(dashboards, d), GroupByGnetID))
+
+	a.Instance, b.Instance = "prod", "dev"
+	require.Equal(t, []Group{
+		{Key: "prod", Dashboards: []Dashboard{a}, Detections: 3},
+		{Key: "dev", Dashboards: []Dashboard{b}, Detections: 1},
+	}, GroupDashboards([]Dashboard{a, b}, GroupByInstance))
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+)
+
+//go:embed templates/report.html
+var htmlReportTemplate string
+
+// htmlReport is the data rendered by the HTML report template.
+type htmlReport struct {
+	Generated string
+	Summary   Summary
+	Plugins   []countBar
+	Folders   []countBar
+	Rows      []htmlRow
+}
+
+// countBar is a bar of a chart of the reports (HTML and XLSX), with its width in percent of the largest bar.
+type countBar struct {
+	Label   string
+	Count   int
+	Percent int
+}
+
+// htmlRow is a row of the detections table of the HTML report.
+type htmlRow struct {
+	Dashboard     string
+	URL           string
+	Folder        string
+	PluginID      string
+	DetectionType DetectionType
+	Severity      Severity
+	Panel         string
+	Updated       string
+}
+
+// HTMLOutputter outputs a self-contained HTML report, with the summary, charts of the detections per plugin
+// and folder, and a sortable and filterable table of the detections linking to the dashboards.
+// The report has no external dependencies, so it can be sent by email.
+type HTMLOutputter struct {
+	writer io.Writer
+	now    func() time.Time
+}
+
+// NewHTMLOutputter returns a new HTMLOutputter writing to w.
+func NewHTMLOutputter(w io.Writer) HTMLOutputter {
+	return HTMLOutputter{writer: w, now: time.Now}
+}
+
+func (o HTMLOutputter) Output(v []Dashboard) error {
+	tmpl, err := template.New("report").Parse(htmlReportTemplate)
+	if err != nil {
+		return err
+	}
+	summary := NewSummary(v)
+	report := htmlReport{
+		Generated: o.now().UTC().Format(time.RFC3339),
+		Summary:   summary,
+		Plugins:   countBars(summary.PluginIDs),
+		Folders:   countBars(summary.Folders),
+	}
+	for _, dashboard := range v {
+		folder := dashboard.Folder
+		if folder == "" {
+			folder = generalFolder
+		}
+		for _, detection := range dashboard.Detections {
+			report.Rows = append(report.Rows, htmlRow{
+				Dashboard:     dashboard.Title,
+				URL:           dashboard.URL,
+				Folder:        folder,
+				PluginID:      detection.PluginID,
+				DetectionType: detection.DetectionType,
+				Severity:      detection.Severity,
+				Panel:         detection.Title,
+				Updated:       dashboard.Updated,
+			})
+		}
+	}
+	return tmpl.Execute(o.writer, report)
+}
+
+// countBars returns the bars of a chart of the given counts, from the largest to the smallest, then by label.
+func countBars(counts map[string]int) []countBar {
+	bars := make([]countBar, 0, len(counts))
+	var largest int
+	for label, count := range counts {
+		bars = append(bars, countBar{Label: label, Count: count})
+		if count > largest {
+			largest = count
+		}
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Count != bars[j].Count {
+			return bars[i].Count > bars[j].Count
+		}
+		return bars[i].Label < bars[j].Label
+	})
+	for i := range bars {
+		bars[i].Percent = bars[i].Count * 100 / largest
+	}
+	return bars
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLOutputter(t *testing.T) {
+	dashboards := []Dashboard{
+		{
+			Title: "A <script>", URL: "http://grafana.example.com/d/a/a", Folder: "Team",
+			Detections: []Detection{
+				{PluginID: "graph", DetectionType: DetectionTypePanel, Severity: SeverityAutoMigratable, Title: "Requests"},
+				{PluginID: "akumuli-datasource", DetectionType: DetectionTypeDatasource, Title: "Latency"},
+			},
+		},
+		{Title: "B", URL: "http://grafana.example.com/d/b/b", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}},
+		{Title: "C", URL: "http://grafana.example.com/d/c/c"},
+	}
+
+	var buf bytes.Buffer
+	o := NewHTMLOutputter(&buf)
+	o.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	require.NoError(t, o.Output(dashboards))
+	report := buf.String()
+	require.Contains(t, report, "Generated on 2024-01-02T03:04:05Z")
+	require.Contains(t, report, `<div class="value">3</div>dashboards</div>`)
+	require.Contains(t, report, `<div class="value">2</div>dashboards with Angular plugins</div>`)
+	require.Contains(t, report, `<div class="value">3</div>detections</div>`)
+	// Bars are sorted by count, and scaled to the largest one
+	require.Contains(t, report, `<span class="label" title="graph">graph</span><span class="fill" style="width: 100%"></span>2</div>
+<div class="bar"><span class="label" title="akumuli-datasource">akumuli-datasource</span><span class="fill" style="width: 50%"></span>1</div>`)
+	require.Contains(t, report, `<span class="label" title="General">General</span><span class="fill" style="width: 50%"></span>1</div>`)
+	// Values are escaped
+	require.Contains(t, report, `<td><a href="http://grafana.example.com/d/a/a">A &lt;script&gt;</a></td><td>Team</td><td>graph</td><td>panel</td><td>auto-migratable</td><td>Requests</td>`)
+	require.Contains(t, report, `<td><a href="http://grafana.example.com/d/b/b">B</a></td><td>General</td>`)
+	require.NotContains(t, report, ">C<")
+}
@@ -0,0 +1,13 @@
+package output
+
+import _ "embed"
+
+// CurrentReportSchemaVersion is the version of the JSON schema of the reports, written in each dashboard.
+// It's incremented when fields are renamed or removed, or their meaning changes. Adding fields doesn't change it.
+const CurrentReportSchemaVersion = 1
+
+// JSONSchema is the JSON Schema of the JSON output: the list of dashboards, the Report with -summary or the Summary
+// with -summary-only.
+//
+//go:embed jsonschema.json
+var JSONSchema []byte
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(JSONSchema, &schema))
+
+	// jsonFields returns the names of the JSON fields of the given struct, sorted
+	jsonFields := func(v interface{}) []string {
+		var out []string
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			require.NotEmpty(t, name, "field %s.%s must have an explicit JSON name", typ.Name(), typ.Field(i).Name)
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	// properties returns the names of the properties of the given definition of the schema, sorted
+	properties := func(def string) []string {
+		var out []string
+		for name := range schema.Defs[def].Properties {
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	for def, v := range map[string]interface{}{
+		"dashboard":         Dashboard{},
+		"detection":         Detection{},
+		"gridPos":           GridPos{},
+		"communityRevision": CommunityRevision{},
+		"summary":           Summary{},
+		"report":            Report{},
+	} {
+		expected := jsonFields(v)
+		if def == "dashboard" {
+			expected = append(expected, "ReportSchemaVersion")
+			sort.Strings(expected)
+		}
+		require.Equal(t, expected, properties(def), def)
+	}
+}
+
+func TestReportSchemaVersion(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONOutputter(&buf).Output([]Dashboard{
+		{UID: "a", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel}}},
+	}))
+	var out []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out, 1)
+	require.Equal(t, float64(CurrentReportSchemaVersion), out[0]["ReportSchemaVersion"])
+	require.Equal(t, "a", out[0]["UID"])
+
+	t.Run("read", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "report.json")
+		require.NoError(t, os.WriteFile(fn, buf.Bytes(), 0o644))
+		dashboards, err := ReadJSON(fn)
+		require.NoError(t, err)
+		require.Equal(t, "a", dashboards[0].UID)
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "report.json")
+		require.NoError(t, os.WriteFile(fn, []byte(`[{"ReportSchemaVersion": 1000, "UID": "a"}]`), 0o644))
+		_, err := ReadJSON(fn)
+		require.ErrorContains(t, err, "newer than the supported version")
+	})
+}
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ReadJSON reads a report written by JSONOutputter from the given file, with or without summary.
+func ReadJSON(fn string) ([]Dashboard, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var report Report
+		if err := json.Unmarshal(b, &report); err != nil {
+			return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+		}
+		var versions struct {
+			Dashboards []reportSchemaVersion `json:"dashboards"`
+		}
+		if err := json.Unmarshal(b, &versions); err != nil {
+			return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+		}
+		if err := checkReportSchemaVersion(fn, versions.Dashboards); err != nil {
+			return nil, err
+		}
+		return report.Dashboards, nil
+	}
+	var out []Dashboard
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	var versions []reportSchemaVersion
+	if err := json.Unmarshal(b, &versions); err != nil {
+		return nil, fmt.Errorf("unmarshal %q: %w", fn, err)
+	}
+	if err := checkReportSchemaVersion(fn, versions); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// reportSchemaVersion is the version of the JSON schema of a dashboard of a report (see Dashboard.MarshalJSON),
+// 0 for the reports written before the versioning.
+type reportSchemaVersion struct {
+	ReportSchemaVersion int `json:"ReportSchemaVersion"`
+}
+
+// checkReportSchemaVersion returns an error if the dashboards of the report in file fn have been written with
+// a newer version of the JSON schema, whose fields may have changed.
+func checkReportSchemaVersion(fn string, versions []reportSchemaVersion) error {
+	for _, v := range versions {
+		if v.ReportSchemaVersion > CurrentReportSchemaVersion {
+			return fmt.Errorf(
+				"report %q has schema version %d, newer than the supported version %d",
+				fn, v.ReportSchemaVersion, CurrentReportSchemaVersion,
+			)
+		}
+	}
+	return nil
+}
+
+// ReportKey returns the key identifying the given dashboard across the reports of a Grafana instance: its UID,
+// or its URL if it has no UID (e.g.: offline mode), prefixed by its instance when scanning multiple instances.
+// The URL is only a fallback, as it changes when the dashboard is renamed or the base URL changes.
+func ReportKey(dashboard Dashboard) string {
+	key := dashboard.UID
+	if key == "" {
+		key = dashboard.URL
+	}
+	if dashboard.Instance != "" {
+		key = dashboard.Instance + "/" + key
+	}
+	return key
+}
+
+// mergeKey returns the key identifying the given dashboard across the merged reports: its URL, or its UID
+// if the URL is empty, prefixed by its instance when scanning multiple instances (URLs can be relative).
+// Unlike ReportKey, it distinguishes the dashboards with the same UID in the reports of different Grafana instances.
+func mergeKey(dashboard Dashboard) string {
+	key := dashboard.URL
+	if key == "" {
+		key = dashboard.UID
+	}
+	if dashboard.Instance != "" {
+		key = dashboard.Instance + "/" + key
+	}
+	return key
+}
+
+// Merge combines the given reports into one, sorted by URL.
+// Dashboards are identified by their URL (or UID, if the URL is empty). If a dashboard is present in more
+// than one report, the one in the last report wins, so reports should be passed from the oldest to the newest.
+func Merge(reports ...[]Dashboard) []Dashboard {
+	byKey := map[string]Dashboard{}
+	for _, report := range reports {
+		for _, dashboard := range report {
+			byKey[mergeKey(dashboard)] = dashboard
+		}
+	}
+	out := make([]Dashboard, 0, len(byKey))
+	for _, dashboard := range byKey {
+		out = append(out, dashboard)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].URL != out[j].URL {
+			return out[i].URL < out[j].URL
+		}
+		return out[i].UID < out[j].UID
+	})
+	return out
+}
@@ -0,0 +1,34 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	older := []Dashboard{
+		{URL: "http://a.example.com/d/1/one", Title: "one (old)", Detections: []Detection{{PluginID: "graph"}}},
+		{URL: "http://a.example.com/d/2/two", Title: "two", Detections: []Detection{{PluginID: "graph"}}},
+	}
+	newer := []Dashboard{
+		{URL: "http://a.example.com/d/1/one", Title: "one", Detections: []Detection{{PluginID: "grafana-worldmap-panel"}}},
+		{URL: "http://b.example.com/d/1/one", Title: "one (other instance)", Detections: []Detection{{PluginID: "graph"}}},
+		{UID: "offline.json", Title: "offline", Detections: []Detection{{PluginID: "graph"}}},
+	}
+	merged := Merge(older, newer)
+	var titles []string
+	for _, dashboard := range merged {
+		titles = append(titles, dashboard.Title)
+	}
+	require.Equal(t, []string{"offline", "one", "two", "one (other instance)"}, titles)
+	require.Equal(t, "grafana-worldmap-panel", merged[1].Detections[0].PluginID)
+
+	t.Run("instances", func(t *testing.T) {
+		merged := Merge([]Dashboard{
+			{Instance: "prod", URL: "d/1/one", Title: "one (prod)"},
+			{Instance: "dev", URL: "d/1/one", Title: "one (dev)"},
+		})
+		require.Len(t, merged, 2)
+	})
+}
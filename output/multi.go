@@ -0,0 +1,27 @@
+package output
+
+import "errors"
+
+// MultiOutputter outputs the dashboards with several Outputters, e.g. the readable output to stdout
+// and JSON to a file.
+type MultiOutputter struct {
+	outputters []Outputter
+}
+
+// NewMultiOutputter returns a new MultiOutputter outputting the dashboards with the given outputters, in order.
+func NewMultiOutputter(outputters ...Outputter) MultiOutputter {
+	return MultiOutputter{outputters: outputters}
+}
+
+// Output outputs the dashboards with each outputter. A failing outputter doesn't stop the others,
+// their errors are returned together.
+func (o MultiOutputter) Output(v []Dashboard) error {
+	var errs []error
+	for _, outputter := range o.outputters {
+		// Some outputters modify the slice in place (e.g.: JSONOutputter removes the dashboards without findings)
+		if err := outputter.Output(append([]Dashboard(nil), v...)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
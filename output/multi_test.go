@@ -0,0 +1,80 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// outputterFunc is an Outputter implemented by a function.
+type outputterFunc func(v []Dashboard) error
+
+func (f outputterFunc) Output(v []Dashboard) error {
+	return f(v)
+}
+
+func TestMultiOutputter(t *testing.T) {
+	dashboards := []Dashboard{
+		{Title: "react"},
+		{Title: "angular", URL: "/d/a/a", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}},
+	}
+	var jsonBuf, readableBuf bytes.Buffer
+	csvFn := filepath.Join(t.TempDir(), "out.csv")
+	var titles []string
+	err := NewMultiOutputter(
+		// The JSON outputter removes the dashboards without findings in place, the next outputters must not see it
+		NewJSONOutputter(&jsonBuf),
+		outputterFunc(func(v []Dashboard) error {
+			for _, dashboard := range v {
+				titles = append(titles, dashboard.Title)
+			}
+			return errors.New("failed")
+		}),
+		NewWriterReadableOutput(&readableBuf),
+		NewFileOutputter(csvFn, func(w io.Writer) Outputter { return NewDetectionsCSVOutputter(w) }),
+	).Output(dashboards)
+	require.EqualError(t, err, "failed")
+	require.Equal(t, []string{"react", "angular"}, titles)
+	require.Contains(t, jsonBuf.String(), `"Title": "angular"`)
+	require.NotContains(t, jsonBuf.String(), "react")
+	require.Contains(t, readableBuf.String(), `Found dashboard with Angular plugins "angular" "/d/a/a":`)
+	require.NotContains(t, readableBuf.String(), "INFO: ")
+	b, err := os.ReadFile(csvFn)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "angular,/d/a/a")
+}
+
+func TestParseOutputSpec(t *testing.T) {
+	for _, tc := range []struct {
+		spec      string
+		expFormat string
+		expPath   string
+		expErr    string
+	}{
+		{spec: "report.json", expFormat: FormatJSON, expPath: "report.json"},
+		{spec: "out/Report.XLSX", expFormat: FormatXLSX, expPath: "out/Report.XLSX"},
+		{spec: "report.txt", expFormat: FormatText, expPath: "report.txt"},
+		{spec: "detections-csv=detections.csv", expFormat: FormatDetectionsCSV, expPath: "detections.csv"},
+		{spec: "json=report", expFormat: FormatJSON, expPath: "report"},
+		// Not a format, so the whole value is the path
+		{spec: "a=b.csv", expFormat: FormatCSV, expPath: "a=b.csv"},
+		{spec: "report", expErr: `can't guess the format of output "report" from its extension, pass it as "format=path"`},
+		{spec: "json=", expErr: `missing path in output "json="`},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			format, path, err := ParseOutputSpec(tc.spec)
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expFormat, format)
+			require.Equal(t, tc.expPath, path)
+		})
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/grafana/detect-angular-dashboards/logger"
 )
@@ -14,18 +18,147 @@ const (
 	DetectionTypePanel       DetectionType = "panel"
 	DetectionTypeDatasource  DetectionType = "datasource"
 	DetectionTypeLegacyPanel DetectionType = "legacyPanel"
+
+	DetectionTypeTemplateVariable DetectionType = "templateVariable"
+	DetectionTypeLegacyOptions    DetectionType = "legacyOptions"
+	DetectionTypeTextAngularMode  DetectionType = "textAngularMode"
+
+	// DetectionTypeUnknown is for plugins whose Angular status can't be determined (e.g.: private plugins
+	// with Grafana < 10.1.0), which must be verified manually.
+	DetectionTypeUnknown DetectionType = "unknown"
+)
+
+// detectionTypes are all the detection types.
+var detectionTypes = []DetectionType{
+	DetectionTypePanel, DetectionTypeDatasource, DetectionTypeLegacyPanel, DetectionTypeTemplateVariable,
+	DetectionTypeLegacyOptions, DetectionTypeTextAngularMode, DetectionTypeUnknown,
+}
+
+// ValidateDetectionType returns an error if the given detection type is not a known detection type.
+func ValidateDetectionType(detectionType string) error {
+	for _, t := range detectionTypes {
+		if string(t) == detectionType {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported detection type %q", detectionType)
+}
+
+// Severity classifies detections by the effort required to fix them.
+type Severity string
+
+const (
+	// SeverityAutoMigratable is for detections that are migrated to React automatically.
+	SeverityAutoMigratable Severity = "auto-migratable"
+
+	// SeverityReplacementAvailable is for plugins that can be replaced by a React plugin,
+	// or upgraded to a version that doesn't use Angular.
+	SeverityReplacementAvailable Severity = "replacement-available"
+
+	// SeverityNoReplacement is for plugins without a known replacement.
+	SeverityNoReplacement Severity = "no-replacement"
+
+	// SeverityLow is for detections that don't break the panel, but may make it render differently.
+	SeverityLow Severity = "low"
+
+	// SeverityUnknown is for plugins that may or may not use Angular.
+	SeverityUnknown Severity = "unknown"
 )
 
+// GridPos is the position (X, Y) and size (W, H) of a panel in the dashboard grid, which is 24 columns wide.
+type GridPos struct {
+	X int `json:"X"`
+	Y int `json:"Y"`
+	W int `json:"W"`
+	H int `json:"H"`
+}
+
 type Detection struct {
 	// PluginID is the plugin ID that triggered the detection.
-	PluginID string
+	PluginID string `json:"PluginID"`
 
 	// DetectionType identifies the type of the detection.
-	DetectionType DetectionType
+	DetectionType DetectionType `json:"DetectionType"`
 
 	// Title is the title of the panel that triggered the detection.
 	// It is used so the user can identify the panel on the dashboard.
-	Title string
+	// For template variables, it's the name of the variable.
+	Title string `json:"Title"`
+
+	// PanelID is the id of the panel that triggered the detection, since titles may be empty or duplicated.
+	// It's omitted for template variables.
+	PanelID int `json:"PanelID,omitempty"`
+
+	// PanelURL is the URL to view the panel that triggered the detection (using the viewPanel parameter).
+	// It's omitted for template variables and in offline mode.
+	PanelURL string `json:"PanelURL,omitempty"`
+
+	// Severity classifies the detection by the effort required to fix it.
+	Severity Severity `json:"Severity,omitempty"`
+
+	// LatestVersion is the latest version of the plugin in GCOM.
+	// It's empty if the plugin is not in GCOM, such as core and private plugins.
+	LatestVersion string `json:"LatestVersion,omitempty"`
+
+	// LatestIsAngular is true if LatestVersion still uses Angular, so upgrading the plugin doesn't fix the detection.
+	// It's nil if LatestVersion is empty.
+	LatestIsAngular *bool `json:"LatestIsAngular,omitempty"`
+
+	// SignatureType is the signature level of the plugin in GCOM (e.g.: "grafana", "commercial", "community").
+	SignatureType string `json:"SignatureType,omitempty"`
+
+	// Deprecated is true if the plugin is deprecated in GCOM, so it's no longer maintained.
+	Deprecated bool `json:"Deprecated,omitempty"`
+
+	// LastRelease is the date of the last release of the plugin in GCOM.
+	LastRelease string `json:"LastRelease,omitempty"`
+
+	// LastReleaseDaysAgo is the number of days elapsed since LastRelease.
+	LastReleaseDaysAgo *int `json:"LastReleaseDaysAgo,omitempty"`
+
+	// LegacyOptions are the panel options only used by the Angular version of the plugin.
+	// It's only populated for DetectionTypeLegacyOptions.
+	LegacyOptions []string `json:"LegacyOptions,omitempty"`
+
+	// LossyOptions are the panel options that don't survive the automatic migration of the panel to React,
+	// so the panel must be checked after the migration.
+	LossyOptions []string `json:"LossyOptions,omitempty"`
+
+	// ReviewOptions are the panel options that are migrated automatically to React, but whose result may differ
+	// from the Angular panel, so the migrated panel should be reviewed manually.
+	ReviewOptions []string `json:"ReviewOptions,omitempty"`
+
+	// SuggestedReplacement is the id of the React plugin suggested to replace the Angular plugin, if known.
+	SuggestedReplacement string `json:"SuggestedReplacement,omitempty"`
+
+	// LibraryPanelUID and LibraryPanel are the uid and the name of the library panel
+	// that triggered the detection, if any. Fixing the library panel fixes all the dashboards using it.
+	LibraryPanelUID string `json:"LibraryPanelUID,omitempty"`
+	LibraryPanel    string `json:"LibraryPanel,omitempty"`
+
+	// Repeat is the template variable used to repeat the panel, if any.
+	// A repeated panel is rendered once for each value of the variable.
+	Repeat string `json:"Repeat,omitempty"`
+
+	// RowRepeat is the template variable used to repeat the row containing the panel, if any.
+	RowRepeat string `json:"RowRepeat,omitempty"`
+
+	// Row is the title of the row containing the panel, if any.
+	Row string `json:"Row,omitempty"`
+
+	// GridPos is the position and size of the panel in the dashboard grid, to find it among panels with the same title.
+	// It's nil for template variables and dashboards using the old row-based layout.
+	GridPos *GridPos `json:"GridPos,omitempty"`
+
+	// IntroducedVersion is the dashboard version in which the plugin was introduced.
+	// It is only populated when running with the dashboard version history enabled.
+	IntroducedVersion int `json:"IntroducedVersion,omitempty"`
+
+	// IntroducedBy is the user that created IntroducedVersion.
+	IntroducedBy string `json:"IntroducedBy,omitempty"`
+
+	// Introduced is the creation time of IntroducedVersion.
+	Introduced string `json:"Introduced,omitempty"`
 }
 
 func (d Detection) String() string {
@@ -35,24 +168,254 @@ func (d Detection) String() string {
 	case DetectionTypeDatasource:
 		return fmt.Sprintf("Found panel with angular data source %q (%q)", d.Title, d.PluginID)
 	case DetectionTypeLegacyPanel:
+		if len(d.LossyOptions) > 0 {
+			return fmt.Sprintf(`Found legacy plugin %q in panel %q. `+
+				`It can be migrated to a React-based panel by Grafana when opening the dashboard, `+
+				`but options %s are not migrated.`,
+				d.PluginID,
+				d.Title,
+				strings.Join(d.LossyOptions, ", "),
+			)
+		}
 		return fmt.Sprintf(`Found legacy plugin %q in panel %q. `+
 			`It can be migrated to a React-based panel by Grafana when opening the dashboard.`,
 			d.PluginID,
 			d.Title,
 		)
+	case DetectionTypeTemplateVariable:
+		return fmt.Sprintf("Found template variable %q with angular data source (%q)", d.Title, d.PluginID)
+	case DetectionTypeLegacyOptions:
+		return fmt.Sprintf(
+			`Found Angular options %s in panel %q using the React version of plugin %q. `+
+				`The panel may render differently than before the migration.`,
+			strings.Join(d.LegacyOptions, ", "),
+			d.Title,
+			d.PluginID,
+		)
+	case DetectionTypeTextAngularMode:
+		return fmt.Sprintf(
+			`Found text panel %q with Angular templates. The content may render differently without Angular.`,
+			d.Title,
+		)
+	case DetectionTypeUnknown:
+		return fmt.Sprintf(
+			`Found plugin %q in %q, whose Angular status is unknown (e.g.: private plugin). Verify it manually.`,
+			d.PluginID,
+			d.Title,
+		)
 	}
 	return ""
 }
 
 type Dashboard struct {
-	Detections []Detection
-	URL        string
-	Title      string
-	Folder     string
-	UpdatedBy  string
-	CreatedBy  string
-	Created    string
-	Updated    string
+	// Instance is the name of the Grafana instance of the dashboard, when scanning multiple instances.
+	Instance string `json:"Instance,omitempty"`
+
+	Detections []Detection `json:"Detections"`
+	UID        string      `json:"UID"`
+	URL        string      `json:"URL"`
+	Title      string      `json:"Title"`
+	Folder     string      `json:"Folder"`
+	FolderUID  string      `json:"FolderUID,omitempty"`
+	UpdatedBy  string      `json:"UpdatedBy"`
+	CreatedBy  string      `json:"CreatedBy"`
+	Created    string      `json:"Created"`
+	Updated    string      `json:"Updated"`
+
+	// CreatedDaysAgo and UpdatedDaysAgo are the number of whole days elapsed since Created and Updated.
+	// They are omitted if the timestamps are not available.
+	CreatedDaysAgo *int `json:"CreatedDaysAgo,omitempty"`
+	UpdatedDaysAgo *int `json:"UpdatedDaysAgo,omitempty"`
+
+	// Views is the number of views of the dashboard, from usage insights (Grafana Enterprise and Cloud).
+	// It's nil if usage insights are not available.
+	Views *int `json:"Views,omitempty"`
+
+	// LastViewed is the time the dashboard was last viewed, from usage insights.
+	LastViewed string `json:"LastViewed,omitempty"`
+
+	// LastViewedDaysAgo is the number of whole days elapsed since LastViewed.
+	LastViewedDaysAgo *int `json:"LastViewedDaysAgo,omitempty"`
+
+	// Priority estimates how much breaking the dashboard would hurt: the number of views, weighted by the
+	// severity of its worst detection. Dashboards without detections have a zero priority.
+	// It's nil if usage insights are not available.
+	Priority *int `json:"Priority,omitempty"`
+
+	// GnetID is the id of the grafana.com dashboard the dashboard has been imported from, if any.
+	// A newer revision of the community dashboard may not use Angular plugins anymore, and can be re-imported.
+	GnetID int `json:"GnetID,omitempty"`
+
+	// CommunityRevision is the latest revision of the grafana.com dashboard the dashboard has been imported from,
+	// if GnetID is set and the revisions have been checked.
+	CommunityRevision *CommunityRevision `json:"CommunityRevision,omitempty"`
+
+	// SchemaVersion is the schema version of the dashboard JSON model. Old schema versions are a hint of
+	// legacy panels (e.g.: "table" panels with a schema version < 24), fixed by upgrading the dashboard schema.
+	// It's 0 for the dashboards using the v2 schema, which has no schema version.
+	SchemaVersion int `json:"SchemaVersion,omitempty"`
+
+	// Public is true if the dashboard is shared publicly.
+	Public bool `json:"Public"`
+
+	// Deleted is true if the dashboard is in the trash. Restoring it would reintroduce its Angular plugins.
+	Deleted bool `json:"Deleted,omitempty"`
+
+	// Orphaned is true if the users that created and last updated the dashboard have been deleted.
+	// Orphaned dashboards may be deleted rather than migrated.
+	Orphaned bool `json:"Orphaned,omitempty"`
+
+	// Provisioned is true if the dashboard is provisioned, so it can't be fixed from the UI.
+	Provisioned bool `json:"Provisioned"`
+
+	// ProvisionedExternalID is the file the dashboard is provisioned from, if Provisioned is true.
+	ProvisionedExternalID string `json:"ProvisionedExternalID"`
+
+	// Editors are the users ("user:<login>"), teams ("team:<team name>") and roles ("role:<role>")
+	// that can edit the dashboard, directly or through its folder, so they can fix it.
+	Editors []string `json:"Editors,omitempty"`
+
+	// Owners are the names of the teams owning the folder of the dashboard: the teams with the highest
+	// permission on the folder. They can be used to send each team the dashboards it should fix.
+	Owners []string `json:"Owners,omitempty"`
+
+	// HomeDashboardFor contains the preferences that set the dashboard as home dashboard:
+	// "org", "team:<team name>" or "user".
+	HomeDashboardFor []string `json:"HomeDashboardFor,omitempty"`
+
+	// LinkedAngularDashboards are the URLs of the dashboards with detections linked from this dashboard,
+	// through dashboard links, panel links or text panels.
+	LinkedAngularDashboards []string `json:"LinkedAngularDashboards,omitempty"`
+}
+
+// CommunityRevision is the latest revision of a grafana.com dashboard.
+type CommunityRevision struct {
+	Revision int `json:"Revision"`
+
+	// Angular is true if the revision has Angular detections too. If it's false, re-importing the revision
+	// in place of the dashboard removes its Angular detections, but also its local changes.
+	Angular bool `json:"Angular"`
+
+	// ImportFile is the file with the payload to re-import the revision with the /api/dashboards/import endpoint,
+	// if it has been generated.
+	ImportFile string `json:"ImportFile,omitempty"`
+}
+
+// GnetURL returns the URL of the grafana.com dashboard with the given id.
+func GnetURL(gnetID int) string {
+	return "https://grafana.com/grafana/dashboards/" + strconv.Itoa(gnetID)
+}
+
+// MarshalJSON encodes the dashboard with a "ReportSchemaVersion" field, the version of the JSON schema of the report
+// (see JSONSchema), so consumers can detect incompatible changes.
+func (d Dashboard) MarshalJSON() ([]byte, error) {
+	// dashboard doesn't have the MarshalJSON method, to not recurse
+	type dashboard Dashboard
+	return json.Marshal(struct {
+		ReportSchemaVersion int `json:"ReportSchemaVersion"`
+		dashboard
+	}{ReportSchemaVersion: CurrentReportSchemaVersion, dashboard: dashboard(d)})
+}
+
+// PluginIDs returns the sorted ids of the plugins of the detections of the dashboard, without duplicates.
+func (d Dashboard) PluginIDs() []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, detection := range d.Detections {
+		if _, ok := seen[detection.PluginID]; ok || detection.PluginID == "" {
+			continue
+		}
+		seen[detection.PluginID] = struct{}{}
+		out = append(out, detection.PluginID)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// HasFindings returns true if the dashboard has detections, or links to dashboards with detections.
+func (d Dashboard) HasFindings() bool {
+	return len(d.Detections) > 0 || len(d.LinkedAngularDashboards) > 0
+}
+
+type PluginType string
+
+const (
+	PluginTypePanel      PluginType = "panel"
+	PluginTypeDatasource PluginType = "datasource"
+)
+
+// PluginUsage is the usage of a panel or datasource plugin across all the dashboards.
+type PluginUsage struct {
+	PluginID string
+	Type     PluginType
+
+	// Angular is true if the plugin is an Angular plugin.
+	Angular bool
+
+	// Count is the number of panels using the plugin.
+	// For datasources, query template variables using the datasource are counted as well.
+	Count int
+
+	// Dashboards are the URLs of the dashboards using the plugin.
+	Dashboards []string
+}
+
+// DatasourceRemap is a datasource using an Angular plugin, with the datasource using its React replacement
+// that could replace it in the panels.
+type DatasourceRemap struct {
+	UID      string
+	Name     string
+	PluginID string
+
+	// TargetPluginID is the suggested React replacement of PluginID.
+	TargetPluginID string
+
+	// TargetUID and TargetName identify the datasource using TargetPluginID that should replace the datasource.
+	// They are empty if the replacement can't be determined, in which case Candidates must be checked manually.
+	TargetUID  string `json:",omitempty"`
+	TargetName string `json:",omitempty"`
+
+	// Match is how the target has been chosen: "url" if it's the only candidate with the same URL,
+	// "only-candidate" if it's the only datasource using TargetPluginID.
+	Match string `json:",omitempty"`
+
+	// Candidates are the uids of the datasources using TargetPluginID, when the target can't be determined.
+	Candidates []string `json:",omitempty"`
+}
+
+// DashboardFix is a dashboard whose legacy panels are migrated to React by the fix command.
+type DashboardFix struct {
+	UID   string
+	Title string
+	URL   string
+
+	// Panels are the panels migrated to React (or to migrate, in dry-run mode).
+	Panels []PanelFix
+
+	// Skipped are the panels that are not migrated, because they have options that don't survive the migration.
+	Skipped []PanelFix `json:",omitempty"`
+
+	// Backup is the file the previous version of the dashboard has been written to before saving it.
+	Backup string `json:",omitempty"`
+
+	// Output is the file the converted dashboard has been written to for review, instead of saving it.
+	Output string `json:",omitempty"`
+
+	// Saved is true if the dashboard has been saved with the migrated panels.
+	Saved bool
+
+	// Error is the reason the dashboard could not be fixed, if any.
+	Error string `json:",omitempty"`
+}
+
+// PanelFix is a panel converted from an Angular plugin to its React replacement.
+type PanelFix struct {
+	Title          string
+	PluginID       string
+	TargetPluginID string
+
+	// LossyOptions are the options that can't be converted, for skipped panels.
+	LossyOptions []string `json:",omitempty"`
 }
 
 type Outputter interface {
@@ -61,39 +424,356 @@ type Outputter interface {
 
 type LoggerReadableOutput struct {
 	log *logger.LeveledLogger
+
+	// groupBy groups the dashboards by plugin, folder or creator instead of listing them one by one, if not empty.
+	groupBy string
+
+	// summaryOnly is true to only log the summary, without the dashboards.
+	summaryOnly bool
 }
 
 func NewLoggerReadableOutput(log *logger.LeveledLogger) LoggerReadableOutput {
 	return LoggerReadableOutput{log: log}
 }
 
+// NewWriterReadableOutput returns a new LoggerReadableOutput writing the readable output to w, without the log
+// prefixes, e.g. to write it to a file.
+func NewWriterReadableOutput(w io.Writer) LoggerReadableOutput {
+	return LoggerReadableOutput{log: &logger.LeveledLogger{
+		Logger:      log.New(w, "", 0),
+		WarnLogger:  log.New(w, "", 0),
+		ErrorLogger: log.New(w, "", 0),
+	}}
+}
+
+// WithGroupBy returns a copy of the LoggerReadableOutput that groups the dashboards with detections
+// as described by GroupDashboards, instead of listing them one by one. An empty groupBy disables grouping.
+func (o LoggerReadableOutput) WithGroupBy(groupBy string) LoggerReadableOutput {
+	o.groupBy = groupBy
+	return o
+}
+
+// WithSummaryOnly returns a copy of the LoggerReadableOutput that only logs the summary, without listing
+// the dashboards, if enabled is true.
+func (o LoggerReadableOutput) WithSummaryOnly(enabled bool) LoggerReadableOutput {
+	o.summaryOnly = enabled
+	return o
+}
+
 func (o LoggerReadableOutput) Output(v []Dashboard) error {
+	switch {
+	case o.summaryOnly:
+	case o.groupBy != "":
+		o.logGroups(v)
+	default:
+		o.logDashboards(v)
+	}
+	o.logSchemaVersionSummary(v)
+	o.logSummary(NewSummary(v))
+	return nil
+}
+
+// logDashboards logs the findings of each dashboard.
+func (o LoggerReadableOutput) logDashboards(v []Dashboard) {
 	for _, dashboard := range v {
-		if len(dashboard.Detections) == 0 {
+		if !dashboard.HasFindings() {
 			o.log.Verbose().Log("Checking dashboard %q %q", dashboard.Title, dashboard.URL)
 			continue
 		}
-		o.log.Log("Found dashboard with Angular plugins %q %q:", dashboard.Title, dashboard.URL)
+		if len(dashboard.Detections) == 0 {
+			o.log.Warn(
+				"Dashboard %q %q links to dashboards with Angular plugins: %s",
+				dashboard.Title, dashboard.URL, strings.Join(dashboard.LinkedAngularDashboards, ", "),
+			)
+			continue
+		}
+		if dashboard.Public {
+			o.log.Warn(
+				"Found PUBLIC dashboard with Angular plugins %q %q, "+
+					"it will break for external viewers if Angular support is disabled:",
+				dashboard.Title, dashboard.URL,
+			)
+		} else {
+			o.log.Log("Found dashboard with Angular plugins %q %q:", dashboard.Title, dashboard.URL)
+		}
+		for _, detail := range dashboardDetails(dashboard) {
+			if detail.warn {
+				o.log.Warn("%s", detail.text)
+			} else {
+				o.log.Log("%s", detail.text)
+			}
+		}
 		for _, detection := range dashboard.Detections {
 			o.log.Log(detection.String())
+			for _, detail := range detectionDetails(detection) {
+				o.log.Log("  %s", detail.text)
+			}
+		}
+	}
+}
+
+// readableDetail is a line of details about a dashboard or a detection in the readable outputs.
+type readableDetail struct {
+	text string
+
+	// warn is true for the details that are logged as warnings.
+	warn bool
+
+	// inline is true for the details that TerminalOutputter renders inline instead, in the title of the dashboard
+	// or in the columns of the detection.
+	inline bool
+}
+
+// dashboardDetails returns the details about the given dashboard with findings, after its title and URL.
+func dashboardDetails(dashboard Dashboard) []readableDetail {
+	var out []readableDetail
+	add := func(format string, args ...interface{}) {
+		out = append(out, readableDetail{text: fmt.Sprintf(format, args...)})
+	}
+	if dashboard.Instance != "" {
+		out = append(out, readableDetail{text: fmt.Sprintf("Dashboard is on instance %q", dashboard.Instance), inline: true})
+	}
+	if dashboard.Views != nil {
+		if dashboard.LastViewed != "" {
+			add("Dashboard has %d views, last viewed %s", *dashboard.Views, dashboard.LastViewed)
+		} else {
+			add("Dashboard has %d views", *dashboard.Views)
+		}
+	}
+	if dashboard.Priority != nil {
+		add("Priority: %d", *dashboard.Priority)
+	}
+	if dashboard.Deleted {
+		add("Dashboard is in the trash, restoring it would reintroduce Angular plugins")
+	}
+	if dashboard.Orphaned {
+		add("Dashboard is orphaned, the users that created and last updated it have been deleted")
+	}
+	if len(dashboard.HomeDashboardFor) > 0 {
+		out = append(out, readableDetail{
+			text: fmt.Sprintf("Dashboard is the home dashboard for %s", strings.Join(dashboard.HomeDashboardFor, ", ")),
+			warn: true,
+		})
+	}
+	if len(dashboard.LinkedAngularDashboards) > 0 {
+		add("Dashboard links to other dashboards with Angular plugins: %s", strings.Join(dashboard.LinkedAngularDashboards, ", "))
+	}
+	if len(dashboard.Editors) > 0 {
+		add("Dashboard can be edited by %s", strings.Join(dashboard.Editors, ", "))
+	}
+	if len(dashboard.Owners) > 0 {
+		add("Dashboard is owned by teams %s", strings.Join(dashboard.Owners, ", "))
+	}
+	if dashboard.GnetID > 0 {
+		add(
+			"Dashboard has been imported from grafana.com (%s), check for a newer revision without Angular plugins",
+			GnetURL(dashboard.GnetID),
+		)
+	}
+	if rev := dashboard.CommunityRevision; rev != nil && !rev.Angular {
+		if rev.ImportFile != "" {
+			add("Revision %d of the grafana.com dashboard has no Angular plugins, re-import it with %s", rev.Revision, rev.ImportFile)
+		} else {
+			add("Revision %d of the grafana.com dashboard has no Angular plugins, re-import it", rev.Revision)
+		}
+	}
+	if dashboard.Provisioned {
+		add("Dashboard is provisioned from %q, it must be fixed in the provisioning source", dashboard.ProvisionedExternalID)
+	}
+	return out
+}
+
+// detectionDetails returns the details about the given detection, after Detection.String.
+func detectionDetails(detection Detection) []readableDetail {
+	var out []readableDetail
+	add := func(format string, args ...interface{}) {
+		out = append(out, readableDetail{text: fmt.Sprintf(format, args...)})
+	}
+	if detection.PanelURL != "" {
+		add("panel: %s", detection.PanelURL)
+	}
+	if detection.Severity != "" {
+		out = append(out, readableDetail{text: fmt.Sprintf("severity: %s", detection.Severity), inline: true})
+	}
+	if detection.LatestIsAngular != nil {
+		if *detection.LatestIsAngular {
+			add("latest version %s still uses Angular", detection.LatestVersion)
+		} else {
+			add("latest version %s does not use Angular, upgrade the plugin", detection.LatestVersion)
+		}
+	}
+	if detection.Deprecated {
+		add("plugin is deprecated")
+	}
+	if detection.SignatureType != "" {
+		add("signature: %s", detection.SignatureType)
+	}
+	if detection.LastRelease != "" {
+		add("last release: %s", detection.LastRelease)
+	}
+	if len(detection.LossyOptions) > 0 && detection.DetectionType != DetectionTypeLegacyPanel {
+		add("options not migrated automatically: %s", strings.Join(detection.LossyOptions, ", "))
+	}
+	if len(detection.ReviewOptions) > 0 {
+		add("manual review recommended after the migration: %s", strings.Join(detection.ReviewOptions, ", "))
+	}
+	if detection.SuggestedReplacement != "" {
+		out = append(out, readableDetail{text: fmt.Sprintf("suggested replacement: %q", detection.SuggestedReplacement), inline: true})
+	}
+	if detection.LibraryPanelUID != "" {
+		add("library panel: %q (%s)", detection.LibraryPanel, detection.LibraryPanelUID)
+	}
+	if detection.Repeat != "" {
+		add("repeated for each value of variable %q", detection.Repeat)
+	}
+	if detection.RowRepeat != "" {
+		add("in a row repeated for each value of variable %q", detection.RowRepeat)
+	}
+	if detection.Row != "" {
+		add("in row %q", detection.Row)
+	}
+	if detection.GridPos != nil {
+		add("position: x=%d, y=%d", detection.GridPos.X, detection.GridPos.Y)
+	}
+	if detection.IntroducedVersion > 0 {
+		add("introduced in version %d by %q (%s)", detection.IntroducedVersion, detection.IntroducedBy, detection.Introduced)
+	}
+	return out
+}
+
+// logGroups logs the dashboards with detections grouped by o.groupBy, the groups with the most detections first.
+func (o LoggerReadableOutput) logGroups(v []Dashboard) {
+	for _, group := range GroupDashboards(v, o.groupBy) {
+		o.log.Log("%s %q: %d detections in %d dashboards", groupLabels[o.groupBy], group.Key, group.Detections, len(group.Dashboards))
+		for _, dashboard := range group.Dashboards {
+			o.log.Log("  %q %q", dashboard.Title, dashboard.URL)
+		}
+	}
+}
+
+// logSchemaVersionSummary logs the number of dashboards and detections for each dashboard schema version with detections.
+func (o LoggerReadableOutput) logSchemaVersionSummary(v []Dashboard) {
+	lines := schemaVersionSummary(v)
+	if len(lines) == 0 {
+		return
+	}
+	o.log.Log("Detections by dashboard schema version:")
+	for _, line := range lines {
+		o.log.Log("  %s", line)
+	}
+}
+
+// schemaVersionSummary returns a line with the number of dashboards and detections for each dashboard schema version
+// with detections.
+func schemaVersionSummary(v []Dashboard) []string {
+	var out []string
+	for _, count := range CountBySchemaVersion(v) {
+		if count.Detections == 0 {
+			continue
+		}
+		schemaVersion := "unknown"
+		if count.SchemaVersion > 0 {
+			schemaVersion = strconv.Itoa(count.SchemaVersion)
+		}
+		detectionTypes := make([]string, 0, len(count.DetectionTypes))
+		for detectionType, n := range count.DetectionTypes {
+			detectionTypes = append(detectionTypes, fmt.Sprintf("%s: %d", detectionType, n))
+		}
+		sort.Strings(detectionTypes)
+		out = append(out, fmt.Sprintf(
+			"schema version %s: %d detections in %d of %d dashboards (%s)",
+			schemaVersion, count.Detections, count.DashboardsWithDetections, count.Dashboards, strings.Join(detectionTypes, ", "),
+		))
+	}
+	return out
+}
+
+// logSummary logs the given summary, with the counts sorted by number of detections.
+func (o LoggerReadableOutput) logSummary(summary Summary) {
+	o.log.Log(
+		"Summary: %d detections in %d of %d dashboards",
+		summary.Detections, summary.DashboardsWithDetections, summary.Dashboards,
+	)
+	detectionTypes := make(map[string]int, len(summary.DetectionTypes))
+	for detectionType, n := range summary.DetectionTypes {
+		detectionTypes[string(detectionType)] = n
+	}
+	gnetDashboards := make(map[string]int, len(summary.GnetIDs))
+	for gnetID, n := range summary.GnetIDs {
+		gnetDashboards[GnetURL(gnetID)] = n
+	}
+	for _, counts := range []struct {
+		name   string
+		counts map[string]int
+	}{
+		{name: "plugin", counts: summary.PluginIDs},
+		{name: "detection type", counts: detectionTypes},
+		{name: "folder", counts: summary.Folders},
+		{name: "grafana.com dashboard", counts: gnetDashboards},
+	} {
+		if len(counts.counts) == 0 {
+			continue
+		}
+		o.log.Log("Detections by %s:", counts.name)
+		keys := make([]string, 0, len(counts.counts))
+		for k := range counts.counts {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if counts.counts[keys[i]] != counts.counts[keys[j]] {
+				return counts.counts[keys[i]] > counts.counts[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			o.log.Log("  %s: %d", k, counts.counts[k])
 		}
 	}
-	return nil
 }
 
 type JSONOutputter struct {
 	writer io.Writer
+
+	// summary is true to wrap the dashboards in a Report with their summary.
+	summary bool
+
+	// summaryOnly is true to only output the summary, without the dashboards.
+	summaryOnly bool
 }
 
 func NewJSONOutputter(w io.Writer) JSONOutputter {
 	return JSONOutputter{writer: w}
 }
 
+// WithSummary returns a copy of the JSONOutputter that outputs a Report with the summary of the dashboards
+// instead of the list of dashboards, if enabled is true.
+func (o JSONOutputter) WithSummary(enabled bool) JSONOutputter {
+	o.summary = enabled
+	return o
+}
+
+// WithSummaryOnly returns a copy of the JSONOutputter that only outputs the Summary of the dashboards,
+// if enabled is true.
+func (o JSONOutputter) WithSummaryOnly(enabled bool) JSONOutputter {
+	o.summaryOnly = enabled
+	return o
+}
+
 func (o JSONOutputter) Output(v []Dashboard) error {
+	if o.summaryOnly {
+		enc := json.NewEncoder(o.writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(NewSummary(v))
+	}
+	// The summary includes the dashboards without findings, compute it before removing them
+	var summary Summary
+	if o.summary {
+		summary = NewSummary(v)
+	}
 	var j int
 	for i, dashboard := range v {
-		// Remove dashboards without detections
-		if len(dashboard.Detections) == 0 {
+		// Remove dashboards without findings
+		if !dashboard.HasFindings() {
 			continue
 		}
 		v[j] = v[i]
@@ -102,5 +782,8 @@ func (o JSONOutputter) Output(v []Dashboard) error {
 	v = v[:j]
 	enc := json.NewEncoder(o.writer)
 	enc.SetIndent("", "  ")
+	if o.summary {
+		return enc.Encode(Report{Dashboards: v, Summary: summary})
+	}
 	return enc.Encode(v)
 }
@@ -0,0 +1,48 @@
+package output
+
+import "sort"
+
+// SchemaVersionCount is the number of dashboards and detections for a dashboard schema version.
+type SchemaVersionCount struct {
+	// SchemaVersion is the schema version, 0 if unknown (e.g.: reports generated by older versions of the tool).
+	SchemaVersion int
+
+	// Dashboards is the number of scanned dashboards.
+	Dashboards int
+
+	// DashboardsWithDetections is the number of dashboards with Angular detections.
+	DashboardsWithDetections int
+
+	// Detections is the number of Angular detections.
+	Detections int
+
+	// DetectionTypes is the number of detections for each detection type.
+	DetectionTypes map[DetectionType]int
+}
+
+// CountBySchemaVersion returns the number of dashboards and detections for each schema version of the given dashboards,
+// sorted by schema version. It helps targeting bulk schema upgrades, as old schema versions correlate with legacy panels.
+func CountBySchemaVersion(dashboards []Dashboard) []SchemaVersionCount {
+	bySchemaVersion := map[int]*SchemaVersionCount{}
+	for _, dashboard := range dashboards {
+		count, ok := bySchemaVersion[dashboard.SchemaVersion]
+		if !ok {
+			count = &SchemaVersionCount{SchemaVersion: dashboard.SchemaVersion, DetectionTypes: map[DetectionType]int{}}
+			bySchemaVersion[dashboard.SchemaVersion] = count
+		}
+		count.Dashboards++
+		if len(dashboard.Detections) > 0 {
+			count.DashboardsWithDetections++
+		}
+		count.Detections += len(dashboard.Detections)
+		for _, detection := range dashboard.Detections {
+			count.DetectionTypes[detection.DetectionType]++
+		}
+	}
+	out := make([]SchemaVersionCount, 0, len(bySchemaVersion))
+	for _, count := range bySchemaVersion {
+		out = append(out, *count)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SchemaVersion < out[j].SchemaVersion })
+	return out
+}
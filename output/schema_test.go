@@ -0,0 +1,24 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountBySchemaVersion(t *testing.T) {
+	require.Empty(t, CountBySchemaVersion(nil))
+	require.Equal(t, []SchemaVersionCount{
+		{SchemaVersion: 0, Dashboards: 1, DashboardsWithDetections: 0, DetectionTypes: map[DetectionType]int{}},
+		{
+			SchemaVersion: 16, Dashboards: 2, DashboardsWithDetections: 2, Detections: 3,
+			DetectionTypes: map[DetectionType]int{DetectionTypeLegacyPanel: 2, DetectionTypePanel: 1},
+		},
+		{SchemaVersion: 39, Dashboards: 1, DashboardsWithDetections: 0, DetectionTypes: map[DetectionType]int{}},
+	}, CountBySchemaVersion([]Dashboard{
+		{SchemaVersion: 39},
+		{SchemaVersion: 16, Detections: []Detection{{DetectionType: DetectionTypeLegacyPanel}, {DetectionType: DetectionTypePanel}}},
+		{},
+		{SchemaVersion: 16, Detections: []Detection{{DetectionType: DetectionTypeLegacyPanel}}},
+	}))
+}
@@ -0,0 +1,48 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// SortByViews sorts dashboards by number of views, most viewed first.
+	SortByViews = "views"
+
+	// SortByPriority sorts dashboards by priority, highest priority first.
+	SortByPriority = "priority"
+)
+
+// ValidateSortBy returns an error if the given sort order is not supported.
+// An empty sort order keeps the dashboards in the order they have been checked.
+func ValidateSortBy(by string) error {
+	switch by {
+	case "", SortByViews, SortByPriority:
+		return nil
+	}
+	return fmt.Errorf("unsupported sort order %q", by)
+}
+
+// Sort sorts the given dashboards in place, in the given order.
+// Dashboards without views or priority (e.g.: without usage insights) are sorted last, by URL.
+func Sort(dashboards []Dashboard, by string) {
+	var key func(Dashboard) *int
+	switch by {
+	case SortByViews:
+		key = func(dashboard Dashboard) *int { return dashboard.Views }
+	case SortByPriority:
+		key = func(dashboard Dashboard) *int { return dashboard.Priority }
+	default:
+		return
+	}
+	sort.SliceStable(dashboards, func(i, j int) bool {
+		vi, vj := key(dashboards[i]), key(dashboards[j])
+		switch {
+		case vi != nil && vj != nil && *vi != *vj:
+			return *vi > *vj
+		case (vi == nil) != (vj == nil):
+			return vi != nil
+		}
+		return dashboards[i].URL < dashboards[j].URL
+	})
+}
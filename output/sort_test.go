@@ -0,0 +1,54 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSort(t *testing.T) {
+	views := func(v int) *int { return &v }
+	dashboards := []Dashboard{
+		{URL: "d/b", Views: nil},
+		{URL: "d/c", Views: views(3)},
+		{URL: "d/a", Views: nil},
+		{URL: "d/d", Views: views(42)},
+		{URL: "d/e", Views: views(3)},
+	}
+
+	t.Run("unsorted", func(t *testing.T) {
+		unsorted := append([]Dashboard(nil), dashboards...)
+		Sort(unsorted, "")
+		require.Equal(t, dashboards, unsorted)
+	})
+
+	t.Run("views", func(t *testing.T) {
+		Sort(dashboards, SortByViews)
+		var urls []string
+		for _, dashboard := range dashboards {
+			urls = append(urls, dashboard.URL)
+		}
+		require.Equal(t, []string{"d/d", "d/c", "d/e", "d/a", "d/b"}, urls)
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		prioritized := []Dashboard{
+			{URL: "d/a", Views: views(42), Priority: views(0)},
+			{URL: "d/b"},
+			{URL: "d/c", Views: views(3), Priority: views(9)},
+		}
+		Sort(prioritized, SortByPriority)
+		var urls []string
+		for _, dashboard := range prioritized {
+			urls = append(urls, dashboard.URL)
+		}
+		require.Equal(t, []string{"d/c", "d/a", "d/b"}, urls)
+	})
+}
+
+func TestValidateSortBy(t *testing.T) {
+	require.NoError(t, ValidateSortBy(""))
+	require.NoError(t, ValidateSortBy(SortByViews))
+	require.NoError(t, ValidateSortBy(SortByPriority))
+	require.Error(t, ValidateSortBy("title"))
+}
@@ -0,0 +1,67 @@
+package output
+
+// generalFolder is the name of the root folder, used for the dashboards that are not in a folder.
+const generalFolder = "General"
+
+// Summary is the summary of a report: the number of dashboards and detections, broken down by plugin,
+// detection type and folder.
+type Summary struct {
+	// Dashboards is the number of scanned dashboards.
+	Dashboards int `json:"Dashboards"`
+
+	// DashboardsWithDetections is the number of dashboards with Angular detections.
+	DashboardsWithDetections int `json:"DashboardsWithDetections"`
+
+	// Detections is the number of Angular detections.
+	Detections int `json:"Detections"`
+
+	// PluginIDs is the number of detections for each plugin id.
+	PluginIDs map[string]int `json:"PluginIDs"`
+
+	// DetectionTypes is the number of detections for each detection type.
+	DetectionTypes map[DetectionType]int `json:"DetectionTypes"`
+
+	// Folders is the number of detections for each folder title ("General" for the dashboards not in a folder).
+	Folders map[string]int `json:"Folders"`
+
+	// GnetIDs is the number of detections for each grafana.com dashboard the dashboards have been imported from.
+	GnetIDs map[int]int `json:"GnetIDs"`
+}
+
+// Report is the JSON report with a summary, written by JSONOutputter when the summary is enabled.
+type Report struct {
+	Dashboards []Dashboard `json:"dashboards"`
+	Summary    Summary     `json:"summary"`
+}
+
+// NewSummary returns the summary of the given dashboards.
+// It must be called with all the scanned dashboards, including the ones without detections.
+func NewSummary(dashboards []Dashboard) Summary {
+	summary := Summary{
+		Dashboards:     len(dashboards),
+		PluginIDs:      map[string]int{},
+		DetectionTypes: map[DetectionType]int{},
+		Folders:        map[string]int{},
+		GnetIDs:        map[int]int{},
+	}
+	for _, dashboard := range dashboards {
+		if len(dashboard.Detections) == 0 {
+			continue
+		}
+		summary.DashboardsWithDetections++
+		summary.Detections += len(dashboard.Detections)
+		folder := dashboard.Folder
+		if folder == "" {
+			folder = generalFolder
+		}
+		summary.Folders[folder] += len(dashboard.Detections)
+		if dashboard.GnetID > 0 {
+			summary.GnetIDs[dashboard.GnetID] += len(dashboard.Detections)
+		}
+		for _, detection := range dashboard.Detections {
+			summary.PluginIDs[detection.PluginID]++
+			summary.DetectionTypes[detection.DetectionType]++
+		}
+	}
+	return summary
+}
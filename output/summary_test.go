@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary(t *testing.T) {
+	dashboards := []Dashboard{
+		{URL: "d/a", Folder: "Team", Detections: []Detection{
+			{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel},
+			{PluginID: "grafana-worldmap-panel", DetectionType: DetectionTypePanel},
+		}},
+		{URL: "d/b", GnetID: 1860, Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel}}},
+		{URL: "d/c", Folder: "Team"},
+	}
+
+	t.Run("new", func(t *testing.T) {
+		require.Equal(t, Summary{
+			PluginIDs: map[string]int{}, DetectionTypes: map[DetectionType]int{}, Folders: map[string]int{}, GnetIDs: map[int]int{},
+		}, NewSummary(nil))
+		require.Equal(t, Summary{
+			Dashboards:               3,
+			DashboardsWithDetections: 2,
+			Detections:               3,
+			PluginIDs:                map[string]int{"graph": 2, "grafana-worldmap-panel": 1},
+			DetectionTypes:           map[DetectionType]int{DetectionTypeLegacyPanel: 2, DetectionTypePanel: 1},
+			Folders:                  map[string]int{"Team": 2, "General": 1},
+			GnetIDs:                  map[int]int{1860: 1},
+		}, NewSummary(dashboards))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewJSONOutputter(&buf).WithSummary(true).Output(append([]Dashboard(nil), dashboards...)))
+		var report Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+		require.Len(t, report.Dashboards, 2)
+		require.Equal(t, NewSummary(dashboards), report.Summary)
+
+		// Reports with a summary can be read back
+		fn := filepath.Join(t.TempDir(), "report.json")
+		require.NoError(t, os.WriteFile(fn, buf.Bytes(), 0o600))
+		read, err := ReadJSON(fn)
+		require.NoError(t, err)
+		require.Equal(t, report.Dashboards, read)
+	})
+
+	t.Run("json summary only", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewJSONOutputter(&buf).WithSummaryOnly(true).Output(dashboards))
+		var summary Summary
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &summary))
+		require.Equal(t, NewSummary(dashboards), summary)
+	})
+
+	t.Run("readable summary only", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewWriterReadableOutput(&buf).WithSummaryOnly(true).Output(dashboards))
+		require.NotContains(t, buf.String(), "Found dashboard")
+		require.Contains(t, buf.String(), "Summary: 3 detections in 2 of 3 dashboards\n")
+		require.Contains(t, buf.String(), "Detections by plugin:\n  graph: 2\n  grafana-worldmap-panel: 1\n")
+	})
+}
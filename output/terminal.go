@@ -0,0 +1,256 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape codes of the colors of the terminal output.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// severityColors are the colors of the severities, from the least to the most severe.
+var severityColors = map[Severity]string{
+	SeverityLow:                  ansiCyan,
+	SeverityAutoMigratable:       ansiGreen,
+	SeverityUnknown:              ansiMagenta,
+	SeverityReplacementAvailable: ansiYellow,
+	SeverityNoReplacement:        ansiRed,
+}
+
+// detectionTypeColors are the colors of the detection types.
+var detectionTypeColors = map[DetectionType]string{
+	DetectionTypePanel:            ansiYellow,
+	DetectionTypeLegacyPanel:      ansiGreen,
+	DetectionTypeDatasource:       ansiBlue,
+	DetectionTypeTemplateVariable: ansiCyan,
+	DetectionTypeUnknown:          ansiMagenta,
+}
+
+// TerminalOutputter renders the dashboards for a terminal: grouped by folder, with the detections of each dashboard
+// in aligned columns, colored by detection type and severity, followed by the summary. It renders the same details
+// as LoggerReadableOutput.
+type TerminalOutputter struct {
+	writer io.Writer
+	color  bool
+
+	// summaryOnly is true to only render the summary, without the dashboards.
+	summaryOnly bool
+}
+
+// NewTerminalOutputter returns a new TerminalOutputter writing to w, with colors if color is true.
+func NewTerminalOutputter(w io.Writer, color bool) TerminalOutputter {
+	return TerminalOutputter{writer: w, color: color}
+}
+
+// WithSummaryOnly returns a copy of the TerminalOutputter that only renders the summary, without listing
+// the dashboards, if enabled is true.
+func (o TerminalOutputter) WithSummaryOnly(enabled bool) TerminalOutputter {
+	o.summaryOnly = enabled
+	return o
+}
+
+func (o TerminalOutputter) Output(v []Dashboard) error {
+	var b strings.Builder
+	if !o.summaryOnly {
+		o.renderFolders(&b, v)
+	}
+	o.renderSchemaVersionSummary(&b, v)
+	o.renderSummary(&b, NewSummary(v))
+	_, err := io.WriteString(o.writer, b.String())
+	return err
+}
+
+// renderFolders renders the dashboards with findings grouped by folder, the folders in the order of their first
+// dashboard, so the order of the dashboards (see Sort) is kept within each folder.
+func (o TerminalOutputter) renderFolders(b *strings.Builder, v []Dashboard) {
+	var folders []string
+	byFolder := map[string][]Dashboard{}
+	for _, dashboard := range v {
+		if !dashboard.HasFindings() {
+			continue
+		}
+		folder := dashboard.Folder
+		if folder == "" {
+			folder = generalFolder
+		}
+		if _, ok := byFolder[folder]; !ok {
+			folders = append(folders, folder)
+		}
+		byFolder[folder] = append(byFolder[folder], dashboard)
+	}
+	for _, folder := range folders {
+		dashboards := byFolder[folder]
+		fmt.Fprintf(b, "%s %s\n", o.paint(ansiBold, folder), o.paint(ansiDim, fmt.Sprintf("(%s)", plural(len(dashboards), "dashboard"))))
+		for _, dashboard := range dashboards {
+			o.renderDashboard(b, dashboard)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// renderDashboard renders the given dashboard and its details, then its detections, one per line, with their details.
+func (o TerminalOutputter) renderDashboard(b *strings.Builder, dashboard Dashboard) {
+	title := dashboard.Title
+	if dashboard.Instance != "" {
+		title = dashboard.Instance + " / " + title
+	}
+	fmt.Fprintf(b, "  %s %s", o.paint(ansiBold, title), o.paint(ansiDim, dashboard.URL))
+	if dashboard.Public {
+		fmt.Fprintf(b, " [%s]", o.paint(ansiRed, "public"))
+	}
+	b.WriteString("\n")
+	o.renderDetails(b, "    ", dashboardDetails(dashboard))
+
+	rows := make([][]string, 0, len(dashboard.Detections))
+	for _, detection := range dashboard.Detections {
+		panel := ""
+		if detection.Title != "" {
+			panel = fmt.Sprintf("%q", detection.Title)
+		}
+		replacement := ""
+		if detection.SuggestedReplacement != "" {
+			replacement = "→ " + detection.SuggestedReplacement
+		}
+		rows = append(rows, []string{detection.PluginID, string(detection.DetectionType), string(detection.Severity), panel, replacement})
+	}
+	widths := columnWidths(rows)
+	for i, detection := range dashboard.Detections {
+		cells := make([]string, 0, len(rows[i]))
+		for j, cell := range rows[i] {
+			padded := cell
+			if j < len(rows[i])-1 {
+				padded += strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+			}
+			switch {
+			case cell == "":
+			case j == 1:
+				padded = o.paint(detectionTypeColors[detection.DetectionType], padded)
+			case j == 2:
+				padded = o.paint(severityColors[detection.Severity], padded)
+			}
+			cells = append(cells, padded)
+		}
+		b.WriteString(strings.TrimRight("    "+strings.Join(cells, "  "), " ") + "\n")
+		o.renderDetails(b, "      ", detectionDetails(detection))
+	}
+}
+
+// renderDetails renders the given details with the given indentation, except the ones rendered inline.
+func (o TerminalOutputter) renderDetails(b *strings.Builder, indent string, details []readableDetail) {
+	for _, detail := range details {
+		if detail.inline {
+			continue
+		}
+		color := ansiDim
+		if detail.warn {
+			color = ansiYellow
+		}
+		b.WriteString(indent + o.paint(color, detail.text) + "\n")
+	}
+}
+
+// renderSchemaVersionSummary renders the number of dashboards and detections for each dashboard schema version
+// with detections.
+func (o TerminalOutputter) renderSchemaVersionSummary(b *strings.Builder, v []Dashboard) {
+	lines := schemaVersionSummary(v)
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s\n", o.paint(ansiBold, "Detections by dashboard schema version:"))
+	for _, line := range lines {
+		b.WriteString("  " + line + "\n")
+	}
+}
+
+// renderSummary renders the summary, with the counts per plugin, detection type and folder in aligned columns.
+func (o TerminalOutputter) renderSummary(b *strings.Builder, summary Summary) {
+	fmt.Fprintf(
+		b, "%s %s in %d of %s\n",
+		o.paint(ansiBold, "Summary:"), plural(summary.Detections, "detection"),
+		summary.DashboardsWithDetections, plural(summary.Dashboards, "dashboard"),
+	)
+	detectionTypes := make(map[string]int, len(summary.DetectionTypes))
+	for detectionType, n := range summary.DetectionTypes {
+		detectionTypes[string(detectionType)] = n
+	}
+	gnetDashboards := make(map[string]int, len(summary.GnetIDs))
+	for gnetID, n := range summary.GnetIDs {
+		gnetDashboards[GnetURL(gnetID)] = n
+	}
+	for _, counts := range []struct {
+		name   string
+		counts map[string]int
+		colors func(label string) string
+	}{
+		{name: "plugin", counts: summary.PluginIDs},
+		{
+			name: "detection type", counts: detectionTypes,
+			colors: func(label string) string { return detectionTypeColors[DetectionType(label)] },
+		},
+		{name: "folder", counts: summary.Folders},
+		{name: "grafana.com dashboard", counts: gnetDashboards},
+	} {
+		if len(counts.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "%s\n", o.paint(ansiBold, "Detections by "+counts.name+":"))
+		bars := countBars(counts.counts)
+		var width int
+		for _, bar := range bars {
+			if n := utf8.RuneCountInString(bar.Label); n > width {
+				width = n
+			}
+		}
+		for _, bar := range bars {
+			label := bar.Label + strings.Repeat(" ", width-utf8.RuneCountInString(bar.Label))
+			if counts.colors != nil {
+				label = o.paint(counts.colors(bar.Label), label)
+			}
+			fmt.Fprintf(b, "  %s  %d\n", label, bar.Count)
+		}
+	}
+}
+
+// paint returns s in the given color, if colors are enabled and color is not empty.
+func (o TerminalOutputter) paint(color, s string) string {
+	if !o.color || color == "" || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// columnWidths returns the width of each column of the given rows, in runes.
+func columnWidths(rows [][]string) []int {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	return widths
+}
+
+// plural returns n followed by the given noun, with an "s" if n is not 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
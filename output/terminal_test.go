@@ -0,0 +1,109 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalOutputter(t *testing.T) {
+	views := 12
+	dashboards := []Dashboard{
+		{
+			Title: "A", URL: "/d/a/a", Folder: "Team", Public: true, Views: &views,
+			Detections: []Detection{
+				{
+					PluginID: "graph", DetectionType: DetectionTypeLegacyPanel, Severity: SeverityAutoMigratable, Title: "Requests",
+					PanelURL: "/d/a/a?viewPanel=1",
+				},
+				{
+					PluginID: "grafana-worldmap-panel", DetectionType: DetectionTypePanel, Severity: SeverityReplacementAvailable,
+					Title: "Map", SuggestedReplacement: "geomap",
+				},
+			},
+		},
+		{Title: "B", URL: "/d/b/b", Detections: []Detection{{PluginID: "akumuli-datasource", DetectionType: DetectionTypeDatasource}}},
+		{Title: "C", URL: "/d/c/c", Folder: "Team", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypeLegacyPanel}}},
+		{Title: "D", URL: "/d/d/d"},
+		{Title: "E", URL: "/d/e/e", LinkedAngularDashboards: []string{"/d/a/a"}},
+	}
+
+	t.Run("without colors", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewTerminalOutputter(&buf, false).Output(dashboards))
+		require.Equal(t, `Team (2 dashboards)
+  A /d/a/a [public]
+    Dashboard has 12 views
+    graph                   legacyPanel  auto-migratable        "Requests"
+      panel: /d/a/a?viewPanel=1
+    grafana-worldmap-panel  panel        replacement-available  "Map"       → geomap
+  C /d/c/c
+    graph  legacyPanel
+
+General (2 dashboards)
+  B /d/b/b
+    akumuli-datasource  datasource
+  E /d/e/e
+    Dashboard links to other dashboards with Angular plugins: /d/a/a
+
+Detections by dashboard schema version:
+  schema version unknown: 4 detections in 3 of 5 dashboards (datasource: 1, legacyPanel: 2, panel: 1)
+Summary: 4 detections in 3 of 5 dashboards
+Detections by plugin:
+  graph                   2
+  akumuli-datasource      1
+  grafana-worldmap-panel  1
+Detections by detection type:
+  legacyPanel  2
+  datasource   1
+  panel        1
+Detections by folder:
+  Team     3
+  General  1
+`, buf.String())
+	})
+
+	t.Run("with colors", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewTerminalOutputter(&buf, true).Output(dashboards))
+		require.Contains(t, buf.String(), ansiYellow+"replacement-available"+ansiReset)
+		require.Contains(t, buf.String(), ansiGreen+"auto-migratable      "+ansiReset)
+		require.Contains(t, buf.String(), ansiBlue+"datasource"+ansiReset)
+		require.Contains(t, buf.String(), ansiRed+"public"+ansiReset)
+	})
+
+	t.Run("summary only", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewTerminalOutputter(&buf, false).WithSummaryOnly(true).Output(dashboards))
+		require.Contains(t, buf.String(), "Summary: 4 detections in 3 of 5 dashboards\n")
+		require.NotContains(t, buf.String(), "/d/a/a")
+	})
+
+	t.Run("same details as the readable output", func(t *testing.T) {
+		priority, isAngular := 30, false
+		dashboard := Dashboard{
+			Title: "F", URL: "/d/f/f", Instance: "prod", Views: &views, LastViewed: "2024-03-01", Priority: &priority,
+			Deleted: true, Orphaned: true, HomeDashboardFor: []string{"org"}, Editors: []string{"user:admin"},
+			Owners: []string{"Team A"}, GnetID: 123, CommunityRevision: &CommunityRevision{Revision: 4},
+			Provisioned: true, ProvisionedExternalID: "dashboards/f.json",
+			Detections: []Detection{{
+				PluginID: "graph", DetectionType: DetectionTypePanel, PanelURL: "/d/f/f?viewPanel=1",
+				LatestVersion: "2.0.0", LatestIsAngular: &isAngular, Deprecated: true, SignatureType: "community",
+				LastRelease: "2020-01-01", LossyOptions: []string{"legend"}, ReviewOptions: []string{"thresholds"},
+				LibraryPanel: "Shared", LibraryPanelUID: "lib", Repeat: "host", RowRepeat: "dc", Row: "Overview",
+				GridPos: &GridPos{X: 1, Y: 2}, IntroducedVersion: 3, IntroducedBy: "admin", Introduced: "2023-01-01",
+			}},
+		}
+		var buf bytes.Buffer
+		require.NoError(t, NewTerminalOutputter(&buf, false).Output([]Dashboard{dashboard}))
+		require.Contains(t, buf.String(), "prod / F")
+		details := append(dashboardDetails(dashboard), detectionDetails(dashboard.Detections[0])...)
+		require.Len(t, details, 24)
+		for _, detail := range details {
+			if !detail.inline {
+				require.Contains(t, buf.String(), detail.text)
+			}
+		}
+	})
+}
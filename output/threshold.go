@@ -0,0 +1,36 @@
+package output
+
+import "fmt"
+
+// ValidateSeverity returns an error if the given severity is not empty and not a known severity.
+func ValidateSeverity(severity string) error {
+	if severity == "" || severityRank(Severity(severity)) >= 0 {
+		return nil
+	}
+	return fmt.Errorf("unsupported severity %q", severity)
+}
+
+// severityRank returns the index of the given severity in severityOrder, -1 if it's unknown.
+func severityRank(severity Severity) int {
+	for i, s := range severityOrder {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// CountDetections returns the number of detections in the given dashboards with the given severity
+// or a more severe one (see severityOrder), or of all the detections if minSeverity is empty.
+func CountDetections(dashboards []Dashboard, minSeverity Severity) int {
+	minRank := severityRank(minSeverity)
+	var n int
+	for _, dashboard := range dashboards {
+		for _, detection := range dashboard.Detections {
+			if minSeverity == "" || severityRank(detection.Severity) >= minRank {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,23 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountDetections(t *testing.T) {
+	dashboards := []Dashboard{
+		{Detections: []Detection{{Severity: SeverityAutoMigratable}, {Severity: SeverityNoReplacement}}},
+		{Detections: []Detection{{Severity: SeverityLow}, {Severity: SeverityReplacementAvailable}}},
+		{Detections: []Detection{}},
+	}
+	require.Equal(t, 4, CountDetections(dashboards, ""))
+	require.Equal(t, 3, CountDetections(dashboards, SeverityAutoMigratable))
+	require.Equal(t, 2, CountDetections(dashboards, SeverityReplacementAvailable))
+	require.Equal(t, 1, CountDetections(dashboards, SeverityNoReplacement))
+
+	require.NoError(t, ValidateSeverity(""))
+	require.NoError(t, ValidateSeverity("no-replacement"))
+	require.Error(t, ValidateSeverity("critical"))
+}
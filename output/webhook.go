@@ -0,0 +1,212 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header containing the HMAC-SHA256 signature of the webhook payload,
+// in the form "sha256=<hex digest>".
+const SignatureHeader = "X-Signature-256"
+
+const (
+	// WebhookPayloadDetections is the webhook payload with the list of dashboards with findings (default).
+	WebhookPayloadDetections = "detections"
+
+	// WebhookPayloadSummary is the webhook payload with the Summary of the dashboards.
+	WebhookPayloadSummary = "summary"
+
+	// WebhookPayloadChanges is the webhook payload with the detections that are new, and resolved, since the previous
+	// run (see WebhookChanges).
+	WebhookPayloadChanges = "changes"
+)
+
+// WebhookChanges is the WebhookPayloadChanges payload.
+type WebhookChanges struct {
+	// New are the dashboards with detections that are new since the previous run, with only the new detections.
+	New []Dashboard `json:"new"`
+
+	// Resolved are the dashboards with detections of the previous run that are gone, with only the resolved detections.
+	Resolved []Dashboard `json:"resolved"`
+}
+
+// defaultWebhookRetryDelay is the delay before the first retry of a failed webhook request, doubled at each retry.
+const defaultWebhookRetryDelay = time.Second
+
+// webhookTimeout is the timeout of each webhook request, retried like the other failures,
+// so an unresponsive receiver doesn't block the runs.
+const webhookTimeout = 30 * time.Second
+
+// ValidateWebhookPayload returns an error if the given webhook payload is not supported.
+func ValidateWebhookPayload(payload string) error {
+	switch payload {
+	case "", WebhookPayloadDetections, WebhookPayloadSummary, WebhookPayloadChanges:
+		return nil
+	}
+	return fmt.Errorf(
+		"unsupported payload %q, must be %q, %q or %q",
+		payload, WebhookPayloadDetections, WebhookPayloadSummary, WebhookPayloadChanges,
+	)
+}
+
+// ParseWebhookHeaders parses the given "Name: value" headers.
+func ParseWebhookHeaders(headers []string) (http.Header, error) {
+	out := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, must be \"Name: value\"", header)
+		}
+		out.Add(name, strings.TrimSpace(value))
+	}
+	return out, nil
+}
+
+// WebhookOutputter sends the dashboards with findings as JSON to a webhook.
+type WebhookOutputter struct {
+	url        string
+	secret     string
+	payload    string
+	headers    http.Header
+	retries    int
+	retryDelay time.Duration
+	httpClient *http.Client
+
+	// previous are the dashboards of the previous run, for WebhookPayloadChanges.
+	previous    []Dashboard
+	hasPrevious bool
+}
+
+// NewWebhookOutputter returns a new WebhookOutputter that sends the detections to the given URL.
+// If secret is not empty, the payload is signed with it and the signature is sent in the SignatureHeader header,
+// so the receiver can verify that the payload has been sent by the detector.
+func NewWebhookOutputter(url, secret string) WebhookOutputter {
+	return WebhookOutputter{url: url, secret: secret, retryDelay: defaultWebhookRetryDelay, httpClient: &http.Client{Timeout: webhookTimeout}}
+}
+
+// WithPayload returns a copy of the WebhookOutputter sending the given payload (WebhookPayloadDetections,
+// WebhookPayloadSummary or WebhookPayloadChanges).
+func (o WebhookOutputter) WithPayload(payload string) WebhookOutputter {
+	o.payload = payload
+	return o
+}
+
+// WithHeaders returns a copy of the WebhookOutputter sending the given additional headers, e.g. for authentication.
+func (o WebhookOutputter) WithHeaders(headers http.Header) WebhookOutputter {
+	o.headers = headers
+	return o
+}
+
+// WithRetries returns a copy of the WebhookOutputter retrying failed requests (network errors, 429 and 5xx status
+// codes) up to the given number of times, with an exponential backoff.
+func (o WebhookOutputter) WithRetries(retries int) WebhookOutputter {
+	o.retries = retries
+	return o
+}
+
+// WithPrevious returns a copy of the WebhookOutputter comparing the dashboards with the given ones of the previous run,
+// for WebhookPayloadChanges.
+func (o WebhookOutputter) WithPrevious(previous []Dashboard) WebhookOutputter {
+	o.previous = previous
+	o.hasPrevious = true
+	return o
+}
+
+// Output sends the payload of the given dashboards to the webhook.
+// With WebhookPayloadChanges, nothing is sent if there is no previous run, or no changes since it.
+func (o WebhookOutputter) Output(v []Dashboard) error {
+	if o.payload == WebhookPayloadChanges {
+		if !o.hasPrevious {
+			return nil
+		}
+		if changes := o.changes(v); len(changes.New) == 0 && len(changes.Resolved) == 0 {
+			return nil
+		}
+	}
+	delay := o.retryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := o.send(v)
+		if err == nil || !retry || attempt == o.retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// send sends the request of the given dashboards once. It returns true if the request failed and can be retried.
+func (o WebhookOutputter) send(v []Dashboard) (bool, error) {
+	req, err := o.NewRequest(v)
+	if err != nil {
+		return false, err
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// NewRequest returns the request that Output sends for the given dashboards, without sending it.
+// It can be used to validate the webhook configuration in dry-run mode.
+func (o WebhookOutputter) NewRequest(v []Dashboard) (*http.Request, error) {
+	var payload interface{}
+	switch o.payload {
+	case WebhookPayloadSummary:
+		payload = NewSummary(v)
+	case WebhookPayloadChanges:
+		payload = o.changes(v)
+	default:
+		// Do not modify v in place, it may be used by other outputters
+		dashboards := make([]Dashboard, 0, len(v))
+		for _, dashboard := range v {
+			if dashboard.HasFindings() {
+				dashboards = append(dashboards, dashboard)
+			}
+		}
+		payload = dashboards
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range o.headers {
+		req.Header[name] = values
+	}
+	if o.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(body, o.secret))
+	}
+	return req, nil
+}
+
+// changes returns the WebhookChanges of the given dashboards since the previous run.
+func (o WebhookOutputter) changes(v []Dashboard) WebhookChanges {
+	diff := Diff(o.previous, v)
+	return WebhookChanges{New: diff.AddedDetections, Resolved: diff.RemovedDetections}
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of the given payload, using the given secret as key.
+func Sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
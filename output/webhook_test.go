@@ -0,0 +1,172 @@
+package output
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookOutputter(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		secret string
+	}{
+		{name: "signed", secret: "s3cr3t"},
+		{name: "unsigned", secret: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			var signature string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				require.NoError(t, err)
+				signature = r.Header.Get(SignatureHeader)
+			}))
+			defer srv.Close()
+
+			dashboards := []Dashboard{
+				{Title: "angular", Detections: []Detection{{PluginID: "grafana-worldmap-panel", DetectionType: DetectionTypePanel}}},
+				{Title: "react"},
+			}
+			require.NoError(t, NewWebhookOutputter(srv.URL, tc.secret).Output(dashboards))
+
+			var received []Dashboard
+			require.NoError(t, json.Unmarshal(body, &received))
+			require.Len(t, received, 1)
+			require.Equal(t, "angular", received[0].Title)
+			require.Len(t, dashboards, 2, "input should not be modified")
+			if tc.secret == "" {
+				require.Empty(t, signature)
+				return
+			}
+			require.True(t, hmac.Equal([]byte("sha256="+Sign(body, tc.secret)), []byte(signature)))
+			require.NotEqual(t, "sha256="+Sign(body, "wrong"), signature)
+		})
+	}
+}
+
+func TestWebhookOutputterOptions(t *testing.T) {
+	dashboards := []Dashboard{
+		{Title: "angular", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}},
+		{Title: "react"},
+	}
+
+	t.Run("summary payload and headers", func(t *testing.T) {
+		var summary Summary
+		var authorization []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&summary))
+			authorization = r.Header.Values("Authorization")
+		}))
+		defer srv.Close()
+
+		headers, err := ParseWebhookHeaders([]string{"authorization: Bearer t0k3n"})
+		require.NoError(t, err)
+		o := NewWebhookOutputter(srv.URL, "").WithPayload(WebhookPayloadSummary).WithHeaders(headers)
+		require.NoError(t, o.Output(dashboards))
+		require.Equal(t, 2, summary.Dashboards)
+		require.Equal(t, 1, summary.DashboardsWithDetections)
+		require.Equal(t, map[string]int{"graph": 1}, summary.PluginIDs)
+		require.Equal(t, []string{"Bearer t0k3n"}, authorization)
+	})
+
+	t.Run("changes payload", func(t *testing.T) {
+		var requests int
+		var changes WebhookChanges
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&changes))
+		}))
+		defer srv.Close()
+
+		o := NewWebhookOutputter(srv.URL, "").WithPayload(WebhookPayloadChanges)
+		require.NoError(t, o.Output(dashboards))
+		require.Zero(t, requests, "nothing should be sent without a previous run")
+		require.NoError(t, o.WithPrevious(dashboards).Output(dashboards))
+		require.Zero(t, requests, "nothing should be sent without changes")
+
+		worldmap := Detection{PluginID: "grafana-worldmap-panel", DetectionType: DetectionTypePanel}
+		current := []Dashboard{
+			{Title: "angular", Detections: []Detection{worldmap}},
+			{Title: "react"},
+		}
+		require.NoError(t, o.WithPrevious(dashboards).Output(current))
+		require.Equal(t, 1, requests)
+		require.Equal(t, WebhookChanges{
+			New:      []Dashboard{{Title: "angular", Detections: []Detection{worldmap}}},
+			Resolved: []Dashboard{{Title: "angular", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}}},
+		}, changes)
+	})
+
+	for _, tc := range []struct {
+		name        string
+		statusCodes []int
+		retries     int
+		expRequests int
+		expErr      string
+	}{
+		{name: "retried until success", statusCodes: []int{503, 429, 200}, retries: 2, expRequests: 3},
+		{name: "retries exhausted", statusCodes: []int{500, 500, 500}, retries: 1, expRequests: 2, expErr: "bad status code: 500"},
+		{name: "client error not retried", statusCodes: []int{400, 200}, retries: 2, expRequests: 1, expErr: "bad status code: 400"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCodes[requests])
+				requests++
+			}))
+			defer srv.Close()
+
+			o := NewWebhookOutputter(srv.URL, "").WithRetries(tc.retries)
+			o.retryDelay = time.Millisecond
+			err := o.Output(dashboards)
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expRequests, requests)
+		})
+	}
+
+	t.Run("timeout retried", func(t *testing.T) {
+		var requests atomic.Int32
+		unblock := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				<-unblock
+			}
+		}))
+		defer srv.Close()
+		defer close(unblock)
+
+		o := NewWebhookOutputter(srv.URL, "").WithRetries(1)
+		require.Equal(t, webhookTimeout, o.httpClient.Timeout)
+		o.httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+		o.retryDelay = time.Millisecond
+		require.NoError(t, o.Output(dashboards))
+		require.EqualValues(t, 2, requests.Load())
+	})
+}
+
+func TestParseWebhookHeaders(t *testing.T) {
+	headers, err := ParseWebhookHeaders([]string{"X-Team: dashboards", "x-team:  platform ", "Authorization: Basic a:b"})
+	require.NoError(t, err)
+	require.Equal(t, http.Header{
+		"X-Team":        {"dashboards", "platform"},
+		"Authorization": {"Basic a:b"},
+	}, headers)
+
+	_, err = ParseWebhookHeaders([]string{"no colon"})
+	require.EqualError(t, err, `invalid header "no colon", must be "Name: value"`)
+	_, err = ParseWebhookHeaders([]string{": value"})
+	require.Error(t, err)
+}
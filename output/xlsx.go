@@ -0,0 +1,366 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// xlsxSummarySheet is the name of the summary sheet of the XLSX report.
+	xlsxSummarySheet = "Summary"
+
+	// xlsxMaxSheetName is the maximum length of a sheet name in Excel.
+	xlsxMaxSheetName = 31
+)
+
+// xlsxDetectionsHeader is the header of the sheets listing the detections of a folder.
+var xlsxDetectionsHeader = []string{
+	"Dashboard", "URL", "PluginID", "DetectionType", "Severity", "Panel", "CreatedBy", "Updated",
+}
+
+// xlsxCell is a cell of an XLSX sheet: a string, a number, or a hyperlink to a URL.
+type xlsxCell struct {
+	value  string
+	number bool
+	url    string
+	bold   bool
+}
+
+// xlsxSheet is a sheet of an XLSX workbook.
+type xlsxSheet struct {
+	name string
+	rows [][]xlsxCell
+	// filter is true to add filters to the header of the sheet (the first row).
+	filter bool
+}
+
+// XLSXOutputter outputs an Excel workbook with a summary sheet (the number of dashboards and detections,
+// and the detections per plugin and per folder), followed by one sheet per folder listing its detections.
+// The workbook is written with the standard library only, without formulas nor macros.
+type XLSXOutputter struct {
+	writer io.Writer
+}
+
+// NewXLSXOutputter returns a new XLSXOutputter writing to w.
+func NewXLSXOutputter(w io.Writer) XLSXOutputter {
+	return XLSXOutputter{writer: w}
+}
+
+func (o XLSXOutputter) Output(v []Dashboard) error {
+	summary := NewSummary(v)
+	sheets := []xlsxSheet{xlsxSummary(summary)}
+
+	byFolder := map[string][]Dashboard{}
+	for _, dashboard := range v {
+		if len(dashboard.Detections) == 0 {
+			continue
+		}
+		folder := dashboard.Folder
+		if folder == "" {
+			folder = generalFolder
+		}
+		byFolder[folder] = append(byFolder[folder], dashboard)
+	}
+	names := map[string]struct{}{strings.ToLower(xlsxSummarySheet): {}}
+	for _, folder := range sortedFolders(byFolder) {
+		sheet := xlsxSheet{name: xlsxSheetName(folder, names), filter: true}
+		sheet.rows = append(sheet.rows, xlsxHeader(xlsxDetectionsHeader))
+		for _, dashboard := range byFolder[folder] {
+			// Relative URLs (-relative-urls) can't be opened from Excel
+			var link string
+			if strings.HasPrefix(dashboard.URL, "http://") || strings.HasPrefix(dashboard.URL, "https://") {
+				link = dashboard.URL
+			}
+			for _, detection := range dashboard.Detections {
+				sheet.rows = append(sheet.rows, []xlsxCell{
+					{value: dashboard.Title, url: link},
+					{value: dashboard.URL},
+					{value: detection.PluginID},
+					{value: string(detection.DetectionType)},
+					{value: string(detection.Severity)},
+					{value: detection.Title},
+					{value: dashboard.CreatedBy},
+					{value: dashboard.Updated},
+				})
+			}
+		}
+		sheets = append(sheets, sheet)
+	}
+	return writeXLSX(o.writer, sheets)
+}
+
+// xlsxSummary returns the summary sheet of the given summary.
+func xlsxSummary(summary Summary) xlsxSheet {
+	sheet := xlsxSheet{name: xlsxSummarySheet}
+	add := func(cells ...xlsxCell) {
+		sheet.rows = append(sheet.rows, cells)
+	}
+	count := func(n int) xlsxCell {
+		return xlsxCell{value: strconv.Itoa(n), number: true}
+	}
+	add(xlsxCell{value: "Dashboards", bold: true}, count(summary.Dashboards))
+	add(xlsxCell{value: "Dashboards with detections", bold: true}, count(summary.DashboardsWithDetections))
+	add(xlsxCell{value: "Detections", bold: true}, count(summary.Detections))
+	for _, breakdown := range []struct {
+		header string
+		counts map[string]int
+	}{
+		{"Plugin", summary.PluginIDs},
+		{"Folder", summary.Folders},
+	} {
+		add()
+		add(xlsxHeader([]string{breakdown.header, "Detections"})...)
+		for _, bar := range countBars(breakdown.counts) {
+			add(xlsxCell{value: bar.Label}, count(bar.Count))
+		}
+	}
+	return sheet
+}
+
+// xlsxHeader returns a header row with the given titles.
+func xlsxHeader(titles []string) []xlsxCell {
+	row := make([]xlsxCell, 0, len(titles))
+	for _, title := range titles {
+		row = append(row, xlsxCell{value: title, bold: true})
+	}
+	return row
+}
+
+// sortedFolders returns the folders of the given map, sorted by title.
+func sortedFolders(byFolder map[string][]Dashboard) []string {
+	folders := make([]string, 0, len(byFolder))
+	for folder := range byFolder {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+// xlsxSheetName returns a valid sheet name for the given folder title, unique among the given names
+// (case-insensitive), which it's added to. Excel forbids some characters, and names longer than 31 characters.
+func xlsxSheetName(folder string, names map[string]struct{}) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, folder)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "_"
+	}
+	base := []rune(name)
+	for i := 2; ; i++ {
+		if runes := []rune(name); len(runes) > xlsxMaxSheetName {
+			name = string(runes[:xlsxMaxSheetName])
+		}
+		if _, ok := names[strings.ToLower(name)]; !ok {
+			break
+		}
+		suffix := fmt.Sprintf(" (%d)", i)
+		if len(base)+len(suffix) > xlsxMaxSheetName {
+			name = string(base[:xlsxMaxSheetName-len(suffix)]) + suffix
+		} else {
+			name = string(base) + suffix
+		}
+	}
+	names[strings.ToLower(name)] = struct{}{}
+	return name
+}
+
+// xlsxColumn returns the name of the column with the given index, from 0 ("A", ..., "Z", "AA", ...).
+func xlsxColumn(i int) string {
+	var name string
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// writeXLSX writes a workbook with the given sheets to w.
+func writeXLSX(w io.Writer, sheets []xlsxSheet) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xlsxWorkbook(sheets)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+		{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="3"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font>` +
+			`<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+			`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+			`<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for _, f := range files {
+		if err := writeZipFile(zw, f.name, f.content); err != nil {
+			return err
+		}
+	}
+	for i, sheet := range sheets {
+		if err := writeXLSXSheet(zw, i+1, sheet); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeZipFile writes a file with the given name and content to the archive.
+func writeZipFile(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+func xlsxContentTypes(sheets int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func xlsxWorkbook(sheets []xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets>`)
+	var filters bool
+	for _, sheet := range sheets {
+		filters = filters || sheet.filter
+	}
+	if filters {
+		// Filters need a defined name for their range, or Excel repairs the workbook
+		b.WriteString(`<definedNames>`)
+		for i, sheet := range sheets {
+			if sheet.filter {
+				fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">%s!%s</definedName>`,
+					i, xmlEscape(quoteSheetName(sheet.name)), xlsxFilterRange(sheet, true))
+			}
+		}
+		b.WriteString(`</definedNames>`)
+	}
+	b.WriteString(`</workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(sheets int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// quoteSheetName quotes the given sheet name for references from formulas and defined names.
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// xlsxFilterRange returns the range of the filters of the given sheet, from the header to the last row,
+// with absolute references if absolute is true.
+func xlsxFilterRange(sheet xlsxSheet, absolute bool) string {
+	var columns int
+	for _, row := range sheet.rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if absolute {
+		return fmt.Sprintf("$A$1:$%s$%d", xlsxColumn(columns-1), len(sheet.rows))
+	}
+	return fmt.Sprintf("A1:%s%d", xlsxColumn(columns-1), len(sheet.rows))
+}
+
+// writeXLSXSheet writes the given sheet, with the given number, and its hyperlinks, to the archive.
+func writeXLSXSheet(zw *zip.Writer, n int, sheet xlsxSheet) error {
+	var b, rels bytes.Buffer
+	var links []string
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	if sheet.filter {
+		// Freeze the header
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	b.WriteString(`<sheetFormatPr defaultRowHeight="15"/><cols><col min="1" max="1" width="40" customWidth="1"/><col min="2" max="8" width="20" customWidth="1"/></cols>`)
+	b.WriteString(`<sheetData>`)
+	for i, row := range sheet.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := xlsxColumn(j) + strconv.Itoa(i+1)
+			style := 0
+			switch {
+			case cell.bold:
+				style = 1
+			case cell.url != "":
+				style = 2
+				links = append(links, fmt.Sprintf(`<hyperlink ref="%s" r:id="rId%d"/>`, ref, len(links)+1))
+				fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`,
+					len(links), xmlEscape(cell.url))
+			}
+			if cell.number {
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, cell.value)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(cell.value))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+	if sheet.filter {
+		fmt.Fprintf(&b, `<autoFilter ref="%s"/>`, xlsxFilterRange(sheet, false))
+	}
+	if len(links) > 0 {
+		b.WriteString(`<hyperlinks>` + strings.Join(links, "") + `</hyperlinks>`)
+	}
+	b.WriteString(`</worksheet>`)
+	if err := writeZipFile(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", n), b.String()); err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return writeZipFile(zw, fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", n), `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+rels.String()+`</Relationships>`)
+}
+
+// xmlEscape returns the given text escaped for XML. Characters invalid in XML are replaced.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
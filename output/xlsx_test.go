@@ -0,0 +1,90 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXLSXOutputter(t *testing.T) {
+	dashboards := []Dashboard{
+		{
+			Title: "A & B", URL: "http://grafana.example.com/d/a/a", Folder: "Team [prod]",
+			Detections: []Detection{
+				{PluginID: "graph", DetectionType: DetectionTypePanel, Title: "Requests"},
+				{PluginID: "akumuli-datasource", DetectionType: DetectionTypeDatasource, Title: "Latency"},
+			},
+		},
+		{Title: "B", URL: "d/b", Detections: []Detection{{PluginID: "graph", DetectionType: DetectionTypePanel}}},
+		{Title: "C", URL: "d/c"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewXLSXOutputter(&buf).Output(dashboards))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(b)
+
+		// All the files are well-formed XML
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, f.Name)
+		}
+	}
+	require.ElementsMatch(t, []string{
+		"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml",
+		"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml", "xl/worksheets/_rels/sheet3.xml.rels",
+	}, keys(files))
+
+	// Summary, then one sheet per folder, sorted
+	require.Contains(t, files["xl/workbook.xml"], `<sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="General" sheetId="2" r:id="rId2"/><sheet name="Team _prod_" sheetId="3" r:id="rId3"/>`)
+	require.Contains(t, files["xl/worksheets/sheet1.xml"], `<row r="3"><c r="A3" s="1" t="inlineStr"><is><t xml:space="preserve">Detections</t></is></c><c r="B3" s="0"><v>3</v></c></row>`)
+	require.Contains(t, files["xl/worksheets/sheet1.xml"], `<c r="A6" s="0" t="inlineStr"><is><t xml:space="preserve">graph</t></is></c><c r="B6" s="0"><v>2</v></c>`)
+	require.Contains(t, files["xl/worksheets/sheet2.xml"], `<autoFilter ref="A1:H2"/>`)
+	require.NotContains(t, files["xl/worksheets/sheet2.xml"], "<hyperlinks>")
+	require.Contains(t, files["xl/worksheets/sheet3.xml"], `<c r="A2" s="2" t="inlineStr"><is><t xml:space="preserve">A &amp; B</t></is></c>`)
+	require.Contains(t, files["xl/worksheets/sheet3.xml"], `<hyperlinks><hyperlink ref="A2" r:id="rId1"/><hyperlink ref="A3" r:id="rId2"/></hyperlinks>`)
+	require.Contains(t, files["xl/worksheets/_rels/sheet3.xml.rels"], `Target="http://grafana.example.com/d/a/a" TargetMode="External"`)
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	names := map[string]struct{}{"summary": {}}
+	require.Equal(t, "summary (2)", xlsxSheetName("summary", names))
+	require.Equal(t, "a_b_c", xlsxSheetName("a/b:c", names))
+	long := strings.Repeat("x", 40)
+	require.Equal(t, strings.Repeat("x", 31), xlsxSheetName(long, names))
+	require.Equal(t, strings.Repeat("x", 27)+" (2)", xlsxSheetName(long, names))
+	require.Equal(t, "_", xlsxSheetName("''", names))
+}
+
+func TestXLSXColumn(t *testing.T) {
+	require.Equal(t, "A", xlsxColumn(0))
+	require.Equal(t, "Z", xlsxColumn(25))
+	require.Equal(t, "AA", xlsxColumn(26))
+	require.Equal(t, "AZ", xlsxColumn(51))
+	require.Equal(t, "BA", xlsxColumn(52))
+}
+
+// keys returns the keys of the given map.
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
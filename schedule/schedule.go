@@ -0,0 +1,191 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the times of the detection runs in server mode.
+type Schedule interface {
+	// Next returns the first time of the schedule after t, or the zero time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a Schedule running every interval.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Jitter returns a random duration between 0 and max (excluded), to add to the scheduled times so that instances
+// started at the same time don't query Grafana at the same time. It returns 0 if max is not positive.
+func Jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// macros are the supported shorthands of cron expressions.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field is the range of the values of a cron field.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	// 7 is also Sunday
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Cron is a Schedule of a cron expression, evaluated in the time zone of the times passed to Next.
+type Cron struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// dayOfMonthAny and dayOfWeekAny are true if the field starts with "*". As in cron, if both fields are restricted,
+	// a day matches if either field matches.
+	dayOfMonthAny, dayOfWeekAny bool
+}
+
+// ParseCron parses a standard cron expression with 5 fields (minute, hour, day of month, month, day of week),
+// e.g. "30 2 * * 1-5". Each field is "*", a value, a range ("1-5") or a list of them ("1,3,5"),
+// optionally with a step ("*/15", "0-30/10"). The @yearly, @monthly, @weekly, @daily and @hourly macros are supported.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[expr]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid cron expression %q: must have %d fields", expr, len(fields))
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		var err error
+		if bits[i], err = parseField(part, fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %s: %w", expr, fields[i].name, err)
+		}
+	}
+	c := &Cron{
+		minute:        bits[0],
+		hour:          bits[1],
+		dayOfMonth:    bits[2],
+		month:         bits[3],
+		dayOfWeek:     bits[4],
+		dayOfMonthAny: strings.HasPrefix(parts[2], "*"),
+		dayOfWeekAny:  strings.HasPrefix(parts[4], "*"),
+	}
+	// Sunday is both 0 and 7
+	if c.dayOfWeek&(1<<7) != 0 {
+		c.dayOfWeek |= 1
+	}
+	if c.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("invalid cron expression %q: never matches", expr)
+	}
+	return c, nil
+}
+
+// parseField returns the bit set of the values of the given cron field.
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+		start, end := f.min, f.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseValue(from, f); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if end, err = parseValue(to, f); err != nil {
+					return 0, err
+				}
+				if end < start {
+					return 0, fmt.Errorf("invalid range %q", rangePart)
+				}
+			case !hasStep:
+				end = start
+			}
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parses a value of the given cron field.
+func parseValue(s string, f field) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q, must be between %d and %d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// maxSearch is how far Next looks for a matching time, e.g. for February 29th.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first minute after t matching the expression, in the location of t, or the zero time if there is none.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case c.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.matchesDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay returns true if the day of t matches the day of month and day of week fields.
+func (c *Cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if c.dayOfMonthAny || c.dayOfWeekAny {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2024, 9, 11, 16, 59, 4, 0, time.UTC)
+	require.Equal(t, now.Add(5*time.Minute), Every(5*time.Minute).Next(now))
+}
+
+func TestJitter(t *testing.T) {
+	require.Zero(t, Jitter(0))
+	for i := 0; i < 100; i++ {
+		jitter := Jitter(time.Minute)
+		require.GreaterOrEqual(t, jitter, time.Duration(0))
+		require.Less(t, jitter, time.Minute)
+	}
+}
+
+func TestCron(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 9, 11, 16, 59, 4, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		exp  time.Time
+	}{
+		{expr: "* * * * *", exp: time.Date(2024, 9, 11, 17, 0, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", exp: time.Date(2024, 9, 11, 17, 0, 0, 0, time.UTC)},
+		{expr: "30 2 * * *", exp: time.Date(2024, 9, 12, 2, 30, 0, 0, time.UTC)},
+		{expr: "@daily", exp: time.Date(2024, 9, 12, 0, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", exp: time.Date(2024, 9, 11, 17, 0, 0, 0, time.UTC)},
+		{expr: "0 3 * * 6,7", exp: time.Date(2024, 9, 14, 3, 0, 0, 0, time.UTC)},
+		{expr: "0 3 * * 0", exp: time.Date(2024, 9, 15, 3, 0, 0, 0, time.UTC)},
+		{expr: "0 1-5/2 * * 1-5", exp: time.Date(2024, 9, 12, 1, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", exp: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)},
+		// Either the day of month or the day of week matches
+		{expr: "0 0 13 * 5", exp: time.Date(2024, 9, 13, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 12 * 5", exp: time.Date(2024, 9, 12, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", exp: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			c, err := ParseCron(tc.expr)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, c.Next(now))
+		})
+	}
+
+	t.Run("time zone", func(t *testing.T) {
+		c, err := ParseCron("0 2 * * *")
+		require.NoError(t, err)
+		loc := time.FixedZone("UTC+2", 2*60*60)
+		require.Equal(t, time.Date(2024, 9, 12, 2, 0, 0, 0, loc), c.Next(now.In(loc)))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "0 0 31 2 *"} {
+			_, err := ParseCron(expr)
+			require.Error(t, err, expr)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Auth are the credentials required by the endpoints: a bearer token and HTTP basic authentication credentials.
+// Either is accepted if both are set, and the endpoints are public if neither is.
+type Auth struct {
+	// token is the bearer token, if any.
+	token string
+
+	// user and password are the basic auth credentials, if user is not empty.
+	user     string
+	password string
+}
+
+// NewAuth returns the credentials requiring the given bearer token and basic auth credentials ("user:password"),
+// if not empty.
+func NewAuth(token, basicAuth string) (Auth, error) {
+	auth := Auth{token: token}
+	if basicAuth != "" {
+		var ok bool
+		auth.user, auth.password, ok = strings.Cut(basicAuth, ":")
+		if !ok || auth.user == "" || auth.password == "" {
+			return Auth{}, fmt.Errorf("basic auth credentials must be \"user:password\"")
+		}
+	}
+	return auth, nil
+}
+
+// Enabled returns true if credentials are required.
+func (a Auth) Enabled() bool {
+	return a.token != "" || a.user != ""
+}
+
+// wrap returns a handler that calls the given handler if the request has the bearer token or the basic auth
+// credentials, and responds with 401 Unauthorized otherwise. It returns h as is if no credentials are required.
+func (a Auth) wrap(h http.HandlerFunc) http.HandlerFunc {
+	if !a.Enabled() {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.token != "" && authorized(r, a.token) {
+			h(w, r)
+			return
+		}
+		if user, password, ok := r.BasicAuth(); ok && a.user != "" &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(a.user)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1 {
+			h(w, r)
+			return
+		}
+		if a.user != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="detect-angular-dashboards"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+}
+
+// authorized returns true if the request has the given bearer token in its Authorization header.
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// CORS is the CORS configuration of the endpoints, so web UIs can call them from browsers.
+type CORS struct {
+	// origins are the allowed origins, "*" for any origin. CORS is disabled if empty.
+	origins []string
+
+	// methods are the allowed methods, as the value of the Access-Control-Allow-Methods header.
+	methods string
+}
+
+// NewCORS returns the CORS configuration allowing the given origins and methods, GET and HEAD if methods is empty.
+func NewCORS(origins, methods []string) CORS {
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead}
+	}
+	return CORS{origins: origins, methods: strings.Join(methods, ", ")}
+}
+
+// allowedOrigin returns the value of the Access-Control-Allow-Origin header for the given origin,
+// or an empty string if the origin is not allowed.
+func (c CORS) allowedOrigin(origin string) string {
+	for _, o := range c.origins {
+		if o == "*" || o == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// wrap returns a handler adding the CORS headers to the responses to the allowed origins, and answering
+// their preflight requests, before the authentication as browsers don't send the credentials with them.
+func (c CORS) wrap(h http.Handler) http.Handler {
+	if len(c.origins) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := c.allowedOrigin(r.Header.Get("Origin"))
+		if origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{HeaderTotalCount, "Link", "Location"}, ", "))
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	_, err := NewAuth("", "user")
+	require.Error(t, err)
+	_, err = NewAuth("", ":password")
+	require.Error(t, err)
+
+	auth, err := NewAuth("token", "user:pass:word")
+	require.NoError(t, err)
+	require.True(t, auth.Enabled())
+	srv := newTestServer(testDetector{}, Config{Auth: auth}, Hooks{})
+	h := srv.Handler()
+
+	for _, tc := range []struct {
+		name    string
+		path    string
+		setAuth func(r *http.Request)
+		expCode int
+	}{
+		{name: "no credentials", path: "/status", expCode: http.StatusUnauthorized},
+		{name: "token", path: "/status", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, expCode: http.StatusOK},
+		{name: "wrong token", path: "/status", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, expCode: http.StatusUnauthorized},
+		{name: "basic auth", path: "/status", setAuth: func(r *http.Request) { r.SetBasicAuth("user", "pass:word") }, expCode: http.StatusOK},
+		{name: "wrong password", path: "/status", setAuth: func(r *http.Request) { r.SetBasicAuth("user", "pass") }, expCode: http.StatusUnauthorized},
+		{name: "scans", path: "/scans/missing", expCode: http.StatusUnauthorized},
+		{name: "scans with token", path: "/scans/missing", setAuth: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, expCode: http.StatusNotFound},
+		{name: "detections", path: "/detections", expCode: http.StatusUnauthorized},
+		{name: "healthz", path: "/healthz", expCode: http.StatusOK},
+		{name: "ready", path: "/ready", expCode: http.StatusServiceUnavailable},
+		{name: "ui", path: "/", expCode: http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.setAuth != nil {
+				tc.setAuth(req)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			require.Equal(t, tc.expCode, rec.Code)
+			if tc.expCode == http.StatusUnauthorized {
+				require.Equal(t, `Basic realm="detect-angular-dashboards"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("scan", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/scan", nil))
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, srv.scans.byID)
+	})
+
+	t.Run("bearer only", func(t *testing.T) {
+		auth, err := NewAuth("token", "")
+		require.NoError(t, err)
+		rec := get(t, newTestServer(testDetector{}, Config{Auth: auth}, Hooks{}).Handler(), "/summary")
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		auth, err := NewAuth("", "")
+		require.NoError(t, err)
+		require.False(t, auth.Enabled())
+		require.Equal(t, http.StatusOK, get(t, newTestServer(testDetector{}, Config{Auth: auth}, Hooks{}).Handler(), "/status").Code)
+	})
+}
+
+func TestCORS(t *testing.T) {
+	auth, err := NewAuth("token", "")
+	require.NoError(t, err)
+	h := newTestServer(testDetector{}, Config{
+		Auth: auth,
+		CORS: NewCORS([]string{"https://ui.example.com"}, nil),
+	}, Hooks{}).Handler()
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/detections", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		// Answered without credentials, as browsers don't send them
+		rec := request(http.MethodOptions, "https://ui.example.com")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Equal(t, "https://ui.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "GET, HEAD", rec.Header().Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "Authorization, Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	})
+
+	t.Run("allowed origin", func(t *testing.T) {
+		rec := request(http.MethodGet, "https://ui.example.com")
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, "https://ui.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "X-Total-Count, Link, Location", rec.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		rec := request(http.MethodOptions, "https://evil.example.com")
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+		rec = request(http.MethodGet, "https://evil.example.com")
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("any origin and methods", func(t *testing.T) {
+		cors := NewCORS([]string{"*"}, []string{http.MethodGet, http.MethodPost})
+		require.Equal(t, "https://other.example.com", cors.allowedOrigin("https://other.example.com"))
+		require.Equal(t, "GET, POST", cors.methods)
+	})
+}
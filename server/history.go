@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/audit"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// HistoryRun is a detection run kept in the History.
+type HistoryRun struct {
+	// ID identifies the run, from 1 for the first run since the server started.
+	ID int
+
+	// Time is the time the run finished, as RFC3339 in UTC.
+	Time string
+
+	audit.Counts
+
+	// dashboards are the dashboards with detections of the run.
+	dashboards []output.Dashboard
+}
+
+// History keeps the results of the last complete detection runs of the server mode, for the /history and /diff endpoints.
+type History struct {
+	mu   sync.Mutex
+	size int
+	runs []HistoryRun
+	// lastID is the id of the last run
+	lastID int
+}
+
+// newHistory returns a new History keeping the last size runs.
+func newHistory(size int) *History {
+	return &History{size: size}
+}
+
+// add records the results of a complete detection run finished at the given time, forgetting the oldest run if needed.
+func (h *History) add(dashboards []output.Dashboard, now time.Time) {
+	if h.size == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	h.runs = append(h.runs, HistoryRun{
+		ID:         h.lastID,
+		Time:       now.UTC().Format(time.RFC3339),
+		Counts:     *audit.CountDashboards(dashboards),
+		dashboards: filterAngularDashboards(dashboards),
+	})
+	if len(h.runs) > h.size {
+		h.runs = h.runs[1:]
+	}
+}
+
+// list returns the kept runs, from the oldest to the newest.
+func (h *History) list() []HistoryRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]HistoryRun{}, h.runs...)
+}
+
+// get returns the kept run with the given id.
+func (h *History) get(id int) (HistoryRun, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, run := range h.runs {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return HistoryRun{}, false
+}
+
+// handleHistoryRequest handles the /history HTTP endpoint, which returns the kept detection runs, from the oldest to the newest.
+func (s *Server) handleHistoryRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, s.history.list())
+}
+
+// handleDiffRequest handles the /diff HTTP endpoint, which returns the difference between the dashboards with detections
+// of the runs with the ids of the "from" and "to" query parameters: by default, the last run and the kept run before it.
+// Without "from", if no run is kept before "to" (e.g.: the first run since the server started), all the dashboards
+// with detections of "to" are new, and From is null.
+func (s *Server) handleDiffRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	runs := s.history.list()
+	if len(runs) == 0 {
+		http.Error(w, "Not Ready", http.StatusServiceUnavailable)
+		return
+	}
+	// run returns the run with the id of the given query parameter, or the run with the given default id
+	run := func(param string, defaultID int) (HistoryRun, int, error) {
+		id := defaultID
+		if v := r.URL.Query().Get(param); v != "" {
+			var err error
+			if id, err = strconv.Atoi(v); err != nil {
+				return HistoryRun{}, http.StatusBadRequest, fmt.Errorf("invalid %s %q", param, v)
+			}
+		}
+		out, ok := s.history.get(id)
+		if !ok {
+			return HistoryRun{}, http.StatusNotFound, fmt.Errorf("run %d not found, see /history for the kept runs", id)
+		}
+		return out, 0, nil
+	}
+	to, status, err := run("to", runs[len(runs)-1].ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	var from *HistoryRun
+	if r.URL.Query().Get("from") != "" {
+		fromRun, status, err := run("from", 0)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		from = &fromRun
+	} else {
+		for i := range runs {
+			if runs[i].ID < to.ID {
+				from = &runs[i]
+			}
+		}
+	}
+	var fromDashboards []output.Dashboard
+	if from != nil {
+		fromDashboards = from.dashboards
+	}
+	s.writeJSON(w, struct {
+		From *HistoryRun
+		To   HistoryRun
+		output.ReportDiff
+	}{From: from, To: to, ReportDiff: output.Diff(fromDashboards, to.dashboards)})
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("eviction", func(t *testing.T) {
+		history := newHistory(2)
+		for i := 0; i < 3; i++ {
+			history.add(testDashboards[:i+1], now.Add(time.Duration(i)*time.Minute))
+		}
+		runs := history.list()
+		require.Len(t, runs, 2)
+		require.Equal(t, 2, runs[0].ID)
+		require.Equal(t, 3, runs[1].ID)
+		require.Equal(t, "2024-01-01T00:02:00Z", runs[1].Time)
+		require.Equal(t, 3, runs[1].DashboardsWithDetections)
+		_, ok := history.get(1)
+		require.False(t, ok)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		history := newHistory(0)
+		history.add(testDashboards, now)
+		require.Empty(t, history.list())
+	})
+}
+
+func TestDiffRequest(t *testing.T) {
+	srv := newTestServer(testDetector{}, Config{KeepRuns: 3}, Hooks{})
+	h := srv.Handler()
+	require.Equal(t, http.StatusServiceUnavailable, get(t, h, "/diff").Code)
+
+	type diffResponse struct {
+		From *HistoryRun
+		To   HistoryRun
+		output.ReportDiff
+	}
+	diff := func(query string) diffResponse {
+		rec := get(t, h, "/diff"+query)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var out diffResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		return out
+	}
+
+	// With a single run, all its dashboards with detections are new
+	now := time.Now()
+	srv.history.add(testDashboards[:1], now)
+	first := diff("")
+	require.Nil(t, first.From)
+	require.Equal(t, 1, first.To.ID)
+	require.Len(t, first.New, 1)
+
+	srv.history.add(testDashboards[:2], now)
+	last := diff("")
+	require.Equal(t, 1, last.From.ID)
+	require.Equal(t, 2, last.To.ID)
+	require.Len(t, last.New, 1)
+	require.Equal(t, "b", last.New[0].UID)
+
+	// The run before may have been forgotten, the kept run before is used
+	srv.history.add(testDashboards[:3], now)
+	srv.history.add(testDashboards[:3], now)
+	last = diff("?to=3")
+	require.Equal(t, 2, last.From.ID)
+	require.Nil(t, diff("?to=2").From)
+
+	for _, tc := range []struct {
+		query   string
+		expCode int
+	}{
+		{query: "from=x", expCode: http.StatusBadRequest},
+		{query: "to=1.5", expCode: http.StatusBadRequest},
+		{query: "from=7", expCode: http.StatusNotFound},
+		{query: "to=7", expCode: http.StatusNotFound},
+		{query: "from=4&to=2", expCode: http.StatusOK},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.expCode, get(t, h, "/diff?"+tc.query).Code)
+		})
+	}
+
+	t.Run("history", func(t *testing.T) {
+		rec := get(t, h, "/history")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var runs []HistoryRun
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &runs))
+		require.Len(t, runs, 3)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		h := newTestServer(testDetector{}, Config{}, Hooks{}).Handler()
+		// Served by the UI handler
+		require.NotContains(t, get(t, h, "/history").Header().Get("Content-Type"), "application/json")
+	})
+}
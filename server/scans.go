@@ -0,0 +1,224 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/detector"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// Statuses of the scans requested with POST /scan.
+const (
+	scanStatusQueued    = "queued"
+	scanStatusRunning   = "running"
+	scanStatusCompleted = "completed"
+	scanStatusFailed    = "failed"
+)
+
+// maxQueuedScans is the maximum number of scans waiting to run, and maxScans the number of scans whose status is kept.
+const (
+	maxQueuedScans = 10
+	maxScans       = 100
+)
+
+// Scan is a detection run requested with POST /scan.
+type Scan struct {
+	ID string
+
+	// Scope restricts the scan to some folders and dashboards, all the dashboards are scanned if it's empty.
+	Scope detector.Scope
+
+	// Status is "queued", "running", "completed" or "failed", with the error in Error.
+	Status string
+	Error  string `json:",omitempty"`
+
+	// Requested, Started and Finished are the times the scan was requested, started and finished, as RFC3339.
+	Requested string
+	Started   string `json:",omitempty"`
+	Finished  string `json:",omitempty"`
+
+	// Dashboards and DashboardsWithDetections are the number of dashboards scanned, and with detections,
+	// once the scan is completed.
+	Dashboards               int
+	DashboardsWithDetections int
+
+	// Progress is the progress of the scan while it's running.
+	Progress *detector.ProgressStatus `json:",omitempty"`
+
+	// progress counts the dashboards of the scan if it has a scope, the complete scans use the progress of the server.
+	progress *detector.Progress
+}
+
+// Scans are the scans requested with POST /scan. Their ids are queued for Server.RunDetections.
+type Scans struct {
+	mu   sync.Mutex
+	byID map[string]*Scan
+	// ids are the ids of the scans, from the oldest to the newest, to forget the oldest ones
+	ids []string
+
+	queue chan string
+}
+
+func newScans() *Scans {
+	return &Scans{byID: map[string]*Scan{}, queue: make(chan string, maxQueuedScans)}
+}
+
+// enqueue queues a new scan of the given scope, and returns it. It fails if too many scans are queued.
+func (s *Scans) enqueue(scope detector.Scope, now time.Time) (Scan, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return Scan{}, fmt.Errorf("generate scan id: %w", err)
+	}
+	scan := &Scan{ID: hex.EncodeToString(b), Scope: scope, Status: scanStatusQueued, Requested: now.UTC().Format(time.RFC3339)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case s.queue <- scan.ID:
+	default:
+		return Scan{}, fmt.Errorf("too many scans queued")
+	}
+	s.byID[scan.ID] = scan
+	s.ids = append(s.ids, scan.ID)
+	if len(s.ids) > maxScans {
+		delete(s.byID, s.ids[0])
+		s.ids = s.ids[1:]
+	}
+	return *scan, nil
+}
+
+// get returns the scan with the given id.
+func (s *Scans) get(id string) (Scan, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scan, ok := s.byID[id]
+	if !ok {
+		return Scan{}, false
+	}
+	return *scan, true
+}
+
+// start marks the scan with the given id as running, and returns its scope, with the progress counting
+// its dashboards if the scope is not empty (see detector.RunScope).
+func (s *Scans) start(id string, now time.Time) (detector.Scope, *detector.Progress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scan, ok := s.byID[id]
+	if !ok {
+		// Forgotten while queued, run it anyway
+		return detector.Scope{}, nil
+	}
+	scan.Status = scanStatusRunning
+	scan.Started = now.UTC().Format(time.RFC3339)
+	if !scan.Scope.IsEmpty() {
+		scan.progress = detector.NewProgress()
+	}
+	return scan.Scope, scan.progress
+}
+
+// finish records the results of the scan with the given id.
+func (s *Scans) finish(id string, data []output.Dashboard, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scan, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	scan.Finished = now.UTC().Format(time.RFC3339)
+	if err != nil {
+		scan.Status, scan.Error = scanStatusFailed, err.Error()
+		return
+	}
+	summary := output.NewSummary(data)
+	scan.Status = scanStatusCompleted
+	scan.Dashboards, scan.DashboardsWithDetections = summary.Dashboards, summary.DashboardsWithDetections
+}
+
+// mergeScopedResults returns the previous results with the results of the dashboards in the given scope replaced
+// by the results of a scan of the scope. The previous results of the dashboards in the scope that are not in the
+// results of the scan anymore (e.g.: deleted) are removed.
+func mergeScopedResults(previous, scoped []output.Dashboard, scope detector.Scope) []output.Dashboard {
+	uids := map[string]struct{}{}
+	folderUIDs := map[string]struct{}{}
+	for _, uid := range scope.DashboardUIDs {
+		uids[uid] = struct{}{}
+	}
+	for _, uid := range scope.FolderUIDs {
+		folderUIDs[uid] = struct{}{}
+	}
+	for _, dashboard := range scoped {
+		if _, ok := uids[dashboard.UID]; ok {
+			// In the scope by uid, the other dashboards of its folder are not
+			continue
+		}
+		uids[dashboard.UID] = struct{}{}
+		// In the scope by folder, e.g. in a subfolder of a folder of the scope
+		if dashboard.FolderUID != "" {
+			folderUIDs[dashboard.FolderUID] = struct{}{}
+		}
+	}
+	out := make([]output.Dashboard, 0, len(previous)+len(scoped))
+	for _, dashboard := range previous {
+		if _, ok := uids[dashboard.UID]; ok {
+			continue
+		}
+		if _, ok := folderUIDs[dashboard.FolderUID]; ok && dashboard.FolderUID != "" {
+			continue
+		}
+		out = append(out, dashboard)
+	}
+	return append(out, scoped...)
+}
+
+// handleScanRequest handles the /scan HTTP endpoint, which queues an on-demand scan of the dashboards in the folders
+// with the "folderUid" query parameters and with the "uid" query parameters, or of all the dashboards without them.
+func (s *Server) handleScanRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	scan, err := s.scans.enqueue(detector.Scope{FolderUIDs: query["folderUid"], DashboardUIDs: query["uid"]}, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	s.log.Log("Queued scan %s", scan.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/scans/"+scan.ID)
+	w.WriteHeader(http.StatusAccepted)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(scan); err != nil {
+		s.log.Errorf("http server: %s\n", err)
+	}
+}
+
+// handleScanStatusRequest handles the /scans/<id> HTTP endpoint, which returns the status of a scan queued with
+// POST /scan, with its progress while it's running: the progress of the server for the complete scans.
+func (s *Server) handleScanStatusRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scan, ok := s.scans.get(strings.TrimPrefix(r.URL.Path, "/scans/"))
+	if !ok {
+		http.Error(w, "Scan not found", http.StatusNotFound)
+		return
+	}
+	if scan.Status == scanStatusRunning {
+		progress := s.config.Progress
+		if scan.progress != nil {
+			progress = scan.progress
+		}
+		status := progress.Status(time.Now())
+		scan.Progress = &status
+	}
+	s.writeJSON(w, scan)
+}
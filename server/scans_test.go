@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/detector"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+func TestScans(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("lifecycle", func(t *testing.T) {
+		scans := newScans()
+		scope := detector.Scope{FolderUIDs: []string{"team-a"}}
+		scan, err := scans.enqueue(scope, now)
+		require.NoError(t, err)
+		require.Len(t, scan.ID, 16)
+		require.Equal(t, scanStatusQueued, scan.Status)
+		require.Equal(t, scan.ID, <-scans.queue)
+
+		gotScope, progress := scans.start(scan.ID, now.Add(time.Second))
+		require.Equal(t, scope, gotScope)
+		require.NotNil(t, progress)
+		got, ok := scans.get(scan.ID)
+		require.True(t, ok)
+		require.Equal(t, scanStatusRunning, got.Status)
+		require.Equal(t, "2024-01-01T00:00:01Z", got.Started)
+
+		scans.finish(scan.ID, []output.Dashboard{
+			{UID: "a", Detections: []output.Detection{{PluginID: "graph"}}},
+			{UID: "b"},
+		}, nil, now.Add(2*time.Second))
+		got, _ = scans.get(scan.ID)
+		require.Equal(t, scanStatusCompleted, got.Status)
+		require.Equal(t, "2024-01-01T00:00:02Z", got.Finished)
+		require.Equal(t, 2, got.Dashboards)
+		require.Equal(t, 1, got.DashboardsWithDetections)
+
+		_, ok = scans.get("missing")
+		require.False(t, ok)
+	})
+
+	t.Run("complete scans use the progress of the server", func(t *testing.T) {
+		scans := newScans()
+		scan, err := scans.enqueue(detector.Scope{}, now)
+		require.NoError(t, err)
+		scope, progress := scans.start(scan.ID, now)
+		require.True(t, scope.IsEmpty())
+		require.Nil(t, progress)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		scans := newScans()
+		scan, err := scans.enqueue(detector.Scope{DashboardUIDs: []string{"a"}}, now)
+		require.NoError(t, err)
+		scans.start(scan.ID, now)
+		scans.finish(scan.ID, nil, errors.New("boom"), now)
+		got, _ := scans.get(scan.ID)
+		require.Equal(t, scanStatusFailed, got.Status)
+		require.Equal(t, "boom", got.Error)
+	})
+
+	t.Run("too many queued", func(t *testing.T) {
+		scans := newScans()
+		for i := 0; i < maxQueuedScans; i++ {
+			_, err := scans.enqueue(detector.Scope{}, now)
+			require.NoError(t, err)
+		}
+		_, err := scans.enqueue(detector.Scope{}, now)
+		require.EqualError(t, err, "too many scans queued")
+	})
+
+	t.Run("oldest forgotten", func(t *testing.T) {
+		scans := newScans()
+		var first string
+		for i := 0; i <= maxScans; i++ {
+			scan, err := scans.enqueue(detector.Scope{}, now)
+			require.NoError(t, err)
+			<-scans.queue
+			if i == 0 {
+				first = scan.ID
+			}
+		}
+		_, ok := scans.get(first)
+		require.False(t, ok)
+		require.Len(t, scans.byID, maxScans)
+
+		// A forgotten scan is run anyway, on all the dashboards
+		scope, progress := scans.start(first, now)
+		require.True(t, scope.IsEmpty())
+		require.Nil(t, progress)
+	})
+}
+
+func TestMergeScopedResults(t *testing.T) {
+	previous := []output.Dashboard{
+		{UID: "root"},
+		{UID: "a1", FolderUID: "a"},
+		{UID: "a2", FolderUID: "a"},
+		{UID: "b1", FolderUID: "b"},
+		{UID: "deleted", FolderUID: "b"},
+		{UID: "moved", FolderUID: "c"},
+	}
+	for _, tc := range []struct {
+		name    string
+		scoped  []output.Dashboard
+		scope   detector.Scope
+		expUIDs []string
+	}{
+		{
+			name:    "folder replaced",
+			scope:   detector.Scope{FolderUIDs: []string{"a"}},
+			scoped:  []output.Dashboard{{UID: "a1", FolderUID: "a", Title: "new"}},
+			expUIDs: []string{"root", "b1", "deleted", "moved", "a1"},
+		},
+		{
+			name:    "deleted dashboard removed",
+			scope:   detector.Scope{DashboardUIDs: []string{"deleted"}},
+			expUIDs: []string{"root", "a1", "a2", "b1", "moved"},
+		},
+		{
+			// The scope lists the top folder, the results have the subfolders
+			name:    "subfolders replaced",
+			scope:   detector.Scope{FolderUIDs: []string{"parent"}},
+			scoped:  []output.Dashboard{{UID: "b1", FolderUID: "b"}},
+			expUIDs: []string{"root", "a1", "a2", "moved", "b1"},
+		},
+		{
+			name:    "moved dashboard replaced",
+			scope:   detector.Scope{DashboardUIDs: []string{"moved"}},
+			scoped:  []output.Dashboard{{UID: "moved", FolderUID: "a"}},
+			expUIDs: []string{"root", "a1", "a2", "b1", "deleted", "moved"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeScopedResults(previous, tc.scoped, tc.scope)
+			var uids []string
+			for _, dashboard := range merged {
+				uids = append(uids, dashboard.UID)
+			}
+			require.Equal(t, tc.expUIDs, uids)
+		})
+	}
+}
@@ -0,0 +1,465 @@
+// Package server implements the server mode: it runs the detection on a schedule or on demand,
+// and serves the results of the last run over HTTP.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/detector"
+	"github.com/grafana/detect-angular-dashboards/logger"
+	"github.com/grafana/detect-angular-dashboards/output"
+	"github.com/grafana/detect-angular-dashboards/schedule"
+	"github.com/grafana/detect-angular-dashboards/ui"
+)
+
+// HeaderTotalCount is the response header with the total number of items of paginated endpoints.
+const HeaderTotalCount = "X-Total-Count"
+
+// shutdownTimeout is the maximum time to wait for the in-flight requests and detection run on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// Detector runs the detection for the Server, see detector.Detector.
+type Detector interface {
+	RunScope(ctx context.Context, scope detector.Scope, progress *detector.Progress) ([]output.Dashboard, error)
+	FolderTree(ctx context.Context, data []output.Dashboard) *output.Folder
+}
+
+// Config is the configuration of a Server.
+type Config struct {
+	// Auth are the credentials required by the endpoints, except /ready and /healthz.
+	Auth Auth
+
+	// CORS allows web UIs served from other origins to call the endpoints.
+	CORS CORS
+
+	// Schedule is the schedule of the detection runs, delayed by up to Jitter.
+	Schedule schedule.Schedule
+	Jitter   time.Duration
+
+	// NoInitialRun skips the detection run on start, the first run happens on Schedule.
+	NoInitialRun bool
+
+	// KeepRuns is the number of complete runs kept for the /history and /diff endpoints, disabled if 0.
+	KeepRuns int
+
+	// SortBy is the order of the dashboards, see output.Sort.
+	SortBy string
+
+	// Progress is the progress of the complete runs of the Detector (see detector.WithProgress), served by /status.
+	Progress *detector.Progress
+}
+
+// Hooks are the side effects of the detection runs. They are called from the goroutine of the detection runs,
+// so they don't need to be safe for concurrent use. Each of them can be nil.
+type Hooks struct {
+	// AfterRun is called after each run, complete or not, e.g. to save the scan state.
+	AfterRun func()
+
+	// Completed is called with the sorted results of each complete run, or its error, before they are served.
+	// The results of the scans restricted to some dashboards are partial, so they are not passed.
+	Completed func(ctx context.Context, data []output.Dashboard, err error)
+
+	// Reload reloads the configuration, on RequestReload. The error is logged.
+	Reload func() error
+}
+
+// Server runs the detection on a schedule or on demand, and serves the results of the last run.
+type Server struct {
+	log      *logger.LeveledLogger
+	config   Config
+	detector Detector
+	hooks    Hooks
+
+	mu      sync.Mutex
+	data    []output.Dashboard
+	folders *output.Folder
+	summary *output.Summary
+
+	// ready is true once results have been published, for the readiness probe
+	ready atomic.Bool
+
+	history *History
+	scans   *Scans
+
+	// reload is signaled by RequestReload, to reload the configuration between detection runs
+	reload chan struct{}
+}
+
+// New returns a new Server running the detection with the given Detector.
+func New(log *logger.LeveledLogger, d Detector, config Config, hooks Hooks) *Server {
+	if config.Progress == nil {
+		config.Progress = detector.NewProgress()
+	}
+	return &Server{
+		log:      log,
+		config:   config,
+		detector: d,
+		hooks:    hooks,
+		history:  newHistory(config.KeepRuns),
+		scans:    newScans(),
+		reload:   make(chan struct{}, 1),
+	}
+}
+
+// Publish serves the given results, e.g. the ones of the last run before a restart, and marks the server as ready.
+func (s *Server) Publish(ctx context.Context, data []output.Dashboard) {
+	folders := s.detector.FolderTree(ctx, data)
+	summary := output.NewSummary(data)
+
+	s.log.Log("Updating Output Data")
+	s.mu.Lock()
+	s.data = data
+	s.folders = folders
+	s.summary = &summary
+	s.mu.Unlock()
+
+	if !s.ready.Swap(true) {
+		s.log.Log("Updating readiness probe to ready")
+	}
+}
+
+// RequestReload reloads the configuration (see Hooks.Reload) after the in-flight detection run, if any.
+func (s *Server) RequestReload() {
+	select {
+	case s.reload <- struct{}{}:
+	default:
+		// A reload is already pending
+	}
+}
+
+// ListenAndServe serves the endpoints on the given address and runs the detection until ctx is cancelled,
+// then shuts the server down gracefully and waits for the in-flight detection run to be cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.RunDetections(ctx)
+	}()
+
+	server := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		s.log.Log("Listening on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.log.Error("ListenAndServe(): %s", err)
+		}
+	}()
+
+	<-ctx.Done()
+	s.log.Log("Received signal. Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	// Wait for the in-flight detection run to be cancelled, so its requests and files are not abandoned midway
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.log.Warn("Detection run still running after %s, exiting anyway", shutdownTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	s.log.Log("Server gracefully stopped")
+	return nil
+}
+
+// RunDetections runs the detection on schedule, on demand (POST /scan) and on start (unless Config.NoInitialRun),
+// until ctx is cancelled. The in-flight run is cancelled with ctx.
+func (s *Server) RunDetections(ctx context.Context) {
+	next := s.config.Schedule.Next(time.Now())
+	timer := time.NewTimer(time.Until(next) + schedule.Jitter(s.config.Jitter))
+	defer timer.Stop()
+	run := make(chan struct{}, 1)
+	if s.config.NoInitialRun {
+		s.log.Log("Skipping the initial detection run, next run at %s", next.Format(time.RFC3339))
+	} else {
+		run <- struct{}{}
+	}
+
+	for {
+		var scanID string
+		select {
+		case <-ctx.Done():
+			return
+		case <-run:
+		case <-timer.C:
+			// Skip the runs missed while the previous run was running
+			now := time.Now()
+			if next = s.config.Schedule.Next(next); next.Before(now) {
+				next = s.config.Schedule.Next(now)
+			}
+			timer.Reset(time.Until(next) + schedule.Jitter(s.config.Jitter))
+		case scanID = <-s.scans.queue:
+		case <-s.reload:
+			if s.hooks.Reload == nil {
+				continue
+			}
+			if err := s.hooks.Reload(); err != nil {
+				s.log.Errorf("reload configuration, keeping the current one: %s\n", err)
+			} else {
+				s.log.Log("Configuration reloaded")
+			}
+			continue
+		}
+		if !s.runDetection(ctx, scanID) {
+			return
+		}
+	}
+}
+
+// runDetection runs the detection, for the scan with the given id if not empty, and serves its results.
+// It returns false if ctx is cancelled.
+func (s *Server) runDetection(ctx context.Context, scanID string) bool {
+	var scope detector.Scope
+	var scopeProgress *detector.Progress
+	if scanID != "" {
+		scope, scopeProgress = s.scans.start(scanID, time.Now())
+		s.log.Log("Detecting Angular dashboards for scan %s", scanID)
+	} else {
+		s.log.Log("Detecting Angular dashboards")
+	}
+	data, err := s.detector.RunScope(ctx, scope, scopeProgress)
+	if scanID != "" {
+		s.scans.finish(scanID, data, err, time.Now())
+	}
+	if s.hooks.AfterRun != nil {
+		s.hooks.AfterRun()
+	}
+	if ctx.Err() != nil {
+		// Shutting down, the interrupted run is resumed from the checkpoint after a restart, if any
+		s.log.Log("Detection run cancelled")
+		return false
+	}
+	if !scope.IsEmpty() {
+		// The results of a scoped scan are partial: they replace the previous results of the dashboards
+		// in the scope, but are not passed to Hooks.Completed nor kept in the history
+		if err != nil {
+			s.log.Errorf("scan %s: %s\n", scanID, err)
+			return true
+		}
+		s.mu.Lock()
+		merged := mergeScopedResults(s.data, data, scope)
+		s.mu.Unlock()
+		output.Sort(merged, s.config.SortBy)
+		s.Publish(ctx, merged)
+		return true
+	}
+	if err == nil {
+		output.Sort(data, s.config.SortBy)
+	}
+	if s.hooks.Completed != nil {
+		s.hooks.Completed(ctx, data, err)
+	}
+	if err != nil {
+		s.log.Errorf("%s\n", err)
+		return true
+	}
+	s.history.add(data, time.Now())
+	s.Publish(ctx, data)
+	return true
+}
+
+// Handler returns the handler of the endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	auth := s.config.Auth
+	mux.HandleFunc("/detections", auth.wrap(s.handleDetectionsRequest))
+	mux.HandleFunc("/folders", auth.wrap(s.handleFoldersRequest))
+	mux.HandleFunc("/summary", auth.wrap(s.handleSummaryRequest))
+	if s.config.KeepRuns > 0 {
+		mux.HandleFunc("/history", auth.wrap(s.handleHistoryRequest))
+		mux.HandleFunc("/diff", auth.wrap(s.handleDiffRequest))
+	}
+	mux.HandleFunc("/ready", s.handleReadyRequest)
+	mux.HandleFunc("/healthz", handleHealthzRequest)
+	mux.HandleFunc("/scan", auth.wrap(s.handleScanRequest))
+	mux.HandleFunc("/scans/", auth.wrap(s.handleScanStatusRequest))
+	mux.HandleFunc("/status", auth.wrap(s.handleStatusRequest))
+	mux.HandleFunc("/-/reload", auth.wrap(s.handleReloadRequest))
+	// The page has no data and is served without authentication, it asks for the token when /detections requires it
+	mux.Handle("/", ui.Handler())
+	return s.config.CORS.wrap(mux)
+}
+
+// writeJSON writes the given value as indented JSON.
+func (s *Server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		s.log.Errorf("http server: %s\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleDetectionsRequest handles the /detections HTTP endpoint, which returns the dashboards with detections,
+// filtered by the query parameters (see parseDetectionsFilter) and paginated (see parsePagination).
+func (s *Server) handleDetectionsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter, err := parseDetectionsFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	angularDashboards := filter.Apply(filterAngularDashboards(s.data))
+	start, end, err := parsePagination(r.URL.Query(), len(angularDashboards))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set(HeaderTotalCount, strconv.Itoa(len(angularDashboards)))
+	if end < len(angularDashboards) {
+		next := *r.URL
+		query := next.Query()
+		query.Set("offset", strconv.Itoa(end))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	s.writeJSON(w, angularDashboards[start:end])
+}
+
+// parseDetectionsFilter returns the filter of the dashboards requested with the "folder", "plugin", "detectionType"
+// and "creator" query parameters. Each parameter can be repeated to match any of its values.
+func parseDetectionsFilter(query url.Values) (output.Filter, error) {
+	filter := output.Filter{
+		Folders:   query["folder"],
+		PluginIDs: query["plugin"],
+		Creators:  query["creator"],
+	}
+	for _, v := range query["detectionType"] {
+		if err := output.ValidateDetectionType(v); err != nil {
+			return output.Filter{}, err
+		}
+		filter.DetectionTypes = append(filter.DetectionTypes, output.DetectionType(v))
+	}
+	return filter, nil
+}
+
+// parsePagination returns the bounds of the page of n items requested with the "limit" and "offset" query parameters.
+// Without limit, all the items after offset are returned, so requests without parameters return all the items.
+func parsePagination(query url.Values, n int) (start, end int, err error) {
+	end = n
+	if v := query.Get("offset"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil || start < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", v)
+		}
+		if start > n {
+			start = n
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", v)
+		}
+		if limit < n-start {
+			end = start + limit
+		}
+	}
+	return start, end, nil
+}
+
+// handleFoldersRequest handles the /folders HTTP endpoint, which returns the folder tree annotated with detection counts.
+func (s *Server) handleFoldersRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.folders == nil {
+		http.Error(w, "Not Ready", http.StatusServiceUnavailable)
+		return
+	}
+	s.writeJSON(w, s.folders)
+}
+
+// handleSummaryRequest handles the /summary HTTP endpoint, which returns the summary of the last detection run.
+func (s *Server) handleSummaryRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.summary == nil {
+		http.Error(w, "Not Ready", http.StatusServiceUnavailable)
+		return
+	}
+	s.writeJSON(w, s.summary)
+}
+
+// handleStatusRequest handles the /status HTTP endpoint, returning the progress of the running scan, or of the last one.
+func (s *Server) handleStatusRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, s.config.Progress.Status(time.Now()))
+}
+
+// handleReadyRequest handles the /ready HTTP endpoint, the readiness probe: ready once results are served.
+func (s *Server) handleReadyRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.ready.Load() {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Ready"))
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("Not Ready"))
+	}
+}
+
+// handleReloadRequest handles the /-/reload HTTP endpoint, which reloads the configuration files like SIGHUP.
+// The reload is asynchronous: it happens after the in-flight detection run, if any, and its result is logged.
+func (s *Server) handleReloadRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.RequestReload()
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("Reload requested"))
+}
+
+// handleHealthzRequest handles the /healthz HTTP endpoint, the liveness probe. Unlike /ready, it doesn't depend on
+// the detection runs: it succeeds as long as the process serves HTTP requests, even before the first run completes
+// or when runs fail (e.g.: Grafana is unreachable), so the process isn't restarted for errors a restart doesn't fix.
+func handleHealthzRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// filterAngularDashboards filters dashboards to include only those with findings.
+func filterAngularDashboards(dashboards []output.Dashboard) []output.Dashboard {
+	var angularDashboards []output.Dashboard
+	for _, dashboard := range dashboards {
+		if dashboard.HasFindings() {
+			angularDashboards = append(angularDashboards, dashboard)
+		}
+	}
+	return angularDashboards
+}
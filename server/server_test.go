@@ -0,0 +1,334 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/detect-angular-dashboards/detector"
+	"github.com/grafana/detect-angular-dashboards/logger"
+	"github.com/grafana/detect-angular-dashboards/output"
+	"github.com/grafana/detect-angular-dashboards/schedule"
+)
+
+// testDetector is a Detector returning the results of run.
+type testDetector struct {
+	run func(ctx context.Context, scope detector.Scope) ([]output.Dashboard, error)
+}
+
+func (d testDetector) RunScope(ctx context.Context, scope detector.Scope, _ *detector.Progress) ([]output.Dashboard, error) {
+	return d.run(ctx, scope)
+}
+
+func (d testDetector) FolderTree(_ context.Context, data []output.Dashboard) *output.Folder {
+	return &output.Folder{Title: "General", Dashboards: len(data)}
+}
+
+// newTestServer returns a Server with the given Detector and Config, running the detection once a day.
+func newTestServer(d Detector, config Config, hooks Hooks) *Server {
+	if config.Schedule == nil {
+		config.Schedule = schedule.Every(24 * time.Hour)
+	}
+	return New(logger.NewLeveledLogger(false), d, config, hooks)
+}
+
+// get sends a GET request to the given handler, and returns the response.
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+// testDashboards are dashboards with and without detections.
+var testDashboards = []output.Dashboard{
+	{UID: "a", Folder: "Team A", Detections: []output.Detection{{PluginID: "graph", DetectionType: output.DetectionTypePanel}}},
+	{UID: "b", Folder: "Team B", Detections: []output.Detection{{PluginID: "worldmap", DetectionType: output.DetectionTypePanel}}},
+	{UID: "c", Folder: "Team B", Detections: []output.Detection{{PluginID: "graph", DetectionType: output.DetectionTypeDatasource}}},
+	{UID: "clean", Folder: "Team A"},
+}
+
+func TestServer(t *testing.T) {
+	t.Run("not ready", func(t *testing.T) {
+		h := newTestServer(testDetector{}, Config{}, Hooks{}).Handler()
+		require.Equal(t, http.StatusServiceUnavailable, get(t, h, "/ready").Code)
+		require.Equal(t, http.StatusServiceUnavailable, get(t, h, "/summary").Code)
+		require.Equal(t, http.StatusServiceUnavailable, get(t, h, "/folders").Code)
+		require.Equal(t, http.StatusOK, get(t, h, "/healthz").Code)
+	})
+
+	t.Run("published", func(t *testing.T) {
+		srv := newTestServer(testDetector{}, Config{}, Hooks{})
+		srv.Publish(context.Background(), testDashboards)
+		h := srv.Handler()
+		require.Equal(t, http.StatusOK, get(t, h, "/ready").Code)
+
+		rec := get(t, h, "/summary")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var summary output.Summary
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+		require.Equal(t, 4, summary.Dashboards)
+		require.Equal(t, 3, summary.DashboardsWithDetections)
+
+		rec = get(t, h, "/folders")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var folder output.Folder
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &folder))
+		require.Equal(t, output.Folder{Title: "General", Dashboards: 4}, folder)
+	})
+
+	t.Run("detections", func(t *testing.T) {
+		srv := newTestServer(testDetector{}, Config{}, Hooks{})
+		srv.Publish(context.Background(), testDashboards)
+		h := srv.Handler()
+		uids := func(rec *httptest.ResponseRecorder) []string {
+			var dashboards []output.Dashboard
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &dashboards))
+			var out []string
+			for _, dashboard := range dashboards {
+				out = append(out, dashboard.UID)
+			}
+			return out
+		}
+
+		rec := get(t, h, "/detections")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, []string{"a", "b", "c"}, uids(rec))
+		require.Equal(t, "3", rec.Header().Get(HeaderTotalCount))
+		require.Empty(t, rec.Header().Get("Link"))
+
+		rec = get(t, h, "/detections?limit=2")
+		require.Equal(t, []string{"a", "b"}, uids(rec))
+		require.Equal(t, "3", rec.Header().Get(HeaderTotalCount))
+		require.Equal(t, `</detections?limit=2&offset=2>; rel="next"`, rec.Header().Get("Link"))
+
+		rec = get(t, h, "/detections?plugin=graph&detectionType=panel")
+		require.Equal(t, []string{"a"}, uids(rec))
+		require.Equal(t, "1", rec.Header().Get(HeaderTotalCount))
+
+		require.Equal(t, http.StatusBadRequest, get(t, h, "/detections?detectionType=nope").Code)
+		require.Equal(t, http.StatusBadRequest, get(t, h, "/detections?limit=0").Code)
+	})
+
+	t.Run("methods", func(t *testing.T) {
+		h := newTestServer(testDetector{}, Config{}, Hooks{}).Handler()
+		for _, path := range []string{"/detections", "/folders", "/summary", "/status", "/scan", "/-/reload"} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, nil))
+			require.Equal(t, http.StatusMethodNotAllowed, rec.Code, path)
+		}
+	})
+}
+
+func TestRunDetections(t *testing.T) {
+	t.Run("complete runs", func(t *testing.T) {
+		completed := make(chan error, 1)
+		var afterRuns int
+		few, many := 1, 10
+		srv := newTestServer(testDetector{run: func(context.Context, detector.Scope) ([]output.Dashboard, error) {
+			return []output.Dashboard{{UID: "b", Views: &few}, {UID: "a", Views: &many}}, nil
+		}}, Config{KeepRuns: 2, SortBy: output.SortByViews}, Hooks{
+			AfterRun: func() { afterRuns++ },
+			Completed: func(_ context.Context, data []output.Dashboard, err error) {
+				// The results are sorted before the hook
+				require.Equal(t, "a", data[0].UID)
+				completed <- err
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go srv.RunDetections(ctx)
+
+		require.NoError(t, <-completed)
+		require.Eventually(t, func() bool { return srv.ready.Load() }, time.Second, 10*time.Millisecond)
+		require.Equal(t, 1, afterRuns)
+		require.Len(t, srv.history.list(), 1)
+	})
+
+	t.Run("failed run", func(t *testing.T) {
+		completed := make(chan error, 1)
+		srv := newTestServer(testDetector{run: func(context.Context, detector.Scope) ([]output.Dashboard, error) {
+			return nil, errors.New("boom")
+		}}, Config{KeepRuns: 2}, Hooks{
+			Completed: func(_ context.Context, _ []output.Dashboard, err error) { completed <- err },
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go srv.RunDetections(ctx)
+
+		require.EqualError(t, <-completed, "boom")
+		require.False(t, srv.ready.Load())
+		require.Empty(t, srv.history.list())
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		running := make(chan struct{})
+		cancelled := make(chan error, 1)
+		srv := newTestServer(testDetector{run: func(ctx context.Context, _ detector.Scope) ([]output.Dashboard, error) {
+			close(running)
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		}}, Config{}, Hooks{
+			Completed: func(context.Context, []output.Dashboard, error) {
+				t.Error("cancelled runs are not completed")
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			srv.RunDetections(ctx)
+		}()
+
+		<-running
+		cancel()
+		require.ErrorIs(t, <-cancelled, context.Canceled)
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("RunDetections did not return after the cancellation")
+		}
+	})
+
+	t.Run("listen and serve", func(t *testing.T) {
+		srv := newTestServer(testDetector{}, Config{NoInitialRun: true}, Hooks{})
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() { errs <- srv.ListenAndServe(ctx, "127.0.0.1:0") }()
+		cancel()
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(shutdownTimeout):
+			t.Fatal("ListenAndServe did not return after the cancellation")
+		}
+	})
+
+	t.Run("scoped scan", func(t *testing.T) {
+		scopes := make(chan detector.Scope, 2)
+		srv := newTestServer(testDetector{run: func(_ context.Context, scope detector.Scope) ([]output.Dashboard, error) {
+			defer func() { scopes <- scope }()
+			if scope.IsEmpty() {
+				return testDashboards, nil
+			}
+			return []output.Dashboard{{UID: "a", Folder: "Team A"}}, nil
+		}}, Config{KeepRuns: 2}, Hooks{
+			Completed: func(_ context.Context, data []output.Dashboard, _ error) {
+				require.Len(t, data, len(testDashboards), "the scoped scans are not completed")
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go srv.RunDetections(ctx)
+		require.True(t, (<-scopes).IsEmpty())
+		h := srv.Handler()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/scan?uid=a", nil))
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var scan Scan
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+		require.Equal(t, "/scans/"+scan.ID, rec.Header().Get("Location"))
+		require.Equal(t, detector.Scope{DashboardUIDs: []string{"a"}}, <-scopes)
+
+		require.Eventually(t, func() bool {
+			scan, _ := srv.scans.get(scan.ID)
+			return scan.Status == scanStatusCompleted
+		}, time.Second, 10*time.Millisecond)
+		rec = get(t, h, "/scans/"+scan.ID)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+		require.Equal(t, scanStatusCompleted, scan.Status)
+		require.Equal(t, http.StatusNotFound, get(t, h, "/scans/missing").Code)
+
+		// The results of the dashboard are replaced, and the scan is not kept in the history
+		require.Eventually(t, func() bool {
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			return len(filterAngularDashboards(srv.data)) == 2
+		}, time.Second, 10*time.Millisecond)
+		require.Len(t, srv.history.list(), 1)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		reloaded := make(chan struct{}, 1)
+		srv := newTestServer(testDetector{}, Config{NoInitialRun: true}, Hooks{
+			Reload: func() error {
+				reloaded <- struct{}{}
+				return nil
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go srv.RunDetections(ctx)
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second):
+			t.Fatal("the configuration was not reloaded")
+		}
+	})
+}
+
+func TestParsePagination(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		n        int
+		expStart int
+		expEnd   int
+		expErr   string
+	}{
+		{query: "", n: 10, expStart: 0, expEnd: 10},
+		{query: "limit=3", n: 10, expStart: 0, expEnd: 3},
+		{query: "limit=3&offset=8", n: 10, expStart: 8, expEnd: 10},
+		{query: "offset=4", n: 10, expStart: 4, expEnd: 10},
+		{query: "offset=20", n: 10, expStart: 10, expEnd: 10},
+		{query: "limit=0", n: 10, expErr: `invalid limit "0"`},
+		{query: "limit=x", n: 10, expErr: `invalid limit "x"`},
+		{query: "offset=-1", n: 10, expErr: `invalid offset "-1"`},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+			start, end, err := parsePagination(query, tc.n)
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expStart, start)
+			require.Equal(t, tc.expEnd, end)
+		})
+	}
+}
+
+func TestParseDetectionsFilter(t *testing.T) {
+	query, err := url.ParseQuery("folder=Team+A&folder=b&plugin=graph&detectionType=panel&detectionType=datasource&creator=admin")
+	require.NoError(t, err)
+	filter, err := parseDetectionsFilter(query)
+	require.NoError(t, err)
+	require.Equal(t, output.Filter{
+		Folders:        []string{"Team A", "b"},
+		PluginIDs:      []string{"graph"},
+		DetectionTypes: []output.DetectionType{output.DetectionTypePanel, output.DetectionTypeDatasource},
+		Creators:       []string{"admin"},
+	}, filter)
+
+	filter, err = parseDetectionsFilter(url.Values{})
+	require.NoError(t, err)
+	require.Equal(t, output.Filter{}, filter)
+
+	_, err = parseDetectionsFilter(url.Values{"detectionType": {"nope"}})
+	require.EqualError(t, err, `unsupported detection type "nope"`)
+}
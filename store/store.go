@@ -0,0 +1,212 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/audit"
+	"github.com/grafana/detect-angular-dashboards/output"
+)
+
+// Run is a detection run recorded in the trend file.
+type Run struct {
+	ID int64
+
+	// Time is the time the run has been recorded, as RFC3339 in UTC.
+	Time string
+
+	audit.Counts
+}
+
+// Delta is the difference between the dashboards with detections of a run and of the previous run.
+type Delta struct {
+	// Previous is the previous run, nil for the first run.
+	Previous *Run
+
+	// ReportDiff is the difference between the detections of the run and of the previous run (see output.Diff),
+	// so a new Angular panel in a dashboard that already had detections is a change.
+	// The dashboards of the previous run only have the fields recorded in the trend file (see recordedDashboard).
+	output.ReportDiff
+}
+
+// record is a line of the trend file: a run, with its dashboards with detections.
+// They are not named Dashboards, which would hide the count of dashboards of the run in JSON.
+type record struct {
+	Run
+	DetectedDashboards []recordedDashboard
+}
+
+// recordedDashboard is a dashboard with detections of a recorded run, with what identifies it and its detections.
+type recordedDashboard struct {
+	Instance   string `json:",omitempty"`
+	UID        string
+	URL        string
+	Title      string
+	Detections []recordedDetection
+}
+
+// recordedDetection is a detection of a recorded dashboard.
+type recordedDetection struct {
+	PluginID      string
+	DetectionType output.DetectionType
+	Title         string
+	PanelID       int `json:",omitempty"`
+}
+
+// newRecordedDashboard returns the recordedDashboard of the given dashboard.
+func newRecordedDashboard(dashboard output.Dashboard) recordedDashboard {
+	out := recordedDashboard{
+		Instance:   dashboard.Instance,
+		UID:        dashboard.UID,
+		URL:        dashboard.URL,
+		Title:      dashboard.Title,
+		Detections: make([]recordedDetection, 0, len(dashboard.Detections)),
+	}
+	for _, detection := range dashboard.Detections {
+		out.Detections = append(out.Detections, recordedDetection{
+			PluginID:      detection.PluginID,
+			DetectionType: detection.DetectionType,
+			Title:         detection.Title,
+			PanelID:       detection.PanelID,
+		})
+	}
+	return out
+}
+
+// dashboard returns the output.Dashboard of the recorded dashboard, with only the recorded fields.
+func (r recordedDashboard) dashboard() output.Dashboard {
+	out := output.Dashboard{
+		Instance:   r.Instance,
+		UID:        r.UID,
+		URL:        r.URL,
+		Title:      r.Title,
+		Detections: make([]output.Detection, 0, len(r.Detections)),
+	}
+	for _, detection := range r.Detections {
+		out.Detections = append(out.Detections, output.Detection{
+			PluginID:      detection.PluginID,
+			DetectionType: detection.DetectionType,
+			Title:         detection.Title,
+			PanelID:       detection.PanelID,
+		})
+	}
+	return out
+}
+
+// Store records the results of the detection runs in a trend file, to track the progress of the migration.
+// The trend file has a JSON record per run, with the detections of its dashboards, so it needs no database
+// server or driver, and runs are only ever appended to it.
+type Store struct {
+	mu   sync.Mutex
+	file *os.File
+	now  func() time.Time
+}
+
+// Open opens the trend file with the given name, creating it if needed.
+func Open(fn string) (*Store, error) {
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{file: f, now: time.Now}
+	// Fail early if the file is not a trend file
+	if _, err := s.records(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the trend file.
+func (s *Store) Close() error {
+	return s.file.Close()
+}
+
+// RecordRun records the given results of a detection run, and returns the difference with the previous run.
+// The runs are assumed to scan the same dashboards, so runs with filters or time budgets can't be compared.
+func (s *Store) RecordRun(ctx context.Context, dashboards []output.Dashboard) (*Delta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.records()
+	if err != nil {
+		return nil, fmt.Errorf("get previous run: %w", err)
+	}
+	var delta Delta
+	var previous []output.Dashboard
+	rec := record{Run: Run{ID: 1, Time: s.now().UTC().Format(time.RFC3339), Counts: *audit.CountDashboards(dashboards)}}
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		delta.Previous = &last.Run
+		rec.ID = last.ID + 1
+		for _, dashboard := range last.DetectedDashboards {
+			previous = append(previous, dashboard.dashboard())
+		}
+	}
+	for _, dashboard := range dashboards {
+		if len(dashboard.Detections) > 0 {
+			rec.DetectedDashboards = append(rec.DetectedDashboards, newRecordedDashboard(dashboard))
+		}
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return nil, fmt.Errorf("write run: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return nil, fmt.Errorf("write run: %w", err)
+	}
+	delta.ReportDiff = output.Diff(previous, dashboards)
+	return &delta, nil
+}
+
+// Runs returns all the recorded runs, from the oldest to the newest.
+func (s *Store) Runs(ctx context.Context) ([]Run, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.records()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Run, 0, len(records))
+	for _, rec := range records {
+		out = append(out, rec.Run)
+	}
+	return out, nil
+}
+
+// records reads all the records of the trend file, from the oldest to the newest.
+func (s *Store) records() ([]record, error) {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var out []record
+	scanner := bufio.NewScanner(s.file)
+	// A record has all the dashboards with detections
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", s.file.Name(), line, err)
+		}
+		out = append(out, rec)
+	}
+	return out, scanner.Err()
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/detect-angular-dashboards/output"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "detections.jsonl")
+	s, err := Open(fn)
+	require.NoError(t, err)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(24 * time.Hour)
+		return now
+	}
+
+	graph := []output.Detection{{PluginID: "graph", DetectionType: output.DetectionTypeLegacyPanel, Title: "Requests", PanelID: 1}}
+	worldmap := []output.Detection{{PluginID: "grafana-worldmap-panel", DetectionType: output.DetectionTypePanel, Title: "Map", PanelID: 2}}
+	keys := func(dashboards []output.Dashboard) []string {
+		var out []string
+		for _, dashboard := range dashboards {
+			out = append(out, output.ReportKey(dashboard))
+		}
+		return out
+	}
+	delta, err := s.RecordRun(context.Background(), []output.Dashboard{
+		{UID: "a", URL: "/d/a", Detections: graph},
+		{UID: "b", URL: "/d/b", Detections: graph},
+		{UID: "c", URL: "/d/c", Detections: []output.Detection{}},
+	})
+	require.NoError(t, err)
+	require.Nil(t, delta.Previous)
+	require.Equal(t, []string{"a", "b"}, keys(delta.New))
+	require.NoError(t, s.Close())
+
+	// The runs are kept when the trend file is reopened
+	s, err = Open(fn)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+	s.now = func() time.Time { return now.Add(24 * time.Hour) }
+	delta, err = s.RecordRun(context.Background(), []output.Dashboard{
+		{UID: "a", URL: "/d/a", Detections: []output.Detection{}},
+		{UID: "b", URL: "/d/b-renamed", Detections: append(graph, worldmap...)},
+		{UID: "c", URL: "/d/c", Detections: graph},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, delta.Previous)
+	require.Equal(t, "2024-03-02T12:00:00Z", delta.Previous.Time)
+	require.Equal(t, []string{"c"}, keys(delta.New))
+	require.Equal(t, []string{"a"}, keys(delta.Resolved))
+	// The detections of the previous run are recorded, so a new panel in a dashboard that had detections is a change
+	require.Equal(t, []string{"b"}, keys(delta.Changed))
+	require.Equal(t, []output.Dashboard{{UID: "b", URL: "/d/b-renamed", Detections: worldmap}}, delta.AddedDetections[:1])
+	require.Equal(t, graph, delta.Resolved[0].Detections)
+
+	runs, err := s.Runs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, int64(2), runs[1].ID)
+	require.Equal(t, "2024-03-03T12:00:00Z", runs[1].Time)
+	require.Equal(t, 3, runs[1].Dashboards)
+	require.Equal(t, 2, runs[1].DashboardsWithDetections)
+	require.Equal(t, 3, runs[1].Detections)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "detections.jsonl")
+	require.NoError(t, os.WriteFile(fn, []byte("SQLite format 3\x00"), 0o600))
+	_, err := Open(fn)
+	require.ErrorContains(t, err, "line 1")
+}
@@ -0,0 +1,34 @@
+// Package ui provides the web UI of the server mode, to browse the dashboards with detections.
+package ui
+
+import (
+	"bytes"
+	_ "embed"
+	"net/http"
+	"time"
+)
+
+// index is the single page of the UI. It fetches the detections from the /detections endpoint of the server.
+//
+//go:embed index.html
+var index []byte
+
+// modTime is the time the UI was loaded, used for the Last-Modified header of the page.
+var modTime = time.Now()
+
+// Handler returns the http.Handler serving the UI at "/". Other paths are not found, since "/" matches all the paths
+// that no other handler matches.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, r, "index.html", modTime, bytes.NewReader(index))
+	})
+}
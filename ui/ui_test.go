@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		method    string
+		path      string
+		expStatus int
+	}{
+		{name: "index", method: http.MethodGet, path: "/", expStatus: http.StatusOK},
+		{name: "head", method: http.MethodHead, path: "/", expStatus: http.StatusOK},
+		{name: "other path", method: http.MethodGet, path: "/unknown", expStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, path: "/", expStatus: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler().ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			require.Equal(t, tc.expStatus, rec.Code)
+			if tc.expStatus != http.StatusOK {
+				return
+			}
+			require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			if tc.method == http.MethodGet {
+				require.Contains(t, rec.Body.String(), `fetch("detections"`)
+			}
+		})
+	}
+}
+
+// TestScript runs the functions of the script of the page with Node.js, without a browser, on the detections
+// of testdata/detections.json.
+func TestScript(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+	page := string(index)
+	start, end := strings.Index(page, "<script>"), strings.Index(page, "</script>")
+	require.True(t, start >= 0 && end > start)
+	script := page[start+len("<script>") : end]
+
+	// A minimal DOM, rendering the elements as text
+	const dom = `
+globalThis.document = {
+  createElement: (tag) => ({children: [], setAttribute() {}, append(...c) { this.children.push(...c); }}),
+  createTextNode: (text) => text,
+};
+const text = (e) => typeof e === "string" ? e : e.children.map(text).join("");
+`
+	const test = `
+const data = JSON.parse(require("fs").readFileSync("testdata/detections.json", "utf8"));
+console.log(JSON.stringify(pluginCounts(data)));
+data.forEach((d) => console.log(text(dashboardRow(d))));
+console.log(data.filter((d) => matches(d, "", new Set(["graph"]))).map((d) => d.UID).join(","));
+console.log(data.filter((d) => matches(d, "/d/a/a", new Set())).map((d) => d.UID).join(","));
+`
+	fn := filepath.Join(t.TempDir(), "test.js")
+	require.NoError(t, os.WriteFile(fn, []byte(dom+script+test), 0o600))
+	out, err := exec.Command(node, fn).CombinedOutput()
+	require.NoError(t, err, string(out))
+	require.Equal(t, `{"graph":1,"grafana-worldmap-panel":1}
+ATeamRequests graph · legacyPanelMap grafana-worldmap-panel · paneladmin
+EGenerallinks to /d/a/a
+a
+e
+`, string(out))
+}